@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/chaitin/panda-wiki/domain"
+)
+
+type EvalCaseReq struct {
+	Question        string   `json:"question" validate:"required"`
+	ExpectedNodeIDs []string `json:"expected_node_ids" validate:"required,min=1"`
+}
+
+type EvalSetListReq struct {
+	KbId string `json:"kb_id" query:"kb_id" validate:"required"`
+}
+
+type EvalSetListItemResp struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CaseCount   int       `json:"case_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type EvalSetDetailReq struct {
+	KbId string `json:"kb_id" query:"kb_id" validate:"required"`
+	ID   string `json:"id" query:"id" validate:"required"`
+}
+
+type EvalSetDetailResp struct {
+	domain.EvalSet
+	Cases []*domain.EvalCase `json:"cases"`
+}
+
+type CreateEvalSetReq struct {
+	KbId        string        `json:"kb_id" validate:"required"`
+	Name        string        `json:"name" validate:"required"`
+	Description string        `json:"description"`
+	Cases       []EvalCaseReq `json:"cases" validate:"max=500,dive"`
+}
+
+// UpdateEvalSetReq replaces the name, description and cases of the set
+type UpdateEvalSetReq struct {
+	KbId        string        `json:"kb_id" validate:"required"`
+	ID          string        `json:"id" validate:"required"`
+	Name        string        `json:"name" validate:"required"`
+	Description string        `json:"description"`
+	Cases       []EvalCaseReq `json:"cases" validate:"max=500,dive"`
+}
+
+type DeleteEvalSetReq struct {
+	KbId string `json:"kb_id" query:"kb_id" validate:"required"`
+	ID   string `json:"id" query:"id" validate:"required"`
+}
+
+type CreateEvalRunReq struct {
+	KbId  string `json:"kb_id" validate:"required"`
+	SetID string `json:"set_id" validate:"required"`
+	K     int    `json:"k" validate:"omitempty,min=1,max=50"` // defaults to 10
+	Note  string `json:"note"`
+}
+
+type EvalRunListReq struct {
+	KbId  string `json:"kb_id" query:"kb_id" validate:"required"`
+	SetID string `json:"set_id" query:"set_id" validate:"required"`
+}
+
+type EvalRunListItemResp struct {
+	ID        string                 `json:"id"`
+	SetID     string                 `json:"set_id"`
+	K         int                    `json:"k"`
+	CaseCount int                    `json:"case_count"`
+	Recall    float64                `json:"recall"`
+	MRR       float64                `json:"mrr"`
+	Note      string                 `json:"note"`
+	Settings  domain.EvalRunSettings `json:"settings" gorm:"type:jsonb"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+type EvalRunDetailReq struct {
+	KbId string `json:"kb_id" query:"kb_id" validate:"required"`
+	ID   string `json:"id" query:"id" validate:"required"`
+}
@@ -0,0 +1,28 @@
+package v1
+
+import (
+	"github.com/chaitin/panda-wiki/domain"
+)
+
+type FailedTaskListReq struct {
+	KBId   string                  `json:"kb_id" query:"kb_id" validate:"required"`
+	Status domain.FailedTaskStatus `json:"status" query:"status" validate:"omitempty,oneof=failed replayed"`
+	domain.Pager
+}
+
+type FailedTaskListResp = domain.PaginatedResult[[]*domain.FailedTask]
+
+type FailedTaskDetailReq struct {
+	KBId string `json:"kb_id" query:"kb_id" validate:"required"`
+	ID   string `json:"id" query:"id" validate:"required"`
+}
+
+type FailedTaskDetailResp struct {
+	*domain.FailedTask
+	Payload string `json:"payload"`
+}
+
+type FailedTaskReplayReq struct {
+	KBId string   `json:"kb_id" validate:"required"`
+	IDs  []string `json:"ids" validate:"required,min=1,max=100"`
+}
@@ -0,0 +1,22 @@
+package v1
+
+import (
+	"github.com/chaitin/panda-wiki/domain"
+)
+
+type HandoffListReq struct {
+	KBId   string               `json:"kb_id" query:"kb_id" validate:"required"`
+	Status domain.HandoffStatus `json:"status" query:"status" validate:"omitempty,oneof=pending processing resolved"`
+	domain.Pager
+}
+
+type HandoffListResp = domain.PaginatedResult[[]*domain.Handoff]
+
+// HandoffUpdateReq picks up or resolves a handoff, the user updating it
+// becomes its assignee
+type HandoffUpdateReq struct {
+	KBId   string               `json:"kb_id" validate:"required"`
+	ID     string               `json:"id" validate:"required"`
+	Status domain.HandoffStatus `json:"status" validate:"required,oneof=pending processing resolved"`
+	Note   string               `json:"note" validate:"max=2000"`
+}
@@ -0,0 +1,44 @@
+package v1
+
+import (
+	"github.com/chaitin/panda-wiki/domain"
+)
+
+type QAPairListReq struct {
+	KBId   string `json:"kb_id" query:"kb_id" validate:"required"`
+	Search string `json:"search" query:"search"`
+	domain.Pager
+}
+
+type QAPairListResp = domain.PaginatedResult[[]*domain.QAPair]
+
+type QAPairCreateReq struct {
+	KBId       string                  `json:"kb_id" validate:"required"`
+	Question   string                  `json:"question" validate:"required"`
+	Answer     string                  `json:"answer" validate:"required"`
+	Keywords   []string                `json:"keywords" validate:"required_if=MatchType keyword,max=20"`
+	MatchType  domain.QAPairMatchType  `json:"match_type" validate:"required,oneof=exact keyword semantic"`
+	AnswerMode domain.QAPairAnswerMode `json:"answer_mode" validate:"required,oneof=verbatim context"`
+}
+
+type QAPairUpdateReq struct {
+	ID string `json:"id" validate:"required"`
+	QAPairCreateReq
+}
+
+type QAPairDeleteReq struct {
+	KBId string `json:"kb_id" query:"kb_id" validate:"required"`
+	ID   string `json:"id" query:"id" validate:"required"`
+}
+
+// QAPairPromoteReq turns an assistant message into a pair, the question
+// defaults to the user message it answered and the answer to the message
+type QAPairPromoteReq struct {
+	KBId       string                  `json:"kb_id" validate:"required"`
+	MessageID  string                  `json:"message_id" validate:"required"`
+	Question   string                  `json:"question"`
+	Answer     string                  `json:"answer"`
+	Keywords   []string                `json:"keywords" validate:"required_if=MatchType keyword,max=20"`
+	MatchType  domain.QAPairMatchType  `json:"match_type" validate:"required,oneof=exact keyword semantic"`
+	AnswerMode domain.QAPairAnswerMode `json:"answer_mode" validate:"required,oneof=verbatim context"`
+}
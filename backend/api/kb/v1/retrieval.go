@@ -0,0 +1,56 @@
+package v1
+
+import "github.com/chaitin/panda-wiki/domain"
+
+type RetrievalDebugReq struct {
+	KBId     string `json:"kb_id" validate:"required"`
+	Question string `json:"question" validate:"required"`
+	// nil skips permission filtering, an empty list is a user without groups
+	AuthGroupIDs []int `json:"auth_group_ids"`
+	// signed in user the federated kbs are searched as, 0 is an anonymous user
+	AuthUserID uint                    `json:"auth_user_id"`
+	Tags       []string                `json:"tags" validate:"max=20"`
+	AppType    domain.AppType          `json:"app_type"` // retrieval settings of the app, defaults to web
+	History    []RetrievalDebugMessage `json:"history" validate:"max=50,dive"`
+}
+
+type RetrievalDebugMessage struct {
+	Role    string `json:"role" validate:"required,oneof=user assistant system"`
+	Content string `json:"content"`
+}
+
+type RetrievalDebugChunk struct {
+	Source   string   `json:"source"` // vector or keyword
+	ChunkID  string   `json:"chunk_id"`
+	DocID    string   `json:"doc_id"`
+	NodeID   string   `json:"node_id"`
+	NodeName string   `json:"node_name"`
+	Seq      uint     `json:"seq"`
+	Content  string   `json:"content"`
+	Score    float64  `json:"score"` // similarity for vector, ts_rank for keyword
+	Selected bool     `json:"selected"`
+	Rerank   *float64 `json:"rerank_score,omitempty"`
+}
+
+type RetrievalDebugDoc struct {
+	NodeID      string   `json:"node_id"`
+	NodeName    string   `json:"node_name"`
+	FusionScore float64  `json:"fusion_score"`
+	RerankScore *float64 `json:"rerank_score,omitempty"`
+	ChunkCount  int      `json:"chunk_count"`
+}
+
+type RetrievalDebugResp struct {
+	RewrittenQuery string                   `json:"rewritten_query"`
+	Settings       domain.RetrievalSettings `json:"settings"`
+	// every candidate chunk of the vector and keyword legs
+	Candidates []*RetrievalDebugChunk `json:"candidates"`
+	// candidates only retrieved when permission filtering is skipped
+	Dropped []*RetrievalDebugChunk `json:"dropped"`
+	// chunks left out of the prompt by the context limit of the chat models
+	BudgetDropped []*RetrievalDebugChunk `json:"budget_dropped"`
+	// documents given to the model in rank order
+	Documents []*RetrievalDebugDoc     `json:"documents"`
+	Prompt    []*RetrievalDebugMessage `json:"prompt"`
+	Citations []domain.ChunkCitation   `json:"citations"`
+}
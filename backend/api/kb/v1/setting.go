@@ -0,0 +1,46 @@
+package v1
+
+type GetRankFusionSettingReq struct {
+	KBId string `json:"kb_id" query:"kb_id" validate:"required"`
+}
+
+type RankFusionSettingResp struct {
+	VectorWeight  float64 `json:"vector_weight"`
+	KeywordWeight float64 `json:"keyword_weight"`
+}
+
+type UpdateRankFusionSettingReq struct {
+	KBId          string  `json:"kb_id" validate:"required"`
+	VectorWeight  float64 `json:"vector_weight" validate:"gte=0,lte=10"`
+	KeywordWeight float64 `json:"keyword_weight" validate:"gte=0,lte=10"`
+}
+
+type GetRerankSettingReq struct {
+	KBId string `json:"kb_id" query:"kb_id" validate:"required"`
+}
+
+type RerankSettingResp struct {
+	Enabled bool `json:"enabled"`
+	TopN    int  `json:"top_n"`
+}
+
+type UpdateRerankSettingReq struct {
+	KBId    string `json:"kb_id" validate:"required"`
+	Enabled bool   `json:"enabled"`
+	TopN    int    `json:"top_n" validate:"gte=1,lte=50"`
+}
+
+type GetChatModelSettingReq struct {
+	KBId string `json:"kb_id" query:"kb_id" validate:"required"`
+}
+
+type ChatModelSettingResp struct {
+	ModelID          string   `json:"model_id"`
+	FallbackModelIDs []string `json:"fallback_model_ids"`
+}
+
+type UpdateChatModelSettingReq struct {
+	KBId             string   `json:"kb_id" validate:"required"`
+	ModelID          string   `json:"model_id"`
+	FallbackModelIDs []string `json:"fallback_model_ids" validate:"max=5"`
+}
@@ -0,0 +1,33 @@
+package v1
+
+import (
+	"github.com/chaitin/panda-wiki/domain"
+)
+
+type ReindexJobDetailReq struct {
+	ID string `json:"id" query:"id"` // empty for the latest job
+}
+
+type ReindexJobActionReq struct {
+	ID string `json:"id" validate:"required"`
+}
+
+type ReindexJobUpdateReq struct {
+	ID        string `json:"id" validate:"required"`
+	RateLimit int    `json:"rate_limit" validate:"required,min=1,max=1000"` // nodes dispatched per second
+}
+
+type ReindexJobKBResp struct {
+	*domain.ReindexJobKB
+	KBName   string `json:"kb_name"`
+	Finished bool   `json:"finished"`
+}
+
+type ReindexJobResp struct {
+	*domain.ReindexJob
+	Total      int                 `json:"total"`
+	Dispatched int                 `json:"dispatched"`
+	Succeeded  int                 `json:"succeeded"`
+	Failed     int                 `json:"failed"`
+	KBs        []*ReindexJobKBResp `json:"kbs"`
+}
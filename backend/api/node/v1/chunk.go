@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"github.com/chaitin/panda-wiki/domain"
+)
+
+type NodeChunkListReq struct {
+	KbId   string `json:"kb_id" query:"kb_id" validate:"required"`
+	NodeId string `json:"node_id" query:"node_id" validate:"required"`
+}
+
+type NodeChunkListResp struct {
+	Chunks    []*domain.ChunkListItemResp `json:"chunks"`
+	Overrides []*domain.NodeChunkOverride `json:"overrides"`
+}
+
+type NodeChunkAddReq struct {
+	KbId    string `json:"kb_id" validate:"required"`
+	NodeId  string `json:"node_id" validate:"required"`
+	Content string `json:"content" validate:"required"`
+}
+
+type NodeChunkUpdateReq struct {
+	KbId    string `json:"kb_id" validate:"required"`
+	NodeId  string `json:"node_id" validate:"required"`
+	ChunkId string `json:"chunk_id" validate:"required"`
+	Content string `json:"content" validate:"required"`
+}
+
+// NodeChunkActionReq pins or excludes a chunk
+type NodeChunkActionReq struct {
+	KbId    string `json:"kb_id" validate:"required"`
+	NodeId  string `json:"node_id" validate:"required"`
+	ChunkId string `json:"chunk_id" validate:"required"`
+}
+
+type NodeChunkOverrideDeleteReq struct {
+	KbId   string `json:"kb_id" query:"kb_id" validate:"required"`
+	NodeId string `json:"node_id" query:"node_id" validate:"required"`
+	ID     string `json:"id" query:"id" validate:"required"`
+}
@@ -32,6 +32,7 @@ type NodeDetailResp struct {
 	EditorAccount    string                 `json:"editor_account"`
 	PublisherAccount string                 `json:"publisher_account" gorm:"-"`
 	PV               int64                  `json:"pv" gorm:"-"`
+	Tags             []string               `json:"tags" gorm:"-"`
 }
 
 type NodePermissionReq struct {
@@ -0,0 +1,33 @@
+package v1
+
+type TagListReq struct {
+	KbId string `query:"kb_id" json:"kb_id" validate:"required"`
+}
+
+type TagListItemResp struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	NodeCount int64  `json:"node_count"`
+}
+
+type CreateTagReq struct {
+	KbId string `json:"kb_id" validate:"required"`
+	Name string `json:"name" validate:"required,max=32"`
+}
+
+type UpdateTagReq struct {
+	KbId string `json:"kb_id" validate:"required"`
+	ID   string `json:"id" validate:"required"`
+	Name string `json:"name" validate:"required,max=32"`
+}
+
+type DeleteTagReq struct {
+	KbId string `query:"kb_id" json:"kb_id" validate:"required"`
+	ID   string `query:"id" json:"id" validate:"required"`
+}
+
+type NodeTagsUpdateReq struct {
+	KbId string   `json:"kb_id" validate:"required"`
+	IDs  []string `json:"ids" validate:"required,min=1"`
+	Tags []string `json:"tags" validate:"max=20,dive,required,max=32"`
+}
@@ -60,7 +60,7 @@ func createApp() (*App, error) {
 	if err != nil {
 		return nil, err
 	}
-	ragService, err := rag.NewRAGService(configConfig, logger)
+	ragService, err := rag.NewRAGService(configConfig, db, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -73,8 +73,18 @@ func createApp() (*App, error) {
 	}
 	ragRepository := mq2.NewRAGRepository(mqProducer)
 	userRepository := pg2.NewUserRepository(db, logger)
+	settingRepo := pg2.NewSettingRepo(db, logger)
+	modelRepository := pg2.NewModelRepository(db, logger)
+	systemSettingRepo := pg2.NewSystemSettingRepo(db, logger)
+	reindexRepository := pg2.NewReindexRepository(db, logger)
+	qaPairRepository := pg2.NewQAPairRepository(db, logger)
+	conversationRepository := pg2.NewConversationRepository(db, logger)
+	answerCacheRepo := cache2.NewAnswerCacheRepo(cacheCache)
+	qaPairUsecase := usecase.NewQAPairUsecase(qaPairRepository, conversationRepository, answerCacheRepo, ragService, logger)
+	reindexUsecase := usecase.NewReindexUsecase(reindexRepository, nodeRepository, knowledgeBaseRepository, ragRepository, qaPairUsecase, logger)
+	modelUsecase := usecase.NewModelUsecase(modelRepository, nodeRepository, ragRepository, ragService, logger, configConfig, knowledgeBaseRepository, systemSettingRepo, settingRepo, reindexUsecase)
 	kbRepo := cache2.NewKBRepo(cacheCache)
-	knowledgeBaseUsecase, err := usecase.NewKnowledgeBaseUsecase(knowledgeBaseRepository, nodeRepository, navRepository, ragRepository, userRepository, ragService, kbRepo, logger, configConfig)
+	knowledgeBaseUsecase, err := usecase.NewKnowledgeBaseUsecase(knowledgeBaseRepository, nodeRepository, navRepository, ragRepository, userRepository, settingRepo, modelUsecase, ragService, kbRepo, answerCacheRepo, logger, configConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -86,21 +96,25 @@ func createApp() (*App, error) {
 		return nil, err
 	}
 	userHandler := v1.NewUserHandler(echo, baseHandler, logger, userUsecase, authMiddleware, configConfig, cacheCache)
-	conversationRepository := pg2.NewConversationRepository(db, logger)
-	modelRepository := pg2.NewModelRepository(db, logger)
 	promptRepo := pg2.NewPromptRepo(db, logger)
-	llmUsecase := usecase.NewLLMUsecase(configConfig, ragService, conversationRepository, knowledgeBaseRepository, nodeRepository, modelRepository, promptRepo, logger)
-	knowledgeBaseHandler := v1.NewKnowledgeBaseHandler(baseHandler, echo, knowledgeBaseUsecase, llmUsecase, authMiddleware, logger)
 	appRepository := pg2.NewAppRepository(db, logger)
+	authRepo := pg2.NewAuthRepo(db, logger, cacheCache)
+	nodeAttachmentRepository := pg2.NewNodeAttachmentRepository(db, logger)
+	llmUsecase := usecase.NewLLMUsecase(configConfig, ragService, conversationRepository, knowledgeBaseRepository, nodeRepository, modelRepository, promptRepo, settingRepo, appRepository, authRepo, nodeAttachmentRepository, modelUsecase, logger)
+	failedTaskRepository := pg2.NewFailedTaskRepository(db, logger)
+	failedTaskUsecase := usecase.NewFailedTaskUsecase(failedTaskRepository, ragRepository, reindexUsecase, logger)
+	handoffRepository := pg2.NewHandoffRepository(db, logger)
+	handoffUsecase := usecase.NewHandoffUsecase(handoffRepository, logger)
+	knowledgeBaseHandler := v1.NewKnowledgeBaseHandler(baseHandler, echo, knowledgeBaseUsecase, llmUsecase, failedTaskUsecase, qaPairUsecase, handoffUsecase, authMiddleware, logger)
+	tagRepository := pg2.NewTagRepository(db, logger)
 	minioClient, err := s3.NewMinioClient(configConfig)
 	if err != nil {
 		return nil, err
 	}
-	authRepo := pg2.NewAuthRepo(db, logger, cacheCache)
-	systemSettingRepo := pg2.NewSystemSettingRepo(db, logger)
-	modelUsecase := usecase.NewModelUsecase(modelRepository, nodeRepository, ragRepository, ragService, logger, configConfig, knowledgeBaseRepository, systemSettingRepo)
-	nodeUsecase := usecase.NewNodeUsecase(nodeRepository, navRepository, appRepository, ragRepository, userRepository, knowledgeBaseRepository, llmUsecase, ragService, logger, minioClient, modelRepository, authRepo, modelUsecase)
-	nodeHandler := v1.NewNodeHandler(baseHandler, echo, nodeUsecase, authMiddleware, logger)
+	nodeUsecase := usecase.NewNodeUsecase(nodeRepository, navRepository, tagRepository, appRepository, ragRepository, userRepository, knowledgeBaseRepository, llmUsecase, ragService, logger, minioClient, modelRepository, authRepo, modelUsecase)
+	nodeChunkRepository := pg2.NewNodeChunkRepository(db, logger)
+	nodeChunkUsecase := usecase.NewNodeChunkUsecase(nodeRepository, knowledgeBaseRepository, nodeChunkRepository, ragRepository, ragService, logger)
+	nodeHandler := v1.NewNodeHandler(baseHandler, echo, nodeUsecase, nodeChunkUsecase, authMiddleware, logger)
 	geoRepo := cache2.NewGeoCache(cacheCache, db, logger)
 	ipdbIPDB, err := ipdb.NewIPDB(configConfig, logger)
 	if err != nil {
@@ -108,16 +122,18 @@ func createApp() (*App, error) {
 	}
 	ipAddressRepo := ipdb2.NewIPAddressRepo(ipdbIPDB, logger)
 	conversationUsecase := usecase.NewConversationUsecase(conversationRepository, nodeRepository, geoRepo, logger, ipAddressRepo, authRepo)
+	answerCacheUsecase := usecase.NewAnswerCacheUsecase(ragService, knowledgeBaseRepository, conversationRepository, answerCacheRepo, logger)
 	blockWordRepo := pg2.NewBlockWordRepo(db, logger)
-	chatUsecase, err := usecase.NewChatUsecase(llmUsecase, knowledgeBaseRepository, conversationUsecase, modelUsecase, appRepository, blockWordRepo, nodeRepository, authRepo, logger)
+	chatQuotaRepo := cache2.NewChatQuotaRepo(cacheCache)
+	chatUsecase, err := usecase.NewChatUsecase(llmUsecase, knowledgeBaseRepository, conversationUsecase, modelUsecase, answerCacheUsecase, qaPairUsecase, handoffUsecase, appRepository, blockWordRepo, nodeRepository, tagRepository, authRepo, chatQuotaRepo, logger)
 	if err != nil {
 		return nil, err
 	}
-	appUsecase := usecase.NewAppUsecase(appRepository, authRepo, navRepository, nodeRepository, knowledgeBaseRepository, nodeUsecase, logger, configConfig, chatUsecase, cacheCache)
+	appUsecase := usecase.NewAppUsecase(appRepository, authRepo, navRepository, nodeRepository, knowledgeBaseRepository, nodeUsecase, logger, configConfig, chatUsecase, modelUsecase, cacheCache)
 	appHandler := v1.NewAppHandler(echo, baseHandler, logger, authMiddleware, appUsecase, modelUsecase, conversationUsecase, configConfig)
 	fileUsecase := usecase.NewFileUsecase(logger, minioClient, configConfig, systemSettingRepo)
 	fileHandler := v1.NewFileHandler(echo, baseHandler, logger, authMiddleware, minioClient, configConfig, fileUsecase)
-	modelHandler := v1.NewModelHandler(echo, baseHandler, logger, authMiddleware, modelUsecase, llmUsecase)
+	modelHandler := v1.NewModelHandler(echo, baseHandler, logger, authMiddleware, modelUsecase, llmUsecase, reindexUsecase)
 	conversationHandler := v1.NewConversationHandler(echo, baseHandler, logger, authMiddleware, conversationUsecase)
 	mqConsumer, err := mq.NewMQConsumer(configConfig, logger)
 	if err != nil {
@@ -143,6 +159,9 @@ func createApp() (*App, error) {
 	authV1Handler := v1.NewAuthV1Handler(echo, baseHandler, logger, authUsecase)
 	navUsecase := usecase.NewNavUsecase(navRepository, nodeRepository, ragRepository, logger)
 	navHandler := v1.NewNavHandler(baseHandler, echo, navUsecase, authMiddleware, logger)
+	evalRepository := pg2.NewEvalRepository(db, logger)
+	evalUsecase := usecase.NewEvalUsecase(evalRepository, appRepository, knowledgeBaseRepository, nodeRepository, settingRepo, llmUsecase, modelUsecase, logger)
+	evalHandler := v1.NewEvalHandler(baseHandler, echo, evalUsecase, authMiddleware, logger)
 	apiHandlers := &v1.APIHandlers{
 		UserHandler:          userHandler,
 		KnowledgeBaseHandler: knowledgeBaseHandler,
@@ -157,6 +176,7 @@ func createApp() (*App, error) {
 		CommentHandler:       commentHandler,
 		AuthV1Handler:        authV1Handler,
 		NavHandler:           navHandler,
+		EvalHandler:          evalHandler,
 	}
 	shareNodeHandler := share.NewShareNodeHandler(baseHandler, echo, nodeUsecase, logger)
 	shareNavHandler := share.NewShareNavHandler(baseHandler, echo, navUsecase, logger)
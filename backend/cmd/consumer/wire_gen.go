@@ -35,57 +35,80 @@ func createApp() (*App, error) {
 	if err != nil {
 		return nil, err
 	}
-	ragService, err := rag.NewRAGService(configConfig, logger)
+	db, err := pg.NewDB(configConfig)
 	if err != nil {
 		return nil, err
 	}
-	db, err := pg.NewDB(configConfig)
+	ragService, err := rag.NewRAGService(configConfig, db, logger)
 	if err != nil {
 		return nil, err
 	}
 	nodeRepository := pg2.NewNodeRepository(db, logger)
+	tagRepository := pg2.NewTagRepository(db, logger)
 	knowledgeBaseRepository := pg2.NewKnowledgeBaseRepository(db, configConfig, logger, ragService)
 	conversationRepository := pg2.NewConversationRepository(db, logger)
 	modelRepository := pg2.NewModelRepository(db, logger)
 	promptRepo := pg2.NewPromptRepo(db, logger)
-	llmUsecase := usecase.NewLLMUsecase(configConfig, ragService, conversationRepository, knowledgeBaseRepository, nodeRepository, modelRepository, promptRepo, logger)
+	settingRepo := pg2.NewSettingRepo(db, logger)
+	appRepository := pg2.NewAppRepository(db, logger)
+	cacheCache, err := cache.NewCache(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	authRepo := pg2.NewAuthRepo(db, logger, cacheCache)
+	nodeAttachmentRepository := pg2.NewNodeAttachmentRepository(db, logger)
 	mqProducer, err := mq.NewMQProducer(configConfig, logger)
 	if err != nil {
 		return nil, err
 	}
 	ragRepository := mq2.NewRAGRepository(mqProducer)
 	systemSettingRepo := pg2.NewSystemSettingRepo(db, logger)
-	modelUsecase := usecase.NewModelUsecase(modelRepository, nodeRepository, ragRepository, ragService, logger, configConfig, knowledgeBaseRepository, systemSettingRepo)
-	ragmqHandler, err := mq3.NewRAGMQHandler(mqConsumer, logger, ragService, nodeRepository, knowledgeBaseRepository, llmUsecase, modelUsecase)
+	reindexRepository := pg2.NewReindexRepository(db, logger)
+	qaPairRepository := pg2.NewQAPairRepository(db, logger)
+	answerCacheRepo := cache2.NewAnswerCacheRepo(cacheCache)
+	qaPairUsecase := usecase.NewQAPairUsecase(qaPairRepository, conversationRepository, answerCacheRepo, ragService, logger)
+	reindexUsecase := usecase.NewReindexUsecase(reindexRepository, nodeRepository, knowledgeBaseRepository, ragRepository, qaPairUsecase, logger)
+	modelUsecase := usecase.NewModelUsecase(modelRepository, nodeRepository, ragRepository, ragService, logger, configConfig, knowledgeBaseRepository, systemSettingRepo, settingRepo, reindexUsecase)
+	llmUsecase := usecase.NewLLMUsecase(configConfig, ragService, conversationRepository, knowledgeBaseRepository, nodeRepository, modelRepository, promptRepo, settingRepo, appRepository, authRepo, nodeAttachmentRepository, modelUsecase, logger)
+	nodeChunkRepository := pg2.NewNodeChunkRepository(db, logger)
+	nodeChunkUsecase := usecase.NewNodeChunkUsecase(nodeRepository, knowledgeBaseRepository, nodeChunkRepository, ragRepository, ragService, logger)
+	minioClient, err := s3.NewMinioClient(configConfig)
 	if err != nil {
 		return nil, err
 	}
-	ragDocUpdateHandler, err := mq3.NewRagDocUpdateHandler(mqConsumer, logger, nodeRepository)
+	nodeAttachmentUsecase, err := usecase.NewNodeAttachmentUsecase(nodeRepository, knowledgeBaseRepository, tagRepository, nodeAttachmentRepository, ragRepository, ragService, minioClient, mqConsumer, logger)
 	if err != nil {
 		return nil, err
 	}
-	cacheCache, err := cache.NewCache(configConfig)
+	imageCaptionRepository := pg2.NewImageCaptionRepository(db, logger)
+	imageCaptionUsecase := usecase.NewImageCaptionUsecase(llmUsecase, modelUsecase, imageCaptionRepository, minioClient, logger)
+	ragmqHandler, err := mq3.NewRAGMQHandler(mqConsumer, logger, ragService, nodeRepository, tagRepository, knowledgeBaseRepository, llmUsecase, modelUsecase, reindexUsecase, nodeChunkUsecase, nodeAttachmentUsecase, imageCaptionUsecase)
+	if err != nil {
+		return nil, err
+	}
+	ragDocUpdateHandler, err := mq3.NewRagDocUpdateHandler(mqConsumer, logger, nodeRepository)
 	if err != nil {
 		return nil, err
 	}
 	statRepository := pg2.NewStatRepository(db, cacheCache)
-	appRepository := pg2.NewAppRepository(db, logger)
 	ipdbIPDB, err := ipdb.NewIPDB(configConfig, logger)
 	if err != nil {
 		return nil, err
 	}
 	ipAddressRepo := ipdb2.NewIPAddressRepo(ipdbIPDB, logger)
 	geoRepo := cache2.NewGeoCache(cacheCache, db, logger)
-	authRepo := pg2.NewAuthRepo(db, logger, cacheCache)
 	statUseCase := usecase.NewStatUseCase(statRepository, nodeRepository, conversationRepository, appRepository, ipAddressRepo, geoRepo, authRepo, knowledgeBaseRepository, logger)
 	navRepository := pg2.NewNavRepository(db, logger)
 	userRepository := pg2.NewUserRepository(db, logger)
-	minioClient, err := s3.NewMinioClient(configConfig)
+	nodeUsecase := usecase.NewNodeUsecase(nodeRepository, navRepository, tagRepository, appRepository, ragRepository, userRepository, knowledgeBaseRepository, llmUsecase, ragService, logger, minioClient, modelRepository, authRepo, modelUsecase)
+	cronHandler, err := mq3.NewCronHandler(logger, statRepository, nodeRepository, statUseCase, nodeUsecase, nodeAttachmentUsecase)
 	if err != nil {
 		return nil, err
 	}
-	nodeUsecase := usecase.NewNodeUsecase(nodeRepository, navRepository, appRepository, ragRepository, userRepository, knowledgeBaseRepository, llmUsecase, ragService, logger, minioClient, modelRepository, authRepo, modelUsecase)
-	cronHandler, err := mq3.NewCronHandler(logger, statRepository, nodeRepository, statUseCase, nodeUsecase)
+	reindexHandler := mq3.NewReindexHandler(logger, reindexUsecase)
+	failedTaskRepository := pg2.NewFailedTaskRepository(db, logger)
+	failedTaskUsecase := usecase.NewFailedTaskUsecase(failedTaskRepository, ragRepository, reindexUsecase, logger)
+	deadLetterHandler, err := mq3.NewDeadLetterHandler(mqConsumer, logger, failedTaskUsecase)
 	if err != nil {
 		return nil, err
 	}
@@ -93,6 +116,8 @@ func createApp() (*App, error) {
 		RAGMQHandler:        ragmqHandler,
 		RagDocUpdateHandler: ragDocUpdateHandler,
 		StatCronHandler:     cronHandler,
+		ReindexHandler:      reindexHandler,
+		DeadLetterHandler:   deadLetterHandler,
 	}
 	app := &App{
 		MQConsumer:      mqConsumer,
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	v1 "github.com/chaitin/panda-wiki/api/eval/v1"
+)
+
+// eval replays a golden question set through retrieval and prints the
+// scores, runs are stored and also listed in the admin api
+func main() {
+	kbID := flag.String("kb", "", "knowledge base id")
+	setID := flag.String("set", "", "eval set id, list the sets of the kb when empty")
+	k := flag.Int("k", 10, "cutoff of recall@k and MRR")
+	note := flag.String("note", "", "note stored with the run")
+	flag.Parse()
+	if *kbID == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	app, err := createApp()
+	if err != nil {
+		panic(err)
+	}
+	ctx := context.Background()
+
+	if *setID == "" {
+		sets, err := app.EvalUsecase.GetSetList(ctx, *kbID)
+		if err != nil {
+			panic(err)
+		}
+		for _, set := range sets {
+			fmt.Printf("%s\t%s\t%d cases\n", set.ID, set.Name, set.CaseCount)
+		}
+		return
+	}
+
+	// previous run to compare with
+	runs, err := app.EvalUsecase.GetRunList(ctx, *kbID, *setID)
+	if err != nil {
+		panic(err)
+	}
+
+	run, err := app.EvalUsecase.Run(ctx, &v1.CreateEvalRunReq{
+		KbId:  *kbID,
+		SetID: *setID,
+		K:     *k,
+		Note:  *note,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, result := range run.Results {
+		if len(result.MissedNodeIDs) == 0 && result.Error == "" {
+			continue
+		}
+		fmt.Printf("MISS  %s\n", result.Question)
+		if result.Error != "" {
+			fmt.Printf("      error: %s\n", result.Error)
+		}
+		fmt.Printf("      missed: %v\n", result.MissedNodeIDs)
+		fmt.Printf("      retrieved: %v\n", result.RetrievedNodeIDs)
+	}
+	fmt.Printf("run %s: %d cases, recall@%d %.4f, MRR %.4f\n", run.ID, run.CaseCount, run.K, run.Recall, run.MRR)
+	fmt.Printf("embedding model %q, rerank model %q\n", run.Settings.EmbeddingModel, run.Settings.RerankModel)
+	if len(runs) > 0 {
+		prev := runs[0]
+		fmt.Printf("previous run %s (%s): recall@%d %.4f (%+.4f), MRR %.4f (%+.4f)\n",
+			prev.ID, prev.CreatedAt.Format("2006-01-02 15:04"), prev.K,
+			prev.Recall, run.Recall-prev.Recall, prev.MRR, run.MRR-prev.MRR)
+	}
+}
@@ -0,0 +1,80 @@
+// Code generated by Wire. DO NOT EDIT.
+
+//go:generate go run -mod=mod github.com/google/wire/cmd/wire
+//go:build !wireinject
+// +build !wireinject
+
+package main
+
+import (
+	"github.com/chaitin/panda-wiki/config"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/mq"
+	cache2 "github.com/chaitin/panda-wiki/repo/cache"
+	mq2 "github.com/chaitin/panda-wiki/repo/mq"
+	pg2 "github.com/chaitin/panda-wiki/repo/pg"
+	"github.com/chaitin/panda-wiki/store/cache"
+	"github.com/chaitin/panda-wiki/store/pg"
+	"github.com/chaitin/panda-wiki/store/rag"
+	"github.com/chaitin/panda-wiki/usecase"
+)
+
+// Injectors from wire.go:
+
+func createApp() (*App, error) {
+	configConfig, err := config.NewConfig()
+	if err != nil {
+		return nil, err
+	}
+	logger := log.NewLogger(configConfig)
+	db, err := pg.NewDB(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	evalRepository := pg2.NewEvalRepository(db, logger)
+	appRepository := pg2.NewAppRepository(db, logger)
+	ragService, err := rag.NewRAGService(configConfig, db, logger)
+	if err != nil {
+		return nil, err
+	}
+	knowledgeBaseRepository := pg2.NewKnowledgeBaseRepository(db, configConfig, logger, ragService)
+	nodeRepository := pg2.NewNodeRepository(db, logger)
+	settingRepo := pg2.NewSettingRepo(db, logger)
+	conversationRepository := pg2.NewConversationRepository(db, logger)
+	modelRepository := pg2.NewModelRepository(db, logger)
+	promptRepo := pg2.NewPromptRepo(db, logger)
+	cacheCache, err := cache.NewCache(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	authRepo := pg2.NewAuthRepo(db, logger, cacheCache)
+	nodeAttachmentRepository := pg2.NewNodeAttachmentRepository(db, logger)
+	mqProducer, err := mq.NewMQProducer(configConfig, logger)
+	if err != nil {
+		return nil, err
+	}
+	ragRepository := mq2.NewRAGRepository(mqProducer)
+	systemSettingRepo := pg2.NewSystemSettingRepo(db, logger)
+	reindexRepository := pg2.NewReindexRepository(db, logger)
+	qaPairRepository := pg2.NewQAPairRepository(db, logger)
+	answerCacheRepo := cache2.NewAnswerCacheRepo(cacheCache)
+	qaPairUsecase := usecase.NewQAPairUsecase(qaPairRepository, conversationRepository, answerCacheRepo, ragService, logger)
+	reindexUsecase := usecase.NewReindexUsecase(reindexRepository, nodeRepository, knowledgeBaseRepository, ragRepository, qaPairUsecase, logger)
+	modelUsecase := usecase.NewModelUsecase(modelRepository, nodeRepository, ragRepository, ragService, logger, configConfig, knowledgeBaseRepository, systemSettingRepo, settingRepo, reindexUsecase)
+	llmUsecase := usecase.NewLLMUsecase(configConfig, ragService, conversationRepository, knowledgeBaseRepository, nodeRepository, modelRepository, promptRepo, settingRepo, appRepository, authRepo, nodeAttachmentRepository, modelUsecase, logger)
+	evalUsecase := usecase.NewEvalUsecase(evalRepository, appRepository, knowledgeBaseRepository, nodeRepository, settingRepo, llmUsecase, modelUsecase, logger)
+	app := &App{
+		Config:      configConfig,
+		Logger:      logger,
+		EvalUsecase: evalUsecase,
+	}
+	return app, nil
+}
+
+// wire.go:
+
+type App struct {
+	Config      *config.Config
+	Logger      *log.Logger
+	EvalUsecase *usecase.EvalUsecase
+}
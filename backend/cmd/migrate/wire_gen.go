@@ -36,6 +36,7 @@ func createApp() (*App, error) {
 	logger := log.NewLogger(configConfig)
 	nodeRepository := pg2.NewNodeRepository(db, logger)
 	navRepository := pg2.NewNavRepository(db, logger)
+	tagRepository := pg2.NewTagRepository(db, logger)
 	appRepository := pg2.NewAppRepository(db, logger)
 	mqProducer, err := mq.NewMQProducer(configConfig, logger)
 	if err != nil {
@@ -43,7 +44,7 @@ func createApp() (*App, error) {
 	}
 	ragRepository := mq2.NewRAGRepository(mqProducer)
 	userRepository := pg2.NewUserRepository(db, logger)
-	ragService, err := rag.NewRAGService(configConfig, logger)
+	ragService, err := rag.NewRAGService(configConfig, db, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -51,21 +52,28 @@ func createApp() (*App, error) {
 	conversationRepository := pg2.NewConversationRepository(db, logger)
 	modelRepository := pg2.NewModelRepository(db, logger)
 	promptRepo := pg2.NewPromptRepo(db, logger)
-	llmUsecase := usecase.NewLLMUsecase(configConfig, ragService, conversationRepository, knowledgeBaseRepository, nodeRepository, modelRepository, promptRepo, logger)
-	minioClient, err := s3.NewMinioClient(configConfig)
-	if err != nil {
-		return nil, err
-	}
+	settingRepo := pg2.NewSettingRepo(db, logger)
 	cacheCache, err := cache.NewCache(configConfig)
 	if err != nil {
 		return nil, err
 	}
 	authRepo := pg2.NewAuthRepo(db, logger, cacheCache)
+	nodeAttachmentRepository := pg2.NewNodeAttachmentRepository(db, logger)
 	systemSettingRepo := pg2.NewSystemSettingRepo(db, logger)
-	modelUsecase := usecase.NewModelUsecase(modelRepository, nodeRepository, ragRepository, ragService, logger, configConfig, knowledgeBaseRepository, systemSettingRepo)
-	nodeUsecase := usecase.NewNodeUsecase(nodeRepository, navRepository, appRepository, ragRepository, userRepository, knowledgeBaseRepository, llmUsecase, ragService, logger, minioClient, modelRepository, authRepo, modelUsecase)
+	reindexRepository := pg2.NewReindexRepository(db, logger)
+	qaPairRepository := pg2.NewQAPairRepository(db, logger)
+	answerCacheRepo := cache2.NewAnswerCacheRepo(cacheCache)
+	qaPairUsecase := usecase.NewQAPairUsecase(qaPairRepository, conversationRepository, answerCacheRepo, ragService, logger)
+	reindexUsecase := usecase.NewReindexUsecase(reindexRepository, nodeRepository, knowledgeBaseRepository, ragRepository, qaPairUsecase, logger)
+	modelUsecase := usecase.NewModelUsecase(modelRepository, nodeRepository, ragRepository, ragService, logger, configConfig, knowledgeBaseRepository, systemSettingRepo, settingRepo, reindexUsecase)
+	llmUsecase := usecase.NewLLMUsecase(configConfig, ragService, conversationRepository, knowledgeBaseRepository, nodeRepository, modelRepository, promptRepo, settingRepo, appRepository, authRepo, nodeAttachmentRepository, modelUsecase, logger)
+	minioClient, err := s3.NewMinioClient(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	nodeUsecase := usecase.NewNodeUsecase(nodeRepository, navRepository, tagRepository, appRepository, ragRepository, userRepository, knowledgeBaseRepository, llmUsecase, ragService, logger, minioClient, modelRepository, authRepo, modelUsecase)
 	kbRepo := cache2.NewKBRepo(cacheCache)
-	knowledgeBaseUsecase, err := usecase.NewKnowledgeBaseUsecase(knowledgeBaseRepository, nodeRepository, navRepository, ragRepository, userRepository, ragService, kbRepo, logger, configConfig)
+	knowledgeBaseUsecase, err := usecase.NewKnowledgeBaseUsecase(knowledgeBaseRepository, nodeRepository, navRepository, ragRepository, userRepository, settingRepo, modelUsecase, ragService, kbRepo, answerCacheRepo, logger, configConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -74,12 +82,14 @@ func createApp() (*App, error) {
 	migrationFixGroupIds := fns.NewMigrationFixGroupIds(logger, ragRepository)
 	migrationUpdateNodeStatusUnreleased := fns.NewMigrationUpdateNodeStatusUnreleased(logger)
 	migrationCreateFirstNavs := fns.NewMigrationCreateFirstNavs(logger)
+	migrationBackfillNodeReleaseSearchVector := fns.NewMigrationBackfillNodeReleaseSearchVector(logger, nodeRepository)
 	migrationFuncs := &migration.MigrationFuncs{
 		NodeMigration:                       migrationNodeVersion,
 		BotAuthMigration:                    migrationCreateBotAuth,
 		FixGroupIdsMigration:                migrationFixGroupIds,
 		UpdateNodeStatusUnreleasedMigration: migrationUpdateNodeStatusUnreleased,
 		CreateFirstNavs:                     migrationCreateFirstNavs,
+		BackfillNodeReleaseSearchVector:     migrationBackfillNodeReleaseSearchVector,
 	}
 	manager, err := migration.NewManager(db, logger, migrationFuncs)
 	if err != nil {
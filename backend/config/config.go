@@ -47,8 +47,9 @@ type NATSConfig struct {
 }
 
 type RAGConfig struct {
-	Provider string      `mapstructure:"provider"`
-	CTRAG    CTRAGConfig `mapstructure:"ct_rag"`
+	Provider string         `mapstructure:"provider"`
+	CTRAG    CTRAGConfig    `mapstructure:"ct_rag"`
+	PGVector PGVectorConfig `mapstructure:"pgvector"`
 }
 
 type CTRAGConfig struct {
@@ -56,6 +57,11 @@ type CTRAGConfig struct {
 	APIKey  string `mapstructure:"api_key"`
 }
 
+type PGVectorConfig struct {
+	ChunkSize    int `mapstructure:"chunk_size"`    // max tokens per chunk
+	ChunkOverlap int `mapstructure:"chunk_overlap"` // tokens shared by adjacent chunks
+}
+
 type RedisConfig struct {
 	Addr     string `mapstructure:"addr"`
 	Password string `mapstructure:"password"`
@@ -112,6 +118,10 @@ func NewConfig() (*Config, error) {
 				BaseURL: fmt.Sprintf("http://%s.18:5050", SUBNET_PREFIX),
 				APIKey:  "sk-1234567890",
 			},
+			PGVector: PGVectorConfig{
+				ChunkSize:    512,
+				ChunkOverlap: 64,
+			},
 		},
 		Redis: RedisConfig{
 			Addr:     "panda-wiki-redis:6379",
@@ -190,6 +200,9 @@ func overrideWithEnv(c *Config) {
 		c.MQ.NATS.Server = env
 	}
 	// rag
+	if env := os.Getenv("RAG_PROVIDER"); env != "" {
+		c.RAG.Provider = env
+	}
 	if env := os.Getenv("RAG_CT_RAG_BASE_URL"); env != "" {
 		c.RAG.CTRAG.BaseURL = env
 	}
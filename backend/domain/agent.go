@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/chaitin/panda-wiki/consts"
+)
+
+const (
+	DefaultAgentMaxSteps = 5
+	MaxAgentMaxSteps     = 20
+
+	// MaxAgentStepResultRunes is how much of a tool result is streamed and
+	// saved with the message, the model itself gets the full result
+	MaxAgentStepResultRunes = 500
+)
+
+// AgentSettings answers with the kb tools called by the model instead of a
+// single retrieval before the answer
+type AgentSettings struct {
+	Enabled  bool `json:"enabled"`
+	MaxSteps int  `json:"max_steps" validate:"omitempty,min=1,max=20"` // 0 means DefaultAgentMaxSteps
+}
+
+func (s AgentSettings) GetMaxSteps() int {
+	if s.MaxSteps <= 0 {
+		return DefaultAgentMaxSteps
+	}
+	return min(s.MaxSteps, MaxAgentMaxSteps)
+}
+
+// AgentStep is a tool called by the model while answering
+type AgentStep struct {
+	ID        string `json:"id"`
+	Tool      string `json:"tool"`
+	Arguments string `json:"arguments"`
+	Result    string `json:"result,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type AgentSteps []*AgentStep
+
+func (s *AgentSteps) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New(fmt.Sprint("invalid agent steps value type:", value))
+	}
+	return json.Unmarshal(bytes, s)
+}
+
+func (s AgentSteps) Value() (driver.Value, error) {
+	if s == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(s)
+}
+
+// TruncateAgentStepResult shortens a tool result to MaxAgentStepResultRunes
+func TruncateAgentStepResult(result string) string {
+	runes := []rune(result)
+	if len(runes) <= MaxAgentStepResultRunes {
+		return result
+	}
+	return string(runes[:MaxAgentStepResultRunes]) + "..."
+}
+
+// NodeAccessible reports whether a node with the access permission is open
+// to a user, groupNodeIDs are the nodes the groups of the user are granted
+func NodeAccessible(perm consts.NodeAccessPerm, nodeID string, groupNodeIDs map[string]struct{}) bool {
+	switch perm {
+	case consts.NodeAccessPermOpen:
+		return true
+	case consts.NodeAccessPermPartial:
+		_, ok := groupNodeIDs[nodeID]
+		return ok
+	default:
+		return false
+	}
+}
+
+const AgentSystemPrompt = `你可以调用以下工具查阅知识库，再回答用户的问题：
+- search_kb：按关键词或问题检索知识库，返回相关的文档片段
+- list_children：列出目录下的文档和子目录，不传 node_id 时列出根目录
+- get_node：读取文档的完整内容
+- get_node_by_path：按路径读取文档，例如 "产品手册/安装/Linux"
+
+使用要求：
+1. 先检索或浏览知识库，问题涉及多个方面时分多次检索，不要凭空回答；
+2. 工具返回的片段以 [n] 开头，引用时使用其中的序号；
+3. 查阅到足够的信息后直接回答用户，不要提及工具调用的过程。`
+
+const AgentQuestionFormatter = `
+当前日期为：{{.CurrentDate}}。
+
+<question>
+{{.Question}}
+</question>
+`
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chaitin/panda-wiki/consts"
+)
+
+func TestNodeAccessible(t *testing.T) {
+	groupNodeIDs := map[string]struct{}{"granted": {}}
+	tests := []struct {
+		name   string
+		perm   consts.NodeAccessPerm
+		nodeID string
+		want   bool
+	}{
+		{name: "open", perm: consts.NodeAccessPermOpen, nodeID: "other", want: true},
+		{name: "partial granted", perm: consts.NodeAccessPermPartial, nodeID: "granted", want: true},
+		{name: "partial not granted", perm: consts.NodeAccessPermPartial, nodeID: "other", want: false},
+		{name: "closed", perm: consts.NodeAccessPermClosed, nodeID: "granted", want: false},
+		{name: "unset", perm: "", nodeID: "granted", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NodeAccessible(tt.perm, tt.nodeID, groupNodeIDs))
+		})
+	}
+}
+
+func TestAgentSettingsGetMaxSteps(t *testing.T) {
+	assert.Equal(t, DefaultAgentMaxSteps, AgentSettings{}.GetMaxSteps())
+	assert.Equal(t, 8, AgentSettings{MaxSteps: 8}.GetMaxSteps())
+	assert.Equal(t, MaxAgentMaxSteps, AgentSettings{MaxSteps: 100}.GetMaxSteps())
+}
+
+func TestTruncateAgentStepResult(t *testing.T) {
+	assert.Equal(t, "结果", TruncateAgentStepResult("结果"))
+	long := strings.Repeat("文", MaxAgentStepResultRunes+1)
+	assert.Equal(t, strings.Repeat("文", MaxAgentStepResultRunes)+"...", TruncateAgentStepResult(long))
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	DefaultAnswerCacheSimilarityThreshold = 0.95
+	DefaultAnswerCacheTTL                 = 24 * time.Hour
+	// MaxAnswerCacheEntries bounds the answers kept for one cache scope
+	MaxAnswerCacheEntries = 200
+)
+
+// AnswerCacheSettings serves answers of earlier questions that are nearly
+// the same as the new one, zero values fall back to the defaults
+type AnswerCacheSettings struct {
+	Enabled             bool    `json:"enabled"`
+	SimilarityThreshold float64 `json:"similarity_threshold" validate:"omitempty,gt=0,max=1"`
+	TTLMinutes          int     `json:"ttl_minutes" validate:"omitempty,min=1"`
+}
+
+func (s AnswerCacheSettings) GetSimilarityThreshold() float64 {
+	if s.SimilarityThreshold <= 0 {
+		return DefaultAnswerCacheSimilarityThreshold
+	}
+	return s.SimilarityThreshold
+}
+
+func (s AnswerCacheSettings) GetTTL() time.Duration {
+	if s.TTLMinutes <= 0 {
+		return DefaultAnswerCacheTTL
+	}
+	return time.Duration(s.TTLMinutes) * time.Minute
+}
+
+// CachedAnswer is an answer stored in the answer cache with the documents it cited
+type CachedAnswer struct {
+	Question  string              `json:"question"`
+	Embedding []float32           `json:"embedding"`
+	Answer    string              `json:"answer"`
+	Nodes     []*RankedNodeChunks `json:"nodes"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors, 0 when they
+// differ in length or one of them is zero
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// FindCachedAnswer returns the entry most similar to the embedding, nil when
+// none reaches the threshold
+func FindCachedAnswer(entries []*CachedAnswer, embedding []float32, threshold float64) *CachedAnswer {
+	var best *CachedAnswer
+	bestScore := threshold
+	for _, entry := range entries {
+		if score := CosineSimilarity(entry.Embedding, embedding); score >= bestScore {
+			best = entry
+			bestScore = score
+		}
+	}
+	return best
+}
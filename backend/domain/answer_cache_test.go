@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1, CosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(t, 0, CosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.Equal(t, float64(0), CosineSimilarity([]float32{1, 0}, []float32{1}))
+	assert.Equal(t, float64(0), CosineSimilarity([]float32{0, 0}, []float32{1, 1}))
+}
+
+func TestFindCachedAnswer(t *testing.T) {
+	entries := []*CachedAnswer{
+		{Answer: "far", Embedding: []float32{0, 1}},
+		{Answer: "close", Embedding: []float32{1, 0.1}},
+		{Answer: "closest", Embedding: []float32{1, 0.01}},
+	}
+	tests := []struct {
+		name      string
+		threshold float64
+		want      string
+	}{
+		{name: "most similar", threshold: 0.9, want: "closest"},
+		{name: "below threshold", threshold: 0.99999, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindCachedAnswer(entries, []float32{1, 0}, tt.threshold)
+			if tt.want == "" {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.want, got.Answer)
+		})
+	}
+}
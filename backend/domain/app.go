@@ -173,6 +173,22 @@ type AppSettings struct {
 	// MCP Server Settings
 	MCPServerSettings MCPServerSettings `json:"mcp_server_settings,omitempty"`
 	StatsSetting      StatsSetting      `json:"stats_setting"`
+	// retrieval settings
+	RetrievalSettings RetrievalSettings `json:"retrieval_settings"`
+	// answer cache settings
+	AnswerCacheSettings AnswerCacheSettings `json:"answer_cache_settings"`
+	// follow up question suggestions
+	SuggestionSettings SuggestionSettings `json:"suggestion_settings"`
+	// agent mode
+	AgentSettings AgentSettings `json:"agent_settings"`
+	// chat model of the app, overriding the one of the kb
+	ChatModelSetting ChatModelSetting `json:"chat_model_setting"`
+	// requests and tokens quotas of the chat
+	ChatQuotaSettings ChatQuotaSettings `json:"chat_quota_settings"`
+	// groundedness check of the answers
+	GroundednessSettings GroundednessSettings `json:"groundedness_settings"`
+	// fallback and human handoff of the questions the kb cannot answer
+	HandoffSettings HandoffSettings `json:"handoff_settings"`
 }
 
 type WeChatAppAdvancedSetting struct {
@@ -187,6 +203,49 @@ type StatsSetting struct {
 	PVEnable bool `json:"pv_enable"`
 }
 
+const (
+	DefaultRetrievalTopK                = 10
+	DefaultRetrievalSimilarityThreshold = 0.2
+)
+
+// RetrievalSettings tunes document retrieval of an app, zero values fall
+// back to the defaults
+type RetrievalSettings struct {
+	TopK                int      `json:"top_k" validate:"omitempty,min=1,max=100"`
+	SimilarityThreshold *float64 `json:"similarity_threshold" validate:"omitempty,min=0,max=1"`
+	MaxChunksPerDoc     int      `json:"max_chunks_per_doc" validate:"omitempty,min=1,max=50"` // 0 means unlimited
+	MaxContextTokens    int      `json:"max_context_tokens" validate:"omitempty,min=100"`      // 0 means unlimited
+	// other kbs the app may draw answers from, retrieved with their own permissions
+	FederatedKBs []FederatedKB `json:"federated_kbs,omitempty" validate:"omitempty,max=10,dive"`
+}
+
+// FederatedKB is an extra kb of an app, results of kbs with a higher weight rank higher
+type FederatedKB struct {
+	KBID   string  `json:"kb_id" validate:"required"`
+	Weight float64 `json:"weight" validate:"omitempty,gt=0,max=10"` // 0 means 1
+}
+
+func (f FederatedKB) GetWeight() float64 {
+	if f.Weight <= 0 {
+		return 1
+	}
+	return f.Weight
+}
+
+func (s RetrievalSettings) GetTopK() int {
+	if s.TopK <= 0 {
+		return DefaultRetrievalTopK
+	}
+	return s.TopK
+}
+
+func (s RetrievalSettings) GetSimilarityThreshold() float64 {
+	if s.SimilarityThreshold == nil {
+		return DefaultRetrievalSimilarityThreshold
+	}
+	return *s.SimilarityThreshold
+}
+
 type ConversationSetting struct {
 	CopyrightInfo        string `json:"copyright_info"`
 	CopyrightHideEnabled bool   `json:"copyright_hide_enabled"`
@@ -583,6 +642,22 @@ type AppSettingsResp struct {
 	// MCP Server Settings
 	MCPServerSettings MCPServerSettings `json:"mcp_server_settings,omitempty"`
 	StatsSetting      StatsSetting      `json:"stats_setting"`
+	// retrieval settings
+	RetrievalSettings RetrievalSettings `json:"retrieval_settings"`
+	// answer cache settings
+	AnswerCacheSettings AnswerCacheSettings `json:"answer_cache_settings"`
+	// follow up question suggestions
+	SuggestionSettings SuggestionSettings `json:"suggestion_settings"`
+	// agent mode
+	AgentSettings AgentSettings `json:"agent_settings"`
+	// chat model of the app, overriding the one of the kb
+	ChatModelSetting ChatModelSetting `json:"chat_model_setting"`
+	// requests and tokens quotas of the chat
+	ChatQuotaSettings ChatQuotaSettings `json:"chat_quota_settings"`
+	// groundedness check of the answers
+	GroundednessSettings GroundednessSettings `json:"groundedness_settings"`
+	// fallback and human handoff of the questions the kb cannot answer
+	HandoffSettings HandoffSettings `json:"handoff_settings"`
 }
 
 type WebAppLandingConfigResp struct {
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+
+	"github.com/cloudwego/eino/schema"
 )
 
 type ChatRequest struct {
@@ -14,6 +16,7 @@ type ChatRequest struct {
 	Nonce          string   `json:"nonce"`
 	AppType        AppType  `json:"app_type" validate:"required,oneof=1 2"`
 	CaptchaToken   string   `json:"captcha_token"`
+	Tags           []string `json:"tags" validate:"max=20"` // only answer from nodes with any of the tags
 
 	KBID  string `json:"-" validate:"required"`
 	AppID string `json:"-"`
@@ -22,11 +25,23 @@ type ChatRequest struct {
 
 	RemoteIP string           `json:"-"`
 	Info     ConversationInfo `json:"-"`
-	Prompt   string           `json:"-"`
+	Prompt   string           `json:"-"` // replaces the kb prompt
+	// layered on top of the kb prompt, e.g. system messages of api clients
+	SystemPrompt string `json:"-"`
+	// earlier turns supplied by the client, used instead of the stored
+	// messages of the conversation
+	History []*schema.Message `json:"-"`
+	// tools of the client the model may call, the calls are passed back to
+	// the client in a tool_calls event
+	Tools      []*schema.ToolInfo `json:"-"`
+	ToolChoice *schema.ToolChoice `json:"-"`
+	// tool calls after the question and their results sent back by the client
+	ToolMessages []*schema.Message `json:"-"`
 }
 
 type ChatRagOnlyRequest struct {
-	Message string `json:"message" validate:"required"`
+	Message string   `json:"message" validate:"required"`
+	Tags    []string `json:"tags" validate:"max=20"`
 
 	KBID string `json:"-" validate:"required"`
 
@@ -79,10 +94,12 @@ func (m MessageFrom) String() string {
 }
 
 type ChatSearchReq struct {
-	Message      string `json:"message" validate:"required"`
-	CaptchaToken string `json:"captcha_token"`
+	Message      string   `json:"message" validate:"required"`
+	CaptchaToken string   `json:"captcha_token"`
+	Tags         []string `json:"tags" validate:"max=20"`
 
-	KBID string `json:"-" validate:"required"`
+	KBID    string  `json:"-" validate:"required"`
+	AppType AppType `json:"-"`
 
 	RemoteIP   string `json:"-"`
 	AuthUserID uint   `json:"-"`
@@ -0,0 +1,50 @@
+package domain
+
+import "fmt"
+
+// SSEErrorChatQuotaExceeded is the error of the sse event rejecting a chat
+// over its quota
+const SSEErrorChatQuotaExceeded = "chat_quota_exceeded"
+
+// MaxChatQuotaTopUsers is how many users the consumption view lists
+const MaxChatQuotaTopUsers = 20
+
+// ChatQuotaSettings limits the chat of an app, as a whole and per user. A
+// user is the user of the bot platform, the auth user, or the ip of anonymous
+// visitors. 0 means unlimited.
+type ChatQuotaSettings struct {
+	AppRequestsPerMinute  int64 `json:"app_requests_per_minute" validate:"min=0"`
+	AppTokensPerDay       int64 `json:"app_tokens_per_day" validate:"min=0"`
+	UserRequestsPerMinute int64 `json:"user_requests_per_minute" validate:"min=0"`
+	UserTokensPerDay      int64 `json:"user_tokens_per_day" validate:"min=0"`
+}
+
+func (s ChatQuotaSettings) Enabled() bool {
+	return s.AppRequestsPerMinute > 0 || s.AppTokensPerDay > 0 || s.UserRequestsPerMinute > 0 || s.UserTokensPerDay > 0
+}
+
+// ChatQuotaUser returns the user the quota of a chat is counted for. Bot
+// chats share the auth of the bot, their users are told apart by the user id
+// of the platform.
+func ChatQuotaUser(userInfo UserInfo, remoteIP string) string {
+	if userInfo.UserID != "" {
+		return "uid:" + userInfo.UserID
+	}
+	if userInfo.AuthUserID > 0 {
+		return fmt.Sprintf("user:%d", userInfo.AuthUserID)
+	}
+	return "ip:" + remoteIP
+}
+
+type ChatQuotaUserUsage struct {
+	User        string `json:"user"`
+	TokensToday int64  `json:"tokens_today"`
+}
+
+type ChatQuotaUsageResp struct {
+	AppID              string                `json:"app_id"`
+	Settings           ChatQuotaSettings     `json:"settings"`
+	RequestsThisMinute int64                 `json:"requests_this_minute"`
+	TokensToday        int64                 `json:"tokens_today"`
+	TopUsers           []*ChatQuotaUserUsage `json:"top_users"`
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChatQuotaUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		userInfo UserInfo
+		remoteIP string
+		want     string
+	}{
+		{name: "bot user", userInfo: UserInfo{AuthUserID: 1, UserID: "wx-user"}, want: "uid:wx-user"},
+		{name: "auth user", userInfo: UserInfo{AuthUserID: 2}, remoteIP: "10.0.0.1", want: "user:2"},
+		{name: "anonymous", remoteIP: "10.0.0.1", want: "ip:10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ChatQuotaUser(tt.userInfo, tt.remoteIP))
+		})
+	}
+}
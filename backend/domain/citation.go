@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ChunkCitation is a retrieved chunk the answer can cite as [CitationID]
+type ChunkCitation struct {
+	CitationID int    `json:"citation_id"`
+	ChunkID    string `json:"chunk_id"`
+	NodeID     string `json:"node_id"`
+	NodeName   string `json:"node_name"`
+	Attachment string `json:"attachment,omitempty"` // file linked from the node the chunk comes from
+	Source     string `json:"source"`               // e.g. "Manual.pdf (linked from Node X)"
+	KBID       string `json:"kb_id,omitempty"`
+	URL        string `json:"url,omitempty"` // set when the node belongs to another kb
+	Seq        uint   `json:"seq"`
+	Content    string `json:"content"`
+}
+
+type ChunkCitations []ChunkCitation
+
+func (c *ChunkCitations) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New(fmt.Sprint("invalid chunk citations value type:", value))
+	}
+	return json.Unmarshal(bytes, c)
+}
+
+func (c ChunkCitations) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(c)
+}
+
+// AssignCitationIDs numbers the chunks of the ranked nodes from 1 in rank
+// order, the numbers are shown to the model and the client
+func AssignCitationIDs(nodes []*RankedNodeChunks) {
+	id := 0
+	for _, node := range nodes {
+		for _, chunk := range node.Chunks {
+			id++
+			chunk.CitationID = id
+		}
+	}
+}
+
+// Citations returns the numbered chunks of the node
+func (n *RankedNodeChunks) Citations() []ChunkCitation {
+	citations := make([]ChunkCitation, 0, len(n.Chunks))
+	for _, chunk := range n.Chunks {
+		if chunk.CitationID == 0 {
+			continue
+		}
+		citations = append(citations, ChunkCitation{
+			CitationID: chunk.CitationID,
+			ChunkID:    chunk.ID,
+			NodeID:     n.NodeID,
+			NodeName:   n.NodeName,
+			Attachment: n.Attachment,
+			Source:     n.SourceName(),
+			KBID:       n.KBID,
+			URL:        n.ExternalURL(),
+			Seq:        chunk.Seq,
+			Content:    chunk.Content,
+		})
+	}
+	return citations
+}
+
+var citationMarkRegexp = regexp.MustCompile(`\[(\d+)\]`)
+
+// CitedChunks returns the numbered chunks cited as [n] in the answer,
+// ordered by citation id. Thinking content is ignored.
+func CitedChunks(answer string, nodes []*RankedNodeChunks) ChunkCitations {
+	if idx := strings.LastIndex(answer, "</think>"); idx >= 0 {
+		answer = answer[idx+len("</think>"):]
+	}
+	cited := make(map[int]struct{})
+	for _, match := range citationMarkRegexp.FindAllStringSubmatch(answer, -1) {
+		if id, err := strconv.Atoi(match[1]); err == nil {
+			cited[id] = struct{}{}
+		}
+	}
+	citations := make(ChunkCitations, 0)
+	if len(cited) == 0 {
+		return citations
+	}
+	for _, node := range nodes {
+		for _, citation := range node.Citations() {
+			if _, ok := cited[citation.CitationID]; ok {
+				citations = append(citations, citation)
+			}
+		}
+	}
+	return citations
+}
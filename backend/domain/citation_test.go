@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCitedChunks(t *testing.T) {
+	nodes := []*RankedNodeChunks{
+		{NodeID: "n1", NodeName: "Install", Chunks: []*NodeContentChunk{{ID: "c1", Seq: 0}, {ID: "c2", Seq: 3}}},
+		{NodeID: "n2", NodeName: "Upgrade", Chunks: []*NodeContentChunk{{ID: "c3", Seq: 1}}},
+	}
+	AssignCitationIDs(nodes)
+
+	tests := []struct {
+		name     string
+		answer   string
+		expected []string
+	}{
+		{
+			name:     "inline and linked marks",
+			answer:   "Run the script [3]. Then restart [[1](https://wiki/node/n1)].",
+			expected: []string{"c1", "c3"},
+		},
+		{
+			name:     "unknown ids are ignored",
+			answer:   "See [2] and [9].",
+			expected: []string{"c2"},
+		},
+		{
+			name:     "thinking is ignored",
+			answer:   "<think>maybe [1]</think>\nNo documents apply.",
+			expected: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			citations := CitedChunks(tt.answer, nodes)
+			chunkIDs := make([]string, 0, len(citations))
+			for _, citation := range citations {
+				chunkIDs = append(chunkIDs, citation.ChunkID)
+			}
+			assert.Equal(t, tt.expected, chunkIDs)
+		})
+	}
+}
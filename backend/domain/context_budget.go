@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	// DefaultContextWindow is assumed for models without a context window set
+	DefaultContextWindow = 32768
+	// DefaultReservedOutputTokens is kept for the answer of models without
+	// max tokens set
+	DefaultReservedOutputTokens = 4096
+	// MessageOverheadTokens is what the role and separators of a message take
+	MessageOverheadTokens = 4
+)
+
+// ContextLimit returns how many tokens the prompt may take with the model,
+// its context window less the tokens kept for the answer
+func (m *Model) ContextLimit() int {
+	window := m.Parameters.ContextWindow
+	if window <= 0 {
+		window = DefaultContextWindow
+	}
+	reserved := m.Parameters.MaxTokens
+	if reserved <= 0 || reserved > window/2 {
+		reserved = min(DefaultReservedOutputTokens, window/4)
+	}
+	return window - reserved
+}
+
+// ChatContextLimit returns the context limit the prompt fits in with any
+// model of a fallback chain
+func ChatContextLimit(models []*Model) int {
+	limit := 0
+	for _, model := range models {
+		if l := model.ContextLimit(); limit == 0 || l < limit {
+			limit = l
+		}
+	}
+	return limit
+}
+
+// HistoryContextLimit is the share of the context limit the history and its
+// summary may take, the rest is left to the documents
+func HistoryContextLimit(contextLimit int) int {
+	return contextLimit / 3
+}
+
+// KeepRecentHistory returns the index of the first history message kept to
+// fit maxTokens, keeping the newest messages. The kept history starts with a
+// user message so no answer is left without its question.
+func KeepRecentHistory(messages []*schema.Message, tokens []int, maxTokens int) int {
+	used := 0
+	start := len(messages)
+	for i := len(messages) - 1; i >= 0; i-- {
+		if used+tokens[i] > maxTokens {
+			break
+		}
+		used += tokens[i]
+		start = i
+	}
+	for start < len(messages) && messages[start].Role != schema.User {
+		start++
+	}
+	return start
+}
+
+const ConversationSummaryPrompt = `你是对话摘要助手。请把之前的对话摘要与新的对话内容合并为一份新的摘要：
+1. 保留用户关心的问题、已经给出的关键结论、数据和约定；
+2. 省略寒暄和重复的内容，不要编造对话中没有的信息；
+3. 使用与对话相同的语言，不超过 500 字，只输出摘要。`
+
+const ConversationSummaryUserFormatter = `之前的对话摘要：
+{{.Summary}}
+
+新的对话内容：
+{{.Messages}}`
+
+// ConversationSummaryHeader introduces the summary in the system prompt
+const ConversationSummaryHeader = "\n\n以下是与用户之前对话的摘要，可作为回答的背景：\n"
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelContextLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		param ModelParam
+		want  int
+	}{
+		{name: "default", param: ModelParam{}, want: DefaultContextWindow - DefaultReservedOutputTokens},
+		{name: "max tokens", param: ModelParam{ContextWindow: 128000, MaxTokens: 8192}, want: 128000 - 8192},
+		{name: "small window", param: ModelParam{ContextWindow: 8000}, want: 6000},
+		{name: "max tokens too large", param: ModelParam{ContextWindow: 8000, MaxTokens: 8000}, want: 6000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, (&Model{Parameters: tt.param}).ContextLimit())
+		})
+	}
+	assert.Equal(t, 6000, ChatContextLimit([]*Model{
+		{Parameters: ModelParam{ContextWindow: 128000}},
+		{Parameters: ModelParam{ContextWindow: 8000}},
+	}))
+}
+
+func TestKeepRecentHistory(t *testing.T) {
+	messages := []*schema.Message{
+		schema.UserMessage("q1"),
+		schema.AssistantMessage("a1", nil),
+		schema.UserMessage("q2"),
+		schema.AssistantMessage("a2", nil),
+	}
+	tests := []struct {
+		name      string
+		tokens    []int
+		maxTokens int
+		want      int
+	}{
+		{name: "all fit", tokens: []int{10, 10, 10, 10}, maxTokens: 40, want: 0},
+		{name: "last turn", tokens: []int{10, 10, 10, 10}, maxTokens: 25, want: 2},
+		{name: "no orphan answer", tokens: []int{10, 10, 10, 10}, maxTokens: 35, want: 2},
+		{name: "nothing fits", tokens: []int{10, 10, 10, 50}, maxTokens: 40, want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, KeepRecentHistory(messages, tt.tokens, tt.maxTokens))
+		})
+	}
+}
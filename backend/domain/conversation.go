@@ -22,6 +22,11 @@ type Conversation struct {
 	RemoteIP  string           `json:"remote_ip"`
 	Info      ConversationInfo `json:"info" gorm:"type:jsonb"`
 	CreatedAt time.Time        `json:"created_at"`
+
+	// running summary of the turns no longer sent to the model, up to and
+	// including the message SummaryMessageID
+	Summary          string `json:"summary"`
+	SummaryMessageID string `json:"summary_message_id"`
 }
 
 type ConversationMessage struct {
@@ -50,6 +55,13 @@ type ConversationMessage struct {
 
 	// parent_id
 	ParentID string `json:"parent_id"`
+
+	// chunks cited by an assistant answer
+	Citations ChunkCitations `json:"citations" gorm:"column:citations;type:jsonb"`
+	// kb tools called in agent mode
+	AgentSteps AgentSteps `json:"agent_steps" gorm:"column:agent_steps;type:jsonb"`
+	// verdict of the groundedness judge, only kept for low scores
+	Groundedness *Groundedness `json:"groundedness,omitempty" gorm:"column:groundedness;type:jsonb"`
 }
 
 type FeedBackInfo struct {
@@ -77,6 +89,8 @@ type ConversationReference struct {
 	NodeID string `json:"node_id"`
 	Name   string `json:"name"`
 	URL    string `json:"url"`
+
+	RerankScore *float64 `json:"rerank_score,omitempty"`
 }
 
 type ConversationListReq struct {
@@ -87,6 +101,9 @@ type ConversationListReq struct {
 
 	RemoteIP *string `json:"remote_ip" query:"remote_ip"`
 
+	// only conversations with answers of low groundedness
+	LowGroundedness bool `json:"low_groundedness" query:"low_groundedness"`
+
 	Pager
 }
 
@@ -104,6 +121,9 @@ type ConversationListItem struct {
 	CreatedAt time.Time `json:"created_at"`
 
 	FeedBackInfo *FeedBackInfo `json:"feedback_info" gorm:"-"` // 用户反馈信息
+
+	// lowest groundedness score of the answers, set when one is low
+	GroundednessScore *float64 `json:"groundedness_score,omitempty"`
 }
 
 type ConversationDetailResp struct {
@@ -156,5 +176,7 @@ type ShareConversationMessage struct {
 	Role       schema.RoleType `json:"role"`
 	Content    string          `json:"content"`
 	ImagePaths pq.StringArray  `json:"image_paths"`
+	Citations  ChunkCitations  `json:"citations,omitempty"`
+	AgentSteps AgentSteps      `json:"agent_steps,omitempty"`
 	CreatedAt  time.Time       `json:"created_at"`
 }
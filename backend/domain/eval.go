@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// table: eval_sets
+type EvalSet struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:text"`
+	KBID        string    `json:"kb_id" gorm:"column:kb_id;type:text;not null"`
+	Name        string    `json:"name" gorm:"column:name;type:text;not null"`
+	Description string    `json:"description" gorm:"column:description;type:text;not null;default:''"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at;type:timestamptz;not null;default:now()"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at;type:timestamptz;not null;default:now()"`
+}
+
+func (EvalSet) TableName() string {
+	return "eval_sets"
+}
+
+// table: eval_cases
+type EvalCase struct {
+	ID              string         `json:"id" gorm:"primaryKey;type:text"`
+	SetID           string         `json:"set_id" gorm:"column:set_id;type:text;not null"`
+	KBID            string         `json:"kb_id" gorm:"column:kb_id;type:text;not null"`
+	Question        string         `json:"question" gorm:"column:question;type:text;not null"`
+	ExpectedNodeIDs pq.StringArray `json:"expected_node_ids" gorm:"column:expected_node_ids;type:text[];not null;default:{}"`
+	CreatedAt       time.Time      `json:"created_at" gorm:"column:created_at;type:timestamptz;not null;default:now()"`
+	UpdatedAt       time.Time      `json:"updated_at" gorm:"column:updated_at;type:timestamptz;not null;default:now()"`
+}
+
+func (EvalCase) TableName() string {
+	return "eval_cases"
+}
+
+// table: eval_runs
+type EvalRun struct {
+	ID        string          `json:"id" gorm:"primaryKey;type:text"`
+	SetID     string          `json:"set_id" gorm:"column:set_id;type:text;not null"`
+	KBID      string          `json:"kb_id" gorm:"column:kb_id;type:text;not null"`
+	K         int             `json:"k" gorm:"column:k;not null"`
+	CaseCount int             `json:"case_count" gorm:"column:case_count;not null"`
+	Recall    float64         `json:"recall" gorm:"column:recall;not null"`
+	MRR       float64         `json:"mrr" gorm:"column:mrr;not null"`
+	Note      string          `json:"note" gorm:"column:note;type:text;not null;default:''"`
+	Settings  EvalRunSettings `json:"settings" gorm:"column:settings;type:jsonb;not null"`
+	Results   EvalCaseResults `json:"results,omitempty" gorm:"column:results;type:jsonb;not null"`
+	CreatedAt time.Time       `json:"created_at" gorm:"column:created_at;type:timestamptz;not null;default:now()"`
+}
+
+func (EvalRun) TableName() string {
+	return "eval_runs"
+}
+
+// EvalRunSettings snapshots the retrieval settings a run was made with, so
+// runs before and after a change can be told apart
+type EvalRunSettings struct {
+	EmbeddingModel string            `json:"embedding_model"`
+	RerankModel    string            `json:"rerank_model"`
+	RankFusion     RankFusionSetting `json:"rank_fusion"`
+	Rerank         RerankSetting     `json:"rerank"`
+	Retrieval      RetrievalSettings `json:"retrieval"`
+}
+
+func (s *EvalRunSettings) Scan(value any) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New(fmt.Sprint("invalid eval run settings value type:", value))
+	}
+	return json.Unmarshal(bytes, s)
+}
+
+func (s EvalRunSettings) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+type EvalCaseResult struct {
+	CaseID           string   `json:"case_id"`
+	Question         string   `json:"question"`
+	ExpectedNodeIDs  []string `json:"expected_node_ids"`
+	RetrievedNodeIDs []string `json:"retrieved_node_ids"`
+	MissedNodeIDs    []string `json:"missed_node_ids"`
+	Recall           float64  `json:"recall"`
+	ReciprocalRank   float64  `json:"reciprocal_rank"`
+	Error            string   `json:"error,omitempty"`
+}
+
+type EvalCaseResults []EvalCaseResult
+
+func (r *EvalCaseResults) Scan(value any) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New(fmt.Sprint("invalid eval case results value type:", value))
+	}
+	return json.Unmarshal(bytes, r)
+}
+
+func (r EvalCaseResults) Value() (driver.Value, error) {
+	if r == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(r)
+}
+
+// ScoreRetrieval compares the top k retrieved nodes with the expected ones,
+// returning recall@k, the reciprocal rank of the first expected node and the
+// expected nodes missing from the top k
+func ScoreRetrieval(expected, retrieved []string, k int) (recall, reciprocalRank float64, missed []string) {
+	if k > 0 && len(retrieved) > k {
+		retrieved = retrieved[:k]
+	}
+	expectedSet := make(map[string]struct{}, len(expected))
+	for _, id := range expected {
+		expectedSet[id] = struct{}{}
+	}
+	found := make(map[string]struct{}, len(expected))
+	for i, id := range retrieved {
+		if _, ok := expectedSet[id]; !ok {
+			continue
+		}
+		if reciprocalRank == 0 {
+			reciprocalRank = 1 / float64(i+1)
+		}
+		found[id] = struct{}{}
+	}
+	if len(expectedSet) > 0 {
+		recall = float64(len(found)) / float64(len(expectedSet))
+	}
+	missed = make([]string, 0)
+	for _, id := range expected {
+		if _, ok := found[id]; ok {
+			continue
+		}
+		// mark duplicated expected ids only once
+		found[id] = struct{}{}
+		missed = append(missed, id)
+	}
+	return recall, reciprocalRank, missed
+}
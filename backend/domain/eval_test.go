@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreRetrieval(t *testing.T) {
+	tests := []struct {
+		name           string
+		expected       []string
+		retrieved      []string
+		k              int
+		recall         float64
+		reciprocalRank float64
+		missed         []string
+	}{
+		{
+			name:           "first hit",
+			expected:       []string{"a"},
+			retrieved:      []string{"a", "b", "c"},
+			k:              3,
+			recall:         1,
+			reciprocalRank: 1,
+			missed:         []string{},
+		},
+		{
+			name:           "partial recall",
+			expected:       []string{"c", "d"},
+			retrieved:      []string{"a", "b", "c"},
+			k:              3,
+			recall:         0.5,
+			reciprocalRank: 1.0 / 3,
+			missed:         []string{"d"},
+		},
+		{
+			name:           "hit beyond k",
+			expected:       []string{"c"},
+			retrieved:      []string{"a", "b", "c"},
+			k:              2,
+			recall:         0,
+			reciprocalRank: 0,
+			missed:         []string{"c"},
+		},
+		{
+			name:      "nothing retrieved",
+			expected:  []string{"a", "b", "a"},
+			retrieved: nil,
+			k:         5,
+			missed:    []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recall, rr, missed := ScoreRetrieval(tt.expected, tt.retrieved, tt.k)
+			assert.InDelta(t, tt.recall, recall, 1e-9)
+			assert.InDelta(t, tt.reciprocalRank, rr, 1e-9)
+			assert.Equal(t, tt.missed, missed)
+		})
+	}
+}
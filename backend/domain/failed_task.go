@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+type FailedTaskStatus string
+
+const (
+	FailedTaskStatusFailed   FailedTaskStatus = "failed"
+	FailedTaskStatusReplayed FailedTaskStatus = "replayed"
+)
+
+// table: failed_tasks
+type FailedTask struct {
+	ID         string           `json:"id" gorm:"primaryKey;type:text"`
+	Topic      string           `json:"topic" gorm:"column:topic;type:text;not null"`
+	KBID       string           `json:"kb_id" gorm:"column:kb_id;type:text;not null;default:''"`
+	Action     string           `json:"action" gorm:"column:action;type:text;not null;default:''"`
+	Payload    []byte           `json:"-" gorm:"column:payload;type:bytea;not null"`
+	Error      string           `json:"error" gorm:"column:error;type:text;not null;default:''"`
+	Attempts   int              `json:"attempts" gorm:"column:attempts;not null;default:0"`
+	Status     FailedTaskStatus `json:"status" gorm:"column:status;type:text;not null"`
+	ReplayedAt *time.Time       `json:"replayed_at" gorm:"column:replayed_at;type:timestamptz"`
+	FailedAt   time.Time        `json:"failed_at" gorm:"column:failed_at;type:timestamptz;not null;default:now()"`
+	CreatedAt  time.Time        `json:"created_at" gorm:"column:created_at;type:timestamptz;not null;default:now()"`
+}
+
+func (FailedTask) TableName() string {
+	return "failed_tasks"
+}
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const DefaultGroundednessThreshold = 0.6
+
+// GroundednessSettings checks after each answer that its claims are supported
+// by the documents it was given
+type GroundednessSettings struct {
+	Enabled   bool    `json:"enabled"`
+	Threshold float64 `json:"threshold" validate:"omitempty,gt=0,lte=1"` // 0 means DefaultGroundednessThreshold
+}
+
+// GetThreshold returns the score below which an answer is stored for review
+func (s GroundednessSettings) GetThreshold() float64 {
+	if s.Threshold <= 0 {
+		return DefaultGroundednessThreshold
+	}
+	return min(s.Threshold, 1)
+}
+
+// Groundedness is the share of the claims of an answer supported by its
+// documents, with the sentences of the claims that are not
+type Groundedness struct {
+	Score                float64  `json:"score"`
+	UnsupportedSentences []string `json:"unsupported_sentences"`
+}
+
+func (g *Groundedness) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New(fmt.Sprint("invalid groundedness value type:", value))
+	}
+	return json.Unmarshal(bytes, g)
+}
+
+func (g Groundedness) Value() (driver.Value, error) {
+	return json.Marshal(g)
+}
+
+const GroundednessJudgePrompt = `你是知识库问答的事实核查员。请逐句检查回答中的事实性陈述是否能被参考文档支持：
+1. 只依据参考文档判断，不要使用文档之外的知识；
+2. 寒暄、总结性的过渡语以及"文档中没有相关信息"之类的说明不算事实性陈述；
+3. score 为被文档支持的事实性陈述所占的比例，取值 0 到 1，没有事实性陈述时为 1；
+4. unsupported_sentences 为不能被文档支持的句子原文；
+5. 只输出 JSON，例如 {"score": 0.8, "unsupported_sentences": ["句子"]}，不要输出其他内容。`
+
+const GroundednessJudgeUserFormatter = `参考文档：
+{{.Documents}}
+
+回答：
+{{.Answer}}`
+
+// ParseGroundedness reads the verdict of the judge, a json object that may be
+// wrapped in a code fence or in text
+func ParseGroundedness(output string) (*Groundedness, error) {
+	start, end := strings.Index(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("no json object in judge output")
+	}
+	var verdict struct {
+		Score                *float64 `json:"score"`
+		UnsupportedSentences []string `json:"unsupported_sentences"`
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), &verdict); err != nil {
+		return nil, fmt.Errorf("parse judge output: %w", err)
+	}
+	if verdict.Score == nil {
+		return nil, fmt.Errorf("no score in judge output")
+	}
+	groundedness := &Groundedness{
+		Score:                max(0, min(*verdict.Score, 1)),
+		UnsupportedSentences: make([]string, 0, len(verdict.UnsupportedSentences)),
+	}
+	for _, sentence := range verdict.UnsupportedSentences {
+		if sentence = strings.TrimSpace(sentence); sentence != "" {
+			groundedness.UnsupportedSentences = append(groundedness.UnsupportedSentences, sentence)
+		}
+	}
+	return groundedness, nil
+}
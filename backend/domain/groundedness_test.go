@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGroundedness(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    *Groundedness
+		wantErr bool
+	}{
+		{
+			name:   "json",
+			output: `{"score": 0.5, "unsupported_sentences": ["a", " "]}`,
+			want:   &Groundedness{Score: 0.5, UnsupportedSentences: []string{"a"}},
+		},
+		{
+			name:   "fenced and clamped",
+			output: "```json\n{\"score\": 1.2}\n```",
+			want:   &Groundedness{Score: 1, UnsupportedSentences: []string{}},
+		},
+		{name: "no score", output: `{"unsupported_sentences": []}`, wantErr: true},
+		{name: "not json", output: "all good", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGroundedness(tt.output)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGroundednessSettingsGetThreshold(t *testing.T) {
+	assert.Equal(t, DefaultGroundednessThreshold, GroundednessSettings{}.GetThreshold())
+	assert.Equal(t, 0.8, GroundednessSettings{Threshold: 0.8}.GetThreshold())
+}
@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const DefaultHandoffFallbackMessage = "抱歉，知识库中暂时没有找到与您问题相关的内容，您的问题已记录，工作人员会尽快跟进。"
+
+// MaxNoAnswerRunes is the length above which an answer is not taken as the
+// model declaring it has no answer, as it likely answers part of the question
+const MaxNoAnswerRunes = 200
+
+// noAnswerPhrases are what the models say when the documents do not answer
+// the question, the first one being asked for by SystemDefaultPrompt
+var noAnswerPhrases = []string{
+	"知识不足以回答",
+	"无法回答这个问题",
+	"无法回答您的问题",
+	"没有找到相关",
+	"未找到相关",
+	"没有相关信息",
+	"don't have enough information",
+	"do not have enough information",
+	"cannot answer this question",
+}
+
+type HandoffConnector string
+
+const (
+	// HandoffConnectorWebhook posts the handoff as json
+	HandoffConnectorWebhook HandoffConnector = "webhook"
+	// HandoffConnectorDingTalk posts to a dingtalk group robot
+	HandoffConnectorDingTalk HandoffConnector = "dingtalk"
+	// HandoffConnectorFeishu posts to a feishu or lark group robot
+	HandoffConnectorFeishu HandoffConnector = "feishu"
+	// HandoffConnectorWecom posts to a wecom group robot
+	HandoffConnectorWecom HandoffConnector = "wecom"
+)
+
+// HandoffSettings answers questions the kb cannot answer with a fallback
+// message and opens a handoff for the staff, notified through the connector
+// of the app
+type HandoffSettings struct {
+	Enabled         bool             `json:"enabled"`
+	FallbackMessage string           `json:"fallback_message" validate:"max=1000"`             // empty means DefaultHandoffFallbackMessage
+	MinRerankScore  float64          `json:"min_rerank_score" validate:"omitempty,gt=0,lte=1"` // 0 means only an empty retrieval is unanswerable
+	Connector       HandoffConnector `json:"connector" validate:"omitempty,oneof=webhook dingtalk feishu wecom"`
+	WebhookURL      string           `json:"webhook_url" validate:"required_with=Connector,omitempty,url"`
+}
+
+func (s HandoffSettings) GetFallbackMessage() string {
+	if strings.TrimSpace(s.FallbackMessage) == "" {
+		return DefaultHandoffFallbackMessage
+	}
+	return s.FallbackMessage
+}
+
+type HandoffReason string
+
+const (
+	HandoffReasonNoDocuments   HandoffReason = "no_documents"
+	HandoffReasonLowScore      HandoffReason = "low_score"
+	HandoffReasonModelDeclined HandoffReason = "model_declined"
+)
+
+type HandoffStatus string
+
+const (
+	HandoffStatusPending    HandoffStatus = "pending"
+	HandoffStatusProcessing HandoffStatus = "processing"
+	HandoffStatusResolved   HandoffStatus = "resolved"
+)
+
+// RetrievalUnanswerable returns why the documents retrieved cannot answer the
+// question, empty when they may. Scores are only compared when every node
+// went through rerank.
+func RetrievalUnanswerable(nodes []*RankedNodeChunks, minRerankScore float64) HandoffReason {
+	if len(nodes) == 0 {
+		return HandoffReasonNoDocuments
+	}
+	if minRerankScore <= 0 {
+		return ""
+	}
+	for _, node := range nodes {
+		if node.RerankScore == nil || *node.RerankScore >= minRerankScore {
+			return ""
+		}
+	}
+	return HandoffReasonLowScore
+}
+
+// IsNoAnswer reports whether the model answered that it has no answer
+func IsNoAnswer(answer string) bool {
+	answer = strings.TrimSpace(answer)
+	if answer == "" || len([]rune(answer)) > MaxNoAnswerRunes {
+		return false
+	}
+	answer = strings.ToLower(answer)
+	for _, phrase := range noAnswerPhrases {
+		if strings.Contains(answer, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// table: handoffs
+type Handoff struct {
+	ID             string           `json:"id" gorm:"primaryKey;type:text"`
+	KBID           string           `json:"kb_id" gorm:"column:kb_id;type:text;not null"`
+	AppID          string           `json:"app_id" gorm:"column:app_id;type:text;not null"`
+	AppType        AppType          `json:"app_type" gorm:"column:app_type;not null"`
+	ConversationID string           `json:"conversation_id" gorm:"column:conversation_id;type:text;not null"`
+	MessageID      string           `json:"message_id" gorm:"column:message_id;type:text;not null"` // assistant message with the fallback
+	Question       string           `json:"question" gorm:"column:question;type:text;not null"`
+	Reason         HandoffReason    `json:"reason" gorm:"column:reason;type:text;not null"`
+	Status         HandoffStatus    `json:"status" gorm:"column:status;type:text;not null"`
+	Assignee       string           `json:"assignee" gorm:"column:assignee;type:text;not null;default:''"` // id of the user who picked it up
+	Note           string           `json:"note" gorm:"column:note;type:text;not null;default:''"`
+	RemoteIP       string           `json:"remote_ip" gorm:"column:remote_ip;type:text;not null;default:''"`
+	Info           ConversationInfo `json:"info" gorm:"column:info;type:jsonb"`
+	CreatedAt      time.Time        `json:"created_at" gorm:"column:created_at;type:timestamptz;not null;default:now()"`
+	UpdatedAt      time.Time        `json:"updated_at" gorm:"column:updated_at;type:timestamptz;not null;default:now()"`
+}
+
+func (Handoff) TableName() string {
+	return "handoffs"
+}
+
+// HandoffNotice is the text sent to the human queue
+func HandoffNotice(handoff *Handoff) string {
+	user := handoff.Info.UserInfo.NickName
+	if user == "" {
+		user = handoff.Info.UserInfo.UserID
+	}
+	if user == "" {
+		user = handoff.RemoteIP
+	}
+	return fmt.Sprintf("有新的转人工请求\n\n来源：%s\n用户：%s\n问题：%s\n原因：%s\n会话：%s",
+		handoffSource(handoff.AppType), user, handoff.Question, handoff.Reason, handoff.ConversationID)
+}
+
+func handoffSource(appType AppType) string {
+	if name := appType.ToSourceType().Name(); name != "" {
+		return name
+	}
+	switch appType {
+	case AppTypeWeb:
+		return "Web 网站"
+	case AppTypeOpenAIAPI:
+		return "OpenAI API"
+	default:
+		return fmt.Sprintf("应用 %d", appType)
+	}
+}
+
+// HandoffWebhookPayload returns the body posted to the connector
+func HandoffWebhookPayload(connector HandoffConnector, handoff *Handoff) any {
+	notice := HandoffNotice(handoff)
+	switch connector {
+	case HandoffConnectorDingTalk:
+		return map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"title": "转人工请求", "text": strings.ReplaceAll(notice, "\n", "\n\n")},
+		}
+	case HandoffConnectorFeishu:
+		return map[string]any{
+			"msg_type": "text",
+			"content":  map[string]string{"text": notice},
+		}
+	case HandoffConnectorWecom:
+		return map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"content": notice},
+		}
+	default:
+		return handoff
+	}
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetrievalUnanswerable(t *testing.T) {
+	low, high := 0.1, 0.9
+	tests := []struct {
+		name  string
+		nodes []*RankedNodeChunks
+		min   float64
+		want  HandoffReason
+	}{
+		{name: "no documents", nodes: nil, min: 0.5, want: HandoffReasonNoDocuments},
+		{name: "no min score", nodes: []*RankedNodeChunks{{RerankScore: &low}}, want: ""},
+		{name: "low score", nodes: []*RankedNodeChunks{{RerankScore: &low}}, min: 0.5, want: HandoffReasonLowScore},
+		{name: "one high score", nodes: []*RankedNodeChunks{{RerankScore: &low}, {RerankScore: &high}}, min: 0.5, want: ""},
+		{name: "not reranked", nodes: []*RankedNodeChunks{{RerankScore: &low}, {}}, min: 0.5, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RetrievalUnanswerable(tt.nodes, tt.min))
+		})
+	}
+}
+
+func TestIsNoAnswer(t *testing.T) {
+	assert.True(t, IsNoAnswer("抱歉，我当前的知识不足以回答这个问题"))
+	assert.True(t, IsNoAnswer("Sorry, I don't have enough information to answer."))
+	assert.False(t, IsNoAnswer("您可以在设置页面修改密码。"))
+	assert.False(t, IsNoAnswer(""))
+}
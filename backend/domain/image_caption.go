@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// MaxCaptionImagesPerNode bounds the vision model calls of one node upsert
+const MaxCaptionImagesPerNode = 20
+
+// MaxCaptionImageSize is the largest image sent to the vision model
+const MaxCaptionImageSize = 10 << 20
+
+const ImageCaptionPrompt = `你是文档图片解析助手。请用中文描述图片内容，供知识库检索使用：
+1. 如果图片中有文字，按阅读顺序完整提取文字；
+2. 如果是截图、流程图、架构图或表格，说明其结构和关键信息；
+3. 只输出描述内容，不要解释或添加额外前后缀。`
+
+// table: image_captions
+type ImageCaption struct {
+	Hash      string    `json:"hash" gorm:"primaryKey;type:text"` // sha256 of the image bytes
+	Caption   string    `json:"caption" gorm:"column:caption;type:text;not null"`
+	Model     string    `json:"model" gorm:"column:model;type:text;not null;default:''"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:timestamptz;not null;default:now()"`
+}
+
+func (ImageCaption) TableName() string {
+	return "image_captions"
+}
+
+var (
+	imageMarkdownRegexp = regexp.MustCompile(`!\[[^\]]*\]\(([^)\s]*/static-file/[^)\s]+)[^)]*\)`)
+	imageHTMLRegexp     = regexp.MustCompile(`(?i)<img\s[^>]*src=["']([^"']*/static-file/[^"']+)["'][^>]*>`)
+)
+
+// ExtractImageURLs returns the static-file images embedded in markdown or html
+// content, deduplicated in order of appearance
+func ExtractImageURLs(content string) []string {
+	urls := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, re := range []*regexp.Regexp{imageMarkdownRegexp, imageHTMLRegexp} {
+		for _, match := range re.FindAllStringSubmatch(content, -1) {
+			if _, ok := seen[match[1]]; ok {
+				continue
+			}
+			seen[match[1]] = struct{}{}
+			urls = append(urls, match[1])
+		}
+	}
+	return urls
+}
+
+// InsertImageCaptions puts the caption of each image right after it, so the
+// text is chunked together with the content around the image
+func InsertImageCaptions(content string, captions map[string]string) string {
+	if len(captions) == 0 {
+		return content
+	}
+	content = imageMarkdownRegexp.ReplaceAllStringFunc(content, func(match string) string {
+		caption, ok := captions[imageMarkdownRegexp.FindStringSubmatch(match)[1]]
+		if !ok {
+			return match
+		}
+		return fmt.Sprintf("%s\n\n> 图片内容：%s\n\n", match, caption)
+	})
+	return imageHTMLRegexp.ReplaceAllStringFunc(content, func(match string) string {
+		caption, ok := captions[imageHTMLRegexp.FindStringSubmatch(match)[1]]
+		if !ok {
+			return match
+		}
+		return fmt.Sprintf("%s<blockquote>图片内容：%s</blockquote>", match, caption)
+	})
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractImageURLs(t *testing.T) {
+	content := `![arch](/static-file/kb1/a.png "title") ![dup](/static-file/kb1/a.png)
+![external](https://example.com/b.png)
+<p><img class="x" src="/static-file/kb1/c.jpg" alt=""></p>`
+	assert.Equal(t, []string{"/static-file/kb1/a.png", "/static-file/kb1/c.jpg"}, ExtractImageURLs(content))
+}
+
+func TestInsertImageCaptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		captions map[string]string
+		want     string
+	}{
+		{
+			name:     "markdown",
+			content:  "before ![a](/static-file/kb1/a.png) after",
+			captions: map[string]string{"/static-file/kb1/a.png": "login page"},
+			want:     "before ![a](/static-file/kb1/a.png)\n\n> 图片内容：login page\n\n after",
+		},
+		{
+			name:     "html",
+			content:  `<p><img src="/static-file/kb1/a.png"></p>`,
+			captions: map[string]string{"/static-file/kb1/a.png": "login page"},
+			want:     `<p><img src="/static-file/kb1/a.png"><blockquote>图片内容：login page</blockquote></p>`,
+		},
+		{
+			name:     "no caption",
+			content:  "![a](/static-file/kb1/a.png)",
+			captions: map[string]string{"/static-file/kb1/b.png": "other"},
+			want:     "![a](/static-file/kb1/a.png)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, InsertImageCaptions(tt.content, tt.captions))
+		})
+	}
+}
@@ -35,13 +35,16 @@ var SystemDefaultPrompt = `
 ID: {文档ID}
 标题: {文档标题}
 URL: {文档URL}
-内容: {文档内容}
+内容:
+[1] {片段内容}
+[2] {片段内容}
 </document>
 <document>
 ID: {文档ID}
 标题: {文档标题}
 URL: {文档URL}
-内容: {文档内容}
+内容:
+[3] {片段内容}
 </document>
 </documents>
 
@@ -52,17 +55,16 @@ URL: {文档URL}
 4.若文档不足以回答用户问题，请直接回答"抱歉，我当前的知识不足以回答这个问题"
 5.如果文档中有相关图片或附件，请在回答中输出相关图片或附件
 6.如果回答的内容引用了文档，请使用内联引用格式标注回答内容的来源：
-	- 你需要给回答中引用的相关文档添加唯一序号，序号从1开始依次递增，跟回答无关的文档不添加序号
+	- 文档内容中的每个片段以 [n] 开头，n 为片段序号，引用时使用被引用片段的序号，不要重新编号
 	- 句号前放置引用标记
-	- 引用使用格式 [[文档序号](URL)]
-	- 如果多个不同文档支持同一观点，使用组合引用：[[文档序号](URL1)],[[文档序号](URL2)],[[文档序号](URLN)]
-  回答结束后，如果有引用列表则按照序号输出，格式如下，没有则不输出
+	- 引用使用格式 [[片段序号](URL)]，URL 为片段所在文档的 URL
+	- 如果多个片段支持同一观点，使用组合引用：[[片段序号](URL1)],[[片段序号](URL2)],[[片段序号](URLN)]
+  回答结束后，如果有引用列表则按照片段序号输出，格式如下，没有则不输出
 	---
 	### 引用列表
-	> [1]. [文档标题1](URL1)
-	> [2]. [文档标题2](URL2)
+	> [n1]. [文档标题1](URL1)
+	> [n2]. [文档标题2](URL2)
 	> ...
-	> [N]. [文档标题N](URLN)
 	---
 
 注意事项：
@@ -132,11 +134,16 @@ func FormatNodeChunks(nodeChunks []*RankedNodeChunks, baseURL string) string {
 	documents := make([]string, 0)
 	for _, result := range nodeChunks {
 		document := strings.Builder{}
-		document.WriteString(fmt.Sprintf("<document>\nID: %s\n标题: %s\nURL: %s\n内容:\n", result.NodeID, result.NodeName, result.GetURL(baseURL)))
+		document.WriteString(fmt.Sprintf("<document>\nID: %s\n标题: %s\nURL: %s\n内容:\n", result.NodeID, result.SourceName(), result.GetURL(baseURL)))
 		for _, chunk := range result.Chunks {
 			// Process content to add baseURL prefix to static-file URLs
 			processedContent := processContentWithBaseURL(chunk.Content, baseURL)
-			document.WriteString(fmt.Sprintf("%s\n", processedContent))
+			if chunk.CitationID > 0 {
+				// numbered chunks can be cited as [n]
+				document.WriteString(fmt.Sprintf("[%d] %s\n", chunk.CitationID, processedContent))
+			} else {
+				document.WriteString(fmt.Sprintf("%s\n", processedContent))
+			}
 		}
 		document.WriteString("</document>")
 		documents = append(documents, document.String())
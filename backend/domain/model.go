@@ -35,7 +35,7 @@ type Model struct {
 	APIHeader  string        `json:"api_header"`
 	BaseURL    string        `json:"base_url"`
 	APIVersion string        `json:"api_version"` // for azure openai
-	Type       ModelType     `json:"type" gorm:"default:chat;uniqueIndex"`
+	Type       ModelType     `json:"type" gorm:"default:chat"`
 
 	IsActive bool `json:"is_active" gorm:"default:false"`
 
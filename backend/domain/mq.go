@@ -1,24 +1,81 @@
 package domain
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
 const (
-	VectorTaskTopic       = "apps.panda-wiki.vector.task"
-	AnydocTaskExportTopic = "anydoc.persistence.doc.task.export"
-	RagDocUpdateTopic     = "raglite.events.doc.update"
+	VectorTaskTopic           = "apps.panda-wiki.vector.task"
+	VectorTaskDeadLetterTopic = "apps.panda-wiki.dead.vector.task"
+	AnydocTaskExportTopic     = "anydoc.persistence.doc.task.export"
+	RagDocUpdateTopic         = "raglite.events.doc.update"
 )
 
 var TopicConsumerName = map[string]string{
-	VectorTaskTopic:       "panda-wiki-vector-consumer",
-	AnydocTaskExportTopic: "anydoc-task-export-consumer",
-	RagDocUpdateTopic:     "raglite-doc-update-consumer",
+	VectorTaskTopic:           "panda-wiki-vector-consumer",
+	VectorTaskDeadLetterTopic: "panda-wiki-vector-dead-letter-consumer",
+	AnydocTaskExportTopic:     "anydoc-task-export-consumer",
+	RagDocUpdateTopic:         "raglite-doc-update-consumer",
+}
+
+// TaskRetryPolicy retries failed messages of a topic with exponential backoff,
+// messages still failing after MaxAttempts deliveries go to DeadLetterTopic
+type TaskRetryPolicy struct {
+	MaxAttempts     int
+	InitialBackoff  time.Duration
+	MaxBackoff      time.Duration
+	DeadLetterTopic string
+}
+
+// Backoff returns the delay before redelivering a message that failed on the given attempt
+func (p TaskRetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, p.MaxBackoff)
+}
+
+var TopicRetryPolicy = map[string]TaskRetryPolicy{
+	VectorTaskTopic: {
+		MaxAttempts:     5,
+		InitialBackoff:  5 * time.Second,
+		MaxBackoff:      5 * time.Minute,
+		DeadLetterTopic: VectorTaskDeadLetterTopic,
+	},
+}
+
+// ErrTaskPermanent marks task errors that retrying can not fix
+var ErrTaskPermanent = errors.New("permanent task error")
+
+func PermanentTaskError(err error) error {
+	return fmt.Errorf("%w: %w", ErrTaskPermanent, err)
+}
+
+func IsRetryableTaskError(err error) bool {
+	return !errors.Is(err, ErrTaskPermanent)
+}
+
+// DeadLetterMessage wraps a message that failed permanently or ran out of retries
+type DeadLetterMessage struct {
+	Topic    string    `json:"topic"`
+	Data     []byte    `json:"data"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failed_at"`
 }
 
 type NodeReleaseVectorRequest struct {
-	KBID          string `json:"kb_id"`
-	NodeReleaseID string `json:"node_release_id"`
-	NodeID        string `json:"node_id"`
-	DocID         string `json:"doc_id"` // for delete
-	Action        string `json:"action"` // upsert, delete, summary
-	GroupIds      []int  `json:"group_ids"`
+	KBID          string   `json:"kb_id"`
+	NodeReleaseID string   `json:"node_release_id"`
+	NodeID        string   `json:"node_id"`
+	DocID         string   `json:"doc_id"` // for delete
+	Action        string   `json:"action"` // upsert, delete, summary, update_group_ids, update_tags, sync_attachments
+	GroupIds      []int    `json:"group_ids"`
+	Tags          []string `json:"tags"`
+	ReindexJobID  string   `json:"reindex_job_id,omitempty"` // progress of the re-index job
 }
 
 // AnydocTaskExportEvent represents the task completion event from anydoc service
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskRetryPolicyBackoff(t *testing.T) {
+	policy := TaskRetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 5 * time.Second,
+		MaxBackoff:     time.Minute,
+	}
+	tests := []struct {
+		attempt int
+		backoff time.Duration
+	}{
+		{attempt: 1, backoff: 5 * time.Second},
+		{attempt: 2, backoff: 10 * time.Second},
+		{attempt: 3, backoff: 20 * time.Second},
+		{attempt: 4, backoff: 40 * time.Second},
+		{attempt: 5, backoff: time.Minute},
+		{attempt: 50, backoff: time.Minute},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.backoff, policy.Backoff(tt.attempt), "attempt %d", tt.attempt)
+	}
+}
+
+func TestIsRetryableTaskError(t *testing.T) {
+	err := errors.New("connection refused")
+	assert.True(t, IsRetryableTaskError(err))
+	assert.False(t, IsRetryableTaskError(PermanentTaskError(err)))
+	assert.ErrorIs(t, PermanentTaskError(err), err)
+}
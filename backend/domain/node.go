@@ -188,34 +188,67 @@ type NodeContentChunk struct {
 	Seq     uint   `json:"seq"`
 	Name    string `json:"name"`
 	Content string `json:"content"`
+
+	Score       float64  `json:"score,omitempty"`        // similarity reported by the rag provider
+	RerankScore *float64 `json:"rerank_score,omitempty"` // set when the chunk went through rerank
+	CitationID  int      `json:"citation_id,omitempty"`  // [n] the answer cites the chunk with
 }
 
 type RankedNodeChunks struct {
+	KBID          string
+	BaseURL       string // base url of the kb when it is not the kb asked, see FederatedKB
 	NodeID        string
 	NodeName      string
 	NodeSummary   string
 	NodeEmoji     string
 	NodePathNames []string
+	Attachment    string // file name when the chunks come from a file linked from the node
 	Chunks        []*NodeContentChunk
+	RerankScore   *float64 // best rerank score of the chunks
 }
 
 func (n *RankedNodeChunks) GetURL(baseURL string) string {
+	if n.BaseURL != "" {
+		baseURL = n.BaseURL
+	}
 	return fmt.Sprintf("%s/node/%s", baseURL, n.NodeID)
 }
 
+// ExternalURL is the url of nodes of another kb, empty for nodes of the kb asked
+func (n *RankedNodeChunks) ExternalURL() string {
+	if n.BaseURL == "" {
+		return ""
+	}
+	return n.GetURL("")
+}
+
+// SourceName is the title the chunks are shown and cited with
+func (n *RankedNodeChunks) SourceName() string {
+	if n.Attachment != "" {
+		return AttachmentSourceName(n.Attachment, n.NodeName)
+	}
+	return n.NodeName
+}
+
 type ChunkListItemResp struct {
-	ID      string `json:"id"`
-	Seq     uint   `json:"seq"`
-	Name    string `json:"name"`
-	Content string `json:"content"`
+	ID         string              `json:"id"`
+	Seq        uint                `json:"seq"`
+	Name       string              `json:"name"`
+	Content    string              `json:"content"`
+	Override   ChunkOverrideAction `json:"override,omitempty"`
+	OverrideID string              `json:"override_id,omitempty"`
 }
 
 type NodeContentChunkSSE struct {
-	NodeID        string   `json:"node_id"`
-	Name          string   `json:"name"`
-	Summary       string   `json:"summary"`
-	Emoji         string   `json:"emoji"`
-	NodePathNames []string `json:"node_path_names"`
+	NodeID        string          `json:"node_id"`
+	Name          string          `json:"name"`
+	Summary       string          `json:"summary"`
+	Emoji         string          `json:"emoji"`
+	NodePathNames []string        `json:"node_path_names"`
+	RerankScore   *float64        `json:"rerank_score,omitempty"`
+	Chunks        []ChunkCitation `json:"chunks,omitempty"` // numbered chunks of the node
+	KBID          string          `json:"kb_id,omitempty"`
+	URL           string          `json:"url,omitempty"` // set when the node belongs to another kb
 }
 
 type RecommendNodeListResp struct {
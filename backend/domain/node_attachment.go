@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type NodeAttachmentStatus string
+
+const (
+	NodeAttachmentStatusPending NodeAttachmentStatus = "pending"
+	NodeAttachmentStatusIndexed NodeAttachmentStatus = "indexed"
+	NodeAttachmentStatusFailed  NodeAttachmentStatus = "failed"
+)
+
+// AttachmentExts are the file types whose text is indexed with the node linking them
+var AttachmentExts = []string{".pdf", ".docx", ".doc", ".xlsx", ".xls", ".pptx", ".txt", ".md"}
+
+// table: node_attachments
+type NodeAttachment struct {
+	ID       string `json:"id" gorm:"primaryKey;type:text"`
+	KBID     string `json:"kb_id" gorm:"column:kb_id;type:text;not null"`
+	NodeID   string `json:"node_id" gorm:"column:node_id;type:text;not null"`
+	FileKey  string `json:"file_key" gorm:"column:file_key;type:text;not null"` // object key in the static-file bucket
+	FileName string `json:"file_name" gorm:"column:file_name;type:text;not null"`
+	// etag of the object when it was indexed, a different one means the file changed
+	ETag      string               `json:"etag" gorm:"column:etag;type:text;not null;default:''"`
+	DocID     string               `json:"doc_id" gorm:"column:doc_id;type:text;not null;default:''"`
+	Status    NodeAttachmentStatus `json:"status" gorm:"column:status;type:text;not null"`
+	Error     string               `json:"error" gorm:"column:error;type:text;not null;default:''"`
+	CreatedAt time.Time            `json:"created_at" gorm:"column:created_at;type:timestamptz;not null;default:now()"`
+	UpdatedAt time.Time            `json:"updated_at" gorm:"column:updated_at;type:timestamptz;not null;default:now()"`
+}
+
+func (NodeAttachment) TableName() string {
+	return "node_attachments"
+}
+
+// AttachmentLink is a file of the static-file bucket linked from node content
+type AttachmentLink struct {
+	FileKey  string
+	FileName string
+}
+
+var (
+	attachmentMarkdownRegexp = regexp.MustCompile(`(?:^|[^!])\[([^\]]*)\]\(([^)\s]*/static-file/[^)\s]+)`)
+	attachmentHTMLRegexp     = regexp.MustCompile(`(?is)<a\s[^>]*href=["']([^"']*/static-file/[^"']+)["'][^>]*>(.*?)</a>`)
+	htmlTagRegexp            = regexp.MustCompile(`<[^>]+>`)
+)
+
+// ExtractAttachmentLinks returns the attachments linked from markdown or html
+// content, deduplicated by file key in order of appearance. Images are skipped.
+func ExtractAttachmentLinks(content string) []AttachmentLink {
+	links := make([]AttachmentLink, 0)
+	seen := make(map[string]struct{})
+	add := func(href, text string) {
+		key, ok := attachmentFileKey(href)
+		if !ok {
+			return
+		}
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		name := strings.TrimSpace(htmlTagRegexp.ReplaceAllString(text, ""))
+		if !isAttachmentFile(name) {
+			name = path.Base(key)
+		}
+		links = append(links, AttachmentLink{FileKey: key, FileName: name})
+	}
+	for _, match := range attachmentMarkdownRegexp.FindAllStringSubmatch(content, -1) {
+		add(match[2], match[1])
+	}
+	for _, match := range attachmentHTMLRegexp.FindAllStringSubmatch(content, -1) {
+		add(match[1], match[2])
+	}
+	return links
+}
+
+func attachmentFileKey(href string) (string, bool) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	_, key, ok := strings.Cut(u.Path, "/"+Bucket+"/")
+	if !ok || key == "" || !isAttachmentFile(key) {
+		return "", false
+	}
+	return key, true
+}
+
+func isAttachmentFile(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, e := range AttachmentExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// AttachmentSourceName is how chunks of the attachment are titled and cited
+func AttachmentSourceName(fileName, nodeName string) string {
+	return fmt.Sprintf("%s (linked from %s)", fileName, nodeName)
+}
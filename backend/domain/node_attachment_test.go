@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractAttachmentLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []AttachmentLink
+	}{
+		{
+			name:    "markdown link",
+			content: "see [Manual.pdf](/static-file/kb1/abc.pdf) for details",
+			want:    []AttachmentLink{{FileKey: "kb1/abc.pdf", FileName: "Manual.pdf"}},
+		},
+		{
+			name:    "markdown link without file name",
+			content: "[download](http://example.com/static-file/kb1/abc.docx)",
+			want:    []AttachmentLink{{FileKey: "kb1/abc.docx", FileName: "abc.docx"}},
+		},
+		{
+			name:    "html link",
+			content: `<p><a href="/static-file/kb1/sheet.xlsx" target="_blank"><span>Prices.xlsx</span></a></p>`,
+			want:    []AttachmentLink{{FileKey: "kb1/sheet.xlsx", FileName: "Prices.xlsx"}},
+		},
+		{
+			name:    "images and other files are skipped",
+			content: "![Manual.pdf](/static-file/kb1/abc.pdf) [logo](/static-file/kb1/logo.png) [site](https://example.com/a.pdf)",
+			want:    []AttachmentLink{},
+		},
+		{
+			name:    "duplicates",
+			content: "[Manual.pdf](/static-file/kb1/abc.pdf) <a href='/static-file/kb1/abc.pdf'>again</a>",
+			want:    []AttachmentLink{{FileKey: "kb1/abc.pdf", FileName: "Manual.pdf"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ExtractAttachmentLinks(tt.content))
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+type ChunkOverrideAction string
+
+const (
+	// ChunkOverridePin keeps the chunk even when re-chunking no longer produces it
+	ChunkOverridePin ChunkOverrideAction = "pin"
+	// ChunkOverrideEdit replaces the content of the chunk
+	ChunkOverrideEdit ChunkOverrideAction = "edit"
+	// ChunkOverrideAdd adds a chunk written by the editor
+	ChunkOverrideAdd ChunkOverrideAction = "add"
+	// ChunkOverrideExclude removes the chunk from retrieval
+	ChunkOverrideExclude ChunkOverrideAction = "exclude"
+)
+
+// table: node_chunk_overrides
+type NodeChunkOverride struct {
+	ID     string              `json:"id" gorm:"primaryKey;type:text"`
+	KBID   string              `json:"kb_id" gorm:"column:kb_id;type:text;not null"`
+	NodeID string              `json:"node_id" gorm:"column:node_id;type:text;not null"`
+	Action ChunkOverrideAction `json:"action" gorm:"column:action;type:text;not null"`
+	// hash of the chunk produced by chunking, empty for added chunks
+	SourceHash string    `json:"source_hash" gorm:"column:source_hash;type:text;not null;default:''"`
+	Content    string    `json:"content" gorm:"column:content;type:text;not null;default:''"`
+	Stale      bool      `json:"stale" gorm:"column:stale;not null;default:false"` // source chunk gone at the last re-chunk
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at;type:timestamptz;not null;default:now()"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at;type:timestamptz;not null;default:now()"`
+}
+
+func (NodeChunkOverride) TableName() string {
+	return "node_chunk_overrides"
+}
+
+// ChunkContentHash identifies a chunk by its content, so overrides can be
+// matched again after the document is re-chunked
+func ChunkContentHash(content string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(content)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChunkOverridePlan is what has to change on freshly produced chunks to
+// apply the overrides of a node
+type ChunkOverridePlan struct {
+	Delete []string          // chunk ids
+	Update map[string]string // chunk id -> content
+	Add    []string          // contents
+	// overrides whose source chunk is gone, edits and excludes of them are skipped
+	Stale map[string]bool
+}
+
+func PlanChunkOverrides(chunks []*NodeContentChunk, overrides []*NodeChunkOverride) *ChunkOverridePlan {
+	plan := &ChunkOverridePlan{
+		Delete: make([]string, 0),
+		Update: make(map[string]string),
+		Add:    make([]string, 0),
+		Stale:  make(map[string]bool),
+	}
+	chunkIDs := make(map[string]string, len(chunks))
+	for _, chunk := range chunks {
+		chunkIDs[ChunkContentHash(chunk.Content)] = chunk.ID
+	}
+	for _, override := range overrides {
+		chunkID, ok := chunkIDs[override.SourceHash]
+		switch override.Action {
+		case ChunkOverrideAdd:
+			// an identical chunk is already there when the plan is applied twice
+			if _, exists := chunkIDs[ChunkContentHash(override.Content)]; !exists {
+				plan.Add = append(plan.Add, override.Content)
+			}
+		case ChunkOverridePin:
+			if !ok {
+				plan.Stale[override.ID] = true
+				if _, exists := chunkIDs[ChunkContentHash(override.Content)]; !exists {
+					plan.Add = append(plan.Add, override.Content)
+				}
+			}
+		case ChunkOverrideEdit:
+			if !ok {
+				plan.Stale[override.ID] = true
+				continue
+			}
+			plan.Update[chunkID] = override.Content
+		case ChunkOverrideExclude:
+			if !ok {
+				plan.Stale[override.ID] = true
+				continue
+			}
+			plan.Delete = append(plan.Delete, chunkID)
+		}
+	}
+	return plan
+}
+
+// MatchChunkOverride returns the override that produced or protects the chunk content
+func MatchChunkOverride(content string, overrides []*NodeChunkOverride) *NodeChunkOverride {
+	hash := ChunkContentHash(content)
+	for _, override := range overrides {
+		switch override.Action {
+		case ChunkOverrideEdit, ChunkOverrideAdd:
+			if ChunkContentHash(override.Content) == hash {
+				return override
+			}
+		case ChunkOverridePin:
+			if override.SourceHash == hash {
+				return override
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanChunkOverrides(t *testing.T) {
+	chunks := []*NodeContentChunk{
+		{ID: "c1", Content: "first"},
+		{ID: "c2", Content: "second"},
+		{ID: "c3", Content: "third "},
+	}
+	overrides := []*NodeChunkOverride{
+		{ID: "o1", Action: ChunkOverrideEdit, SourceHash: ChunkContentHash("first"), Content: "first edited"},
+		{ID: "o2", Action: ChunkOverrideExclude, SourceHash: ChunkContentHash("third")},
+		{ID: "o3", Action: ChunkOverrideAdd, Content: "extra"},
+		{ID: "o4", Action: ChunkOverridePin, SourceHash: ChunkContentHash("second"), Content: "second"},
+		{ID: "o5", Action: ChunkOverridePin, SourceHash: ChunkContentHash("gone"), Content: "gone"},
+		{ID: "o6", Action: ChunkOverrideEdit, SourceHash: ChunkContentHash("removed"), Content: "removed edited"},
+		{ID: "o7", Action: ChunkOverrideExclude, SourceHash: ChunkContentHash("removed too")},
+	}
+
+	plan := PlanChunkOverrides(chunks, overrides)
+	assert.Equal(t, map[string]string{"c1": "first edited"}, plan.Update)
+	assert.Equal(t, []string{"c3"}, plan.Delete)
+	assert.Equal(t, []string{"extra", "gone"}, plan.Add)
+	assert.Equal(t, map[string]bool{"o5": true, "o6": true, "o7": true}, plan.Stale)
+}
+
+func TestPlanChunkOverridesAppliedTwice(t *testing.T) {
+	chunks := []*NodeContentChunk{
+		{ID: "c1", Content: "first"},
+		{ID: "c2", Content: "extra"},
+	}
+	overrides := []*NodeChunkOverride{
+		{ID: "o1", Action: ChunkOverrideAdd, Content: "extra"},
+	}
+
+	plan := PlanChunkOverrides(chunks, overrides)
+	assert.Empty(t, plan.Add)
+	assert.Empty(t, plan.Update)
+	assert.Empty(t, plan.Delete)
+}
+
+func TestMatchChunkOverride(t *testing.T) {
+	overrides := []*NodeChunkOverride{
+		{ID: "edit", Action: ChunkOverrideEdit, SourceHash: ChunkContentHash("a"), Content: "a edited"},
+		{ID: "pin", Action: ChunkOverridePin, SourceHash: ChunkContentHash("b"), Content: "b"},
+		{ID: "add", Action: ChunkOverrideAdd, Content: "c"},
+		{ID: "exclude", Action: ChunkOverrideExclude, SourceHash: ChunkContentHash("d")},
+	}
+	tests := []struct {
+		content string
+		id      string
+	}{
+		{content: "a edited", id: "edit"},
+		{content: "a", id: ""},
+		{content: "b", id: "pin"},
+		{content: " c\n", id: "add"},
+		{content: "d", id: ""},
+	}
+	for _, tt := range tests {
+		override := MatchChunkOverride(tt.content, overrides)
+		if tt.id == "" {
+			assert.Nil(t, override, tt.content)
+			continue
+		}
+		if assert.NotNil(t, override, tt.content) {
+			assert.Equal(t, tt.id, override.ID)
+		}
+	}
+}
@@ -3,7 +3,12 @@ package domain
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/eino-contrib/jsonschema"
+	"github.com/google/uuid"
 )
 
 // OpenAI API 请求结构体
@@ -130,6 +135,7 @@ type OpenAIFunction struct {
 }
 
 type OpenAIToolCall struct {
+	Index    *int               `json:"index,omitempty"` // set in stream chunks
 	ID       string             `json:"id" validate:"required"`
 	Type     string             `json:"type" validate:"required"`
 	Function OpenAIFunctionCall `json:"function" validate:"required"`
@@ -140,17 +146,235 @@ type OpenAIFunctionCall struct {
 	Arguments string `json:"arguments" validate:"required"`
 }
 
+// OpenAIToolChoice is "none", "auto", "required" or a function the model must call
 type OpenAIToolChoice struct {
 	Type     string                `json:"type,omitempty"`
 	Function *OpenAIFunctionChoice `json:"function,omitempty"`
 }
 
+// UnmarshalJSON accepts the string form of tool_choice as well
+func (c *OpenAIToolChoice) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		c.Type = mode
+		return nil
+	}
+	type toolChoice OpenAIToolChoice
+	return json.Unmarshal(data, (*toolChoice)(c))
+}
+
 type OpenAIFunctionChoice struct {
 	Name string `json:"name" validate:"required"`
 }
 
 type OpenAIResponseFormat struct {
-	Type string `json:"type" validate:"required"`
+	Type       string            `json:"type" validate:"required"`
+	JSONSchema *OpenAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type OpenAIJSONSchema struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+	Strict      *bool           `json:"strict,omitempty"`
+}
+
+const jsonObjectInstruction = `请只输出一个合法的 JSON 对象，不要使用 markdown 代码块，也不要输出 JSON 以外的任何内容。`
+
+const jsonSchemaInstruction = `请只输出一个符合以下 JSON Schema 的 JSON 对象，不要使用 markdown 代码块，也不要输出 JSON 以外的任何内容。
+Schema 名称：%s
+%s
+JSON Schema：
+%s`
+
+// Instruction is added to the system prompt so the model answers in the
+// requested format, empty for plain text
+func (f *OpenAIResponseFormat) Instruction() (string, error) {
+	switch f.Type {
+	case "", "text":
+		return "", nil
+	case "json_object":
+		return jsonObjectInstruction, nil
+	case "json_schema":
+		if f.JSONSchema == nil || len(f.JSONSchema.Schema) == 0 {
+			return "", fmt.Errorf("json_schema is required for response_format json_schema")
+		}
+		return fmt.Sprintf(jsonSchemaInstruction, f.JSONSchema.Name, f.JSONSchema.Description, string(f.JSONSchema.Schema)), nil
+	default:
+		return "", fmt.Errorf("unsupported response_format type: %s", f.Type)
+	}
+}
+
+// IsJSON reports whether the answer must be json
+func (f *OpenAIResponseFormat) IsJSON() bool {
+	return f != nil && (f.Type == "json_object" || f.Type == "json_schema")
+}
+
+// TrimJSONFence drops the markdown code fence models tend to wrap json in
+func TrimJSONFence(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") {
+		return content
+	}
+	trimmed = strings.TrimSuffix(trimmed, "```")
+	if _, body, ok := strings.Cut(trimmed, "\n"); ok {
+		return strings.TrimSpace(body)
+	}
+	return content
+}
+
+// ChatTools maps the tools of the request to the model, narrowed to the
+// function named by tool_choice
+func (r *OpenAICompletionsRequest) ChatTools() ([]*schema.ToolInfo, *schema.ToolChoice, error) {
+	tools := make([]*schema.ToolInfo, 0, len(r.Tools))
+	for _, tool := range r.Tools {
+		if tool.Type != "function" || tool.Function == nil {
+			return nil, nil, fmt.Errorf("unsupported tool type: %s", tool.Type)
+		}
+		info := &schema.ToolInfo{
+			Name: tool.Function.Name,
+			Desc: tool.Function.Description,
+		}
+		if len(tool.Function.Parameters) > 0 {
+			data, err := json.Marshal(tool.Function.Parameters)
+			if err != nil {
+				return nil, nil, err
+			}
+			var params jsonschema.Schema
+			if err := json.Unmarshal(data, &params); err != nil {
+				return nil, nil, fmt.Errorf("invalid parameters of tool %s: %w", tool.Function.Name, err)
+			}
+			info.ParamsOneOf = schema.NewParamsOneOfByJSONSchema(&params)
+		}
+		tools = append(tools, info)
+	}
+	if r.ToolChoice == nil || len(tools) == 0 {
+		return tools, nil, nil
+	}
+	var choice schema.ToolChoice
+	switch r.ToolChoice.Type {
+	case "none":
+		choice = schema.ToolChoiceForbidden
+	case "", "auto":
+		choice = schema.ToolChoiceAllowed
+	case "required":
+		choice = schema.ToolChoiceForced
+	case "function":
+		if r.ToolChoice.Function == nil {
+			return nil, nil, fmt.Errorf("function is required for tool_choice function")
+		}
+		idx := slices.IndexFunc(tools, func(tool *schema.ToolInfo) bool { return tool.Name == r.ToolChoice.Function.Name })
+		if idx < 0 {
+			return nil, nil, fmt.Errorf("tool_choice function %s not found in tools", r.ToolChoice.Function.Name)
+		}
+		tools = tools[idx : idx+1]
+		choice = schema.ToolChoiceForced
+	default:
+		return nil, nil, fmt.Errorf("unsupported tool_choice: %s", r.ToolChoice.Type)
+	}
+	return tools, &choice, nil
+}
+
+// NewOpenAIToolCalls converts the tool calls of the model for the response
+func NewOpenAIToolCalls(toolCalls []schema.ToolCall, withIndex bool) []OpenAIToolCall {
+	result := make([]OpenAIToolCall, 0, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		call := OpenAIToolCall{
+			ID:   toolCall.ID,
+			Type: "function",
+			Function: OpenAIFunctionCall{
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
+			},
+		}
+		if withIndex {
+			call.Index = &i
+		}
+		result = append(result, call)
+	}
+	return result
+}
+
+func (m *OpenAIMessage) schemaToolCalls() []schema.ToolCall {
+	toolCalls := make([]schema.ToolCall, 0, len(m.ToolCalls))
+	for _, toolCall := range m.ToolCalls {
+		toolCalls = append(toolCalls, schema.ToolCall{
+			ID:   toolCall.ID,
+			Type: toolCall.Type,
+			Function: schema.FunctionCall{
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
+			},
+		})
+	}
+	return toolCalls
+}
+
+// OpenAIChatInput is a completions request mapped to the chat pipeline
+type OpenAIChatInput struct {
+	Question     string            // the last user message
+	History      []*schema.Message // user and assistant messages before the question
+	SystemPrompt string            // system messages of the client, joined
+	// tool calls of the model after the question and their results
+	ToolMessages []*schema.Message
+}
+
+// ChatInput splits the messages into the question, the history before it and
+// the system prompt of the client. Tool calls before the question are
+// dropped, those after it are kept for the model to go on with.
+func (r *OpenAICompletionsRequest) ChatInput() OpenAIChatInput {
+	var input OpenAIChatInput
+	last := -1
+	for i := len(r.Messages) - 1; i >= 0; i-- {
+		if r.Messages[i].Role == "user" {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return input
+	}
+	systemPrompts := make([]string, 0)
+	for _, msg := range r.Messages[:last+1] {
+		content := ""
+		if msg.Content != nil {
+			content = msg.Content.String()
+		}
+		switch msg.Role {
+		case "system", "developer":
+			if content != "" {
+				systemPrompts = append(systemPrompts, content)
+			}
+		case "user":
+			input.History = append(input.History, schema.UserMessage(content))
+		case "assistant":
+			if content != "" {
+				input.History = append(input.History, schema.AssistantMessage(content, nil))
+			}
+		}
+	}
+	input.Question = input.History[len(input.History)-1].Content
+	input.History = input.History[:len(input.History)-1]
+	input.SystemPrompt = strings.Join(systemPrompts, "\n\n")
+	for _, msg := range r.Messages[last+1:] {
+		content := ""
+		if msg.Content != nil {
+			content = msg.Content.String()
+		}
+		switch msg.Role {
+		case "assistant":
+			input.ToolMessages = append(input.ToolMessages, schema.AssistantMessage(content, msg.schemaToolCalls()))
+		case "tool":
+			input.ToolMessages = append(input.ToolMessages, schema.ToolMessage(content, msg.ToolCallID))
+		}
+	}
+	return input
+}
+
+// OpenAIConversationID is the conversation of the user field of completions
+// requests, stable for the same user of a kb
+func OpenAIConversationID(kbID, user string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("openai-api:"+kbID+":"+user)).String()
 }
 
 // OpenAI API 响应结构体
@@ -161,6 +385,8 @@ type OpenAICompletionsResponse struct {
 	Model   string         `json:"model"`
 	Choices []OpenAIChoice `json:"choices"`
 	Usage   *OpenAIUsage   `json:"usage,omitempty"`
+	// HandoffID is set when the question was handed off to the staff, not part of the openai api
+	HandoffID string `json:"handoff_id,omitempty"`
 }
 
 type OpenAIChoice struct {
@@ -184,6 +410,8 @@ type OpenAIStreamResponse struct {
 	Model   string               `json:"model"`
 	Choices []OpenAIStreamChoice `json:"choices"`
 	Usage   *OpenAIUsage         `json:"usage,omitempty"`
+	// HandoffID is set on the last chunk when the question was handed off to the staff
+	HandoffID string `json:"handoff_id,omitempty"`
 }
 
 type OpenAIStreamChoice struct {
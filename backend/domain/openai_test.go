@@ -2,8 +2,10 @@ package domain
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 
+	"github.com/cloudwego/eino/schema"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -184,3 +186,106 @@ func TestMessageContent_String_NoTextParts(t *testing.T) {
 	})
 	assert.Equal(t, "", mc.String())
 }
+
+func TestOpenAICompletionsRequest_ChatInput(t *testing.T) {
+	req := OpenAICompletionsRequest{
+		Messages: []OpenAIMessage{
+			{Role: "system", Content: NewStringContent("answer in english")},
+			{Role: "user", Content: NewStringContent("what is panda wiki")},
+			{Role: "assistant", Content: NewStringContent("a wiki")},
+			{Role: "developer", Content: NewStringContent("be brief")},
+			{Role: "user", Content: NewArrayContent([]OpenAIContentPart{{Type: "text", Text: "how to deploy it"}})},
+			{Role: "assistant", Content: NewStringContent("dropped")},
+		},
+	}
+	input := req.ChatInput()
+	assert.Equal(t, "how to deploy it", input.Question)
+	assert.Equal(t, "answer in english\n\nbe brief", input.SystemPrompt)
+	require.Len(t, input.History, 2)
+	assert.Equal(t, "what is panda wiki", input.History[0].Content)
+	assert.Equal(t, "a wiki", input.History[1].Content)
+
+	assert.Empty(t, (&OpenAICompletionsRequest{Messages: []OpenAIMessage{{Role: "assistant"}}}).ChatInput().Question)
+}
+
+func TestOpenAIConversationID(t *testing.T) {
+	assert.Equal(t, OpenAIConversationID("kb1", "alice"), OpenAIConversationID("kb1", "alice"))
+	assert.NotEqual(t, OpenAIConversationID("kb1", "alice"), OpenAIConversationID("kb2", "alice"))
+}
+
+func TestOpenAICompletionsRequest_ChatTools(t *testing.T) {
+	body := `{
+		"model": "panda",
+		"messages": [{"role": "user", "content": "weather?"}],
+		"tools": [
+			{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]}}},
+			{"type": "function", "function": {"name": "get_time"}}
+		],
+		"tool_choice": %s
+	}`
+	tests := []struct {
+		name       string
+		toolChoice string
+		wantTools  []string
+		wantChoice schema.ToolChoice
+		wantErr    bool
+	}{
+		{name: "auto", toolChoice: `"auto"`, wantTools: []string{"get_weather", "get_time"}, wantChoice: schema.ToolChoiceAllowed},
+		{name: "none", toolChoice: `"none"`, wantTools: []string{"get_weather", "get_time"}, wantChoice: schema.ToolChoiceForbidden},
+		{name: "function", toolChoice: `{"type": "function", "function": {"name": "get_time"}}`, wantTools: []string{"get_time"}, wantChoice: schema.ToolChoiceForced},
+		{name: "unknown function", toolChoice: `{"type": "function", "function": {"name": "other"}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req OpenAICompletionsRequest
+			require.NoError(t, json.Unmarshal([]byte(fmt.Sprintf(body, tt.toolChoice)), &req))
+			tools, choice, err := req.ChatTools()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			names := make([]string, 0, len(tools))
+			for _, tool := range tools {
+				names = append(names, tool.Name)
+			}
+			assert.Equal(t, tt.wantTools, names)
+			require.NotNil(t, choice)
+			assert.Equal(t, tt.wantChoice, *choice)
+		})
+	}
+}
+
+func TestOpenAICompletionsRequest_ChatInput_ToolMessages(t *testing.T) {
+	req := OpenAICompletionsRequest{
+		Messages: []OpenAIMessage{
+			{Role: "user", Content: NewStringContent("weather in beijing?")},
+			{Role: "assistant", ToolCalls: []OpenAIToolCall{{ID: "call_1", Type: "function", Function: OpenAIFunctionCall{Name: "get_weather", Arguments: `{"city":"beijing"}`}}}},
+			{Role: "tool", ToolCallID: "call_1", Content: NewStringContent("sunny")},
+		},
+	}
+	input := req.ChatInput()
+	assert.Equal(t, "weather in beijing?", input.Question)
+	require.Len(t, input.ToolMessages, 2)
+	assert.Equal(t, "get_weather", input.ToolMessages[0].ToolCalls[0].Function.Name)
+	assert.Equal(t, schema.Tool, input.ToolMessages[1].Role)
+	assert.Equal(t, "call_1", input.ToolMessages[1].ToolCallID)
+}
+
+func TestOpenAIResponseFormat_Instruction(t *testing.T) {
+	instruction, err := (&OpenAIResponseFormat{Type: "text"}).Instruction()
+	require.NoError(t, err)
+	assert.Empty(t, instruction)
+
+	instruction, err = (&OpenAIResponseFormat{Type: "json_schema", JSONSchema: &OpenAIJSONSchema{Name: "answer", Schema: json.RawMessage(`{"type":"object"}`)}}).Instruction()
+	require.NoError(t, err)
+	assert.Contains(t, instruction, `{"type":"object"}`)
+
+	_, err = (&OpenAIResponseFormat{Type: "json_schema"}).Instruction()
+	assert.Error(t, err)
+}
+
+func TestTrimJSONFence(t *testing.T) {
+	assert.Equal(t, `{"a":1}`, TrimJSONFence("```json\n{\"a\":1}\n```"))
+	assert.Equal(t, `{"a":1}`, TrimJSONFence(`{"a":1}`))
+}
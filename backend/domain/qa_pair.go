@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type QAPairMatchType string
+
+const (
+	// QAPairMatchExact matches questions equal to the pair's after normalizing
+	QAPairMatchExact QAPairMatchType = "exact"
+	// QAPairMatchKeyword matches questions containing all keywords of the pair
+	QAPairMatchKeyword QAPairMatchType = "keyword"
+	// QAPairMatchSemantic matches questions whose embedding is close to the pair's
+	QAPairMatchSemantic QAPairMatchType = "semantic"
+)
+
+type QAPairAnswerMode string
+
+const (
+	// QAPairAnswerVerbatim returns the answer as is, without calling the llm
+	QAPairAnswerVerbatim QAPairAnswerMode = "verbatim"
+	// QAPairAnswerContext gives the answer to the llm as top priority context
+	QAPairAnswerContext QAPairAnswerMode = "context"
+)
+
+const DefaultQAPairSimilarityThreshold = 0.9
+
+// table: qa_pairs
+type QAPair struct {
+	ID         string           `json:"id" gorm:"primaryKey;type:text"`
+	KBID       string           `json:"kb_id" gorm:"column:kb_id;type:text;not null"`
+	Question   string           `json:"question" gorm:"column:question;type:text;not null"`
+	Answer     string           `json:"answer" gorm:"column:answer;type:text;not null"`
+	Keywords   pq.StringArray   `json:"keywords" gorm:"column:keywords;type:text[];not null;default:{}"`
+	MatchType  QAPairMatchType  `json:"match_type" gorm:"column:match_type;type:text;not null"`
+	AnswerMode QAPairAnswerMode `json:"answer_mode" gorm:"column:answer_mode;type:text;not null"`
+	// embedding of the question, set for semantic pairs
+	Embedding       pq.Float32Array `json:"-" gorm:"column:embedding;type:real[];not null;default:{}"`
+	SourceMessageID string          `json:"source_message_id" gorm:"column:source_message_id;type:text;not null;default:''"` // assistant message the pair was promoted from
+	HitCount        int64           `json:"hit_count" gorm:"column:hit_count;not null;default:0"`
+	LastHitAt       *time.Time      `json:"last_hit_at" gorm:"column:last_hit_at;type:timestamptz"`
+	CreatedAt       time.Time       `json:"created_at" gorm:"column:created_at;type:timestamptz;not null;default:now()"`
+	UpdatedAt       time.Time       `json:"updated_at" gorm:"column:updated_at;type:timestamptz;not null;default:now()"`
+}
+
+func (QAPair) TableName() string {
+	return "qa_pairs"
+}
+
+// NormalizeQuestion lowercases the question, collapses whitespace and drops
+// trailing punctuation, so exact matching ignores how the question is typed
+func NormalizeQuestion(question string) string {
+	question = strings.Join(strings.Fields(strings.ToLower(question)), " ")
+	return strings.TrimRight(question, "?？!！。.~～ ")
+}
+
+// MatchQAPair returns the pair matching the question, nil when none does.
+// Exact pairs win over keyword pairs, which win over the most similar
+// semantic pair. embedding may be nil when there are no semantic pairs.
+func MatchQAPair(pairs []*QAPair, question string, embedding []float32, threshold float64) *QAPair {
+	normalized := NormalizeQuestion(question)
+	if normalized == "" {
+		return nil
+	}
+	for _, pair := range pairs {
+		if pair.MatchType == QAPairMatchExact && NormalizeQuestion(pair.Question) == normalized {
+			return pair
+		}
+	}
+	for _, pair := range pairs {
+		if pair.MatchType == QAPairMatchKeyword && containsAllKeywords(normalized, pair.Keywords) {
+			return pair
+		}
+	}
+	var best *QAPair
+	bestScore := threshold
+	for _, pair := range pairs {
+		if pair.MatchType != QAPairMatchSemantic {
+			continue
+		}
+		if score := CosineSimilarity(pair.Embedding, embedding); score >= bestScore {
+			best = pair
+			bestScore = score
+		}
+	}
+	return best
+}
+
+func containsAllKeywords(question string, keywords []string) bool {
+	matched := 0
+	for _, keyword := range keywords {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if keyword == "" {
+			continue
+		}
+		if !strings.Contains(question, keyword) {
+			return false
+		}
+		matched++
+	}
+	return matched > 0
+}
+
+const qaPairContextPrompt = `以下是管理员审核过的标准问答。如果用户的问题与之相关，请以标准答案为准进行回答，不要与之矛盾：
+<standard_qa>
+问题：%s
+答案：%s
+</standard_qa>`
+
+// ContextPrompt is the instruction appended to the system prompt for pairs
+// in context mode
+func (p *QAPair) ContextPrompt() string {
+	return fmt.Sprintf(qaPairContextPrompt, p.Question, p.Answer)
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeQuestion(t *testing.T) {
+	assert.Equal(t, "how do i get a refund", NormalizeQuestion("  How do I   get a Refund？ "))
+	assert.Equal(t, "", NormalizeQuestion("?!"))
+}
+
+func TestMatchQAPair(t *testing.T) {
+	exact := &QAPair{ID: "exact", MatchType: QAPairMatchExact, Question: "How do I get a refund?"}
+	keyword := &QAPair{ID: "keyword", MatchType: QAPairMatchKeyword, Keywords: []string{"Refund", "order"}}
+	semantic := &QAPair{ID: "semantic", MatchType: QAPairMatchSemantic, Embedding: []float32{1, 0}}
+	pairs := []*QAPair{semantic, keyword, exact}
+	tests := []struct {
+		name      string
+		question  string
+		embedding []float32
+		want      string
+	}{
+		{name: "keyword", question: "how do i get a refund for my order", embedding: []float32{1, 0}, want: "keyword"},
+		{name: "exact", question: "HOW do i get a refund", embedding: []float32{1, 0}, want: "exact"},
+		{name: "keyword needs all keywords", question: "refund please", embedding: []float32{0, 1}, want: ""},
+		{name: "semantic", question: "money back", embedding: []float32{1, 0.1}, want: "semantic"},
+		{name: "no embedding", question: "money back", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MatchQAPair(pairs, tt.question, tt.embedding, DefaultQAPairSimilarityThreshold)
+			if tt.want == "" {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.want, got.ID)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package domain
+
+import "time"
+
+type ReindexJobStatus string
+
+const (
+	ReindexJobStatusRunning   ReindexJobStatus = "running"
+	ReindexJobStatusPaused    ReindexJobStatus = "paused"
+	ReindexJobStatusCancelled ReindexJobStatus = "cancelled"
+	ReindexJobStatusCompleted ReindexJobStatus = "completed"
+)
+
+// DefaultReindexRateLimit is the number of nodes dispatched per second
+const DefaultReindexRateLimit = 20
+
+// table: reindex_jobs
+type ReindexJob struct {
+	ID             string           `json:"id" gorm:"primaryKey;type:text"`
+	Status         ReindexJobStatus `json:"status" gorm:"column:status;type:text;not null"`
+	EmbeddingModel string           `json:"embedding_model" gorm:"column:embedding_model;type:text;not null;default:''"`
+	RateLimit      int              `json:"rate_limit" gorm:"column:rate_limit;not null;default:0"`
+	Error          string           `json:"error" gorm:"column:error;type:text;not null;default:''"`
+	CreatedAt      time.Time        `json:"created_at" gorm:"column:created_at;type:timestamptz;not null;default:now()"`
+	UpdatedAt      time.Time        `json:"updated_at" gorm:"column:updated_at;type:timestamptz;not null;default:now()"`
+	FinishedAt     *time.Time       `json:"finished_at" gorm:"column:finished_at;type:timestamptz"`
+}
+
+func (ReindexJob) TableName() string {
+	return "reindex_jobs"
+}
+
+func (j *ReindexJob) GetRateLimit() int {
+	if j.RateLimit <= 0 {
+		return DefaultReindexRateLimit
+	}
+	return j.RateLimit
+}
+
+// table: reindex_job_kbs
+type ReindexJobKB struct {
+	JobID      string    `json:"job_id" gorm:"primaryKey;column:job_id;type:text"`
+	KBID       string    `json:"kb_id" gorm:"primaryKey;column:kb_id;type:text"`
+	Total      int       `json:"total" gorm:"column:total;not null;default:0"`
+	Dispatched int       `json:"dispatched" gorm:"column:dispatched;not null;default:0"`
+	Succeeded  int       `json:"succeeded" gorm:"column:succeeded;not null;default:0"`
+	Failed     int       `json:"failed" gorm:"column:failed;not null;default:0"`
+	Cursor     string    `json:"-" gorm:"column:cursor;type:text;not null;default:''"`
+	Exhausted  bool      `json:"exhausted" gorm:"column:exhausted;not null;default:false"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at;type:timestamptz;not null;default:now()"`
+}
+
+func (ReindexJobKB) TableName() string {
+	return "reindex_job_kbs"
+}
+
+// Finished reports whether every dispatched node has been processed
+func (k *ReindexJobKB) Finished() bool {
+	return k.Exhausted && k.Succeeded+k.Failed >= k.Dispatched
+}
@@ -8,6 +8,9 @@ import (
 const (
 	SettingKeySystemPrompt = "system_prompt"
 	SettingBlockWords      = "block_words"
+	SettingKeyRankFusion   = "rank_fusion"
+	SettingKeyRerank       = "rerank"
+	SettingKeyChatModel    = "chat_model"
 	SettingCopyrightInfo   = "本网站由 PandaWiki 提供技术支持"
 )
 
@@ -27,3 +30,64 @@ type SettingRepo interface {
 	GetSetting(ctx context.Context, kbID, key string) (*Setting, error)
 	UpdateSetting(ctx context.Context, kbID, key, value string) error
 }
+
+// RankFusionSetting weights the vector and keyword retrieval legs in
+// reciprocal rank fusion, a zero weight disables the leg
+type RankFusionSetting struct {
+	VectorWeight  float64 `json:"vector_weight"`
+	KeywordWeight float64 `json:"keyword_weight"`
+}
+
+var DefaultRankFusionSetting = RankFusionSetting{
+	VectorWeight:  1,
+	KeywordWeight: 1,
+}
+
+// RerankSetting enables rescoring retrieved chunks with the rerank model,
+// only the TopN best chunks are kept for the prompt
+type RerankSetting struct {
+	Enabled bool `json:"enabled"`
+	TopN    int  `json:"top_n"`
+}
+
+var DefaultRerankSetting = RerankSetting{
+	Enabled: false,
+	TopN:    10,
+}
+
+const MaxChatModelFallbacks = 5
+
+// ChatModelSetting routes the questions of a kb or an app to a chat model,
+// the fallback models are tried in order when it fails
+type ChatModelSetting struct {
+	ModelID          string   `json:"model_id"` // empty means the default chat model
+	FallbackModelIDs []string `json:"fallback_model_ids" validate:"max=5"`
+}
+
+// Override returns the setting with what is set in the setting of an app
+func (s ChatModelSetting) Override(app ChatModelSetting) ChatModelSetting {
+	if app.ModelID != "" {
+		s.ModelID = app.ModelID
+	}
+	if len(app.FallbackModelIDs) > 0 {
+		s.FallbackModelIDs = app.FallbackModelIDs
+	}
+	return s
+}
+
+// ModelIDs returns the model and its fallbacks in order, without duplicates
+func (s ChatModelSetting) ModelIDs() []string {
+	ids := make([]string, 0, len(s.FallbackModelIDs)+1)
+	seen := make(map[string]struct{}, len(s.FallbackModelIDs)+1)
+	for _, id := range append([]string{s.ModelID}, s.FallbackModelIDs...) {
+		if id == "" {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	return ids
+}
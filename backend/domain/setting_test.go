@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChatModelSetting(t *testing.T) {
+	kb := ChatModelSetting{ModelID: "strong", FallbackModelIDs: []string{"backup"}}
+	tests := []struct {
+		name string
+		app  ChatModelSetting
+		want []string
+	}{
+		{name: "kb default", app: ChatModelSetting{}, want: []string{"strong", "backup"}},
+		{name: "app model", app: ChatModelSetting{ModelID: "cheap"}, want: []string{"cheap", "backup"}},
+		{name: "app fallbacks", app: ChatModelSetting{FallbackModelIDs: []string{"cheap", "strong", ""}}, want: []string{"strong", "cheap"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, kb.Override(tt.app).ModelIDs())
+		})
+	}
+	assert.Empty(t, ChatModelSetting{}.ModelIDs())
+}
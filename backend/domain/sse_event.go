@@ -1,8 +1,14 @@
 package domain
 
+import "github.com/cloudwego/eino/schema"
+
 type SSEEvent struct {
-	Type        string               `json:"type"`
-	Content     string               `json:"content"`
-	ChunkResult *NodeContentChunkSSE `json:"chunk_result,omitempty"`
-	Error       string               `json:"error,omitempty"`
+	Type         string               `json:"type"`
+	Content      string               `json:"content"`
+	ChunkResult  *NodeContentChunkSSE `json:"chunk_result,omitempty"`
+	Error        string               `json:"error,omitempty"`
+	Suggestions  []string             `json:"suggestions,omitempty"`
+	ToolCalls    []schema.ToolCall    `json:"tool_calls,omitempty"`
+	AgentStep    *AgentStep           `json:"agent_step,omitempty"`
+	Groundedness *Groundedness        `json:"groundedness,omitempty"`
 }
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	DefaultSuggestionCount = 3
+	MaxSuggestionCount     = 5
+)
+
+// SuggestionSettings offers follow up questions after each answer
+type SuggestionSettings struct {
+	Enabled bool `json:"enabled"`
+	Count   int  `json:"count" validate:"omitempty,min=3,max=5"` // 0 means DefaultSuggestionCount
+}
+
+func (s SuggestionSettings) GetCount() int {
+	if s.Count <= 0 {
+		return DefaultSuggestionCount
+	}
+	return min(s.Count, MaxSuggestionCount)
+}
+
+const SuggestionPrompt = `你是知识库问答助手。请根据用户的问题、回答以及参考文档，生成 {{.Count}} 个用户接下来可能会问的追问问题：
+1. 问题要简短具体，能够从参考文档中找到答案；
+2. 不要重复用户已经问过的问题；
+3. 使用与用户问题相同的语言；
+4. 只输出 JSON 字符串数组，例如 ["问题一", "问题二"]，不要输出其他内容。`
+
+const SuggestionUserFormatter = `用户问题：
+{{.Question}}
+
+回答：
+{{.Answer}}
+
+参考文档：
+{{.Documents}}`
+
+var suggestionPrefixRegexp = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.、)）])\s*`)
+
+// ParseSuggestions reads the questions generated by the llm, a json array or
+// one question per line, dropping duplicates and the question asked
+func ParseSuggestions(output, question string, count int) []string {
+	var candidates []string
+	start, end := strings.Index(output, "["), strings.LastIndex(output, "]")
+	if start < 0 || end <= start || json.Unmarshal([]byte(output[start:end+1]), &candidates) != nil {
+		candidates = strings.Split(output, "\n")
+	}
+	suggestions := make([]string, 0, count)
+	seen := map[string]struct{}{NormalizeQuestion(question): {}}
+	for _, candidate := range candidates {
+		candidate = strings.Trim(suggestionPrefixRegexp.ReplaceAllString(candidate, ""), " \t\"'")
+		normalized := NormalizeQuestion(candidate)
+		if normalized == "" {
+			continue
+		}
+		if _, ok := seen[normalized]; ok {
+			continue
+		}
+		seen[normalized] = struct{}{}
+		suggestions = append(suggestions, candidate)
+		if len(suggestions) == count {
+			break
+		}
+	}
+	return suggestions
+}
+
+// FormatSuggestions renders the suggestions for bot replies, each linking to
+// the wiki asking it when the kb has a base url
+func FormatSuggestions(suggestions []string, baseURL string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n\n**猜你想问：**\n")
+	for _, suggestion := range suggestions {
+		if baseURL == "" {
+			fmt.Fprintf(&b, "\n- %s", suggestion)
+			continue
+		}
+		fmt.Fprintf(&b, "\n- [%s](%s/?ask=%s)", suggestion, strings.TrimRight(baseURL, "/"), url.QueryEscape(suggestion))
+	}
+	return b.String()
+}
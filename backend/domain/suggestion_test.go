@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSuggestions(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		count  int
+		want   []string
+	}{
+		{
+			name:   "json",
+			output: "```json\n[\"如何退款？\", \"退款多久到账？\", \"如何退款\"]\n```",
+			count:  3,
+			want:   []string{"退款多久到账？"},
+		},
+		{
+			name:   "lines",
+			output: "1. 如何开发票？\n2、发票抬头能修改吗？\n- 支持哪些支付方式？",
+			count:  2,
+			want:   []string{"如何开发票？", "发票抬头能修改吗？"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseSuggestions(tt.output, "如何退款", tt.count))
+		})
+	}
+}
+
+func TestFormatSuggestions(t *testing.T) {
+	assert.Equal(t, "", FormatSuggestions(nil, "https://wiki.example.com"))
+	assert.Equal(t, "\n\n**猜你想问：**\n\n- [a b](https://wiki.example.com/?ask=a+b)",
+		FormatSuggestions([]string{"a b"}, "https://wiki.example.com/"))
+	assert.Equal(t, "\n\n**猜你想问：**\n\n- a b", FormatSuggestions([]string{"a b"}, ""))
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// table: tags
+type Tag struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:text"`
+	KBID      string    `json:"kb_id" gorm:"column:kb_id;type:text;not null"`
+	Name      string    `json:"name" gorm:"column:name;type:text;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:timestamptz;not null;default:now()"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;type:timestamptz;not null;default:now()"`
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
+
+// table: node_tags
+type NodeTag struct {
+	KBID      string    `json:"kb_id" gorm:"column:kb_id;type:text;not null"`
+	NodeID    string    `json:"node_id" gorm:"column:node_id;primaryKey;type:text"`
+	TagID     string    `json:"tag_id" gorm:"column:tag_id;primaryKey;type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;type:timestamptz;not null;default:now()"`
+}
+
+func (NodeTag) TableName() string {
+	return "node_tags"
+}
@@ -17,6 +17,7 @@ require (
 	github.com/chaitin/raglite-go-sdk v0.2.1
 	github.com/cloudwego/eino v0.7.3
 	github.com/cloudwego/eino-ext/components/model/deepseek v0.1.0
+	github.com/eino-contrib/jsonschema v1.0.3
 	github.com/getsentry/sentry-go v0.35.1
 	github.com/getsentry/sentry-go/echo v0.35.1
 	github.com/go-ldap/ldap/v3 v3.4.11
@@ -108,7 +109,6 @@ require (
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 	github.com/dlclark/regexp2 v1.11.4 // indirect
 	github.com/dustin/go-humanize v1.0.1 // indirect
-	github.com/eino-contrib/jsonschema v1.0.3 // indirect
 	github.com/evanphx/json-patch v0.5.2 // indirect
 	github.com/fatih/structs v1.1.0 // indirect
 	github.com/felixge/httpsnoop v1.0.4 // indirect
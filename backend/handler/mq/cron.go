@@ -12,20 +12,22 @@ import (
 )
 
 type CronHandler struct {
-	logger      *log.Logger
-	statRepo    *pg.StatRepository
-	nodeRepo    *pg.NodeRepository
-	statUseCase *usecase.StatUseCase
-	nodeUseCase *usecase.NodeUsecase
+	logger            *log.Logger
+	statRepo          *pg.StatRepository
+	nodeRepo          *pg.NodeRepository
+	statUseCase       *usecase.StatUseCase
+	nodeUseCase       *usecase.NodeUsecase
+	attachmentUsecase *usecase.NodeAttachmentUsecase
 }
 
-func NewCronHandler(logger *log.Logger, statRepo *pg.StatRepository, nodeRepo *pg.NodeRepository, statUseCase *usecase.StatUseCase, nodeUseCase *usecase.NodeUsecase) (*CronHandler, error) {
+func NewCronHandler(logger *log.Logger, statRepo *pg.StatRepository, nodeRepo *pg.NodeRepository, statUseCase *usecase.StatUseCase, nodeUseCase *usecase.NodeUsecase, attachmentUsecase *usecase.NodeAttachmentUsecase) (*CronHandler, error) {
 	h := &CronHandler{
-		statRepo:    statRepo,
-		nodeRepo:    nodeRepo,
-		statUseCase: statUseCase,
-		nodeUseCase: nodeUseCase,
-		logger:      logger.WithModule("handler.mq.cron"),
+		statRepo:          statRepo,
+		nodeRepo:          nodeRepo,
+		statUseCase:       statUseCase,
+		nodeUseCase:       nodeUseCase,
+		attachmentUsecase: attachmentUsecase,
+		logger:            logger.WithModule("handler.mq.cron"),
 	}
 	cron := cron.New()
 
@@ -69,6 +71,13 @@ func NewCronHandler(logger *log.Logger, statRepo *pg.StatRepository, nodeRepo *p
 	}
 	h.logger.Info("add cron job", log.String("cron_id", "cleanup_old_node_release_backups"))
 
+	// 每小时40分检查附件文件是否变更
+	if _, err := cron.AddFunc("40 * * * *", h.CheckChangedAttachments); err != nil {
+		h.logger.Error("failed to add cron job for checking changed attachments", log.Error(err))
+		return nil, err
+	}
+	h.logger.Info("add cron job", log.String("cron_id", "check_changed_attachments"))
+
 	cron.Start()
 	h.logger.Info("start cron jobs")
 	return h, nil
@@ -132,3 +141,12 @@ func (h *CronHandler) CleanupOldNodeReleaseBackups() {
 	}
 	h.logger.Info("cleanup old node release backups successful")
 }
+
+func (h *CronHandler) CheckChangedAttachments() {
+	h.logger.Info("check changed attachments start")
+	if err := h.attachmentUsecase.CheckChangedAttachments(context.Background()); err != nil {
+		h.logger.Error("check changed attachments failed", log.Error(err))
+		return
+	}
+	h.logger.Info("check changed attachments successful")
+}
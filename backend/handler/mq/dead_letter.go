@@ -0,0 +1,41 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/mq"
+	"github.com/chaitin/panda-wiki/mq/types"
+	"github.com/chaitin/panda-wiki/usecase"
+)
+
+type DeadLetterHandler struct {
+	logger            *log.Logger
+	failedTaskUsecase *usecase.FailedTaskUsecase
+}
+
+func NewDeadLetterHandler(consumer mq.MQConsumer, logger *log.Logger, failedTaskUsecase *usecase.FailedTaskUsecase) (*DeadLetterHandler, error) {
+	h := &DeadLetterHandler{
+		logger:            logger.WithModule("mq.dead_letter"),
+		failedTaskUsecase: failedTaskUsecase,
+	}
+	if err := consumer.RegisterHandler(domain.VectorTaskDeadLetterTopic, h.HandleDeadLetter); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *DeadLetterHandler) HandleDeadLetter(ctx context.Context, msg types.Message) error {
+	var deadLetter domain.DeadLetterMessage
+	if err := json.Unmarshal(msg.GetData(), &deadLetter); err != nil {
+		h.logger.Error("unmarshal dead letter failed", log.Error(err))
+		return nil
+	}
+	if err := h.failedTaskUsecase.RecordDeadLetter(ctx, &deadLetter); err != nil {
+		return err
+	}
+	h.logger.Warn("task failed", log.String("topic", deadLetter.Topic), log.Int("attempts", deadLetter.Attempts), log.String("error", deadLetter.Error))
+	return nil
+}
@@ -15,6 +15,8 @@ type MQHandlers struct {
 	RAGMQHandler        *RAGMQHandler
 	RagDocUpdateHandler *RagDocUpdateHandler
 	StatCronHandler     *CronHandler
+	ReindexHandler      *ReindexHandler
+	DeadLetterHandler   *DeadLetterHandler
 }
 
 var ProviderSet = wire.NewSet(
@@ -28,10 +30,18 @@ var ProviderSet = wire.NewSet(
 	usecase.NewStatUseCase,
 	usecase.NewNodeUsecase,
 	usecase.NewModelUsecase,
+	usecase.NewReindexUsecase,
+	usecase.NewQAPairUsecase,
+	usecase.NewFailedTaskUsecase,
+	usecase.NewNodeChunkUsecase,
+	usecase.NewNodeAttachmentUsecase,
+	usecase.NewImageCaptionUsecase,
 
 	NewRAGMQHandler,
 	NewRagDocUpdateHandler,
 	NewCronHandler,
+	NewReindexHandler,
+	NewDeadLetterHandler,
 
 	wire.Struct(new(MQHandlers), "*"),
 )
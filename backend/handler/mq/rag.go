@@ -3,6 +3,10 @@ package mq
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
 
 	"github.com/chaitin/panda-wiki/consts"
 	"github.com/chaitin/panda-wiki/domain"
@@ -15,24 +19,34 @@ import (
 )
 
 type RAGMQHandler struct {
-	consumer     mq.MQConsumer
-	logger       *log.Logger
-	rag          rag.RAGService
-	nodeRepo     *pg.NodeRepository
-	kbRepo       *pg.KnowledgeBaseRepository
-	llmUsecase   *usecase.LLMUsecase
-	modelUsecase *usecase.ModelUsecase
+	consumer          mq.MQConsumer
+	logger            *log.Logger
+	rag               rag.RAGService
+	nodeRepo          *pg.NodeRepository
+	tagRepo           *pg.TagRepository
+	kbRepo            *pg.KnowledgeBaseRepository
+	llmUsecase        *usecase.LLMUsecase
+	modelUsecase      *usecase.ModelUsecase
+	reindexUsecase    *usecase.ReindexUsecase
+	nodeChunkUsecase  *usecase.NodeChunkUsecase
+	attachmentUsecase *usecase.NodeAttachmentUsecase
+	captionUsecase    *usecase.ImageCaptionUsecase
 }
 
-func NewRAGMQHandler(consumer mq.MQConsumer, logger *log.Logger, rag rag.RAGService, nodeRepo *pg.NodeRepository, kbRepo *pg.KnowledgeBaseRepository, llmUsecase *usecase.LLMUsecase, modelUsecase *usecase.ModelUsecase) (*RAGMQHandler, error) {
+func NewRAGMQHandler(consumer mq.MQConsumer, logger *log.Logger, rag rag.RAGService, nodeRepo *pg.NodeRepository, tagRepo *pg.TagRepository, kbRepo *pg.KnowledgeBaseRepository, llmUsecase *usecase.LLMUsecase, modelUsecase *usecase.ModelUsecase, reindexUsecase *usecase.ReindexUsecase, nodeChunkUsecase *usecase.NodeChunkUsecase, attachmentUsecase *usecase.NodeAttachmentUsecase, captionUsecase *usecase.ImageCaptionUsecase) (*RAGMQHandler, error) {
 	h := &RAGMQHandler{
-		consumer:     consumer,
-		logger:       logger.WithModule("mq.rag"),
-		rag:          rag,
-		nodeRepo:     nodeRepo,
-		kbRepo:       kbRepo,
-		llmUsecase:   llmUsecase,
-		modelUsecase: modelUsecase,
+		consumer:          consumer,
+		logger:            logger.WithModule("mq.rag"),
+		rag:               rag,
+		nodeRepo:          nodeRepo,
+		tagRepo:           tagRepo,
+		kbRepo:            kbRepo,
+		llmUsecase:        llmUsecase,
+		modelUsecase:      modelUsecase,
+		reindexUsecase:    reindexUsecase,
+		nodeChunkUsecase:  nodeChunkUsecase,
+		attachmentUsecase: attachmentUsecase,
+		captionUsecase:    captionUsecase,
 	}
 	if err := consumer.RegisterHandler(domain.VectorTaskTopic, h.HandleNodeContentVectorRequest); err != nil {
 		return nil, err
@@ -40,102 +54,71 @@ func NewRAGMQHandler(consumer mq.MQConsumer, logger *log.Logger, rag rag.RAGServ
 	return h, nil
 }
 
+// HandleNodeContentVectorRequest returns retryable errors for transient failures,
+// the consumer retries them with backoff and moves them to the dead letter topic at last
 func (h *RAGMQHandler) HandleNodeContentVectorRequest(ctx context.Context, msg types.Message) error {
 	var request domain.NodeReleaseVectorRequest
 	err := json.Unmarshal(msg.GetData(), &request)
 	if err != nil {
-		h.logger.Error("unmarshal node content vector request failed", log.Error(err))
-		return nil
+		return domain.PermanentTaskError(fmt.Errorf("unmarshal node content vector request failed: %w", err))
 	}
 	switch request.Action {
 	case "update_group_ids":
 		h.logger.Info("update node group request", log.Any("request", request), log.Any("group_id", request.GroupIds))
 		kb, err := h.kbRepo.GetKnowledgeBaseByID(ctx, request.KBID)
 		if err != nil {
-			h.logger.Error("get kb failed", log.Error(err))
-			return nil
+			return classifyTaskError(fmt.Errorf("get kb failed: %w", err))
 		}
 		if err := h.rag.UpdateDocumentGroupIDs(ctx, kb.DatasetID, request.DocID, request.GroupIds); err != nil {
-			h.logger.Error("update node group failed", log.Error(err))
-			return nil
+			return fmt.Errorf("update node group failed: %w", err)
 		}
 		h.logger.Info("update node group success", log.Any("doc_id", request.DocID), log.Any("group_ids", request.GroupIds))
 
-	case "upsert":
-		h.logger.Debug("upsert node content vector request", "request", request)
-		nodeRelease, err := h.nodeRepo.GetNodeReleaseWithDirPathByID(ctx, request.NodeReleaseID)
-		if err != nil {
-			h.logger.Error("get node content by ids failed", log.Error(err))
-			return nil
-		}
-		if nodeRelease.Type == domain.NodeTypeFolder {
-			h.logger.Info("node is folder, skip upsert", log.Any("node_release_id", request.NodeReleaseID))
-			return nil
-		}
+	case "update_tags":
+		h.logger.Info("update node tags request", log.Any("request", request))
 		kb, err := h.kbRepo.GetKnowledgeBaseByID(ctx, request.KBID)
 		if err != nil {
-			h.logger.Error("get kb failed", log.Error(err), log.String("kb_id", request.KBID))
-			return nil
+			return classifyTaskError(fmt.Errorf("get kb failed: %w", err))
 		}
-
-		groupIds, err := h.nodeRepo.GetNodeAuthGroupIdsByNodeId(ctx, nodeRelease.NodeID, consts.NodePermNameAnswerable)
-		if err != nil {
-			h.logger.Error("get groupIds failed", log.Error(err), log.String("kb_id", request.KBID))
-			return nil
+		if err := h.rag.UpdateDocumentTags(ctx, kb.DatasetID, request.DocID, request.Tags); err != nil {
+			return fmt.Errorf("update node tags failed: %w", err)
 		}
+		h.logger.Info("update node tags success", log.Any("doc_id", request.DocID), log.Any("tags", request.Tags))
 
-		// upsert node content chunks
-		docID, err := h.rag.UpsertRecords(ctx, &rag.UpsertRecordsRequest{
-			ID:        nodeRelease.ID,
-			Title:     nodeRelease.Name,
-			DatasetID: kb.DatasetID,
-			DocID:     nodeRelease.DocID,
-			Content:   nodeRelease.Content,
-			GroupIDs:  groupIds,
-		})
-		if err != nil {
-			h.logger.Error("upsert node content vector failed", log.Error(err))
-			return nil
+	case "upsert":
+		h.logger.Debug("upsert node content vector request", "request", request)
+		if err := h.upsertNodeRelease(ctx, &request); err != nil {
+			return classifyTaskError(fmt.Errorf("upsert node content vector %s failed: %w", request.NodeReleaseID, err))
 		}
-		// update node doc_id
-		if err := h.nodeRepo.UpdateNodeReleaseDocID(ctx, request.NodeReleaseID, docID); err != nil {
-			h.logger.Error("update node doc_id failed", log.String("node_id", request.NodeReleaseID), log.Error(err))
-			return nil
+		h.reindexUsecase.RecordProgress(ctx, &request, true)
+		h.logger.Info("upsert node content vector success", log.Any("updated_ids", request.NodeReleaseID))
+		if err := h.attachmentUsecase.AsyncSyncAttachments(ctx, request.KBID, request.NodeID, request.NodeReleaseID); err != nil {
+			h.logger.Error("queue attachment sync failed", log.String("node_release_id", request.NodeReleaseID), log.Error(err))
 		}
-		// delete old RAG records
-		// get old doc_ids by node_id
-		oldDocIDs, err := h.nodeRepo.GetOldNodeDocIDsByNodeID(ctx, nodeRelease.ID, nodeRelease.NodeID)
-		if err != nil {
-			h.logger.Error("get old doc_ids by node_id failed", log.String("node_id", nodeRelease.NodeID), log.Error(err))
-			return nil
+	case "sync_attachments":
+		h.logger.Info("sync node attachments request", log.Any("request", request))
+		if err := h.attachmentUsecase.SyncAttachments(ctx, request.NodeReleaseID); err != nil {
+			return classifyTaskError(fmt.Errorf("sync attachments of %s failed: %w", request.NodeReleaseID, err))
 		}
-		if len(oldDocIDs) > 0 {
-			// delete old RAG records
-			if err := h.rag.DeleteRecords(ctx, kb.DatasetID, oldDocIDs); err != nil {
-				h.logger.Error("delete old RAG records failed", log.String("kb_id", kb.ID), log.Error(err))
-				return nil
-			}
-		}
-
-		h.logger.Info("upsert node content vector success", log.Any("updated_ids", request.NodeReleaseID))
+		h.logger.Info("sync node attachments success", log.Any("node_release_id", request.NodeReleaseID))
 	case "delete":
 		h.logger.Info("delete node content vector request", log.Any("request", request))
 		kb, err := h.kbRepo.GetKnowledgeBaseByID(ctx, request.KBID)
 		if err != nil {
-			h.logger.Error("get kb failed", log.Error(err))
-			return nil
+			return classifyTaskError(fmt.Errorf("get kb failed: %w", err))
 		}
 		if err := h.rag.DeleteRecords(ctx, kb.DatasetID, []string{request.DocID}); err != nil {
-			h.logger.Error("delete node content vector failed", log.Error(err))
-			return nil
+			return fmt.Errorf("delete node content vector failed: %w", err)
 		}
 		h.logger.Info("delete node content vector success", log.Any("deleted_id", request.NodeReleaseID), log.Any("deleted_doc_id", request.DocID))
+		if err := h.attachmentUsecase.DeleteOrphanAttachments(ctx, request.KBID); err != nil {
+			return fmt.Errorf("delete attachments of deleted nodes failed: %w", err)
+		}
 	case "summary":
 		h.logger.Info("summary node content vector request", log.Any("request", request))
 		node, err := h.nodeRepo.GetNodeByID(ctx, request.NodeID)
 		if err != nil {
-			h.logger.Error("get node by id failed", log.Error(err))
-			return nil
+			return classifyTaskError(fmt.Errorf("get node by id failed: %w", err))
 		}
 		if node.Type == domain.NodeTypeFolder {
 			h.logger.Info("node is folder, skip summary", log.Any("node_id", request.NodeID))
@@ -144,28 +127,96 @@ func (h *RAGMQHandler) HandleNodeContentVectorRequest(ctx context.Context, msg t
 
 		model, err := h.modelUsecase.GetChatModel(ctx)
 		if err != nil {
-			h.logger.Error("get chat model failed", log.Error(err))
-			return nil
+			return classifyTaskError(fmt.Errorf("get chat model failed: %w", err))
 		}
 
 		summary, err := h.llmUsecase.SummaryNode(ctx, request.KBID, model, node.Name, node.Content)
 		if err != nil {
-			h.logger.Error("summary node content failed", log.Error(err))
-			return nil
+			return fmt.Errorf("summary node content failed: %w", err)
 		}
 		if err := h.nodeRepo.UpdateNodeSummary(ctx, request.KBID, request.NodeID, summary); err != nil {
-			h.logger.Error("update node summary failed", log.Error(err))
-			return nil
+			return fmt.Errorf("update node summary failed: %w", err)
 		}
 		if node.Status == domain.NodeStatusPublished {
 			if err := h.nodeRepo.UpdateNodeStatus(ctx, request.KBID, request.NodeID, domain.NodeStatusDraft); err != nil {
-				h.logger.Error("update node status failed", log.Error(err))
-				return nil
+				return fmt.Errorf("update node status failed: %w", err)
 			}
 		}
 
 		h.logger.Info("summary node content vector success", log.Any("summary_id", request.NodeReleaseID), log.Any("summary", summary))
+	default:
+		return domain.PermanentTaskError(fmt.Errorf("unknown action: %s", request.Action))
+	}
+
+	return nil
+}
+
+// classifyTaskError marks errors of missing records as permanent, they won't
+// show up by retrying
+func classifyTaskError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.PermanentTaskError(err)
+	}
+	return err
+}
+
+func (h *RAGMQHandler) upsertNodeRelease(ctx context.Context, request *domain.NodeReleaseVectorRequest) error {
+	nodeRelease, err := h.nodeRepo.GetNodeReleaseWithDirPathByID(ctx, request.NodeReleaseID)
+	if err != nil {
+		return fmt.Errorf("get node content by ids failed: %w", err)
+	}
+	if nodeRelease.Type == domain.NodeTypeFolder {
+		h.logger.Info("node is folder, skip upsert", log.Any("node_release_id", request.NodeReleaseID))
+		return nil
+	}
+	kb, err := h.kbRepo.GetKnowledgeBaseByID(ctx, request.KBID)
+	if err != nil {
+		return fmt.Errorf("get kb %s failed: %w", request.KBID, err)
+	}
+
+	groupIds, err := h.nodeRepo.GetNodeAuthGroupIdsByNodeId(ctx, nodeRelease.NodeID, consts.NodePermNameAnswerable)
+	if err != nil {
+		return fmt.Errorf("get groupIds failed: %w", err)
+	}
+	nodeTags, err := h.tagRepo.GetTagNamesByNodeIDs(ctx, []string{nodeRelease.NodeID})
+	if err != nil {
+		return fmt.Errorf("get node tags failed: %w", err)
 	}
 
+	// index what the images show along with the text around them
+	content := h.captionUsecase.AddImageCaptions(ctx, nodeRelease.Content)
+
+	// upsert node content chunks
+	docID, err := h.rag.UpsertRecords(ctx, &rag.UpsertRecordsRequest{
+		ID:        nodeRelease.ID,
+		Title:     nodeRelease.Name,
+		DatasetID: kb.DatasetID,
+		DocID:     nodeRelease.DocID,
+		Content:   content,
+		GroupIDs:  groupIds,
+		Tags:      nodeTags[nodeRelease.NodeID],
+	})
+	if err != nil {
+		return fmt.Errorf("upsert records failed: %w", err)
+	}
+	// update node doc_id
+	if err := h.nodeRepo.UpdateNodeReleaseDocID(ctx, request.NodeReleaseID, docID); err != nil {
+		return fmt.Errorf("update node doc_id failed: %w", err)
+	}
+	// re-apply manual chunk changes of editors
+	if err := h.nodeChunkUsecase.ApplyOverrides(ctx, kb.DatasetID, docID, nodeRelease.NodeID); err != nil {
+		return fmt.Errorf("apply chunk overrides failed: %w", err)
+	}
+	// delete old RAG records
+	// get old doc_ids by node_id
+	oldDocIDs, err := h.nodeRepo.GetOldNodeDocIDsByNodeID(ctx, nodeRelease.ID, nodeRelease.NodeID)
+	if err != nil {
+		return fmt.Errorf("get old doc_ids by node_id failed: %w", err)
+	}
+	if len(oldDocIDs) > 0 {
+		if err := h.rag.DeleteRecords(ctx, kb.DatasetID, oldDocIDs); err != nil {
+			return fmt.Errorf("delete old RAG records failed: %w", err)
+		}
+	}
 	return nil
 }
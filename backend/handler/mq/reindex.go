@@ -0,0 +1,24 @@
+package mq
+
+import (
+	"context"
+
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/usecase"
+)
+
+type ReindexHandler struct {
+	logger         *log.Logger
+	reindexUsecase *usecase.ReindexUsecase
+}
+
+func NewReindexHandler(logger *log.Logger, reindexUsecase *usecase.ReindexUsecase) *ReindexHandler {
+	h := &ReindexHandler{
+		logger:         logger.WithModule("handler.mq.reindex"),
+		reindexUsecase: reindexUsecase,
+	}
+	// 持续分发重建索引任务，重启后从检查点继续
+	go h.reindexUsecase.Run(context.Background())
+	h.logger.Info("start reindex dispatcher")
+	return h
+}
@@ -132,7 +132,8 @@ func (h *ShareChatHandler) ChatMessage(c echo.Context) error {
 		if err := h.writeSSEEvent(c, event); err != nil {
 			return err
 		}
-		if event.Type == "done" || event.Type == "error" {
+		// suggestions may follow the done event
+		if event.Type == "error" {
 			break
 		}
 	}
@@ -199,7 +200,8 @@ func (h *ShareChatHandler) ChatWidget(c echo.Context) error {
 		if err := h.writeSSEEvent(c, event); err != nil {
 			return err
 		}
-		if event.Type == "done" || event.Type == "error" {
+		// suggestions may follow the done event
+		if event.Type == "error" {
 			break
 		}
 	}
@@ -285,17 +287,9 @@ func (h *ShareChatHandler) ChatCompletions(c echo.Context) error {
 		return h.sendOpenAIError(c, "messages cannot be empty", "invalid_request_error")
 	}
 
-	// use last user message as message
-	var lastUserMessage string
-	for i := len(req.Messages) - 1; i >= 0; i-- {
-		if req.Messages[i].Role == "user" {
-			if req.Messages[i].Content != nil {
-				lastUserMessage = req.Messages[i].Content.String()
-			}
-			break
-		}
-	}
-	if lastUserMessage == "" {
+	// the last user message is the question, earlier messages its history
+	input := req.ChatInput()
+	if input.Question == "" {
 		return h.sendOpenAIError(c, "no user message found", "invalid_request_error")
 	}
 
@@ -320,11 +314,36 @@ func (h *ShareChatHandler) ChatCompletions(c echo.Context) error {
 		}
 	}
 
+	tools, toolChoice, err := req.ChatTools()
+	if err != nil {
+		return h.sendOpenAIError(c, err.Error(), "invalid_request_error")
+	}
+	systemPrompt := input.SystemPrompt
+	if req.ResponseFormat != nil {
+		instruction, err := req.ResponseFormat.Instruction()
+		if err != nil {
+			return h.sendOpenAIError(c, err.Error(), "invalid_request_error")
+		}
+		if instruction != "" {
+			systemPrompt = strings.TrimSpace(systemPrompt + "\n\n" + instruction)
+		}
+	}
+
 	chatReq := &domain.ChatRequest{
-		Message:  lastUserMessage,
-		KBID:     kbID,
-		AppType:  domain.AppTypeOpenAIAPI,
-		RemoteIP: c.RealIP(),
+		Message:      input.Question,
+		KBID:         kbID,
+		AppType:      domain.AppTypeOpenAIAPI,
+		RemoteIP:     c.RealIP(),
+		SystemPrompt: systemPrompt,
+		History:      input.History,
+		Tools:        tools,
+		ToolChoice:   toolChoice,
+		ToolMessages: input.ToolMessages,
+	}
+	// requests of the same user continue one conversation
+	if req.User != "" {
+		chatReq.ConversationID = domain.OpenAIConversationID(kbID, req.User)
+		chatReq.Info.UserInfo.UserID = req.User
 	}
 
 	// set stream response header
@@ -344,18 +363,43 @@ func (h *ShareChatHandler) ChatCompletions(c echo.Context) error {
 	if req.Stream {
 		return h.handleOpenAIStreamResponse(c, eventCh, req.Model)
 	} else {
-		return h.handleOpenAINonStreamResponse(c, eventCh, req.Model)
+		return h.handleOpenAINonStreamResponse(c, eventCh, req.Model, req.ResponseFormat.IsJSON())
 	}
 }
 
 func (h *ShareChatHandler) handleOpenAIStreamResponse(c echo.Context, eventCh <-chan domain.SSEEvent, model string) error {
 	responseID := "chatcmpl-" + generateID()
 	created := time.Now().Unix()
+	finishReason := "stop"
+	handoffID := ""
 
 	for event := range eventCh {
 		switch event.Type {
 		case "error":
+			if event.Error == domain.SSEErrorChatQuotaExceeded {
+				return h.sendOpenAIRateLimitError(c, event.Content)
+			}
 			return h.sendOpenAIError(c, event.Content, "internal_error")
+		case "tool_calls":
+			finishReason = "tool_calls"
+			streamResp := domain.OpenAIStreamResponse{
+				ID:      responseID,
+				Object:  "chat.completion.chunk",
+				Created: created,
+				Model:   model,
+				Choices: []domain.OpenAIStreamChoice{
+					{
+						Index: 0,
+						Delta: domain.OpenAIMessage{
+							Role:      "assistant",
+							ToolCalls: domain.NewOpenAIToolCalls(event.ToolCalls, true),
+						},
+					},
+				},
+			}
+			if err := h.writeOpenAIStreamEvent(c, streamResp); err != nil {
+				return err
+			}
 		case "data":
 			// send stream response
 			streamResp := domain.OpenAIStreamResponse{
@@ -377,6 +421,8 @@ func (h *ShareChatHandler) handleOpenAIStreamResponse(c echo.Context, eventCh <-
 			if err := h.writeOpenAIStreamEvent(c, streamResp); err != nil {
 				return err
 			}
+		case "handoff":
+			handoffID = event.Content
 		case "done":
 			// send done event
 			streamResp := domain.OpenAIStreamResponse{
@@ -388,9 +434,10 @@ func (h *ShareChatHandler) handleOpenAIStreamResponse(c echo.Context, eventCh <-
 					{
 						Index:        0,
 						Delta:        domain.OpenAIMessage{},
-						FinishReason: stringPtr("stop"),
+						FinishReason: stringPtr(finishReason),
 					},
 				},
+				HandoffID: handoffID,
 			}
 			return h.writeOpenAIStreamEvent(c, streamResp)
 		}
@@ -398,18 +445,32 @@ func (h *ShareChatHandler) handleOpenAIStreamResponse(c echo.Context, eventCh <-
 	return nil
 }
 
-func (h *ShareChatHandler) handleOpenAINonStreamResponse(c echo.Context, eventCh <-chan domain.SSEEvent, model string) error {
+func (h *ShareChatHandler) handleOpenAINonStreamResponse(c echo.Context, eventCh <-chan domain.SSEEvent, model string, jsonFormat bool) error {
 	responseID := "chatcmpl-" + generateID()
 	created := time.Now().Unix()
 
 	var content string
+	var toolCalls []domain.OpenAIToolCall
+	finishReason := "stop"
+	handoffID := ""
 	for event := range eventCh {
 		switch event.Type {
 		case "error":
+			if event.Error == domain.SSEErrorChatQuotaExceeded {
+				return h.sendOpenAIRateLimitError(c, event.Content)
+			}
 			return h.sendOpenAIError(c, event.Content, "internal_error")
 		case "data":
 			content += event.Content
+		case "tool_calls":
+			toolCalls = domain.NewOpenAIToolCalls(event.ToolCalls, false)
+			finishReason = "tool_calls"
+		case "handoff":
+			handoffID = event.Content
 		case "done":
+			if jsonFormat {
+				content = domain.TrimJSONFence(content)
+			}
 			// send complete response
 			resp := domain.OpenAICompletionsResponse{
 				ID:      responseID,
@@ -420,12 +481,14 @@ func (h *ShareChatHandler) handleOpenAINonStreamResponse(c echo.Context, eventCh
 					{
 						Index: 0,
 						Message: domain.OpenAIMessage{
-							Role:    "assistant",
-							Content: domain.NewStringContent(content),
+							Role:      "assistant",
+							Content:   domain.NewStringContent(content),
+							ToolCalls: toolCalls,
 						},
-						FinishReason: "stop",
+						FinishReason: finishReason,
 					},
 				},
+				HandoffID: handoffID,
 			}
 			return c.JSON(http.StatusOK, resp)
 		}
@@ -443,6 +506,18 @@ func (h *ShareChatHandler) sendOpenAIError(c echo.Context, message, errorType st
 	return c.JSON(http.StatusBadRequest, errResp)
 }
 
+// sendOpenAIRateLimitError answers 429 as the OpenAI API does for rate limits
+func (h *ShareChatHandler) sendOpenAIRateLimitError(c echo.Context, message string) error {
+	errResp := domain.OpenAIErrorResponse{
+		Error: domain.OpenAIError{
+			Message: message,
+			Type:    "rate_limit_exceeded",
+			Code:    domain.SSEErrorChatQuotaExceeded,
+		},
+	}
+	return c.JSON(http.StatusTooManyRequests, errResp)
+}
+
 func (h *ShareChatHandler) writeOpenAIStreamEvent(c echo.Context, data domain.OpenAIStreamResponse) error {
 	jsonContent, err := json.Marshal(data)
 	if err != nil {
@@ -481,6 +556,7 @@ func (h *ShareChatHandler) ChatSearch(c echo.Context) error {
 		return h.NewResponseWithError(c, "parse request failed", err)
 	}
 	req.KBID = c.Request().Header.Get("X-KB-ID") // get from caddy header
+	req.AppType = domain.AppTypeWeb
 	if err := c.Validate(&req); err != nil {
 		return h.NewResponseWithError(c, "validate request failed", err)
 	}
@@ -525,6 +601,7 @@ func (h *ShareChatHandler) WidgetSearch(c echo.Context) error {
 		return h.NewResponseWithError(c, "parse request failed", err)
 	}
 	req.KBID = c.Request().Header.Get("X-KB-ID")
+	req.AppType = domain.AppTypeWidget
 	if err := c.Validate(&req); err != nil {
 		return h.NewResponseWithError(c, "validate request failed", err)
 	}
@@ -37,6 +37,7 @@ func NewAppHandler(e *echo.Echo, baseHandler *handler.BaseHandler, logger *log.L
 
 	group := e.Group("/api/v1/app", h.auth.Authorize)
 	group.GET("/detail", h.GetAppDetail, h.auth.ValidateKBUserPerm(consts.UserKBPermissionDocManage))
+	group.GET("/chat_quota", h.GetChatQuotaUsage, h.auth.ValidateKBUserPerm(consts.UserKBPermissionDocManage))
 	group.PUT("", h.UpdateApp, h.auth.ValidateKBUserPerm(consts.UserKBPermissionFullControl))
 	group.DELETE("", h.DeleteApp, h.auth.ValidateKBUserPerm(consts.UserKBPermissionFullControl))
 
@@ -101,6 +102,32 @@ func (h *AppHandler) UpdateApp(c echo.Context) error {
 	if err := c.Bind(&appRequest); err != nil {
 		return h.NewResponseWithError(c, "invalid request", err)
 	}
+	if appRequest.Settings != nil {
+		if err := c.Validate(&appRequest.Settings.RetrievalSettings); err != nil {
+			return h.NewResponseWithError(c, "invalid retrieval settings", err)
+		}
+		if err := c.Validate(&appRequest.Settings.AnswerCacheSettings); err != nil {
+			return h.NewResponseWithError(c, "invalid answer cache settings", err)
+		}
+		if err := c.Validate(&appRequest.Settings.SuggestionSettings); err != nil {
+			return h.NewResponseWithError(c, "invalid suggestion settings", err)
+		}
+		if err := c.Validate(&appRequest.Settings.AgentSettings); err != nil {
+			return h.NewResponseWithError(c, "invalid agent settings", err)
+		}
+		if err := c.Validate(&appRequest.Settings.ChatModelSetting); err != nil {
+			return h.NewResponseWithError(c, "invalid chat model setting", err)
+		}
+		if err := c.Validate(&appRequest.Settings.ChatQuotaSettings); err != nil {
+			return h.NewResponseWithError(c, "invalid chat quota settings", err)
+		}
+		if err := c.Validate(&appRequest.Settings.GroundednessSettings); err != nil {
+			return h.NewResponseWithError(c, "invalid groundedness settings", err)
+		}
+		if err := c.Validate(&appRequest.Settings.HandoffSettings); err != nil {
+			return h.NewResponseWithError(c, "invalid handoff settings", err)
+		}
+	}
 
 	ctx := c.Request().Context()
 	if err := h.usecase.ValidateUpdateApp(ctx, id, &appRequest); err != nil {
@@ -115,6 +142,34 @@ func (h *AppHandler) UpdateApp(c echo.Context) error {
 	return h.NewResponseWithData(c, nil)
 }
 
+// GetChatQuotaUsage get chat quota usage
+//
+//	@Summary		Get chat quota usage
+//	@Description	Get the requests of this minute and the tokens of today of an app against its chat quota
+//	@Tags			app
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			kb_id	query		string	true	"kb id"
+//	@Param			type	query		string	true	"app type"
+//	@Success		200		{object}	domain.PWResponse{data=domain.ChatQuotaUsageResp}
+//	@Router			/api/v1/app/chat_quota [get]
+func (h *AppHandler) GetChatQuotaUsage(c echo.Context) error {
+	kbID := c.QueryParam("kb_id")
+	if kbID == "" {
+		return h.NewResponseWithError(c, "kb id is required", nil)
+	}
+	appType, err := strconv.ParseInt(c.QueryParam("type"), 10, 64)
+	if err != nil {
+		return h.NewResponseWithError(c, "invalid app type", err)
+	}
+	usage, err := h.usecase.GetChatQuotaUsage(c.Request().Context(), kbID, domain.AppType(appType))
+	if err != nil {
+		return h.NewResponseWithError(c, "get chat quota usage failed", err)
+	}
+	return h.NewResponseWithData(c, usage)
+}
+
 // DeleteApp delete app
 //
 //	@Summary		Delete app
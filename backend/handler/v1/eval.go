@@ -0,0 +1,260 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+
+	v1 "github.com/chaitin/panda-wiki/api/eval/v1"
+	"github.com/chaitin/panda-wiki/consts"
+	"github.com/chaitin/panda-wiki/handler"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/middleware"
+	"github.com/chaitin/panda-wiki/usecase"
+)
+
+type EvalHandler struct {
+	*handler.BaseHandler
+	logger  *log.Logger
+	usecase *usecase.EvalUsecase
+	auth    middleware.AuthMiddleware
+}
+
+func NewEvalHandler(
+	baseHandler *handler.BaseHandler,
+	echo *echo.Echo,
+	usecase *usecase.EvalUsecase,
+	auth middleware.AuthMiddleware,
+	logger *log.Logger,
+) *EvalHandler {
+	h := &EvalHandler{
+		BaseHandler: baseHandler,
+		logger:      logger.WithModule("handler.v1.eval"),
+		usecase:     usecase,
+		auth:        auth,
+	}
+
+	group := echo.Group("/api/v1/eval", h.auth.Authorize, h.auth.ValidateKBUserPerm(consts.UserKBPermissionFullControl))
+	group.GET("/set/list", h.GetEvalSetList)
+	group.GET("/set/detail", h.GetEvalSetDetail)
+	group.POST("/set", h.CreateEvalSet)
+	group.PUT("/set", h.UpdateEvalSet)
+	group.DELETE("/set", h.DeleteEvalSet)
+	group.POST("/run", h.CreateEvalRun)
+	group.GET("/run/list", h.GetEvalRunList)
+	group.GET("/run/detail", h.GetEvalRunDetail)
+
+	return h
+}
+
+// GetEvalSetList
+//
+//	@Summary		评测集列表
+//	@Description	Get golden question sets of knowledge base
+//	@Tags			Eval
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	query		v1.EvalSetListReq	true	"Params"
+//	@Success		200		{object}	domain.PWResponse{data=[]v1.EvalSetListItemResp}
+//	@Router			/api/v1/eval/set/list [get]
+func (h *EvalHandler) GetEvalSetList(c echo.Context) error {
+	var req v1.EvalSetListReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	resp, err := h.usecase.GetSetList(c.Request().Context(), req.KbId)
+	if err != nil {
+		return h.NewResponseWithError(c, "get eval set list failed", err)
+	}
+	return h.NewResponseWithData(c, resp)
+}
+
+// GetEvalSetDetail
+//
+//	@Summary		评测集详情
+//	@Description	Get golden question set with its cases
+//	@Tags			Eval
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	query		v1.EvalSetDetailReq	true	"Params"
+//	@Success		200		{object}	domain.PWResponse{data=v1.EvalSetDetailResp}
+//	@Router			/api/v1/eval/set/detail [get]
+func (h *EvalHandler) GetEvalSetDetail(c echo.Context) error {
+	var req v1.EvalSetDetailReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	resp, err := h.usecase.GetSetDetail(c.Request().Context(), req.KbId, req.ID)
+	if err != nil {
+		return h.NewResponseWithError(c, "get eval set detail failed", err)
+	}
+	return h.NewResponseWithData(c, resp)
+}
+
+// CreateEvalSet
+//
+//	@Summary		创建评测集
+//	@Description	Create golden question set, each case lists the nodes expected to be retrieved
+//	@Tags			Eval
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.CreateEvalSetReq	true	"Params"
+//	@Success		200		{object}	domain.PWResponse{data=map[string]string}
+//	@Router			/api/v1/eval/set [post]
+func (h *EvalHandler) CreateEvalSet(c echo.Context) error {
+	var req v1.CreateEvalSetReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	id, err := h.usecase.CreateSet(c.Request().Context(), &req)
+	if err != nil {
+		return h.NewResponseWithError(c, "create eval set failed", err)
+	}
+	return h.NewResponseWithData(c, map[string]string{"id": id})
+}
+
+// UpdateEvalSet
+//
+//	@Summary		更新评测集
+//	@Description	Update golden question set, cases are replaced
+//	@Tags			Eval
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.UpdateEvalSetReq	true	"Params"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/eval/set [put]
+func (h *EvalHandler) UpdateEvalSet(c echo.Context) error {
+	var req v1.UpdateEvalSetReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	if err := h.usecase.UpdateSet(c.Request().Context(), &req); err != nil {
+		return h.NewResponseWithError(c, "update eval set failed", err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
+
+// DeleteEvalSet
+//
+//	@Summary		删除评测集
+//	@Description	Delete golden question set with its cases and runs
+//	@Tags			Eval
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	query		v1.DeleteEvalSetReq	true	"Params"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/eval/set [delete]
+func (h *EvalHandler) DeleteEvalSet(c echo.Context) error {
+	var req v1.DeleteEvalSetReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	if err := h.usecase.DeleteSet(c.Request().Context(), req.KbId, req.ID); err != nil {
+		return h.NewResponseWithError(c, "delete eval set failed", err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
+
+// CreateEvalRun
+//
+//	@Summary		运行评测
+//	@Description	Replay the questions of a set through retrieval and store recall@k, MRR and missed nodes
+//	@Tags			Eval
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.CreateEvalRunReq	true	"Params"
+//	@Success		200		{object}	domain.PWResponse{data=domain.EvalRun}
+//	@Router			/api/v1/eval/run [post]
+func (h *EvalHandler) CreateEvalRun(c echo.Context) error {
+	var req v1.CreateEvalRunReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	resp, err := h.usecase.Run(c.Request().Context(), &req)
+	if err != nil {
+		return h.NewResponseWithError(c, "run eval set failed", err)
+	}
+	return h.NewResponseWithData(c, resp)
+}
+
+// GetEvalRunList
+//
+//	@Summary		评测记录列表
+//	@Description	Get runs of a golden question set, latest first
+//	@Tags			Eval
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	query		v1.EvalRunListReq	true	"Params"
+//	@Success		200		{object}	domain.PWResponse{data=[]v1.EvalRunListItemResp}
+//	@Router			/api/v1/eval/run/list [get]
+func (h *EvalHandler) GetEvalRunList(c echo.Context) error {
+	var req v1.EvalRunListReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	resp, err := h.usecase.GetRunList(c.Request().Context(), req.KbId, req.SetID)
+	if err != nil {
+		return h.NewResponseWithError(c, "get eval run list failed", err)
+	}
+	return h.NewResponseWithData(c, resp)
+}
+
+// GetEvalRunDetail
+//
+//	@Summary		评测记录详情
+//	@Description	Get run with per question results
+//	@Tags			Eval
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	query		v1.EvalRunDetailReq	true	"Params"
+//	@Success		200		{object}	domain.PWResponse{data=domain.EvalRun}
+//	@Router			/api/v1/eval/run/detail [get]
+func (h *EvalHandler) GetEvalRunDetail(c echo.Context) error {
+	var req v1.EvalRunDetailReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	resp, err := h.usecase.GetRunDetail(c.Request().Context(), req.KbId, req.ID)
+	if err != nil {
+		return h.NewResponseWithError(c, "get eval run detail failed", err)
+	}
+	return h.NewResponseWithData(c, resp)
+}
@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+
+	v1 "github.com/chaitin/panda-wiki/api/kb/v1"
+)
+
+// GetFailedTaskList
+//
+//	@Summary		GetFailedTaskList
+//	@Description	List vector tasks of the knowledge base that ran out of retries
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			params	query		v1.FailedTaskListReq	true	"Failed Task List Request"
+//	@Success		200		{object}	domain.PWResponse{data=v1.FailedTaskListResp}
+//	@Router			/api/v1/kb/failed_task/list [get]
+func (h *KnowledgeBaseHandler) GetFailedTaskList(c echo.Context) error {
+	var req v1.FailedTaskListReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	resp, err := h.failedTaskUsecase.GetList(c.Request().Context(), &req)
+	if err != nil {
+		return h.NewResponseWithError(c, "get failed task list failed", err)
+	}
+	return h.NewResponseWithData(c, resp)
+}
+
+// GetFailedTaskDetail
+//
+//	@Summary		GetFailedTaskDetail
+//	@Description	Get a failed vector task with its payload
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			params	query		v1.FailedTaskDetailReq	true	"Failed Task Detail Request"
+//	@Success		200		{object}	domain.PWResponse{data=v1.FailedTaskDetailResp}
+//	@Router			/api/v1/kb/failed_task/detail [get]
+func (h *KnowledgeBaseHandler) GetFailedTaskDetail(c echo.Context) error {
+	var req v1.FailedTaskDetailReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	resp, err := h.failedTaskUsecase.GetDetail(c.Request().Context(), req.KBId, req.ID)
+	if err != nil {
+		return h.NewResponseWithError(c, "get failed task detail failed", err)
+	}
+	return h.NewResponseWithData(c, resp)
+}
+
+// ReplayFailedTasks
+//
+//	@Summary		ReplayFailedTasks
+//	@Description	Publish failed vector tasks to their topic again
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.FailedTaskReplayReq	true	"Failed Task Replay Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/kb/failed_task/replay [post]
+func (h *KnowledgeBaseHandler) ReplayFailedTasks(c echo.Context) error {
+	var req v1.FailedTaskReplayReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.failedTaskUsecase.Replay(c.Request().Context(), req.KBId, req.IDs); err != nil {
+		return h.NewResponseWithError(c, "replay failed tasks failed", err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+
+	v1 "github.com/chaitin/panda-wiki/api/kb/v1"
+)
+
+// GetHandoffList
+//
+//	@Summary		GetHandoffList
+//	@Description	List the questions handed off to the staff as the knowledge base could not answer them
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			params	query		v1.HandoffListReq	true	"Handoff List Request"
+//	@Success		200		{object}	domain.PWResponse{data=v1.HandoffListResp}
+//	@Router			/api/v1/kb/handoff/list [get]
+func (h *KnowledgeBaseHandler) GetHandoffList(c echo.Context) error {
+	var req v1.HandoffListReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	resp, err := h.handoffUsecase.GetList(c.Request().Context(), &req)
+	if err != nil {
+		return h.NewResponseWithError(c, "get handoff list failed", err)
+	}
+	return h.NewResponseWithData(c, resp)
+}
+
+// UpdateHandoff
+//
+//	@Summary		UpdateHandoff
+//	@Description	Pick up or resolve a handoff, the current user becomes its assignee
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.HandoffUpdateReq	true	"Handoff Update Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/kb/handoff [put]
+func (h *KnowledgeBaseHandler) UpdateHandoff(c echo.Context) error {
+	var req v1.HandoffUpdateReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+	userID, ok := h.auth.MustGetUserID(c)
+	if !ok {
+		return h.NewResponseWithError(c, "get user id failed", nil)
+	}
+
+	if err := h.handoffUsecase.Update(c.Request().Context(), &req, userID); err != nil {
+		return h.NewResponseWithError(c, "update handoff failed", err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
@@ -0,0 +1,140 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+
+	v1 "github.com/chaitin/panda-wiki/api/kb/v1"
+)
+
+// GetQAPairList
+//
+//	@Summary		GetQAPairList
+//	@Description	List curated question and answer pairs of the knowledge base with their hit counts
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			params	query		v1.QAPairListReq	true	"QA Pair List Request"
+//	@Success		200		{object}	domain.PWResponse{data=v1.QAPairListResp}
+//	@Router			/api/v1/kb/qa_pair/list [get]
+func (h *KnowledgeBaseHandler) GetQAPairList(c echo.Context) error {
+	var req v1.QAPairListReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	resp, err := h.qaPairUsecase.GetList(c.Request().Context(), &req)
+	if err != nil {
+		return h.NewResponseWithError(c, "get qa pair list failed", err)
+	}
+	return h.NewResponseWithData(c, resp)
+}
+
+// CreateQAPair
+//
+//	@Summary		CreateQAPair
+//	@Description	Create a curated question and answer pair
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.QAPairCreateReq	true	"QA Pair Create Request"
+//	@Success		200		{object}	domain.PWResponse{data=string}
+//	@Router			/api/v1/kb/qa_pair [post]
+func (h *KnowledgeBaseHandler) CreateQAPair(c echo.Context) error {
+	var req v1.QAPairCreateReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	id, err := h.qaPairUsecase.Create(c.Request().Context(), &req)
+	if err != nil {
+		return h.NewResponseWithError(c, "create qa pair failed", err)
+	}
+	return h.NewResponseWithData(c, id)
+}
+
+// UpdateQAPair
+//
+//	@Summary		UpdateQAPair
+//	@Description	Update a curated question and answer pair
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.QAPairUpdateReq	true	"QA Pair Update Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/kb/qa_pair [put]
+func (h *KnowledgeBaseHandler) UpdateQAPair(c echo.Context) error {
+	var req v1.QAPairUpdateReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.qaPairUsecase.Update(c.Request().Context(), &req); err != nil {
+		return h.NewResponseWithError(c, "update qa pair failed", err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
+
+// DeleteQAPair
+//
+//	@Summary		DeleteQAPair
+//	@Description	Delete a curated question and answer pair
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	query		v1.QAPairDeleteReq	true	"QA Pair Delete Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/kb/qa_pair [delete]
+func (h *KnowledgeBaseHandler) DeleteQAPair(c echo.Context) error {
+	var req v1.QAPairDeleteReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.qaPairUsecase.Delete(c.Request().Context(), req.KBId, req.ID); err != nil {
+		return h.NewResponseWithError(c, "delete qa pair failed", err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
+
+// PromoteQAPair
+//
+//	@Summary		PromoteQAPair
+//	@Description	Create a curated pair from an assistant message of a conversation
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.QAPairPromoteReq	true	"QA Pair Promote Request"
+//	@Success		200		{object}	domain.PWResponse{data=string}
+//	@Router			/api/v1/kb/qa_pair/promote [post]
+func (h *KnowledgeBaseHandler) PromoteQAPair(c echo.Context) error {
+	var req v1.QAPairPromoteReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	id, err := h.qaPairUsecase.Promote(c.Request().Context(), &req)
+	if err != nil {
+		return h.NewResponseWithError(c, "promote message failed", err)
+	}
+	return h.NewResponseWithData(c, id)
+}
@@ -0,0 +1,35 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+
+	v1 "github.com/chaitin/panda-wiki/api/kb/v1"
+)
+
+// RetrievalDebug
+//
+//	@Summary		RetrievalDebug
+//	@Description	Run retrieval for a question and return the candidates, permission drops and final prompt without calling the LLM
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.RetrievalDebugReq	true	"Retrieval Debug Request"
+//	@Success		200		{object}	domain.PWResponse{data=v1.RetrievalDebugResp}
+//	@Router			/api/v1/kb/retrieval/debug [post]
+func (h *KnowledgeBaseHandler) RetrievalDebug(c echo.Context) error {
+	var req v1.RetrievalDebugReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	resp, err := h.llmUsecase.DebugRetrieval(c.Request().Context(), &req)
+	if err != nil {
+		return h.NewResponseWithError(c, "debug retrieval failed", err)
+	}
+
+	return h.NewResponseWithData(c, resp)
+}
@@ -0,0 +1,172 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+
+	v1 "github.com/chaitin/panda-wiki/api/kb/v1"
+)
+
+// GetRankFusionSetting
+//
+//	@Summary		GetRankFusionSetting
+//	@Description	Get rank fusion weights of vector and keyword retrieval
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			kb_id	query		string	true	"Knowledge Base ID"
+//	@Success		200		{object}	domain.PWResponse{data=v1.RankFusionSettingResp}
+//	@Router			/api/v1/knowledge_base/setting/rank_fusion [get]
+func (h *KnowledgeBaseHandler) GetRankFusionSetting(c echo.Context) error {
+	var req v1.GetRankFusionSettingReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	resp, err := h.usecase.GetRankFusionSetting(c.Request().Context(), req.KBId)
+	if err != nil {
+		return h.NewResponseWithError(c, "get rank fusion setting failed", err)
+	}
+
+	return h.NewResponseWithData(c, resp)
+}
+
+// UpdateRankFusionSetting
+//
+//	@Summary		UpdateRankFusionSetting
+//	@Description	Update rank fusion weights of vector and keyword retrieval, a zero weight disables the leg
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.UpdateRankFusionSettingReq	true	"Update Rank Fusion Setting Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/knowledge_base/setting/rank_fusion [put]
+func (h *KnowledgeBaseHandler) UpdateRankFusionSetting(c echo.Context) error {
+	var req v1.UpdateRankFusionSettingReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.usecase.UpdateRankFusionSetting(c.Request().Context(), req); err != nil {
+		return h.NewResponseWithError(c, "update rank fusion setting failed", err)
+	}
+
+	return h.NewResponseWithData(c, nil)
+}
+
+// GetRerankSetting
+//
+//	@Summary		GetRerankSetting
+//	@Description	Get rerank setting of knowledge base
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			kb_id	query		string	true	"Knowledge Base ID"
+//	@Success		200		{object}	domain.PWResponse{data=v1.RerankSettingResp}
+//	@Router			/api/v1/knowledge_base/setting/rerank [get]
+func (h *KnowledgeBaseHandler) GetRerankSetting(c echo.Context) error {
+	var req v1.GetRerankSettingReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	resp, err := h.usecase.GetRerankSetting(c.Request().Context(), req.KBId)
+	if err != nil {
+		return h.NewResponseWithError(c, "get rerank setting failed", err)
+	}
+
+	return h.NewResponseWithData(c, resp)
+}
+
+// UpdateRerankSetting
+//
+//	@Summary		UpdateRerankSetting
+//	@Description	Enable or disable reranking retrieved chunks with the rerank model
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.UpdateRerankSettingReq	true	"Update Rerank Setting Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/knowledge_base/setting/rerank [put]
+func (h *KnowledgeBaseHandler) UpdateRerankSetting(c echo.Context) error {
+	var req v1.UpdateRerankSettingReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.usecase.UpdateRerankSetting(c.Request().Context(), req); err != nil {
+		return h.NewResponseWithError(c, "update rerank setting failed", err)
+	}
+
+	return h.NewResponseWithData(c, nil)
+}
+
+// GetChatModelSetting
+//
+//	@Summary		GetChatModelSetting
+//	@Description	Get the chat model and fallback models answering questions of knowledge base
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			kb_id	query		string	true	"Knowledge Base ID"
+//	@Success		200		{object}	domain.PWResponse{data=v1.ChatModelSettingResp}
+//	@Router			/api/v1/knowledge_base/setting/chat_model [get]
+func (h *KnowledgeBaseHandler) GetChatModelSetting(c echo.Context) error {
+	var req v1.GetChatModelSettingReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	resp, err := h.usecase.GetChatModelSetting(c.Request().Context(), req.KBId)
+	if err != nil {
+		return h.NewResponseWithError(c, "get chat model setting failed", err)
+	}
+
+	return h.NewResponseWithData(c, resp)
+}
+
+// UpdateChatModelSetting
+//
+//	@Summary		UpdateChatModelSetting
+//	@Description	Update the chat model of knowledge base, an empty model id uses the default chat model. The fallback models are tried in order when the chat model fails.
+//	@Tags			knowledge_base
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.UpdateChatModelSettingReq	true	"Update Chat Model Setting Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/knowledge_base/setting/chat_model [put]
+func (h *KnowledgeBaseHandler) UpdateChatModelSetting(c echo.Context) error {
+	var req v1.UpdateChatModelSettingReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.usecase.UpdateChatModelSetting(c.Request().Context(), req); err != nil {
+		return h.NewResponseWithError(c, "update chat model setting failed", err)
+	}
+
+	return h.NewResponseWithData(c, nil)
+}
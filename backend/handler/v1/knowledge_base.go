@@ -16,10 +16,13 @@ import (
 
 type KnowledgeBaseHandler struct {
 	*handler.BaseHandler
-	usecase    *usecase.KnowledgeBaseUsecase
-	llmUsecase *usecase.LLMUsecase
-	logger     *log.Logger
-	auth       middleware.AuthMiddleware
+	usecase           *usecase.KnowledgeBaseUsecase
+	llmUsecase        *usecase.LLMUsecase
+	failedTaskUsecase *usecase.FailedTaskUsecase
+	qaPairUsecase     *usecase.QAPairUsecase
+	handoffUsecase    *usecase.HandoffUsecase
+	logger            *log.Logger
+	auth              middleware.AuthMiddleware
 }
 
 func NewKnowledgeBaseHandler(
@@ -27,15 +30,21 @@ func NewKnowledgeBaseHandler(
 	echo *echo.Echo,
 	usecase *usecase.KnowledgeBaseUsecase,
 	llmUsecase *usecase.LLMUsecase,
+	failedTaskUsecase *usecase.FailedTaskUsecase,
+	qaPairUsecase *usecase.QAPairUsecase,
+	handoffUsecase *usecase.HandoffUsecase,
 	auth middleware.AuthMiddleware,
 	logger *log.Logger,
 ) *KnowledgeBaseHandler {
 	h := &KnowledgeBaseHandler{
-		BaseHandler: baseHandler,
-		logger:      logger.WithModule("handler.v1.knowledge_base"),
-		usecase:     usecase,
-		llmUsecase:  llmUsecase,
-		auth:        auth,
+		BaseHandler:       baseHandler,
+		logger:            logger.WithModule("handler.v1.knowledge_base"),
+		usecase:           usecase,
+		llmUsecase:        llmUsecase,
+		failedTaskUsecase: failedTaskUsecase,
+		qaPairUsecase:     qaPairUsecase,
+		handoffUsecase:    handoffUsecase,
+		auth:              auth,
 	}
 
 	group := echo.Group("/api/v1/knowledge_base", h.auth.Authorize)
@@ -57,6 +66,38 @@ func NewKnowledgeBaseHandler(
 	releaseGroup.POST("", h.CreateKBRelease)
 	releaseGroup.GET("/list", h.GetKBReleaseList)
 
+	// setting
+	settingGroup := group.Group("/setting")
+	settingGroup.GET("/rank_fusion", h.GetRankFusionSetting, h.auth.ValidateKBUserPerm(consts.UserKBPermissionNotNull))
+	settingGroup.PUT("/rank_fusion", h.UpdateRankFusionSetting, h.auth.ValidateKBUserPerm(consts.UserKBPermissionFullControl))
+	settingGroup.GET("/rerank", h.GetRerankSetting, h.auth.ValidateKBUserPerm(consts.UserKBPermissionNotNull))
+	settingGroup.PUT("/rerank", h.UpdateRerankSetting, h.auth.ValidateKBUserPerm(consts.UserKBPermissionFullControl))
+	settingGroup.GET("/chat_model", h.GetChatModelSetting, h.auth.ValidateKBUserPerm(consts.UserKBPermissionNotNull))
+	settingGroup.PUT("/chat_model", h.UpdateChatModelSetting, h.auth.ValidateKBUserPerm(consts.UserKBPermissionFullControl))
+
+	// retrieval
+	kbGroup := echo.Group("/api/v1/kb", h.auth.Authorize)
+	kbGroup.POST("/retrieval/debug", h.RetrievalDebug, h.auth.ValidateKBUserPerm(consts.UserKBPermissionFullControl))
+
+	// failed vector tasks
+	failedTaskGroup := kbGroup.Group("/failed_task", h.auth.ValidateKBUserPerm(consts.UserKBPermissionFullControl))
+	failedTaskGroup.GET("/list", h.GetFailedTaskList)
+	failedTaskGroup.GET("/detail", h.GetFailedTaskDetail)
+	failedTaskGroup.POST("/replay", h.ReplayFailedTasks)
+
+	// curated question and answer pairs
+	qaPairGroup := kbGroup.Group("/qa_pair", h.auth.ValidateKBUserPerm(consts.UserKBPermissionDocManage))
+	qaPairGroup.GET("/list", h.GetQAPairList)
+	qaPairGroup.POST("", h.CreateQAPair)
+	qaPairGroup.PUT("", h.UpdateQAPair)
+	qaPairGroup.DELETE("", h.DeleteQAPair)
+	qaPairGroup.POST("/promote", h.PromoteQAPair)
+
+	// questions handed off to the staff
+	handoffGroup := kbGroup.Group("/handoff", h.auth.ValidateKBUserPerm(consts.UserKBPermissionDocManage))
+	handoffGroup.GET("/list", h.GetHandoffList)
+	handoffGroup.PUT("", h.UpdateHandoff)
+
 	return h
 }
 
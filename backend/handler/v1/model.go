@@ -17,22 +17,24 @@ import (
 
 type ModelHandler struct {
 	*handler.BaseHandler
-	logger     *log.Logger
-	auth       middleware.AuthMiddleware
-	usecase    *usecase.ModelUsecase
-	llmUsecase *usecase.LLMUsecase
-	modelkit   *modelkit.ModelKit
+	logger         *log.Logger
+	auth           middleware.AuthMiddleware
+	usecase        *usecase.ModelUsecase
+	llmUsecase     *usecase.LLMUsecase
+	reindexUsecase *usecase.ReindexUsecase
+	modelkit       *modelkit.ModelKit
 }
 
-func NewModelHandler(echo *echo.Echo, baseHandler *handler.BaseHandler, logger *log.Logger, auth middleware.AuthMiddleware, usecase *usecase.ModelUsecase, llmUsecase *usecase.LLMUsecase) *ModelHandler {
+func NewModelHandler(echo *echo.Echo, baseHandler *handler.BaseHandler, logger *log.Logger, auth middleware.AuthMiddleware, usecase *usecase.ModelUsecase, llmUsecase *usecase.LLMUsecase, reindexUsecase *usecase.ReindexUsecase) *ModelHandler {
 	modelkit := modelkit.NewModelKit(logger.Logger)
 	handler := &ModelHandler{
-		BaseHandler: baseHandler,
-		logger:      logger.WithModule("handler.v1.model"),
-		auth:        auth,
-		usecase:     usecase,
-		llmUsecase:  llmUsecase,
-		modelkit:    modelkit,
+		BaseHandler:    baseHandler,
+		logger:         logger.WithModule("handler.v1.model"),
+		auth:           auth,
+		usecase:        usecase,
+		llmUsecase:     llmUsecase,
+		reindexUsecase: reindexUsecase,
+		modelkit:       modelkit,
 	}
 	group := echo.Group("/api/v1/model", handler.auth.Authorize, handler.auth.ValidateUserRole(consts.UserRoleAdmin))
 	group.GET("/list", handler.GetModelList)
@@ -43,6 +45,14 @@ func NewModelHandler(echo *echo.Echo, baseHandler *handler.BaseHandler, logger *
 	group.POST("/switch-mode", handler.SwitchMode)
 	group.GET("/mode-setting", handler.GetModelModeSetting)
 
+	// re-index job after embedding model changes
+	reindexGroup := group.Group("/reindex")
+	reindexGroup.GET("", handler.GetReindexJob)
+	reindexGroup.PUT("", handler.UpdateReindexJob)
+	reindexGroup.POST("/pause", handler.PauseReindexJob)
+	reindexGroup.POST("/resume", handler.ResumeReindexJob)
+	reindexGroup.POST("/cancel", handler.CancelReindexJob)
+
 	return handler
 }
 
@@ -19,23 +19,26 @@ import (
 
 type NodeHandler struct {
 	*handler.BaseHandler
-	logger  *log.Logger
-	usecase *usecase.NodeUsecase
-	auth    middleware.AuthMiddleware
+	logger           *log.Logger
+	usecase          *usecase.NodeUsecase
+	nodeChunkUsecase *usecase.NodeChunkUsecase
+	auth             middleware.AuthMiddleware
 }
 
 func NewNodeHandler(
 	baseHandler *handler.BaseHandler,
 	echo *echo.Echo,
 	usecase *usecase.NodeUsecase,
+	nodeChunkUsecase *usecase.NodeChunkUsecase,
 	auth middleware.AuthMiddleware,
 	logger *log.Logger,
 ) *NodeHandler {
 	h := &NodeHandler{
-		BaseHandler: baseHandler,
-		logger:      logger.WithModule("handler.v1.node"),
-		usecase:     usecase,
-		auth:        auth,
+		BaseHandler:      baseHandler,
+		logger:           logger.WithModule("handler.v1.node"),
+		usecase:          usecase,
+		nodeChunkUsecase: nodeChunkUsecase,
+		auth:             auth,
 	}
 
 	group := echo.Group("/api/v1/node", h.auth.Authorize, h.auth.ValidateKBUserPerm(consts.UserKBPermissionDocManage))
@@ -61,6 +64,21 @@ func NewNodeHandler(
 	group.GET("/permission", h.NodePermission)
 	group.PATCH("/permission/edit", h.NodePermissionEdit)
 
+	// node tag
+	group.GET("/tag/list", h.GetTagList)
+	group.POST("/tag", h.CreateTag)
+	group.PUT("/tag", h.UpdateTag)
+	group.DELETE("/tag", h.DeleteTag)
+	group.PUT("/tags", h.NodeTagsUpdate)
+
+	// chunks
+	group.GET("/chunks", h.GetNodeChunkList)
+	group.POST("/chunks", h.AddNodeChunk)
+	group.PUT("/chunks", h.UpdateNodeChunk)
+	group.POST("/chunks/pin", h.PinNodeChunk)
+	group.POST("/chunks/exclude", h.ExcludeNodeChunk)
+	group.DELETE("/chunks/override", h.DeleteNodeChunkOverride)
+
 	return h
 }
 
@@ -0,0 +1,165 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+
+	v1 "github.com/chaitin/panda-wiki/api/node/v1"
+)
+
+// GetNodeChunkList
+//
+//	@Summary		GetNodeChunkList
+//	@Description	List chunks of the published node and the manual overrides
+//	@Tags			node
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			params	query		v1.NodeChunkListReq	true	"Node Chunk List Request"
+//	@Success		200		{object}	domain.PWResponse{data=v1.NodeChunkListResp}
+//	@Router			/api/v1/node/chunks [get]
+func (h *NodeHandler) GetNodeChunkList(c echo.Context) error {
+	var req v1.NodeChunkListReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	resp, err := h.nodeChunkUsecase.GetChunkList(c.Request().Context(), &req)
+	if err != nil {
+		return h.NewResponseWithError(c, err.Error(), err)
+	}
+	return h.NewResponseWithData(c, resp)
+}
+
+// AddNodeChunk
+//
+//	@Summary		AddNodeChunk
+//	@Description	Add a chunk to the node, kept after re-chunking
+//	@Tags			node
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			body	body		v1.NodeChunkAddReq	true	"Node Chunk Add Request"
+//	@Success		200		{object}	domain.PWResponse{data=domain.ChunkListItemResp}
+//	@Router			/api/v1/node/chunks [post]
+func (h *NodeHandler) AddNodeChunk(c echo.Context) error {
+	var req v1.NodeChunkAddReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	resp, err := h.nodeChunkUsecase.AddChunk(c.Request().Context(), &req)
+	if err != nil {
+		return h.NewResponseWithError(c, err.Error(), err)
+	}
+	return h.NewResponseWithData(c, resp)
+}
+
+// UpdateNodeChunk
+//
+//	@Summary		UpdateNodeChunk
+//	@Description	Edit the content of a chunk, kept after re-chunking while the source chunk exists
+//	@Tags			node
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			body	body		v1.NodeChunkUpdateReq	true	"Node Chunk Update Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/node/chunks [put]
+func (h *NodeHandler) UpdateNodeChunk(c echo.Context) error {
+	var req v1.NodeChunkUpdateReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.nodeChunkUsecase.UpdateChunk(c.Request().Context(), &req); err != nil {
+		return h.NewResponseWithError(c, err.Error(), err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
+
+// PinNodeChunk
+//
+//	@Summary		PinNodeChunk
+//	@Description	Keep the chunk even when the document changes
+//	@Tags			node
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			body	body		v1.NodeChunkActionReq	true	"Node Chunk Action Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/node/chunks/pin [post]
+func (h *NodeHandler) PinNodeChunk(c echo.Context) error {
+	var req v1.NodeChunkActionReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.nodeChunkUsecase.PinChunk(c.Request().Context(), &req); err != nil {
+		return h.NewResponseWithError(c, err.Error(), err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
+
+// ExcludeNodeChunk
+//
+//	@Summary		ExcludeNodeChunk
+//	@Description	Remove the chunk from retrieval, kept after re-chunking
+//	@Tags			node
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			body	body		v1.NodeChunkActionReq	true	"Node Chunk Action Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/node/chunks/exclude [post]
+func (h *NodeHandler) ExcludeNodeChunk(c echo.Context) error {
+	var req v1.NodeChunkActionReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.nodeChunkUsecase.ExcludeChunk(c.Request().Context(), &req); err != nil {
+		return h.NewResponseWithError(c, err.Error(), err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
+
+// DeleteNodeChunkOverride
+//
+//	@Summary		DeleteNodeChunkOverride
+//	@Description	Revert a manual chunk change, the node is re-chunked with the remaining ones
+//	@Tags			node
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			params	query		v1.NodeChunkOverrideDeleteReq	true	"Node Chunk Override Delete Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/node/chunks/override [delete]
+func (h *NodeHandler) DeleteNodeChunkOverride(c echo.Context) error {
+	var req v1.NodeChunkOverrideDeleteReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.nodeChunkUsecase.DeleteOverride(c.Request().Context(), &req); err != nil {
+		return h.NewResponseWithError(c, err.Error(), err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
@@ -0,0 +1,144 @@
+package v1
+
+import (
+	"github.com/labstack/echo/v4"
+
+	v1 "github.com/chaitin/panda-wiki/api/node/v1"
+)
+
+// GetTagList 标签列表
+//
+//	@Tags			NodeTag
+//	@Summary		标签列表
+//	@Description	标签列表
+//	@ID				v1-GetTagList
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	query		v1.TagListReq	true	"para"
+//	@Success		200		{object}	domain.PWResponse{data=[]v1.TagListItemResp}
+//	@Router			/api/v1/node/tag/list [get]
+func (h *NodeHandler) GetTagList(c echo.Context) error {
+	var req v1.TagListReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	tags, err := h.usecase.GetTagList(c.Request().Context(), req.KbId)
+	if err != nil {
+		return h.NewResponseWithError(c, "get tag list failed", err)
+	}
+	return h.NewResponseWithData(c, tags)
+}
+
+// CreateTag 创建标签
+//
+//	@Tags			NodeTag
+//	@Summary		创建标签
+//	@Description	创建标签
+//	@ID				v1-CreateTag
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.CreateTagReq	true	"para"
+//	@Success		200		{object}	domain.PWResponse{data=map[string]string}
+//	@Router			/api/v1/node/tag [post]
+func (h *NodeHandler) CreateTag(c echo.Context) error {
+	var req v1.CreateTagReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	id, err := h.usecase.CreateTag(c.Request().Context(), &req)
+	if err != nil {
+		return h.NewResponseWithError(c, "create tag failed", err)
+	}
+	return h.NewResponseWithData(c, map[string]string{"id": id})
+}
+
+// UpdateTag 更新标签
+//
+//	@Tags			NodeTag
+//	@Summary		更新标签
+//	@Description	更新标签
+//	@ID				v1-UpdateTag
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.UpdateTagReq	true	"para"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/node/tag [put]
+func (h *NodeHandler) UpdateTag(c echo.Context) error {
+	var req v1.UpdateTagReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	if err := h.usecase.UpdateTag(c.Request().Context(), &req); err != nil {
+		return h.NewResponseWithError(c, "update tag failed", err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
+
+// DeleteTag 删除标签
+//
+//	@Tags			NodeTag
+//	@Summary		删除标签
+//	@Description	删除标签, 同时移除文档上的该标签
+//	@ID				v1-DeleteTag
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	query		v1.DeleteTagReq	true	"para"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/node/tag [delete]
+func (h *NodeHandler) DeleteTag(c echo.Context) error {
+	var req v1.DeleteTagReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	if err := h.usecase.DeleteTag(c.Request().Context(), &req); err != nil {
+		return h.NewResponseWithError(c, "delete tag failed", err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
+
+// NodeTagsUpdate 设置文档标签
+//
+//	@Tags			NodeTag
+//	@Summary		设置文档标签
+//	@Description	覆盖设置文档标签, 不存在的标签会自动创建
+//	@ID				v1-NodeTagsUpdate
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.NodeTagsUpdateReq	true	"para"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/node/tags [put]
+func (h *NodeHandler) NodeTagsUpdate(c echo.Context) error {
+	var req v1.NodeTagsUpdateReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "request params is invalid", err)
+	}
+	if err := c.Validate(req); err != nil {
+		return h.NewResponseWithError(c, "validate request params failed", err)
+	}
+
+	if err := h.usecase.UpdateNodeTags(c.Request().Context(), &req); err != nil {
+		return h.NewResponseWithError(c, "update node tags failed", err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
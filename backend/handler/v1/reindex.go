@@ -0,0 +1,141 @@
+package v1
+
+import (
+	"errors"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	v1 "github.com/chaitin/panda-wiki/api/model/v1"
+)
+
+// GetReindexJob
+//
+//	@Summary		GetReindexJob
+//	@Description	Get progress of a re-index job, the latest one when id is empty
+//	@Tags			model
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			id	query		string	false	"job id"
+//	@Success		200	{object}	domain.PWResponse{data=v1.ReindexJobResp}
+//	@Router			/api/v1/model/reindex [get]
+func (h *ModelHandler) GetReindexJob(c echo.Context) error {
+	var req v1.ReindexJobDetailReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+
+	resp, err := h.reindexUsecase.GetJob(c.Request().Context(), req.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return h.NewResponseWithData(c, nil)
+		}
+		return h.NewResponseWithError(c, "get reindex job failed", err)
+	}
+	return h.NewResponseWithData(c, resp)
+}
+
+// UpdateReindexJob
+//
+//	@Summary		UpdateReindexJob
+//	@Description	Update throttling of a re-index job
+//	@Tags			model
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.ReindexJobUpdateReq	true	"Update Reindex Job Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/model/reindex [put]
+func (h *ModelHandler) UpdateReindexJob(c echo.Context) error {
+	var req v1.ReindexJobUpdateReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.reindexUsecase.UpdateRateLimit(c.Request().Context(), req.ID, req.RateLimit); err != nil {
+		return h.NewResponseWithError(c, "update reindex job failed", err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
+
+// PauseReindexJob
+//
+//	@Summary		PauseReindexJob
+//	@Description	Pause a running re-index job
+//	@Tags			model
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.ReindexJobActionReq	true	"Reindex Job Action Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/model/reindex/pause [post]
+func (h *ModelHandler) PauseReindexJob(c echo.Context) error {
+	var req v1.ReindexJobActionReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.reindexUsecase.PauseJob(c.Request().Context(), req.ID); err != nil {
+		return h.NewResponseWithError(c, "pause reindex job failed", err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
+
+// ResumeReindexJob
+//
+//	@Summary		ResumeReindexJob
+//	@Description	Resume a paused re-index job from its checkpoint
+//	@Tags			model
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.ReindexJobActionReq	true	"Reindex Job Action Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/model/reindex/resume [post]
+func (h *ModelHandler) ResumeReindexJob(c echo.Context) error {
+	var req v1.ReindexJobActionReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.reindexUsecase.ResumeJob(c.Request().Context(), req.ID); err != nil {
+		return h.NewResponseWithError(c, "resume reindex job failed", err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
+
+// CancelReindexJob
+//
+//	@Summary		CancelReindexJob
+//	@Description	Cancel a running or paused re-index job
+//	@Tags			model
+//	@Accept			json
+//	@Produce		json
+//	@Security		bearerAuth
+//	@Param			param	body		v1.ReindexJobActionReq	true	"Reindex Job Action Request"
+//	@Success		200		{object}	domain.Response
+//	@Router			/api/v1/model/reindex/cancel [post]
+func (h *ModelHandler) CancelReindexJob(c echo.Context) error {
+	var req v1.ReindexJobActionReq
+	if err := c.Bind(&req); err != nil {
+		return h.NewResponseWithError(c, "invalid request", err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return h.NewResponseWithError(c, "validate request failed", err)
+	}
+
+	if err := h.reindexUsecase.CancelJob(c.Request().Context(), req.ID); err != nil {
+		return h.NewResponseWithError(c, "cancel reindex job failed", err)
+	}
+	return h.NewResponseWithData(c, nil)
+}
@@ -0,0 +1,51 @@
+package fns
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/repo/pg"
+)
+
+type MigrationBackfillNodeReleaseSearchVector struct {
+	Name     string
+	logger   *log.Logger
+	nodeRepo *pg.NodeRepository
+}
+
+func NewMigrationBackfillNodeReleaseSearchVector(logger *log.Logger, nodeRepo *pg.NodeRepository) *MigrationBackfillNodeReleaseSearchVector {
+	return &MigrationBackfillNodeReleaseSearchVector{
+		Name:     "0006_backfill_node_release_search_vector",
+		logger:   logger,
+		nodeRepo: nodeRepo,
+	}
+}
+
+func (m *MigrationBackfillNodeReleaseSearchVector) Execute(tx *gorm.DB) error {
+	// 只需要为当前已索引的发布版本建立全文索引
+	lastID := ""
+	total := 0
+	for {
+		var releases []*domain.NodeRelease
+		if err := tx.Model(&domain.NodeRelease{}).
+			Select("id, type, name, content").
+			Where("doc_id != ''").
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(100).
+			Find(&releases).Error; err != nil {
+			return err
+		}
+		if len(releases) == 0 {
+			break
+		}
+		if err := m.nodeRepo.UpdateNodeReleaseSearchVectorsTx(tx, releases); err != nil {
+			return err
+		}
+		total += len(releases)
+		lastID = releases[len(releases)-1].ID
+	}
+	m.logger.Info("migration backfill node release search vector", log.Int("count", total))
+	return nil
+}
@@ -10,4 +10,5 @@ var ProviderSet = wire.NewSet(
 	NewMigrationFixGroupIds,
 	NewMigrationUpdateNodeStatusUnreleased,
 	NewMigrationCreateFirstNavs,
+	NewMigrationBackfillNodeReleaseSearchVector,
 )
@@ -10,6 +10,7 @@ type MigrationFuncs struct {
 	FixGroupIdsMigration                *fns.MigrationFixGroupIds
 	UpdateNodeStatusUnreleasedMigration *fns.MigrationUpdateNodeStatusUnreleased
 	CreateFirstNavs                     *fns.MigrationCreateFirstNavs
+	BackfillNodeReleaseSearchVector     *fns.MigrationBackfillNodeReleaseSearchVector
 }
 
 func (mf *MigrationFuncs) GetMigrationFuncs() []MigrationFunc {
@@ -34,5 +35,9 @@ func (mf *MigrationFuncs) GetMigrationFuncs() []MigrationFunc {
 		Name: mf.CreateFirstNavs.Name,
 		Fn:   mf.CreateFirstNavs.Execute,
 	})
+	funcs = append(funcs, MigrationFunc{
+		Name: mf.BackfillNodeReleaseSearchVector.Name,
+		Fn:   mf.BackfillNodeReleaseSearchVector.Execute,
+	})
 	return funcs
 }
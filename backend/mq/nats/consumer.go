@@ -2,7 +2,9 @@ package nats
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
+	"time"
 
 	"github.com/nats-io/nats.go"
 
@@ -113,6 +115,7 @@ func (c *MQConsumer) registerJetStreamHandler(topic string, handler func(ctx con
 			c.logger.Error("handle message failed",
 				log.String("topic", topic),
 				log.Error(err))
+			c.handleFailure(topic, msg, err)
 			return
 		}
 
@@ -134,6 +137,55 @@ func (c *MQConsumer) registerJetStreamHandler(topic string, handler func(ctx con
 	return nil
 }
 
+// handleFailure 按主题的重试策略延迟重投消息，超过次数或不可重试时转入死信主题
+func (c *MQConsumer) handleFailure(topic string, msg *nats.Msg, handleErr error) {
+	policy, ok := domain.TopicRetryPolicy[topic]
+	if !ok {
+		return
+	}
+	attempts := 1
+	if meta, err := msg.Metadata(); err == nil {
+		attempts = int(meta.NumDelivered)
+	}
+	if domain.IsRetryableTaskError(handleErr) && attempts < policy.MaxAttempts {
+		backoff := policy.Backoff(attempts)
+		c.logger.Warn("retry message later",
+			log.String("topic", topic),
+			log.Int("attempts", attempts),
+			log.String("backoff", backoff.String()))
+		if err := msg.NakWithDelay(backoff); err != nil {
+			c.logger.Error("failed to nak message", log.String("topic", topic), log.Error(err))
+		}
+		return
+	}
+
+	deadLetter, err := json.Marshal(&domain.DeadLetterMessage{
+		Topic:    topic,
+		Data:     msg.Data,
+		Error:    handleErr.Error(),
+		Attempts: attempts,
+		FailedAt: time.Now(),
+	})
+	if err == nil {
+		_, err = c.js.Publish(policy.DeadLetterTopic, deadLetter)
+	}
+	if err != nil {
+		// keep the message, it will be retried once the dead letter is writable
+		c.logger.Error("failed to publish dead letter", log.String("topic", topic), log.Error(err))
+		if err := msg.NakWithDelay(policy.MaxBackoff); err != nil {
+			c.logger.Error("failed to nak message", log.String("topic", topic), log.Error(err))
+		}
+		return
+	}
+	c.logger.Warn("message moved to dead letter",
+		log.String("topic", topic),
+		log.String("dead_letter_topic", policy.DeadLetterTopic),
+		log.Int("attempts", attempts))
+	if err := msg.Term(); err != nil {
+		c.logger.Error("failed to term message", log.String("topic", topic), log.Error(err))
+	}
+}
+
 func (c *MQConsumer) StartConsumerHandlers(ctx context.Context) error {
 	<-ctx.Done()
 	return nil
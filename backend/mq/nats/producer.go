@@ -30,6 +30,10 @@ func (p *MQProducer) EnsureStreams() error {
 			name:     "scraper",
 			subjects: []string{"apps.panda-wiki.scraper.>"},
 		},
+		{
+			name:     "dead_letter",
+			subjects: []string{"apps.panda-wiki.dead.>"},
+		},
 	}
 
 	for _, stream := range streams {
@@ -113,27 +113,13 @@ func (cfg *WechatServiceConfig) ProcessMessage(msgRet *MsgRet, GetQA bot.GetQAFu
 		if slices.Contains(cfg.equalKeywords, content) || lo.SomeBy(cfg.containKeywords, func(sub string) bool {
 			return strings.Contains(content, sub)
 		}) {
-			// 改变状态为人工接待
-			// 非人工 ->转人工
-			humanList, err := cfg.GetKfHumanList(token, openkfId)
+			transferred, err := cfg.TransferToHuman(token, userId, openkfId)
 			if err != nil {
-				cfg.logger.Error("get human list failed", log.Error(err))
 				return err
 			}
-			// 遍历找到可以接待的员工
-			for _, servicer := range humanList.ServicerList {
-				if servicer.Status == 0 { // 可以接待
-					err := ChangeState(token, userId, openkfId, 3, servicer.UserID)
-					if err != nil {
-						cfg.logger.Error("change state to human failed", log.Error(err))
-						return err
-					}
-					cfg.logger.Info("change state to human successful") // 转人工成功
-					return nil
-				}
+			if transferred {
+				return nil
 			}
-			// 失败
-			cfg.logger.Info("no human available")
 			return cfg.SendResponseToKfTxt(userId, openkfId, "当前没有可用的人工客服", token)
 		}
 	}
@@ -186,6 +172,32 @@ func (cfg *WechatServiceConfig) ProcessMessage(msgRet *MsgRet, GetQA bot.GetQAFu
 	return cfg.SendResponseToKfUrl(userId, openkfId, conversationID, token, content, info.BaseUrl, info.ImagePath, disclaimerContent)
 }
 
+// TransferToHuman 将会话转给第一个可以接待的人工客服，没有可接待的客服时返回 false
+func (cfg *WechatServiceConfig) TransferToHuman(token, userId, openkfId string) (bool, error) {
+	// 改变状态为人工接待
+	// 非人工 ->转人工
+	humanList, err := cfg.GetKfHumanList(token, openkfId)
+	if err != nil {
+		cfg.logger.Error("get human list failed", log.Error(err))
+		return false, err
+	}
+	// 遍历找到可以接待的员工
+	for _, servicer := range humanList.ServicerList {
+		if servicer.Status == 0 { // 可以接待
+			err := ChangeState(token, userId, openkfId, 3, servicer.UserID)
+			if err != nil {
+				cfg.logger.Error("change state to human failed", log.Error(err))
+				return false, err
+			}
+			cfg.logger.Info("change state to human successful") // 转人工成功
+			return true, nil
+		}
+	}
+	// 失败
+	cfg.logger.Info("no human available")
+	return false, nil
+}
+
 func (cfg *WechatServiceConfig) getImageID(token, image string) (string, error) {
 	const minioPrefix = "http://panda-wiki-minio:9000"
 
@@ -0,0 +1,106 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/utils"
+)
+
+// Client calls the /rerank endpoint shared by jina, cohere, siliconflow and
+// most openai compatible model gateways
+type Client struct {
+	httpClient *http.Client
+}
+
+func NewClient() *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type Result struct {
+	Index int     `json:"index"`
+	Score float64 `json:"relevance_score"`
+}
+
+type request struct {
+	Model     string   `json:"model"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      int      `json:"top_n,omitempty"`
+}
+
+type response struct {
+	Results []Result `json:"results"`
+	Error   *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Rerank scores documents against the query, results are ordered by score
+// descending and refer to documents by index
+func (c *Client) Rerank(ctx context.Context, model *domain.Model, query string, documents []string, topN int) ([]Result, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	body, err := json.Marshal(request{
+		Model:     model.Model,
+		Query:     query,
+		Documents: documents,
+		TopN:      topN,
+	})
+	if err != nil {
+		return nil, err
+	}
+	url := strings.TrimSuffix(model.BaseURL, "/") + "/rerank"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if model.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+model.APIKey)
+	}
+	for key, value := range utils.GetHeaderMap(model.APIHeader) {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request rerank failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read rerank response failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rerank request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+	var result response
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal rerank response failed: %w", err)
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("rerank request failed: %s", result.Error.Message)
+	}
+	results := make([]Result, 0, len(result.Results))
+	for _, r := range result.Results {
+		if r.Index >= 0 && r.Index < len(documents) {
+			results = append(results, r)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results, nil
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/store/cache"
+)
+
+type AnswerCacheRepo struct {
+	cache *cache.Cache
+}
+
+func NewAnswerCacheRepo(cache *cache.Cache) *AnswerCacheRepo {
+	return &AnswerCacheRepo{cache: cache}
+}
+
+func answerCacheKey(kbID, scope string) string {
+	return fmt.Sprintf("answer_cache:%s:%s", kbID, scope)
+}
+
+// GetAnswers returns the cached answers of a scope, newest first
+func (r *AnswerCacheRepo) GetAnswers(ctx context.Context, kbID, scope string) ([]*domain.CachedAnswer, error) {
+	values, err := r.cache.LRange(ctx, answerCacheKey(kbID, scope), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	answers := make([]*domain.CachedAnswer, 0, len(values))
+	for _, value := range values {
+		var answer domain.CachedAnswer
+		if err := json.Unmarshal([]byte(value), &answer); err != nil {
+			continue
+		}
+		answers = append(answers, &answer)
+	}
+	return answers, nil
+}
+
+// AddAnswer stores an answer in a scope, the oldest answers are dropped past
+// domain.MaxAnswerCacheEntries and the scope expires ttl after the last answer
+func (r *AnswerCacheRepo) AddAnswer(ctx context.Context, kbID, scope string, answer *domain.CachedAnswer, ttl time.Duration) error {
+	value, err := json.Marshal(answer)
+	if err != nil {
+		return err
+	}
+	key := answerCacheKey(kbID, scope)
+	pipe := r.cache.TxPipeline()
+	pipe.LPush(ctx, key, value)
+	pipe.LTrim(ctx, key, 0, domain.MaxAnswerCacheEntries-1)
+	pipe.Expire(ctx, key, ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// DeleteKBAnswers drops every cached answer of a kb
+func (r *AnswerCacheRepo) DeleteKBAnswers(ctx context.Context, kbID string) error {
+	return r.cache.DeleteKeysWithPrefix(ctx, answerCacheKey(kbID, ""))
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/store/cache"
+)
+
+const (
+	chatRequestsKeyExpiry = 2 * time.Minute
+	chatTokensKeyExpiry   = 48 * time.Hour
+)
+
+type ChatQuotaRepo struct {
+	cache *cache.Cache
+}
+
+func NewChatQuotaRepo(cache *cache.Cache) *ChatQuotaRepo {
+	return &ChatQuotaRepo{cache: cache}
+}
+
+// requests are counted per minute, an empty user counts the whole app
+func chatRequestsKey(appID, user string, now time.Time) string {
+	return fmt.Sprintf("chat_quota:requests:%s:%d:%s", appID, now.Unix()/60, user)
+}
+
+func chatAppTokensKey(appID string, now time.Time) string {
+	return fmt.Sprintf("chat_quota:app_tokens:%s:%s", appID, now.Format("20060102"))
+}
+
+// the tokens of the users of an app are a sorted set, to list the top users
+func chatUserTokensKey(appID string, now time.Time) string {
+	return fmt.Sprintf("chat_quota:user_tokens:%s:%s", appID, now.Format("20060102"))
+}
+
+// IncrRequests counts a request of the app and of the user, returning the
+// requests of both in the current minute
+func (r *ChatQuotaRepo) IncrRequests(ctx context.Context, appID, user string, now time.Time) (int64, int64, error) {
+	appKey := chatRequestsKey(appID, "", now)
+	userKey := chatRequestsKey(appID, user, now)
+	pipe := r.cache.TxPipeline()
+	appRequests := pipe.Incr(ctx, appKey)
+	pipe.Expire(ctx, appKey, chatRequestsKeyExpiry)
+	userRequests := pipe.Incr(ctx, userKey)
+	pipe.Expire(ctx, userKey, chatRequestsKeyExpiry)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, err
+	}
+	return appRequests.Val(), userRequests.Val(), nil
+}
+
+// GetAppRequests returns the requests of the app in the current minute
+func (r *ChatQuotaRepo) GetAppRequests(ctx context.Context, appID string, now time.Time) (int64, error) {
+	return r.getInt(ctx, chatRequestsKey(appID, "", now))
+}
+
+// GetTokens returns the tokens used today by the app and by the user
+func (r *ChatQuotaRepo) GetTokens(ctx context.Context, appID, user string, now time.Time) (int64, int64, error) {
+	appTokens, err := r.getInt(ctx, chatAppTokensKey(appID, now))
+	if err != nil {
+		return 0, 0, err
+	}
+	userTokens, err := r.cache.ZScore(ctx, chatUserTokensKey(appID, now), user).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, 0, err
+	}
+	return appTokens, int64(userTokens), nil
+}
+
+// AddTokens adds the tokens of a chat to the app and the user of today
+func (r *ChatQuotaRepo) AddTokens(ctx context.Context, appID, user string, tokens int64, now time.Time) error {
+	appKey := chatAppTokensKey(appID, now)
+	userKey := chatUserTokensKey(appID, now)
+	pipe := r.cache.TxPipeline()
+	pipe.IncrBy(ctx, appKey, tokens)
+	pipe.Expire(ctx, appKey, chatTokensKeyExpiry)
+	pipe.ZIncrBy(ctx, userKey, float64(tokens), user)
+	pipe.Expire(ctx, userKey, chatTokensKeyExpiry)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetTopUsers returns the users of the app using the most tokens today
+func (r *ChatQuotaRepo) GetTopUsers(ctx context.Context, appID string, now time.Time, limit int64) ([]*domain.ChatQuotaUserUsage, error) {
+	members, err := r.cache.ZRevRangeWithScores(ctx, chatUserTokensKey(appID, now), 0, limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	users := make([]*domain.ChatQuotaUserUsage, 0, len(members))
+	for _, member := range members {
+		user, _ := member.Member.(string)
+		users = append(users, &domain.ChatQuotaUserUsage{
+			User:        user,
+			TokensToday: int64(member.Score),
+		})
+	}
+	return users, nil
+}
+
+func (r *ChatQuotaRepo) getInt(ctx context.Context, key string) (int64, error) {
+	value, err := r.cache.Get(ctx, key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return value, err
+}
@@ -10,4 +10,6 @@ var ProviderSet = wire.NewSet(
 	cache.NewCache,
 	NewKBRepo,
 	NewGeoCache,
+	NewAnswerCacheRepo,
+	NewChatQuotaRepo,
 )
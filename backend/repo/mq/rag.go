@@ -28,3 +28,8 @@ func (r *RAGRepository) AsyncUpdateNodeReleaseVector(ctx context.Context, reques
 	}
 	return nil
 }
+
+// Republish sends a raw task to its topic again
+func (r *RAGRepository) Republish(ctx context.Context, topic string, data []byte) error {
+	return r.producer.Produce(ctx, topic, "", data)
+}
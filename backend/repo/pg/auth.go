@@ -159,6 +159,17 @@ func (r *AuthRepo) GetAuthGroupIdsByAuthId(ctx context.Context, authID uint) ([]
 	return groupIds, nil
 }
 
+// GetAuthGroupIdsByKBID returns all auth group ids of the kb
+func (r *AuthRepo) GetAuthGroupIdsByKBID(ctx context.Context, kbID string) ([]int, error) {
+	groupIds := make([]int, 0)
+	if err := r.db.WithContext(ctx).Model(&domain.AuthGroup{}).
+		Where("kb_id = ?", kbID).
+		Pluck("id", &groupIds).Error; err != nil {
+		return nil, err
+	}
+	return groupIds, nil
+}
+
 // GetAuthGroupIdsWithParentsByAuthId retrieves user's auth group IDs and all parent group IDs (for permission inheritance)
 func (r *AuthRepo) GetAuthGroupIdsWithParentsByAuthId(ctx context.Context, authID uint) ([]int, error) {
 	groupsMap, err := r.getAuthGroupsWithParentsByAuthId(ctx, authID)
@@ -174,6 +185,44 @@ func (r *AuthRepo) GetAuthGroupIdsWithParentsByAuthId(ctx context.Context, authI
 	return result, nil
 }
 
+// GetAuthInKB returns the auth the user of the auth has in another kb. The
+// user is matched by source type and union id, nil for users unknown to the kb.
+func (r *AuthRepo) GetAuthInKB(ctx context.Context, authID uint, kbID string) (*domain.Auth, error) {
+	var auth domain.Auth
+	if err := r.db.WithContext(ctx).Model(&domain.Auth{}).Where("id = ?", authID).First(&auth).Error; err != nil {
+		return nil, err
+	}
+	if auth.KBID == kbID {
+		return &auth, nil
+	}
+	if auth.UnionID == "" {
+		return nil, nil
+	}
+	var kbAuth domain.Auth
+	if err := r.db.WithContext(ctx).Model(&domain.Auth{}).
+		Where("kb_id = ? AND source_type = ? AND union_id = ?", kbID, auth.SourceType, auth.UnionID).
+		First(&kbAuth).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &kbAuth, nil
+}
+
+// GetAuthGroupIdsInKB returns the group ids, with parents, the user of the
+// auth has in another kb, users unknown to the kb have no groups.
+func (r *AuthRepo) GetAuthGroupIdsInKB(ctx context.Context, authID uint, kbID string) ([]int, error) {
+	kbAuth, err := r.GetAuthInKB(ctx, authID, kbID)
+	if err != nil {
+		return nil, err
+	}
+	if kbAuth == nil {
+		return []int{}, nil
+	}
+	return r.GetAuthGroupIdsWithParentsByAuthId(ctx, kbAuth.ID)
+}
+
 func (r *AuthRepo) GetAuthBySourceType(ctx context.Context, sourceType consts.SourceType) (*domain.Auth, error) {
 	var auth *domain.Auth
 	if err := r.db.WithContext(ctx).Model(&domain.Auth{}).Where("source_type = ?", string(sourceType)).First(&auth).Error; err != nil {
@@ -6,6 +6,7 @@ import (
 
 	"github.com/cloudwego/eino/schema"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/chaitin/panda-wiki/domain"
 	"github.com/chaitin/panda-wiki/log"
@@ -38,6 +39,18 @@ func (r *ConversationRepository) CreateConversation(ctx context.Context, convers
 	return r.db.WithContext(ctx).Create(conversation).Error
 }
 
+// CreateConversationIfNotExists reports whether the conversation was created,
+// false when a conversation with the id exists already
+func (r *ConversationRepository) CreateConversationIfNotExists(ctx context.Context, conversation *domain.Conversation) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(conversation)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
 func (r *ConversationRepository) GetConversationList(ctx context.Context, request *domain.ConversationListReq) ([]*domain.ConversationListItem, uint64, error) {
 	conversations := []*domain.ConversationListItem{}
 	query := r.db.WithContext(ctx).
@@ -53,13 +66,18 @@ func (r *ConversationRepository) GetConversationList(ctx context.Context, reques
 	if request.RemoteIP != nil && *request.RemoteIP != "" {
 		query = query.Where("conversations.remote_ip like ?", "%"+*request.RemoteIP+"%")
 	}
+	if request.LowGroundedness {
+		query = query.Where("EXISTS (SELECT 1 FROM conversation_messages WHERE conversation_messages.conversation_id = conversations.id AND conversation_messages.groundedness IS NOT NULL)")
+	}
 	var count int64
 	if err := query.Count(&count).Error; err != nil {
 		return nil, 0, err
 	}
 	if err := query.
 		Joins("left join apps on conversations.app_id = apps.id").
-		Select("conversations.*, apps.name as app_name, apps.type as app_type").
+		Select("conversations.*, apps.name as app_name, apps.type as app_type, " +
+			"(SELECT min((conversation_messages.groundedness->>'score')::float) FROM conversation_messages " +
+			"WHERE conversation_messages.conversation_id = conversations.id AND conversation_messages.groundedness IS NOT NULL) as groundedness_score").
 		Offset(request.Offset()).
 		Limit(request.Limit()).
 		Order("conversations.created_at DESC").
@@ -84,6 +102,34 @@ func (r *ConversationRepository) GetConversationDetail(ctx context.Context, kbID
 	return conversation, nil
 }
 
+func (r *ConversationRepository) GetConversationByID(ctx context.Context, conversationID string) (*domain.Conversation, error) {
+	conversation := &domain.Conversation{}
+	if err := r.db.WithContext(ctx).
+		Model(&domain.Conversation{}).
+		Where("id = ?", conversationID).
+		First(conversation).Error; err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+func (r *ConversationRepository) UpdateConversationSummary(ctx context.Context, conversationID, summary, summaryMessageID string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.Conversation{}).
+		Where("id = ?", conversationID).
+		Updates(map[string]any{
+			"summary":            summary,
+			"summary_message_id": summaryMessageID,
+		}).Error
+}
+
+func (r *ConversationRepository) UpdateMessageGroundedness(ctx context.Context, messageID string, groundedness *domain.Groundedness) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.ConversationMessage{}).
+		Where("id = ?", messageID).
+		Update("groundedness", groundedness).Error
+}
+
 func (r *ConversationRepository) GetConversationReferences(ctx context.Context, conversationID string) ([]*domain.ConversationReference, error) {
 	references := []*domain.ConversationReference{}
 	if err := r.db.WithContext(ctx).
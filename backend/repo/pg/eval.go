@@ -0,0 +1,132 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	v1 "github.com/chaitin/panda-wiki/api/eval/v1"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/store/pg"
+)
+
+type EvalRepository struct {
+	db     *pg.DB
+	logger *log.Logger
+}
+
+func NewEvalRepository(db *pg.DB, logger *log.Logger) *EvalRepository {
+	return &EvalRepository{db: db, logger: logger.WithModule("repo.pg.eval")}
+}
+
+func (r *EvalRepository) GetSetList(ctx context.Context, kbID string) ([]v1.EvalSetListItemResp, error) {
+	sets := make([]v1.EvalSetListItemResp, 0)
+	if err := r.db.WithContext(ctx).
+		Model(&domain.EvalSet{}).
+		Select("eval_sets.id, eval_sets.name, eval_sets.description, eval_sets.created_at, eval_sets.updated_at, COUNT(eval_cases.id) AS case_count").
+		Joins("LEFT JOIN eval_cases ON eval_cases.set_id = eval_sets.id").
+		Where("eval_sets.kb_id = ?", kbID).
+		Group("eval_sets.id").
+		Order("eval_sets.created_at DESC").
+		Find(&sets).Error; err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+func (r *EvalRepository) GetSetByID(ctx context.Context, kbID, id string) (*domain.EvalSet, error) {
+	var set domain.EvalSet
+	if err := r.db.WithContext(ctx).
+		Where("kb_id = ? AND id = ?", kbID, id).
+		First(&set).Error; err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+func (r *EvalRepository) GetCasesBySetID(ctx context.Context, setID string) ([]*domain.EvalCase, error) {
+	cases := make([]*domain.EvalCase, 0)
+	if err := r.db.WithContext(ctx).
+		Where("set_id = ?", setID).
+		Order("created_at ASC, id ASC").
+		Find(&cases).Error; err != nil {
+		return nil, err
+	}
+	return cases, nil
+}
+
+func (r *EvalRepository) CreateSet(ctx context.Context, set *domain.EvalSet, cases []*domain.EvalCase) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(set).Error; err != nil {
+			return err
+		}
+		if len(cases) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&cases, 100).Error
+	})
+}
+
+// UpdateSet updates the set and replaces all of its cases
+func (r *EvalRepository) UpdateSet(ctx context.Context, set *domain.EvalSet, cases []*domain.EvalCase) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.EvalSet{}).
+			Where("kb_id = ? AND id = ?", set.KBID, set.ID).
+			Updates(map[string]any{
+				"name":        set.Name,
+				"description": set.Description,
+				"updated_at":  time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("set_id = ?", set.ID).Delete(&domain.EvalCase{}).Error; err != nil {
+			return err
+		}
+		if len(cases) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&cases, 100).Error
+	})
+}
+
+// DeleteSet removes the set with its cases and runs
+func (r *EvalRepository) DeleteSet(ctx context.Context, kbID, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kb_id = ? AND set_id = ?", kbID, id).Delete(&domain.EvalRun{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("kb_id = ? AND set_id = ?", kbID, id).Delete(&domain.EvalCase{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("kb_id = ? AND id = ?", kbID, id).Delete(&domain.EvalSet{}).Error
+	})
+}
+
+func (r *EvalRepository) CreateRun(ctx context.Context, run *domain.EvalRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+func (r *EvalRepository) GetRunList(ctx context.Context, kbID, setID string) ([]v1.EvalRunListItemResp, error) {
+	runs := make([]v1.EvalRunListItemResp, 0)
+	if err := r.db.WithContext(ctx).
+		Model(&domain.EvalRun{}).
+		Select("id, set_id, k, case_count, recall, mrr, note, settings, created_at").
+		Where("kb_id = ? AND set_id = ?", kbID, setID).
+		Order("created_at DESC").
+		Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (r *EvalRepository) GetRunByID(ctx context.Context, kbID, id string) (*domain.EvalRun, error) {
+	var run domain.EvalRun
+	if err := r.db.WithContext(ctx).
+		Where("kb_id = ? AND id = ?", kbID, id).
+		First(&run).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
@@ -0,0 +1,76 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/store/pg"
+)
+
+type FailedTaskRepository struct {
+	db     *pg.DB
+	logger *log.Logger
+}
+
+func NewFailedTaskRepository(db *pg.DB, logger *log.Logger) *FailedTaskRepository {
+	return &FailedTaskRepository{db: db, logger: logger.WithModule("repo.pg.failed_task")}
+}
+
+func (r *FailedTaskRepository) Create(ctx context.Context, task *domain.FailedTask) error {
+	return r.db.WithContext(ctx).Create(task).Error
+}
+
+func (r *FailedTaskRepository) GetList(ctx context.Context, kbID string, status domain.FailedTaskStatus, offset, limit int) (int64, []*domain.FailedTask, error) {
+	query := r.db.WithContext(ctx).
+		Model(&domain.FailedTask{}).
+		Where("kb_id = ?", kbID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+	tasks := make([]*domain.FailedTask, 0)
+	if err := query.
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&tasks).Error; err != nil {
+		return 0, nil, err
+	}
+	return total, tasks, nil
+}
+
+func (r *FailedTaskRepository) GetByID(ctx context.Context, kbID, id string) (*domain.FailedTask, error) {
+	var task domain.FailedTask
+	if err := r.db.WithContext(ctx).
+		Where("kb_id = ? AND id = ?", kbID, id).
+		First(&task).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (r *FailedTaskRepository) GetByIDs(ctx context.Context, kbID string, ids []string) ([]*domain.FailedTask, error) {
+	tasks := make([]*domain.FailedTask, 0)
+	if err := r.db.WithContext(ctx).
+		Where("kb_id = ? AND id IN ?", kbID, ids).
+		Order("created_at ASC").
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (r *FailedTaskRepository) MarkReplayed(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.FailedTask{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":      domain.FailedTaskStatusReplayed,
+			"replayed_at": time.Now(),
+		}).Error
+}
@@ -0,0 +1,66 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/store/pg"
+)
+
+type HandoffRepository struct {
+	db     *pg.DB
+	logger *log.Logger
+}
+
+func NewHandoffRepository(db *pg.DB, logger *log.Logger) *HandoffRepository {
+	return &HandoffRepository{db: db, logger: logger.WithModule("repo.pg.handoff")}
+}
+
+func (r *HandoffRepository) Create(ctx context.Context, handoff *domain.Handoff) error {
+	return r.db.WithContext(ctx).Create(handoff).Error
+}
+
+func (r *HandoffRepository) GetByID(ctx context.Context, kbID, id string) (*domain.Handoff, error) {
+	var handoff domain.Handoff
+	if err := r.db.WithContext(ctx).
+		Where("kb_id = ? AND id = ?", kbID, id).
+		First(&handoff).Error; err != nil {
+		return nil, err
+	}
+	return &handoff, nil
+}
+
+func (r *HandoffRepository) Update(ctx context.Context, handoff *domain.Handoff) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.Handoff{}).
+		Where("kb_id = ? AND id = ?", handoff.KBID, handoff.ID).
+		Updates(map[string]any{
+			"status":     handoff.Status,
+			"assignee":   handoff.Assignee,
+			"note":       handoff.Note,
+			"updated_at": handoff.UpdatedAt,
+		}).Error
+}
+
+func (r *HandoffRepository) GetList(ctx context.Context, kbID string, status domain.HandoffStatus, offset, limit int) (int64, []*domain.Handoff, error) {
+	query := r.db.WithContext(ctx).
+		Model(&domain.Handoff{}).
+		Where("kb_id = ?", kbID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+	handoffs := make([]*domain.Handoff, 0)
+	if err := query.
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&handoffs).Error; err != nil {
+		return 0, nil, err
+	}
+	return total, handoffs, nil
+}
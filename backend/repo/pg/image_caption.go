@@ -0,0 +1,42 @@
+package pg
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/store/pg"
+)
+
+type ImageCaptionRepository struct {
+	db     *pg.DB
+	logger *log.Logger
+}
+
+func NewImageCaptionRepository(db *pg.DB, logger *log.Logger) *ImageCaptionRepository {
+	return &ImageCaptionRepository{db: db, logger: logger.WithModule("repo.pg.image_caption")}
+}
+
+// GetByHash returns nil when the image has not been captioned yet
+func (r *ImageCaptionRepository) GetByHash(ctx context.Context, hash string) (*domain.ImageCaption, error) {
+	var caption domain.ImageCaption
+	if err := r.db.WithContext(ctx).
+		Where("hash = ?", hash).
+		First(&caption).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &caption, nil
+}
+
+func (r *ImageCaptionRepository) Create(ctx context.Context, caption *domain.ImageCaption) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(caption).Error
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/cloudwego/eino/schema"
+	"github.com/samber/lo"
 	"gorm.io/gorm"
 
 	"github.com/chaitin/panda-wiki/domain"
@@ -82,17 +83,21 @@ func (r *ModelRepository) Delete(ctx context.Context, id string) error {
 	})
 }
 
+// GetChatModel returns the default chat model, the first one created
 func (r *ModelRepository) GetChatModel(ctx context.Context) (*domain.Model, error) {
 	var model domain.Model
 	if err := r.db.WithContext(ctx).
 		Model(&domain.Model{}).
 		Where("type = ?", domain.ModelTypeChat).
+		Order("created_at ASC, id ASC").
 		First(&model).Error; err != nil {
 		return nil, err
 	}
 	return &model, nil
 }
 
+// GetModelByType returns the model of a type with at most one model, chat
+// models use GetChatModel
 func (r *ModelRepository) GetModelByType(ctx context.Context, modelType domain.ModelType) (*domain.Model, error) {
 	var model domain.Model
 	if err := r.db.WithContext(ctx).
@@ -115,6 +120,22 @@ func (r *ModelRepository) GetModelByID(ctx context.Context, modelID string) (*do
 	return &model, nil
 }
 
+func (r *ModelRepository) GetModelsByIDs(ctx context.Context, modelIDs []string) (map[string]*domain.Model, error) {
+	if len(modelIDs) == 0 {
+		return map[string]*domain.Model{}, nil
+	}
+	var models []*domain.Model
+	if err := r.db.WithContext(ctx).
+		Model(&domain.Model{}).
+		Where("id IN ?", modelIDs).
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+	return lo.SliceToMap(models, func(model *domain.Model) (string, *domain.Model) {
+		return model.ID, model
+	}), nil
+}
+
 func (r *ModelRepository) UpdateUsage(ctx context.Context, modelID string, usage *schema.TokenUsage) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// update model usage
@@ -323,6 +323,10 @@ func (r *NodeRepository) Delete(ctx context.Context, kbID string, ids []string)
 		if err := r.backupNodeReleasesTx(tx, allIDs); err != nil {
 			return err
 		}
+		// delete node tags
+		if err := tx.Where("node_id IN ?", allIDs).Delete(&domain.NodeTag{}).Error; err != nil {
+			return err
+		}
 
 		// delete node release
 		var nodeReleases []*domain.NodeRelease
@@ -919,32 +923,33 @@ func (r *NodeRepository) UpdateNodeStatus(ctx context.Context, kbID, nodeID stri
 }
 
 // traverse all nodes by pg cursor
-func (r *NodeRepository) TraverseNodesByCursor(ctx context.Context, callback func(*domain.NodeRelease) error) error {
-	rows, err := r.db.WithContext(ctx).
+// CountReleasedNodes counts nodes of the kb that have at least one release
+func (r *NodeRepository) CountReleasedNodes(ctx context.Context, kbID string) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
 		Model(&domain.NodeRelease{}).
-		Select("DISTINCT ON (node_id) id, node_id, kb_id").
-		Order("node_id, updated_at DESC").
-		Rows()
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var nodeRelease domain.NodeRelease
-		if err := r.db.ScanRows(rows, &nodeRelease); err != nil {
-			return err
-		}
-		if err := callback(&nodeRelease); err != nil {
-			return err
-		}
+		Where("kb_id = ?", kbID).
+		Distinct("node_id").
+		Count(&count).Error; err != nil {
+		return 0, err
 	}
+	return count, nil
+}
 
-	if err := rows.Err(); err != nil {
-		return err
+// GetLatestNodeReleasesAfter returns the latest release of each node ordered
+// by node id, starting after the given node id
+func (r *NodeRepository) GetLatestNodeReleasesAfter(ctx context.Context, kbID, afterNodeID string, limit int) ([]*domain.NodeRelease, error) {
+	releases := make([]*domain.NodeRelease, 0)
+	if err := r.db.WithContext(ctx).
+		Model(&domain.NodeRelease{}).
+		Select("DISTINCT ON (node_id) id, node_id, kb_id").
+		Where("kb_id = ? AND node_id > ?", kbID, afterNodeID).
+		Order("node_id, updated_at DESC").
+		Limit(limit).
+		Find(&releases).Error; err != nil {
+		return nil, err
 	}
-
-	return nil
+	return releases, nil
 }
 
 // CreateNodeReleases create node releases
@@ -988,6 +993,9 @@ func (r *NodeRepository) CreateNodeReleases(ctx context.Context, kbID, userId st
 		if err := tx.CreateInBatches(&nodeReleases, 100).Error; err != nil {
 			return err
 		}
+		if err := r.UpdateNodeReleaseSearchVectorsTx(tx, nodeReleases); err != nil {
+			return fmt.Errorf("update node release search vectors failed: %w", err)
+		}
 		return nil
 	}); err != nil {
 		return nil, err
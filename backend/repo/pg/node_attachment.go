@@ -0,0 +1,80 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/store/pg"
+)
+
+type NodeAttachmentRepository struct {
+	db     *pg.DB
+	logger *log.Logger
+}
+
+func NewNodeAttachmentRepository(db *pg.DB, logger *log.Logger) *NodeAttachmentRepository {
+	return &NodeAttachmentRepository{db: db, logger: logger.WithModule("repo.pg.node_attachment")}
+}
+
+func (r *NodeAttachmentRepository) GetByNodeID(ctx context.Context, nodeID string) ([]*domain.NodeAttachment, error) {
+	attachments := make([]*domain.NodeAttachment, 0)
+	if err := r.db.WithContext(ctx).
+		Where("node_id = ?", nodeID).
+		Order("created_at ASC").
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// GetByDocIDs returns the attachments indexed as the given rag docs, keyed by doc_id
+func (r *NodeAttachmentRepository) GetByDocIDs(ctx context.Context, docIDs []string) (map[string]*domain.NodeAttachment, error) {
+	var attachments []*domain.NodeAttachment
+	if err := r.db.WithContext(ctx).
+		Where("doc_id IN ?", docIDs).
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[string]*domain.NodeAttachment, len(attachments))
+	for _, attachment := range attachments {
+		result[attachment.DocID] = attachment
+	}
+	return result, nil
+}
+
+func (r *NodeAttachmentRepository) GetByStatus(ctx context.Context, status domain.NodeAttachmentStatus) ([]*domain.NodeAttachment, error) {
+	attachments := make([]*domain.NodeAttachment, 0)
+	if err := r.db.WithContext(ctx).
+		Where("status = ?", status).
+		Order("node_id, created_at ASC").
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// GetOrphans returns the attachments whose node has been deleted
+func (r *NodeAttachmentRepository) GetOrphans(ctx context.Context, kbID string) ([]*domain.NodeAttachment, error) {
+	attachments := make([]*domain.NodeAttachment, 0)
+	if err := r.db.WithContext(ctx).
+		Where("kb_id = ?", kbID).
+		Where("NOT EXISTS (SELECT 1 FROM nodes WHERE nodes.id = node_attachments.node_id)").
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (r *NodeAttachmentRepository) Save(ctx context.Context, attachment *domain.NodeAttachment) error {
+	return r.db.WithContext(ctx).Save(attachment).Error
+}
+
+func (r *NodeAttachmentRepository) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Where("id IN ?", ids).
+		Delete(&domain.NodeAttachment{}).Error
+}
@@ -0,0 +1,67 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/store/pg"
+)
+
+type NodeChunkRepository struct {
+	db     *pg.DB
+	logger *log.Logger
+}
+
+func NewNodeChunkRepository(db *pg.DB, logger *log.Logger) *NodeChunkRepository {
+	return &NodeChunkRepository{db: db, logger: logger.WithModule("repo.pg.node_chunk")}
+}
+
+func (r *NodeChunkRepository) GetOverridesByNodeID(ctx context.Context, nodeID string) ([]*domain.NodeChunkOverride, error) {
+	overrides := make([]*domain.NodeChunkOverride, 0)
+	if err := r.db.WithContext(ctx).
+		Where("node_id = ?", nodeID).
+		Order("created_at ASC").
+		Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+func (r *NodeChunkRepository) CreateOverride(ctx context.Context, override *domain.NodeChunkOverride) error {
+	return r.db.WithContext(ctx).Create(override).Error
+}
+
+func (r *NodeChunkRepository) UpdateOverride(ctx context.Context, id string, action domain.ChunkOverrideAction, content string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.NodeChunkOverride{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"action":     action,
+			"content":    content,
+			"stale":      false,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+func (r *NodeChunkRepository) DeleteOverride(ctx context.Context, kbID, nodeID, id string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Where("kb_id = ? AND node_id = ? AND id = ?", kbID, nodeID, id).
+		Delete(&domain.NodeChunkOverride{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// UpdateStaleOverrides marks the given overrides of the node stale and the others fresh
+func (r *NodeChunkRepository) UpdateStaleOverrides(ctx context.Context, nodeID string, staleIDs []string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.NodeChunkOverride{}).
+		Where("node_id = ?", nodeID).
+		Update("stale", gorm.Expr("id = ANY(?)", pq.StringArray(staleIDs))).Error
+}
@@ -0,0 +1,98 @@
+package pg
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+
+	"github.com/chaitin/panda-wiki/consts"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/utils"
+)
+
+// maxKeywordQueryTokens bounds the OR terms of a keyword query
+const maxKeywordQueryTokens = 64
+
+type NodeReleaseKeywordHit struct {
+	DocID   string  `gorm:"column:doc_id"`
+	NodeID  string  `gorm:"column:node_id"`
+	Name    string  `gorm:"column:name"`
+	Content string  `gorm:"column:content"`
+	Rank    float64 `gorm:"column:rank"`
+}
+
+// UpdateNodeReleaseSearchVectorsTx refreshes the full-text search vector of
+// node releases, node names weigh more than content
+func (r *NodeRepository) UpdateNodeReleaseSearchVectorsTx(tx *gorm.DB, releases []*domain.NodeRelease) error {
+	for _, release := range releases {
+		if release.Type != domain.NodeTypeDocument {
+			continue
+		}
+		name := strings.Join(utils.SearchTokens(release.Name, utils.MaxSearchTokens), " ")
+		content := strings.Join(utils.SearchTokens(utils.HTMLToText(release.Content), utils.MaxSearchTokens), " ")
+		if err := tx.Exec(`UPDATE node_releases
+			SET search_vector = setweight(to_tsvector('simple', ?), 'A') || setweight(to_tsvector('simple', ?), 'B')
+			WHERE id = ?`, name, content, release.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchNodeReleasesByKeyword runs a full-text search over the indexed node
+// releases of a kb, honoring the answerable permission of the nodes. When tags
+// is not empty only nodes with any of the tags are matched
+func (r *NodeRepository) SearchNodeReleasesByKeyword(ctx context.Context, kbID, query string, groupIDs []int, tags []string, limit int) ([]*NodeReleaseKeywordHit, error) {
+	tokens := lo.Uniq(utils.SearchTokens(query, 0))
+	if len(tokens) > maxKeywordQueryTokens {
+		tokens = tokens[:maxKeywordQueryTokens]
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	if groupIDs == nil {
+		groupIDs = []int{}
+	}
+	args := []any{
+		strings.Join(tokens, " | "),
+		kbID,
+		consts.NodeAccessPermOpen,
+		consts.NodeAccessPermPartial,
+		consts.NodePermNameAnswerable,
+		pq.Array(groupIDs),
+	}
+	tagFilter := ""
+	if len(tags) > 0 {
+		tagFilter = `AND EXISTS (
+				SELECT 1 FROM node_tags nt JOIN tags t ON t.id = nt.tag_id
+				WHERE nt.node_id = nr.node_id AND t.name = ANY(?)
+			)`
+		args = append(args, pq.Array(tags))
+	}
+	args = append(args, limit)
+	var hits []*NodeReleaseKeywordHit
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT nr.doc_id, nr.node_id, nr.name, nr.content, ts_rank_cd(nr.search_vector, q.query) AS rank
+		FROM node_releases nr
+		JOIN nodes n ON n.id = nr.node_id
+		CROSS JOIN to_tsquery('simple', ?) AS q(query)
+		WHERE nr.kb_id = ?
+			AND nr.doc_id != ''
+			AND nr.search_vector @@ q.query
+			AND (
+				COALESCE(n.permissions->>'answerable', '') IN ('', ?)
+				OR (n.permissions->>'answerable' = ? AND EXISTS (
+					SELECT 1 FROM node_auth_groups g
+					WHERE g.node_id = n.id AND g.perm = ? AND g.auth_group_id = ANY(?)
+				))
+			)
+			`+tagFilter+`
+		ORDER BY rank DESC
+		LIMIT ?`, args...).Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
@@ -20,10 +20,20 @@ var ProviderSet = wire.NewSet(
 	NewCommentRepository,
 	NewPromptRepo,
 	NewBlockWordRepo,
+	NewSettingRepo,
 	NewAuthRepo,
 	NewWechatRepository,
 	NewAPITokenRepo,
 	NewSystemSettingRepo,
 	NewMCPRepository,
 	NewNavRepository,
+	NewTagRepository,
+	NewEvalRepository,
+	NewReindexRepository,
+	NewFailedTaskRepository,
+	NewNodeChunkRepository,
+	NewNodeAttachmentRepository,
+	NewImageCaptionRepository,
+	NewQAPairRepository,
+	NewHandoffRepository,
 )
@@ -0,0 +1,108 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/store/pg"
+)
+
+type QAPairRepository struct {
+	db     *pg.DB
+	logger *log.Logger
+}
+
+func NewQAPairRepository(db *pg.DB, logger *log.Logger) *QAPairRepository {
+	return &QAPairRepository{db: db, logger: logger.WithModule("repo.pg.qa_pair")}
+}
+
+func (r *QAPairRepository) Create(ctx context.Context, pair *domain.QAPair) error {
+	return r.db.WithContext(ctx).Create(pair).Error
+}
+
+func (r *QAPairRepository) Update(ctx context.Context, pair *domain.QAPair) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.QAPair{}).
+		Where("kb_id = ? AND id = ?", pair.KBID, pair.ID).
+		Updates(map[string]any{
+			"question":    pair.Question,
+			"answer":      pair.Answer,
+			"keywords":    pair.Keywords,
+			"match_type":  pair.MatchType,
+			"answer_mode": pair.AnswerMode,
+			"embedding":   pair.Embedding,
+			"updated_at":  time.Now(),
+		}).Error
+}
+
+func (r *QAPairRepository) UpdateEmbedding(ctx context.Context, id string, embedding pq.Float32Array) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.QAPair{}).
+		Where("id = ?", id).
+		Update("embedding", embedding).Error
+}
+
+func (r *QAPairRepository) Delete(ctx context.Context, kbID, id string) error {
+	return r.db.WithContext(ctx).
+		Where("kb_id = ? AND id = ?", kbID, id).
+		Delete(&domain.QAPair{}).Error
+}
+
+func (r *QAPairRepository) GetByID(ctx context.Context, kbID, id string) (*domain.QAPair, error) {
+	var pair domain.QAPair
+	if err := r.db.WithContext(ctx).
+		Where("kb_id = ? AND id = ?", kbID, id).
+		First(&pair).Error; err != nil {
+		return nil, err
+	}
+	return &pair, nil
+}
+
+func (r *QAPairRepository) GetList(ctx context.Context, kbID, search string, offset, limit int) (int64, []*domain.QAPair, error) {
+	query := r.db.WithContext(ctx).
+		Model(&domain.QAPair{}).
+		Where("kb_id = ?", kbID)
+	if search != "" {
+		query = query.Where("question ILIKE ? OR answer ILIKE ?", "%"+search+"%", "%"+search+"%")
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, nil, err
+	}
+	pairs := make([]*domain.QAPair, 0)
+	if err := query.
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&pairs).Error; err != nil {
+		return 0, nil, err
+	}
+	return total, pairs, nil
+}
+
+// GetByKBID returns every pair of a kb, used for matching questions
+func (r *QAPairRepository) GetByKBID(ctx context.Context, kbID string) ([]*domain.QAPair, error) {
+	pairs := make([]*domain.QAPair, 0)
+	if err := r.db.WithContext(ctx).
+		Where("kb_id = ?", kbID).
+		Order("created_at ASC").
+		Find(&pairs).Error; err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+func (r *QAPairRepository) RecordHit(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.QAPair{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"hit_count":   gorm.Expr("hit_count + 1"),
+			"last_hit_at": time.Now(),
+		}).Error
+}
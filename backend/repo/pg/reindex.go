@@ -0,0 +1,146 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/store/pg"
+)
+
+type ReindexRepository struct {
+	db     *pg.DB
+	logger *log.Logger
+}
+
+func NewReindexRepository(db *pg.DB, logger *log.Logger) *ReindexRepository {
+	return &ReindexRepository{db: db, logger: logger.WithModule("repo.pg.reindex")}
+}
+
+// CreateJob cancels unfinished jobs and creates the new one with its kb progress
+func (r *ReindexRepository) CreateJob(ctx context.Context, job *domain.ReindexJob, kbs []*domain.ReindexJobKB) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&domain.ReindexJob{}).
+			Where("status IN ?", []domain.ReindexJobStatus{domain.ReindexJobStatusRunning, domain.ReindexJobStatusPaused}).
+			Updates(map[string]any{
+				"status":      domain.ReindexJobStatusCancelled,
+				"error":       "superseded by job " + job.ID,
+				"updated_at":  now,
+				"finished_at": now,
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		if len(kbs) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&kbs, 100).Error
+	})
+}
+
+func (r *ReindexRepository) GetLatestJob(ctx context.Context) (*domain.ReindexJob, error) {
+	var job domain.ReindexJob
+	if err := r.db.WithContext(ctx).
+		Order("created_at DESC").
+		First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *ReindexRepository) GetJobByID(ctx context.Context, id string) (*domain.ReindexJob, error) {
+	var job domain.ReindexJob
+	if err := r.db.WithContext(ctx).
+		Where("id = ?", id).
+		First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *ReindexRepository) GetJobsByStatus(ctx context.Context, status domain.ReindexJobStatus) ([]*domain.ReindexJob, error) {
+	jobs := make([]*domain.ReindexJob, 0)
+	if err := r.db.WithContext(ctx).
+		Where("status = ?", status).
+		Order("created_at ASC").
+		Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *ReindexRepository) GetJobKBs(ctx context.Context, jobID string) ([]*domain.ReindexJobKB, error) {
+	kbs := make([]*domain.ReindexJobKB, 0)
+	if err := r.db.WithContext(ctx).
+		Where("job_id = ?", jobID).
+		Order("kb_id ASC").
+		Find(&kbs).Error; err != nil {
+		return nil, err
+	}
+	return kbs, nil
+}
+
+// UpdateJobStatus moves the job to status if it is currently in one of from,
+// returns false when the job is not in any of them
+func (r *ReindexRepository) UpdateJobStatus(ctx context.Context, id string, from []domain.ReindexJobStatus, status domain.ReindexJobStatus) (bool, error) {
+	now := time.Now()
+	updates := map[string]any{
+		"status":     status,
+		"updated_at": now,
+	}
+	if status == domain.ReindexJobStatusCancelled || status == domain.ReindexJobStatusCompleted {
+		updates["finished_at"] = now
+	}
+	res := r.db.WithContext(ctx).
+		Model(&domain.ReindexJob{}).
+		Where("id = ? AND status IN ?", id, from).
+		Updates(updates)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// AdvanceJobKB saves the checkpoint after a batch has been dispatched
+func (r *ReindexRepository) AdvanceJobKB(ctx context.Context, jobID, kbID, cursor string, dispatched int, exhausted bool) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.ReindexJobKB{}).
+		Where("job_id = ? AND kb_id = ?", jobID, kbID).
+		Updates(map[string]any{
+			"cursor":     cursor,
+			"dispatched": gorm.Expr("dispatched + ?", dispatched),
+			"exhausted":  exhausted,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// IncrJobKBProgress records the result of one node processed by the consumer
+func (r *ReindexRepository) IncrJobKBProgress(ctx context.Context, jobID, kbID string, success bool) error {
+	column := "failed"
+	if success {
+		column = "succeeded"
+	}
+	return r.db.WithContext(ctx).
+		Model(&domain.ReindexJobKB{}).
+		Where("job_id = ? AND kb_id = ?", jobID, kbID).
+		Updates(map[string]any{
+			column:       gorm.Expr(column + " + 1"),
+			"updated_at": time.Now(),
+		}).Error
+}
+
+func (r *ReindexRepository) UpdateJobRateLimit(ctx context.Context, id string, rateLimit int) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.ReindexJob{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"rate_limit": rateLimit,
+			"updated_at": time.Now(),
+		}).Error
+}
@@ -0,0 +1,147 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/store/pg"
+)
+
+var _ domain.SettingRepo = (*SettingRepo)(nil)
+
+type SettingRepo struct {
+	db     *pg.DB
+	logger *log.Logger
+}
+
+func NewSettingRepo(db *pg.DB, logger *log.Logger) *SettingRepo {
+	return &SettingRepo{
+		db:     db,
+		logger: logger.WithModule("repo.pg.setting"),
+	}
+}
+
+func (r *SettingRepo) CreateOrUpdateSetting(ctx context.Context, setting *domain.Setting) error {
+	now := time.Now()
+	setting.CreatedAt = now
+	setting.UpdatedAt = now
+	return r.db.WithContext(ctx).Table("settings").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "kb_id"}, {Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+		}).
+		Create(setting).Error
+}
+
+func (r *SettingRepo) GetSetting(ctx context.Context, kbID, key string) (*domain.Setting, error) {
+	var setting domain.Setting
+	if err := r.db.WithContext(ctx).Table("settings").
+		Where("kb_id = ? AND key = ?", kbID, key).
+		First(&setting).Error; err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+func (r *SettingRepo) UpdateSetting(ctx context.Context, kbID, key, value string) error {
+	return r.db.WithContext(ctx).Table("settings").
+		Where("kb_id = ? AND key = ?", kbID, key).
+		Updates(map[string]any{
+			"value":      value,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+func (r *SettingRepo) GetRankFusionSetting(ctx context.Context, kbID string) (*domain.RankFusionSetting, error) {
+	fusion := domain.DefaultRankFusionSetting
+	setting, err := r.GetSetting(ctx, kbID, domain.SettingKeyRankFusion)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &fusion, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(setting.Value, &fusion); err != nil {
+		return nil, err
+	}
+	return &fusion, nil
+}
+
+func (r *SettingRepo) UpdateRankFusionSetting(ctx context.Context, kbID string, fusion *domain.RankFusionSetting) error {
+	value, err := json.Marshal(fusion)
+	if err != nil {
+		return err
+	}
+	return r.CreateOrUpdateSetting(ctx, &domain.Setting{
+		KBID:        kbID,
+		Key:         domain.SettingKeyRankFusion,
+		Value:       value,
+		Description: "rank fusion weights of vector and keyword retrieval",
+	})
+}
+
+func (r *SettingRepo) GetRerankSetting(ctx context.Context, kbID string) (*domain.RerankSetting, error) {
+	rerank := domain.DefaultRerankSetting
+	setting, err := r.GetSetting(ctx, kbID, domain.SettingKeyRerank)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &rerank, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(setting.Value, &rerank); err != nil {
+		return nil, err
+	}
+	if rerank.TopN <= 0 {
+		rerank.TopN = domain.DefaultRerankSetting.TopN
+	}
+	return &rerank, nil
+}
+
+func (r *SettingRepo) UpdateRerankSetting(ctx context.Context, kbID string, rerank *domain.RerankSetting) error {
+	value, err := json.Marshal(rerank)
+	if err != nil {
+		return err
+	}
+	return r.CreateOrUpdateSetting(ctx, &domain.Setting{
+		KBID:        kbID,
+		Key:         domain.SettingKeyRerank,
+		Value:       value,
+		Description: "rerank retrieved chunks with the rerank model",
+	})
+}
+
+func (r *SettingRepo) GetChatModelSetting(ctx context.Context, kbID string) (*domain.ChatModelSetting, error) {
+	chatModel := domain.ChatModelSetting{}
+	setting, err := r.GetSetting(ctx, kbID, domain.SettingKeyChatModel)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &chatModel, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(setting.Value, &chatModel); err != nil {
+		return nil, err
+	}
+	return &chatModel, nil
+}
+
+func (r *SettingRepo) UpdateChatModelSetting(ctx context.Context, kbID string, chatModel *domain.ChatModelSetting) error {
+	value, err := json.Marshal(chatModel)
+	if err != nil {
+		return err
+	}
+	return r.CreateOrUpdateSetting(ctx, &domain.Setting{
+		KBID:        kbID,
+		Key:         domain.SettingKeyChatModel,
+		Value:       value,
+		Description: "chat model and fallback models answering questions of the kb",
+	})
+}
@@ -0,0 +1,182 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	v1 "github.com/chaitin/panda-wiki/api/node/v1"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/store/pg"
+)
+
+type TagRepository struct {
+	db     *pg.DB
+	logger *log.Logger
+}
+
+func NewTagRepository(db *pg.DB, logger *log.Logger) *TagRepository {
+	return &TagRepository{db: db, logger: logger.WithModule("repo.pg.tag")}
+}
+
+func (r *TagRepository) GetList(ctx context.Context, kbID string) ([]v1.TagListItemResp, error) {
+	tags := make([]v1.TagListItemResp, 0)
+	if err := r.db.WithContext(ctx).
+		Model(&domain.Tag{}).
+		Select("tags.id, tags.name, COUNT(node_tags.node_id) AS node_count").
+		Joins("LEFT JOIN node_tags ON node_tags.tag_id = tags.id").
+		Where("tags.kb_id = ?", kbID).
+		Group("tags.id, tags.name").
+		Order("tags.name ASC").
+		Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *TagRepository) GetByID(ctx context.Context, kbID, id string) (*domain.Tag, error) {
+	var tag domain.Tag
+	if err := r.db.WithContext(ctx).
+		Where("kb_id = ? AND id = ?", kbID, id).
+		First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *TagRepository) Create(ctx context.Context, tag *domain.Tag) error {
+	return r.db.WithContext(ctx).Create(tag).Error
+}
+
+func (r *TagRepository) Update(ctx context.Context, kbID, id, name string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.Tag{}).
+		Where("kb_id = ? AND id = ?", kbID, id).
+		Updates(map[string]any{
+			"name":       name,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// Delete removes the tag and returns the ids of the nodes it was attached to
+func (r *TagRepository) Delete(ctx context.Context, kbID, id string) ([]string, error) {
+	var nodeIDs []string
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var nodeTags []*domain.NodeTag
+		if err := tx.Where("kb_id = ? AND tag_id = ?", kbID, id).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "node_id"}}}).
+			Delete(&nodeTags).Error; err != nil {
+			return err
+		}
+		for _, nodeTag := range nodeTags {
+			nodeIDs = append(nodeIDs, nodeTag.NodeID)
+		}
+		return tx.Where("kb_id = ? AND id = ?", kbID, id).Delete(&domain.Tag{}).Error
+	}); err != nil {
+		return nil, err
+	}
+	return nodeIDs, nil
+}
+
+func (r *TagRepository) GetNodeIDsByTagID(ctx context.Context, kbID, tagID string) ([]string, error) {
+	var nodeIDs []string
+	if err := r.db.WithContext(ctx).
+		Model(&domain.NodeTag{}).
+		Where("kb_id = ? AND tag_id = ?", kbID, tagID).
+		Pluck("node_id", &nodeIDs).Error; err != nil {
+		return nil, err
+	}
+	return nodeIDs, nil
+}
+
+// GetNodeIDsByTagNames returns the nodes carrying any of the tags
+func (r *TagRepository) GetNodeIDsByTagNames(ctx context.Context, kbID string, names []string) ([]string, error) {
+	var nodeIDs []string
+	if err := r.db.WithContext(ctx).
+		Model(&domain.NodeTag{}).
+		Distinct("node_tags.node_id").
+		Joins("JOIN tags ON tags.id = node_tags.tag_id").
+		Where("node_tags.kb_id = ?", kbID).
+		Where("tags.name = ANY(?)", pq.Array(names)).
+		Pluck("node_tags.node_id", &nodeIDs).Error; err != nil {
+		return nil, err
+	}
+	return nodeIDs, nil
+}
+
+// GetTagNamesByNodeIDs returns the tag names of each node, nodes without
+// tags are absent from the map
+func (r *TagRepository) GetTagNamesByNodeIDs(ctx context.Context, nodeIDs []string) (map[string][]string, error) {
+	result := make(map[string][]string)
+	if len(nodeIDs) == 0 {
+		return result, nil
+	}
+	var rows []struct {
+		NodeID string
+		Name   string
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&domain.NodeTag{}).
+		Select("node_tags.node_id, tags.name").
+		Joins("JOIN tags ON tags.id = node_tags.tag_id").
+		Where("node_tags.node_id IN ?", nodeIDs).
+		Order("tags.name ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.NodeID] = append(result[row.NodeID], row.Name)
+	}
+	return result, nil
+}
+
+// SetNodeTags replaces the tags of the nodes, missing tags are created
+func (r *TagRepository) SetNodeTags(ctx context.Context, kbID string, nodeIDs, names []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kb_id = ? AND node_id IN ?", kbID, nodeIDs).
+			Delete(&domain.NodeTag{}).Error; err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		now := time.Now()
+		newTags := make([]*domain.Tag, len(names))
+		for i, name := range names {
+			newTags[i] = &domain.Tag{
+				ID:        uuid.New().String(),
+				KBID:      kbID,
+				Name:      name,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&newTags).Error; err != nil {
+			return err
+		}
+		var tagIDs []string
+		if err := tx.Model(&domain.Tag{}).
+			Where("kb_id = ? AND name IN ?", kbID, names).
+			Pluck("id", &tagIDs).Error; err != nil {
+			return err
+		}
+		nodeTags := make([]*domain.NodeTag, 0, len(nodeIDs)*len(tagIDs))
+		for _, nodeID := range nodeIDs {
+			for _, tagID := range tagIDs {
+				nodeTags = append(nodeTags, &domain.NodeTag{
+					KBID:      kbID,
+					NodeID:    nodeID,
+					TagID:     tagID,
+					CreatedAt: now,
+				})
+			}
+		}
+		return tx.CreateInBatches(&nodeTags, 100).Error
+	})
+}
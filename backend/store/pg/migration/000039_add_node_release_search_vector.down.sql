@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_node_releases_search_vector;
+
+ALTER TABLE node_releases DROP COLUMN IF EXISTS search_vector;
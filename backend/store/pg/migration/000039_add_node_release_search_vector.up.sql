@@ -0,0 +1,4 @@
+-- full-text search terms of released nodes, used as the keyword leg of retrieval
+ALTER TABLE node_releases ADD COLUMN IF NOT EXISTS search_vector tsvector NULL;
+
+CREATE INDEX IF NOT EXISTS idx_node_releases_search_vector ON node_releases USING GIN (search_vector);
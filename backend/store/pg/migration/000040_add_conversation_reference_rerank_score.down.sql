@@ -0,0 +1 @@
+ALTER TABLE conversation_references DROP COLUMN IF EXISTS rerank_score;
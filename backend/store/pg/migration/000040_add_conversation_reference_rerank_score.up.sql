@@ -0,0 +1,2 @@
+-- rerank score of the cited node, explains why a document was cited
+ALTER TABLE conversation_references ADD COLUMN IF NOT EXISTS rerank_score float8 NULL;
@@ -0,0 +1,23 @@
+-- tags of knowledge base
+CREATE TABLE IF NOT EXISTS tags (
+    id         text        NOT NULL,
+    kb_id      text        NOT NULL,
+    name       text        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT tags_pkey PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_kb_id_name ON tags (kb_id, name);
+
+-- tags of nodes
+CREATE TABLE IF NOT EXISTS node_tags (
+    kb_id      text        NOT NULL,
+    node_id    text        NOT NULL,
+    tag_id     text        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT node_tags_pkey PRIMARY KEY (node_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_tags_tag_id ON node_tags (tag_id);
+CREATE INDEX IF NOT EXISTS idx_node_tags_kb_id ON node_tags (kb_id);
@@ -0,0 +1,44 @@
+-- golden question sets of knowledge base
+CREATE TABLE IF NOT EXISTS eval_sets (
+    id          text        NOT NULL,
+    kb_id       text        NOT NULL,
+    name        text        NOT NULL,
+    description text        NOT NULL DEFAULT '',
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    updated_at  timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT eval_sets_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_eval_sets_kb_id ON eval_sets (kb_id);
+
+-- questions with the nodes expected to be retrieved
+CREATE TABLE IF NOT EXISTS eval_cases (
+    id                text        NOT NULL,
+    set_id            text        NOT NULL,
+    kb_id             text        NOT NULL,
+    question          text        NOT NULL,
+    expected_node_ids text[]      NOT NULL DEFAULT '{}',
+    created_at        timestamptz NOT NULL DEFAULT now(),
+    updated_at        timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT eval_cases_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_eval_cases_set_id ON eval_cases (set_id);
+
+-- replays of a set
+CREATE TABLE IF NOT EXISTS eval_runs (
+    id         text        NOT NULL,
+    set_id     text        NOT NULL,
+    kb_id      text        NOT NULL,
+    k          int         NOT NULL,
+    case_count int         NOT NULL DEFAULT 0,
+    recall     float8      NOT NULL DEFAULT 0,
+    mrr        float8      NOT NULL DEFAULT 0,
+    note       text        NOT NULL DEFAULT '',
+    settings   jsonb       NOT NULL DEFAULT '{}',
+    results    jsonb       NOT NULL DEFAULT '[]',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT eval_runs_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_eval_runs_set_id_created_at ON eval_runs (set_id, created_at);
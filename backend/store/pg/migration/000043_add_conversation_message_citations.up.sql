@@ -0,0 +1,2 @@
+-- retrieved chunks cited by an assistant answer as [n]
+ALTER TABLE conversation_messages ADD COLUMN IF NOT EXISTS citations jsonb NOT NULL DEFAULT '[]';
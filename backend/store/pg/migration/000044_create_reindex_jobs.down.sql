@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS reindex_job_kbs;
+DROP TABLE IF EXISTS reindex_jobs;
@@ -0,0 +1,28 @@
+-- full re-index of all knowledge bases after the embedding model changes
+CREATE TABLE IF NOT EXISTS reindex_jobs (
+    id              text        NOT NULL,
+    status          text        NOT NULL,
+    embedding_model text        NOT NULL DEFAULT '',
+    rate_limit      int         NOT NULL DEFAULT 0,
+    error           text        NOT NULL DEFAULT '',
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    updated_at      timestamptz NOT NULL DEFAULT now(),
+    finished_at     timestamptz,
+    CONSTRAINT reindex_jobs_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reindex_jobs_status ON reindex_jobs (status);
+
+-- per kb progress, cursor is the last dispatched node id
+CREATE TABLE IF NOT EXISTS reindex_job_kbs (
+    job_id      text        NOT NULL,
+    kb_id       text        NOT NULL,
+    total       int         NOT NULL DEFAULT 0,
+    dispatched  int         NOT NULL DEFAULT 0,
+    succeeded   int         NOT NULL DEFAULT 0,
+    failed      int         NOT NULL DEFAULT 0,
+    cursor      text        NOT NULL DEFAULT '',
+    exhausted   boolean     NOT NULL DEFAULT false,
+    updated_at  timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT reindex_job_kbs_pkey PRIMARY KEY (job_id, kb_id)
+);
@@ -0,0 +1,17 @@
+-- mq tasks moved to the dead letter topic
+CREATE TABLE IF NOT EXISTS failed_tasks (
+    id          text        NOT NULL,
+    topic       text        NOT NULL,
+    kb_id       text        NOT NULL DEFAULT '',
+    action      text        NOT NULL DEFAULT '',
+    payload     bytea       NOT NULL,
+    error       text        NOT NULL DEFAULT '',
+    attempts    int         NOT NULL DEFAULT 0,
+    status      text        NOT NULL,
+    replayed_at timestamptz,
+    failed_at   timestamptz NOT NULL DEFAULT now(),
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT failed_tasks_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_failed_tasks_kb_id_created_at ON failed_tasks (kb_id, created_at);
@@ -0,0 +1,16 @@
+-- manual chunk changes of editors, applied again after every re-chunk
+CREATE TABLE IF NOT EXISTS node_chunk_overrides (
+    id          text        NOT NULL,
+    kb_id       text        NOT NULL,
+    node_id     text        NOT NULL,
+    action      text        NOT NULL,
+    source_hash text        NOT NULL DEFAULT '',
+    content     text        NOT NULL DEFAULT '',
+    stale       boolean     NOT NULL DEFAULT false,
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    updated_at  timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT node_chunk_overrides_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_chunk_overrides_node_id ON node_chunk_overrides (node_id);
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_node_chunk_overrides_source ON node_chunk_overrides (node_id, source_hash) WHERE source_hash <> '';
@@ -0,0 +1,18 @@
+-- text of files linked from node content, indexed as records of their own
+CREATE TABLE IF NOT EXISTS node_attachments (
+    id         text        NOT NULL,
+    kb_id      text        NOT NULL,
+    node_id    text        NOT NULL,
+    file_key   text        NOT NULL,
+    file_name  text        NOT NULL,
+    etag       text        NOT NULL DEFAULT '',
+    doc_id     text        NOT NULL DEFAULT '',
+    status     text        NOT NULL,
+    error      text        NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT node_attachments_pkey PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_node_attachments_node_file ON node_attachments (node_id, file_key);
+CREATE INDEX IF NOT EXISTS idx_node_attachments_doc_id ON node_attachments (doc_id);
@@ -0,0 +1,8 @@
+-- vision model captions of document images, keyed by the image content
+CREATE TABLE IF NOT EXISTS image_captions (
+    hash       text        NOT NULL,
+    caption    text        NOT NULL,
+    model      text        NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT image_captions_pkey PRIMARY KEY (hash)
+);
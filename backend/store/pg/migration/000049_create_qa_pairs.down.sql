@@ -0,0 +1 @@
+DROP TABLE IF EXISTS qa_pairs;
@@ -0,0 +1,19 @@
+-- answers approved by the kb team, matched before retrieval
+CREATE TABLE IF NOT EXISTS qa_pairs (
+    id                text        NOT NULL,
+    kb_id             text        NOT NULL,
+    question          text        NOT NULL,
+    answer            text        NOT NULL,
+    keywords          text[]      NOT NULL DEFAULT '{}',
+    match_type        text        NOT NULL,
+    answer_mode       text        NOT NULL,
+    embedding         real[]      NOT NULL DEFAULT '{}',
+    source_message_id text        NOT NULL DEFAULT '',
+    hit_count         bigint      NOT NULL DEFAULT 0,
+    last_hit_at       timestamptz,
+    created_at        timestamptz NOT NULL DEFAULT now(),
+    updated_at        timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT qa_pairs_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_qa_pairs_kb_id ON qa_pairs (kb_id);
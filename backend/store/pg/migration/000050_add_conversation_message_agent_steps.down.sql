@@ -0,0 +1 @@
+ALTER TABLE conversation_messages DROP COLUMN IF EXISTS agent_steps;
@@ -0,0 +1,2 @@
+-- kb tools called by the model in agent mode
+ALTER TABLE conversation_messages ADD COLUMN IF NOT EXISTS agent_steps jsonb NOT NULL DEFAULT '[]';
@@ -0,0 +1,6 @@
+-- keep the default chat model, the first one created
+DELETE FROM models
+WHERE type = 'chat'
+  AND id <> (SELECT id FROM models WHERE type = 'chat' ORDER BY created_at ASC, id ASC LIMIT 1);
+DROP INDEX IF EXISTS idx_models_type;
+CREATE UNIQUE INDEX idx_models_type ON models (type);
@@ -0,0 +1,3 @@
+-- chat models are routed per kb and app, the other types stay unique
+DROP INDEX IF EXISTS idx_models_type;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_models_type ON models (type) WHERE type <> 'chat';
@@ -0,0 +1,3 @@
+-- running summary of the turns rolled out of the context window
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS summary text NOT NULL DEFAULT '';
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS summary_message_id text NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_conversation_messages_low_groundedness;
+ALTER TABLE conversation_messages DROP COLUMN IF EXISTS groundedness;
@@ -0,0 +1,3 @@
+-- verdict of the groundedness judge, only set for answers scored below the threshold
+ALTER TABLE conversation_messages ADD COLUMN IF NOT EXISTS groundedness jsonb;
+CREATE INDEX IF NOT EXISTS idx_conversation_messages_low_groundedness ON conversation_messages (conversation_id) WHERE groundedness IS NOT NULL;
@@ -0,0 +1,21 @@
+-- questions the kb could not answer, waiting for the staff
+CREATE TABLE IF NOT EXISTS handoffs (
+    id              text        NOT NULL,
+    kb_id           text        NOT NULL,
+    app_id          text        NOT NULL,
+    app_type        smallint    NOT NULL,
+    conversation_id text        NOT NULL,
+    message_id      text        NOT NULL,
+    question        text        NOT NULL,
+    reason          text        NOT NULL,
+    status          text        NOT NULL,
+    assignee        text        NOT NULL DEFAULT '',
+    note            text        NOT NULL DEFAULT '',
+    remote_ip       text        NOT NULL DEFAULT '',
+    info            jsonb       NOT NULL DEFAULT '{}',
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    updated_at      timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT handoffs_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_handoffs_kb_id_status ON handoffs (kb_id, status, created_at DESC);
@@ -0,0 +1,33 @@
+-- tables of the pgvector rag provider, rag_chunks needs the vector extension
+-- and is created when the provider starts
+CREATE TABLE IF NOT EXISTS rag_models (
+    id          text        PRIMARY KEY,
+    type        text        NOT NULL UNIQUE,
+    provider    text        NOT NULL DEFAULT '',
+    model       text        NOT NULL,
+    base_url    text        NOT NULL DEFAULT '',
+    api_key     text        NOT NULL DEFAULT '',
+    api_header  text        NOT NULL DEFAULT '',
+    api_version text        NOT NULL DEFAULT '',
+    is_active   boolean     NOT NULL DEFAULT true,
+    updated_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS rag_datasets (
+    id         text        PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS rag_documents (
+    id           text        PRIMARY KEY,
+    dataset_id   text        NOT NULL,
+    name         text        NOT NULL DEFAULT '',
+    status       text        NOT NULL DEFAULT '',
+    progress_msg text        NOT NULL DEFAULT '',
+    group_ids    integer[],
+    tags         text[]      NOT NULL DEFAULT '{}',
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    updated_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rag_documents_dataset_id ON rag_documents (dataset_id);
@@ -0,0 +1,146 @@
+package rag
+
+import (
+	"strings"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// markdownChunker splits markdown documents into token bounded chunks for
+// embedding. Chunks break on headings and blank lines where possible, and a
+// short trailing paragraph is repeated at the start of the next chunk so that
+// context cut at a boundary stays retrievable.
+type markdownChunker struct {
+	encoding  *tiktoken.Tiktoken
+	chunkSize int
+	overlap   int
+}
+
+func newMarkdownChunker(chunkSize, overlap int) (*markdownChunker, error) {
+	encoding, err := tiktoken.GetEncoding("cl100k_base")
+	if err != nil {
+		return nil, err
+	}
+	if chunkSize <= 0 {
+		chunkSize = 512
+	}
+	if overlap < 0 || overlap >= chunkSize {
+		overlap = 0
+	}
+	return &markdownChunker{
+		encoding:  encoding,
+		chunkSize: chunkSize,
+		overlap:   overlap,
+	}, nil
+}
+
+func (c *markdownChunker) tokenCount(text string) int {
+	return len(c.encoding.Encode(text, nil, nil))
+}
+
+func (c *markdownChunker) Split(markdown string) []string {
+	chunks := make([]string, 0)
+	var (
+		current       []string
+		currentTokens int
+		carried       bool // current only holds the overlap of the previous chunk
+	)
+	reset := func() {
+		current = current[:0]
+		currentTokens = 0
+		carried = false
+	}
+	flush := func() {
+		if len(current) == 0 || carried {
+			return
+		}
+		chunks = append(chunks, strings.Join(current, "\n\n"))
+		// carry the last block over when it is short enough to act as overlap
+		last := current[len(current)-1]
+		lastTokens := c.tokenCount(last)
+		reset()
+		if c.overlap > 0 && lastTokens <= c.overlap {
+			current = append(current, last)
+			currentTokens = lastTokens
+			carried = true
+		}
+	}
+
+	for _, block := range splitMarkdownBlocks(markdown) {
+		tokens := c.tokenCount(block)
+		if tokens > c.chunkSize {
+			flush()
+			reset()
+			chunks = append(chunks, c.splitByTokens(block)...)
+			continue
+		}
+		if strings.HasPrefix(block, "#") {
+			// a heading always starts a fresh chunk
+			flush()
+			reset()
+		} else if currentTokens+tokens > c.chunkSize {
+			flush()
+			if currentTokens+tokens > c.chunkSize {
+				// no room for the overlap
+				reset()
+			}
+		}
+		current = append(current, block)
+		currentTokens += tokens
+		carried = false
+	}
+	flush()
+	return chunks
+}
+
+// splitByTokens cuts an oversized block into fixed token windows
+func (c *markdownChunker) splitByTokens(block string) []string {
+	tokens := c.encoding.Encode(block, nil, nil)
+	step := c.chunkSize - c.overlap
+	result := make([]string, 0, len(tokens)/step+1)
+	for i := 0; i < len(tokens); i += step {
+		end := min(i+c.chunkSize, len(tokens))
+		// token windows may cut multi-byte characters, drop the broken bytes
+		text := strings.TrimSpace(strings.ToValidUTF8(c.encoding.Decode(tokens[i:end]), ""))
+		if text != "" {
+			result = append(result, text)
+		}
+		if end == len(tokens) {
+			break
+		}
+	}
+	return result
+}
+
+// splitMarkdownBlocks splits markdown on blank lines, keeping fenced code
+// blocks together
+func splitMarkdownBlocks(markdown string) []string {
+	blocks := make([]string, 0)
+	var (
+		current []string
+		inFence bool
+	)
+	flush := func() {
+		block := strings.TrimSpace(strings.Join(current, "\n"))
+		if block != "" {
+			blocks = append(blocks, block)
+		}
+		current = current[:0]
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence && trimmed == "" {
+			flush()
+			continue
+		}
+		if !inFence && strings.HasPrefix(trimmed, "#") && len(current) > 0 {
+			flush()
+		}
+		current = append(current, line)
+	}
+	flush()
+	return blocks
+}
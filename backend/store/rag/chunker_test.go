@@ -0,0 +1,84 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkoukk/tiktoken-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chaitin/panda-wiki/utils"
+)
+
+func newTestChunker(t *testing.T, chunkSize, overlap int) *markdownChunker {
+	tiktoken.SetBpeLoader(&utils.Localloader{})
+	chunker, err := newMarkdownChunker(chunkSize, overlap)
+	require.NoError(t, err)
+	return chunker
+}
+
+func TestSplitMarkdownBlocks(t *testing.T) {
+	markdown := "# Title\nintro line\n\nparagraph one\n\n```go\nfunc a() {}\n\nfunc b() {}\n```\n## Next\ntext"
+	blocks := splitMarkdownBlocks(markdown)
+	assert.Equal(t, []string{
+		"# Title\nintro line",
+		"paragraph one",
+		"```go\nfunc a() {}\n\nfunc b() {}\n```",
+		"## Next\ntext",
+	}, blocks)
+}
+
+func TestMarkdownChunker_Split(t *testing.T) {
+	tests := []struct {
+		name      string
+		markdown  string
+		chunkSize int
+		overlap   int
+		expected  []string
+	}{
+		{
+			name:      "empty",
+			markdown:  "  \n\n ",
+			chunkSize: 64,
+			expected:  []string{},
+		},
+		{
+			name:      "fits in one chunk",
+			markdown:  "hello\n\nworld",
+			chunkSize: 64,
+			expected:  []string{"hello\n\nworld"},
+		},
+		{
+			name:      "heading starts a new chunk",
+			markdown:  "intro\n\n# Install\nrun it\n\n# Usage\nuse it",
+			chunkSize: 64,
+			overlap:   8,
+			expected:  []string{"intro", "# Install\nrun it", "# Usage\nuse it"},
+		},
+		{
+			name:      "short block is carried over",
+			markdown:  "alpha beta gamma delta\n\nepsilon\n\nzeta eta theta iota",
+			chunkSize: 6,
+			overlap:   2,
+			expected:  []string{"alpha beta gamma delta\n\nepsilon", "epsilon\n\nzeta eta theta iota"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunker := newTestChunker(t, tt.chunkSize, tt.overlap)
+			assert.Equal(t, tt.expected, chunker.Split(tt.markdown))
+		})
+	}
+}
+
+func TestMarkdownChunker_SplitOversizedBlock(t *testing.T) {
+	chunker := newTestChunker(t, 16, 4)
+	block := strings.Repeat("知识库问答助手", 20)
+	chunks := chunker.Split(block)
+	require.Greater(t, len(chunks), 1)
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, chunker.tokenCount(chunk), 16)
+		assert.NotContains(t, chunk, "�")
+	}
+}
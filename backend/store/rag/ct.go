@@ -2,6 +2,7 @@ package rag
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -17,9 +18,11 @@ import (
 )
 
 type CTRAG struct {
-	client *raglite.Client
-	logger *log.Logger
-	mdConv *converter.Converter
+	client    *raglite.Client
+	logger    *log.Logger
+	mdConv    *converter.Converter
+	embedding *embeddingClient
+	chunks    *ctChunkClient
 }
 
 func NewCTRAG(config *config.Config, logger *log.Logger) (*CTRAG, error) {
@@ -31,9 +34,11 @@ func NewCTRAG(config *config.Config, logger *log.Logger) (*CTRAG, error) {
 		return nil, fmt.Errorf("failed to create raglite client: %w", err)
 	}
 	return &CTRAG{
-		client: client,
-		logger: logger.WithModule("store.vector.ct"),
-		mdConv: NewHTML2MDConverter(),
+		client:    client,
+		logger:    logger.WithModule("store.vector.ct"),
+		mdConv:    NewHTML2MDConverter(),
+		embedding: newEmbeddingClient(),
+		chunks:    newCTChunkClient(config.RAG.CTRAG.BaseURL, config.RAG.CTRAG.APIKey),
 	}, nil
 }
 
@@ -66,10 +71,14 @@ func (s *CTRAG) QueryRecords(ctx context.Context, req *QueryRecordsRequest) (str
 		}
 	}
 	s.logger.Debug("retrieving by history msgs", log.Any("history_msgs", req.HistoryMsgs), log.Any("chat_msgs", chatMsgs))
+	topK := req.TopK
+	if topK <= 0 {
+		topK = 10
+	}
 	data := &raglite.RetrieveRequest{
 		DatasetID: req.DatasetID,
 		Query:     req.Query,
-		TopK:      10,
+		TopK:      topK,
 		Metadata: map[string]interface{}{
 			"group_ids": req.GroupIDs,
 		},
@@ -89,6 +98,7 @@ func (s *CTRAG) QueryRecords(ctx context.Context, req *QueryRecordsRequest) (str
 			ID:      chunk.ChunkID,
 			Content: chunk.Content,
 			DocID:   chunk.DocumentID,
+			Score:   chunk.Score,
 		}
 	}
 	return res.Query, nodeChunks, nil
@@ -265,6 +275,22 @@ func (s *CTRAG) UpdateDocumentGroupIDs(ctx context.Context, datasetID string, do
 	return nil
 }
 
+func (s *CTRAG) UpdateDocumentTags(ctx context.Context, datasetID string, docID string, tags []string) error {
+	if tags == nil {
+		// nil tags are omitted from the request, send an empty list to clear them
+		tags = []string{}
+	}
+	_, err := s.client.Documents.Update(ctx, &raglite.UpdateDocumentRequest{
+		DatasetID:  datasetID,
+		DocumentID: docID,
+		Tags:       tags,
+	})
+	if err != nil {
+		return fmt.Errorf("update document tags failed: %w", err)
+	}
+	return nil
+}
+
 func (s *CTRAG) ListDocuments(ctx context.Context, datasetID string, documentIDs []string) ([]Document, error) {
 	res, err := s.client.Documents.List(ctx, &raglite.ListDocumentsRequest{
 		DocumentIDs: documentIDs,
@@ -287,3 +313,43 @@ func (s *CTRAG) ListDocuments(ctx context.Context, datasetID string, documentIDs
 	}
 	return documents, nil
 }
+
+func (s *CTRAG) ListChunks(ctx context.Context, datasetID, docID string) ([]*domain.NodeContentChunk, error) {
+	return s.chunks.ListChunks(ctx, datasetID, docID)
+}
+
+func (s *CTRAG) AddChunk(ctx context.Context, datasetID, docID, content string) (*domain.NodeContentChunk, error) {
+	return s.chunks.AddChunk(ctx, datasetID, docID, content)
+}
+
+func (s *CTRAG) UpdateChunk(ctx context.Context, datasetID, docID, chunkID, content string) error {
+	return s.chunks.UpdateChunk(ctx, datasetID, docID, chunkID, content)
+}
+
+func (s *CTRAG) DeleteChunks(ctx context.Context, datasetID, docID string, chunkIDs []string) error {
+	return s.chunks.DeleteChunks(ctx, datasetID, docID, chunkIDs)
+}
+
+// EmbedQuery calls the embedding model configured in raglite directly, raglite
+// has no embedding api
+func (s *CTRAG) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	models, err := s.GetModelList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get model list failed: %w", err)
+	}
+	var model *domain.Model
+	for _, m := range models {
+		if m.Type == domain.ModelTypeEmbedding {
+			model = m
+			break
+		}
+	}
+	if model == nil {
+		return nil, errors.New("embedding model is not configured")
+	}
+	vectors, err := s.embedding.Embed(ctx, model, []string{text})
+	if err != nil {
+		return nil, fmt.Errorf("embed query failed: %w", err)
+	}
+	return vectors[0], nil
+}
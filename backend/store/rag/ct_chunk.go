@@ -0,0 +1,143 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chaitin/panda-wiki/domain"
+)
+
+// ctChunkClient calls the chunk api of the rag service, raglite-go-sdk has no
+// chunk api. The requests follow the chunk api of sdk/rag.
+type ctChunkClient struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newCTChunkClient(baseURL, apiKey string) *ctChunkClient {
+	return &ctChunkClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type ctChunk struct {
+	ID              string  `json:"id"`
+	Content         string  `json:"content"`
+	DocumentID      string  `json:"document_id"`
+	CreateTimestamp float64 `json:"create_timestamp"`
+}
+
+type ctChunkResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func (c *ctChunkClient) chunksPath(datasetID, docID string) string {
+	return fmt.Sprintf("/api/v1/datasets/%s/documents/%s/chunks", url.PathEscape(datasetID), url.PathEscape(docID))
+}
+
+func (c *ctChunkClient) do(ctx context.Context, method, path string, body, result any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal request failed: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("create request failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response failed: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("chunk api %s %s failed: %d %s", method, path, resp.StatusCode, string(respBody))
+	}
+	var res ctChunkResponse
+	if err := json.Unmarshal(respBody, &res); err != nil {
+		return fmt.Errorf("unmarshal response failed: %w", err)
+	}
+	if res.Code != 0 {
+		return fmt.Errorf("chunk api %s %s failed: code=%d, message=%s", method, path, res.Code, res.Message)
+	}
+	if result != nil && len(res.Data) > 0 {
+		if err := json.Unmarshal(res.Data, result); err != nil {
+			return fmt.Errorf("unmarshal response data failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListChunks returns the chunks of the document in the order they were created
+func (c *ctChunkClient) ListChunks(ctx context.Context, datasetID, docID string) ([]*domain.NodeContentChunk, error) {
+	var data struct {
+		Chunks []ctChunk `json:"chunks"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.chunksPath(datasetID, docID), nil, &data); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(data.Chunks, func(i, j int) bool {
+		return data.Chunks[i].CreateTimestamp < data.Chunks[j].CreateTimestamp
+	})
+	chunks := make([]*domain.NodeContentChunk, len(data.Chunks))
+	for i, chunk := range data.Chunks {
+		chunks[i] = &domain.NodeContentChunk{
+			ID:      chunk.ID,
+			DocID:   docID,
+			Seq:     uint(i + 1),
+			Content: chunk.Content,
+		}
+	}
+	return chunks, nil
+}
+
+func (c *ctChunkClient) AddChunk(ctx context.Context, datasetID, docID, content string) (*domain.NodeContentChunk, error) {
+	var data struct {
+		Chunk ctChunk `json:"chunk"`
+	}
+	body := map[string]any{"content": content}
+	if err := c.do(ctx, http.MethodPost, c.chunksPath(datasetID, docID), body, &data); err != nil {
+		return nil, err
+	}
+	return &domain.NodeContentChunk{
+		ID:      data.Chunk.ID,
+		DocID:   docID,
+		Content: content,
+	}, nil
+}
+
+func (c *ctChunkClient) UpdateChunk(ctx context.Context, datasetID, docID, chunkID, content string) error {
+	path := c.chunksPath(datasetID, docID) + "/" + url.PathEscape(chunkID)
+	return c.do(ctx, http.MethodPut, path, map[string]any{"content": content}, nil)
+}
+
+func (c *ctChunkClient) DeleteChunks(ctx context.Context, datasetID, docID string, chunkIDs []string) error {
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+	return c.do(ctx, http.MethodDelete, c.chunksPath(datasetID, docID), map[string]any{"chunk_ids": chunkIDs}, nil)
+}
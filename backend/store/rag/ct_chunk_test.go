@@ -0,0 +1,56 @@
+package rag
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCTChunkClient(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/datasets/ds/documents/doc/chunks":
+			_, _ = w.Write([]byte(`{"code":0,"data":{"chunks":[` +
+				`{"id":"c2","content":"second","create_timestamp":2},` +
+				`{"id":"c1","content":"first","create_timestamp":1}],"total":2}}`))
+		case "POST /api/v1/datasets/ds/documents/doc/chunks":
+			_, _ = w.Write([]byte(`{"code":0,"data":{"chunk":{"id":"c3"}}}`))
+		case "PUT /api/v1/datasets/ds/documents/doc/chunks/c1":
+			_, _ = w.Write([]byte(`{"code":102,"message":"chunk not found"}`))
+		case "DELETE /api/v1/datasets/ds/documents/doc/chunks":
+			var req struct {
+				ChunkIDs []string `json:"chunk_ids"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			deleted = req.ChunkIDs
+			_, _ = w.Write([]byte(`{"code":0}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client := newCTChunkClient(server.URL+"/", "key")
+
+	chunks, err := client.ListChunks(t.Context(), "ds", "doc")
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	assert.Equal(t, "c1", chunks[0].ID)
+	assert.Equal(t, uint(1), chunks[0].Seq)
+	assert.Equal(t, "second", chunks[1].Content)
+
+	chunk, err := client.AddChunk(t.Context(), "ds", "doc", "third")
+	require.NoError(t, err)
+	assert.Equal(t, "c3", chunk.ID)
+	assert.Equal(t, "third", chunk.Content)
+
+	assert.ErrorContains(t, client.UpdateChunk(t.Context(), "ds", "doc", "c1", "new"), "chunk not found")
+
+	require.NoError(t, client.DeleteChunks(t.Context(), "ds", "doc", []string{"c1", "c2"}))
+	assert.Equal(t, []string{"c1", "c2"}, deleted)
+}
@@ -0,0 +1,118 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/utils"
+)
+
+const embeddingBatchSize = 16
+
+// embeddingClient calls OpenAI compatible /embeddings endpoints
+type embeddingClient struct {
+	httpClient *http.Client
+}
+
+func newEmbeddingClient() *embeddingClient {
+	return &embeddingClient{
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (c *embeddingClient) Embed(ctx context.Context, model *domain.Model, inputs []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(inputs))
+	for start := 0; start < len(inputs); start += embeddingBatchSize {
+		end := min(start+embeddingBatchSize, len(inputs))
+		batch, err := c.embedBatch(ctx, model, inputs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (c *embeddingClient) embedBatch(ctx context.Context, model *domain.Model, inputs []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{
+		Model: model.Model,
+		Input: inputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	url := strings.TrimSuffix(model.BaseURL, "/") + "/embeddings"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if model.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+model.APIKey)
+	}
+	for key, value := range utils.GetHeaderMap(model.APIHeader) {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request embedding failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read embedding response failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding request failed with status %d: %s", resp.StatusCode, truncate(string(respBody), 200))
+	}
+	var result embeddingResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal embedding response failed: %w", err)
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("embedding request failed: %s", result.Error.Message)
+	}
+	if len(result.Data) != len(inputs) {
+		return nil, fmt.Errorf("embedding count mismatch: want %d, got %d", len(inputs), len(result.Data))
+	}
+	sort.Slice(result.Data, func(i, j int) bool {
+		return result.Data[i].Index < result.Data[j].Index
+	})
+	vectors := make([][]float32, len(result.Data))
+	for i, item := range result.Data {
+		vectors[i] = item.Embedding
+	}
+	return vectors, nil
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
@@ -0,0 +1,616 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/pkoukk/tiktoken-go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/chaitin/panda-wiki/config"
+	"github.com/chaitin/panda-wiki/consts"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/store/pg"
+	"github.com/chaitin/panda-wiki/utils"
+)
+
+const (
+	pgvectorTopK = 10
+	// candidates fetched before max chunks per doc is applied
+	pgvectorCandidateFactor = 4
+)
+
+type pgvectorModel struct {
+	ID         string               `gorm:"primaryKey"`
+	Type       domain.ModelType     `gorm:"column:type"`
+	Provider   domain.ModelProvider `gorm:"column:provider"`
+	Model      string               `gorm:"column:model"`
+	BaseURL    string               `gorm:"column:base_url"`
+	APIKey     string               `gorm:"column:api_key"`
+	APIHeader  string               `gorm:"column:api_header"`
+	APIVersion string               `gorm:"column:api_version"`
+	IsActive   bool                 `gorm:"column:is_active"`
+	UpdatedAt  time.Time            `gorm:"column:updated_at"`
+}
+
+func (pgvectorModel) TableName() string {
+	return "rag_models"
+}
+
+func (m *pgvectorModel) toDomain() *domain.Model {
+	return &domain.Model{
+		ID:         m.ID,
+		Provider:   m.Provider,
+		Model:      m.Model,
+		APIKey:     m.APIKey,
+		APIHeader:  m.APIHeader,
+		BaseURL:    m.BaseURL,
+		APIVersion: m.APIVersion,
+		Type:       m.Type,
+		IsActive:   m.IsActive,
+	}
+}
+
+type pgvectorDocument struct {
+	ID          string         `gorm:"primaryKey"`
+	DatasetID   string         `gorm:"column:dataset_id"`
+	Name        string         `gorm:"column:name"`
+	Status      string         `gorm:"column:status"`
+	ProgressMsg string         `gorm:"column:progress_msg"`
+	GroupIDs    pq.Int64Array  `gorm:"column:group_ids;type:integer[]"`
+	Tags        pq.StringArray `gorm:"column:tags;type:text[]"`
+	CreatedAt   time.Time      `gorm:"column:created_at"`
+	UpdatedAt   time.Time      `gorm:"column:updated_at"`
+}
+
+func (pgvectorDocument) TableName() string {
+	return "rag_documents"
+}
+
+type pgvectorChunk struct {
+	ID         string
+	DocumentID string
+	Seq        uint
+	Content    string
+	Distance   float64
+}
+
+// PGVectorRAG stores chunks and embeddings in the panda-wiki postgres with
+// the pgvector extension, so no external RAG service is required.
+type PGVectorRAG struct {
+	db        *pg.DB
+	logger    *log.Logger
+	mdConv    *converter.Converter
+	chunker   *markdownChunker
+	embedding *embeddingClient
+}
+
+func NewPGVectorRAG(config *config.Config, db *pg.DB, logger *log.Logger) (*PGVectorRAG, error) {
+	tiktoken.SetBpeLoader(&utils.Localloader{})
+	chunker, err := newMarkdownChunker(config.RAG.PGVector.ChunkSize, config.RAG.PGVector.ChunkOverlap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chunker: %w", err)
+	}
+	if err := migratePGVectorChunks(db); err != nil {
+		return nil, fmt.Errorf("failed to create pgvector schema, is the vector extension installed: %w", err)
+	}
+	return &PGVectorRAG{
+		db:        db,
+		logger:    logger.WithModule("store.vector.pgvector"),
+		mdConv:    NewHTML2MDConverter(),
+		chunker:   chunker,
+		embedding: newEmbeddingClient(),
+	}, nil
+}
+
+// pgvectorChunksSchema creates rag_chunks, which needs the vector extension
+// only deployments of the pgvector provider have. The dimension depends on the
+// embedding model, all datasets are rebuilt when the model changes.
+var pgvectorChunksSchema = []string{
+	"CREATE EXTENSION IF NOT EXISTS vector",
+	`CREATE TABLE IF NOT EXISTS rag_chunks (
+		id          text    PRIMARY KEY,
+		dataset_id  text    NOT NULL,
+		document_id text    NOT NULL REFERENCES rag_documents (id) ON DELETE CASCADE,
+		seq         integer NOT NULL,
+		content     text    NOT NULL,
+		embedding   vector  NOT NULL
+	)`,
+	"CREATE INDEX IF NOT EXISTS idx_rag_chunks_dataset_id ON rag_chunks (dataset_id)",
+	"CREATE INDEX IF NOT EXISTS idx_rag_chunks_document_id ON rag_chunks (document_id)",
+}
+
+// pgvectorIndexDims are the dimensions with an ann index, queries cast to the
+// dimension of the query vector, other dimensions are scanned
+var pgvectorIndexDims = []int{384, 512, 768, 1024, 1536}
+
+func migratePGVectorChunks(db *pg.DB) error {
+	for _, statement := range pgvectorChunksSchema {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	for _, dim := range pgvectorIndexDims {
+		if err := db.Exec(fmt.Sprintf(
+			"CREATE INDEX IF NOT EXISTS idx_rag_chunks_embedding_%d ON rag_chunks USING hnsw ((embedding::vector(%d)) vector_cosine_ops) WHERE vector_dims(embedding) = %d",
+			dim, dim, dim)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PGVectorRAG) CreateKnowledgeBase(ctx context.Context) (string, error) {
+	id := uuid.New().String()
+	if err := s.db.WithContext(ctx).Exec("INSERT INTO rag_datasets (id) VALUES (?)", id).Error; err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// getEmbeddingModel returns the embedding model synced through UpsertModel,
+// falling back to the active embedding model of manual mode
+func (s *PGVectorRAG) getEmbeddingModel(ctx context.Context) (*domain.Model, error) {
+	var ragModel pgvectorModel
+	err := s.db.WithContext(ctx).
+		Where("type = ? AND is_active = ?", domain.ModelTypeEmbedding, true).
+		First(&ragModel).Error
+	if err == nil {
+		return ragModel.toDomain(), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	var model domain.Model
+	if err := s.db.WithContext(ctx).
+		Where("type = ? AND is_active = ?", domain.ModelTypeEmbedding, true).
+		First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("embedding model is not configured")
+		}
+		return nil, err
+	}
+	return &model, nil
+}
+
+func (s *PGVectorRAG) QueryRecords(ctx context.Context, req *QueryRecordsRequest) (string, []*domain.NodeContentChunk, error) {
+	model, err := s.getEmbeddingModel(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	vectors, err := s.embedding.Embed(ctx, model, []string{pgvectorQueryText(req)})
+	if err != nil {
+		return "", nil, fmt.Errorf("embed query failed: %w", err)
+	}
+	topK := req.TopK
+	if topK <= 0 {
+		topK = pgvectorTopK
+	}
+	limit := topK
+	if req.MaxChunksPerDoc > 0 {
+		limit *= pgvectorCandidateFactor
+	}
+	var candidates []pgvectorChunk
+	if err := s.queryChunks(ctx, req, vectors[0]).Limit(limit).Scan(&candidates).Error; err != nil {
+		return "", nil, fmt.Errorf("query chunks failed: %w", err)
+	}
+
+	nodeChunks := make([]*domain.NodeContentChunk, 0, topK)
+	docChunkCount := make(map[string]int)
+	for _, chunk := range candidates {
+		if len(nodeChunks) >= topK {
+			break
+		}
+		if req.MaxChunksPerDoc > 0 && docChunkCount[chunk.DocumentID] >= req.MaxChunksPerDoc {
+			continue
+		}
+		docChunkCount[chunk.DocumentID]++
+		nodeChunks = append(nodeChunks, &domain.NodeContentChunk{
+			ID:      chunk.ID,
+			DocID:   chunk.DocumentID,
+			Seq:     chunk.Seq,
+			Content: chunk.Content,
+			Score:   1 - chunk.Distance,
+		})
+	}
+	s.logger.Info("retrieve chunks result", log.Int("chunks count", len(nodeChunks)), log.String("query", req.Query))
+	return req.Query, nodeChunks, nil
+}
+
+// pgvectorQueryText is the text embedded for a query. There is no chat model
+// to rewrite the question with the history like the ct provider does, so the
+// last question of the user is prepended to keep follow-ups on topic.
+func pgvectorQueryText(req *QueryRecordsRequest) string {
+	for i := len(req.HistoryMsgs) - 1; i >= 0; i-- {
+		msg := req.HistoryMsgs[i]
+		if msg.Role == schema.User && strings.TrimSpace(msg.Content) != "" {
+			return fmt.Sprintf("%s\n%s", msg.Content, req.Query)
+		}
+	}
+	return req.Query
+}
+
+// queryChunks selects the chunks of the dataset visible to the groups ordered
+// by cosine distance. The embedding is cast to the dimension of the query, so
+// chunks of another dimension are skipped and the ann index of the dimension
+// is used.
+func (s *PGVectorRAG) queryChunks(ctx context.Context, req *QueryRecordsRequest, vector []float32) *gorm.DB {
+	dims := strconv.Itoa(len(vector))
+	embedding := "(c.embedding::vector(" + dims + "))"
+	queryVector := formatVector(vector)
+	query := s.db.WithContext(ctx).
+		Table("rag_chunks AS c").
+		Select("c.id, c.document_id, c.seq, c.content, "+embedding+" <=> ?::vector AS distance", queryVector).
+		Joins("JOIN rag_documents AS d ON d.id = c.document_id").
+		Where("c.dataset_id = ?", req.DatasetID).
+		Where("vector_dims(c.embedding) = "+dims).
+		Where("d.group_ids IS NULL OR d.group_ids && ?::integer[]", pq.Array(toInt64s(req.GroupIDs)))
+	if len(req.Tags) > 0 {
+		query = query.Where("d.tags && ?::text[]", pq.Array(req.Tags))
+	}
+	if req.SimilarityThreshold > 0 {
+		// cosine distance = 1 - cosine similarity
+		query = query.Where(embedding+" <=> ?::vector <= ?", queryVector, 1-req.SimilarityThreshold)
+	}
+	return query.Order("distance ASC")
+}
+
+func (s *PGVectorRAG) UpsertRecords(ctx context.Context, req *UpsertRecordsRequest) (string, error) {
+	markdown := req.Content
+	// if the content is html, convert it to markdown first
+	if utils.IsLikelyHTML(req.Content) {
+		var err error
+		markdown, err = s.mdConv.ConvertString(req.Content)
+		if err != nil {
+			return "", fmt.Errorf("convert html to markdown failed: %w", err)
+		}
+	}
+	docID := req.DocID
+	if docID == "" {
+		docID = uuid.New().String()
+	}
+
+	chunks := s.chunker.Split(markdown)
+	var vectors [][]float32
+	if len(chunks) > 0 {
+		model, err := s.getEmbeddingModel(ctx)
+		if err != nil {
+			return "", err
+		}
+		// prefix the title so that short chunks keep their topic
+		inputs := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			inputs[i] = fmt.Sprintf("%s\n\n%s", req.Title, chunk)
+		}
+		vectors, err = s.embedding.Embed(ctx, model, inputs)
+		if err != nil {
+			return "", fmt.Errorf("embed document chunks failed: %w", err)
+		}
+	}
+
+	document := &pgvectorDocument{
+		ID:        docID,
+		DatasetID: req.DatasetID,
+		Name:      req.Title,
+		Status:    string(consts.NodeRagStatusSucceeded),
+		Tags:      pq.StringArray(req.Tags),
+		UpdatedAt: time.Now(),
+	}
+	if document.Tags == nil {
+		document.Tags = pq.StringArray{}
+	}
+	if req.GroupIDs != nil {
+		document.GroupIDs = toInt64s(req.GroupIDs)
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"dataset_id", "name", "status", "progress_msg", "group_ids", "tags", "updated_at"}),
+		}).Create(document).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM rag_chunks WHERE document_id = ?", docID).Error; err != nil {
+			return err
+		}
+		for i, chunk := range chunks {
+			if err := tx.Exec(
+				"INSERT INTO rag_chunks (id, dataset_id, document_id, seq, content, embedding) VALUES (?, ?, ?, ?, ?, ?::vector)",
+				uuid.New().String(), req.DatasetID, docID, i, chunk, formatVector(vectors[i]),
+			).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("save document chunks failed: %w", err)
+	}
+	return docID, nil
+}
+
+func (s *PGVectorRAG) DeleteRecords(ctx context.Context, datasetID string, docIDs []string) error {
+	if len(docIDs) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).
+		Where("dataset_id = ? AND id IN ?", datasetID, docIDs).
+		Delete(&pgvectorDocument{}).Error
+}
+
+func (s *PGVectorRAG) DeleteKnowledgeBase(ctx context.Context, datasetID string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("dataset_id = ?", datasetID).Delete(&pgvectorDocument{}).Error; err != nil {
+			return err
+		}
+		return tx.Exec("DELETE FROM rag_datasets WHERE id = ?", datasetID).Error
+	})
+}
+
+func (s *PGVectorRAG) UpdateDocumentGroupIDs(ctx context.Context, datasetID string, docID string, groupIds []int) error {
+	var groupIDs any
+	if groupIds != nil {
+		groupIDs = pq.Int64Array(toInt64s(groupIds))
+	}
+	if err := s.db.WithContext(ctx).
+		Model(&pgvectorDocument{}).
+		Where("dataset_id = ? AND id = ?", datasetID, docID).
+		Updates(map[string]any{
+			"group_ids":  groupIDs,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+		return fmt.Errorf("update document group IDs failed: %w", err)
+	}
+	return nil
+}
+
+func (s *PGVectorRAG) UpdateDocumentTags(ctx context.Context, datasetID string, docID string, tags []string) error {
+	if tags == nil {
+		tags = []string{}
+	}
+	if err := s.db.WithContext(ctx).
+		Model(&pgvectorDocument{}).
+		Where("dataset_id = ? AND id = ?", datasetID, docID).
+		Updates(map[string]any{
+			"tags":       pq.StringArray(tags),
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+		return fmt.Errorf("update document tags failed: %w", err)
+	}
+	return nil
+}
+
+func (s *PGVectorRAG) ListDocuments(ctx context.Context, datasetID string, documentIDs []string) ([]Document, error) {
+	query := s.db.WithContext(ctx).Where("dataset_id = ?", datasetID)
+	if len(documentIDs) > 0 {
+		query = query.Where("id IN ?", documentIDs)
+	}
+	var docs []pgvectorDocument
+	if err := query.Find(&docs).Error; err != nil {
+		return nil, err
+	}
+	documents := make([]Document, len(docs))
+	for i, doc := range docs {
+		var groupIDs []int
+		if doc.GroupIDs != nil {
+			groupIDs = make([]int, len(doc.GroupIDs))
+			for j, id := range doc.GroupIDs {
+				groupIDs[j] = int(id)
+			}
+		}
+		documents[i] = Document{
+			ID:          doc.ID,
+			Name:        doc.Name,
+			DatasetID:   doc.DatasetID,
+			Status:      doc.Status,
+			ProgressMsg: doc.ProgressMsg,
+			Tags:        doc.Tags,
+			MetaData:    DocumentMetadata{GroupIDs: groupIDs},
+		}
+	}
+	return documents, nil
+}
+
+func (s *PGVectorRAG) ListChunks(ctx context.Context, datasetID, docID string) ([]*domain.NodeContentChunk, error) {
+	var chunks []pgvectorChunk
+	if err := s.db.WithContext(ctx).
+		Table("rag_chunks").
+		Select("id, document_id, seq, content").
+		Where("dataset_id = ? AND document_id = ?", datasetID, docID).
+		Order("seq ASC").
+		Scan(&chunks).Error; err != nil {
+		return nil, fmt.Errorf("list chunks failed: %w", err)
+	}
+	nodeChunks := make([]*domain.NodeContentChunk, len(chunks))
+	for i, chunk := range chunks {
+		nodeChunks[i] = &domain.NodeContentChunk{
+			ID:      chunk.ID,
+			DocID:   chunk.DocumentID,
+			Seq:     chunk.Seq,
+			Content: chunk.Content,
+		}
+	}
+	return nodeChunks, nil
+}
+
+// embedChunk embeds the content with the document title like UpsertRecords does
+func (s *PGVectorRAG) embedChunk(ctx context.Context, datasetID, docID, content string) (string, error) {
+	var document pgvectorDocument
+	if err := s.db.WithContext(ctx).
+		Where("dataset_id = ? AND id = ?", datasetID, docID).
+		First(&document).Error; err != nil {
+		return "", fmt.Errorf("get document failed: %w", err)
+	}
+	model, err := s.getEmbeddingModel(ctx)
+	if err != nil {
+		return "", err
+	}
+	vectors, err := s.embedding.Embed(ctx, model, []string{fmt.Sprintf("%s\n\n%s", document.Name, content)})
+	if err != nil {
+		return "", fmt.Errorf("embed chunk failed: %w", err)
+	}
+	return formatVector(vectors[0]), nil
+}
+
+func (s *PGVectorRAG) AddChunk(ctx context.Context, datasetID, docID, content string) (*domain.NodeContentChunk, error) {
+	vector, err := s.embedChunk(ctx, datasetID, docID, content)
+	if err != nil {
+		return nil, err
+	}
+	chunk := &domain.NodeContentChunk{
+		ID:      uuid.New().String(),
+		DocID:   docID,
+		Content: content,
+	}
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var seq uint
+		if err := tx.Raw("SELECT COALESCE(MAX(seq) + 1, 0) FROM rag_chunks WHERE document_id = ?", docID).Scan(&seq).Error; err != nil {
+			return err
+		}
+		chunk.Seq = seq
+		return tx.Exec(
+			"INSERT INTO rag_chunks (id, dataset_id, document_id, seq, content, embedding) VALUES (?, ?, ?, ?, ?, ?::vector)",
+			chunk.ID, datasetID, docID, seq, content, vector,
+		).Error
+	}); err != nil {
+		return nil, fmt.Errorf("add chunk failed: %w", err)
+	}
+	return chunk, nil
+}
+
+func (s *PGVectorRAG) UpdateChunk(ctx context.Context, datasetID, docID, chunkID, content string) error {
+	vector, err := s.embedChunk(ctx, datasetID, docID, content)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Exec(
+		"UPDATE rag_chunks SET content = ?, embedding = ?::vector WHERE dataset_id = ? AND document_id = ? AND id = ?",
+		content, vector, datasetID, docID, chunkID,
+	).Error; err != nil {
+		return fmt.Errorf("update chunk failed: %w", err)
+	}
+	return nil
+}
+
+func (s *PGVectorRAG) DeleteChunks(ctx context.Context, datasetID, docID string, chunkIDs []string) error {
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+	if err := s.db.WithContext(ctx).Exec(
+		"DELETE FROM rag_chunks WHERE dataset_id = ? AND document_id = ? AND id IN ?",
+		datasetID, docID, chunkIDs,
+	).Error; err != nil {
+		return fmt.Errorf("delete chunks failed: %w", err)
+	}
+	return nil
+}
+
+func (s *PGVectorRAG) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	model, err := s.getEmbeddingModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := s.embedding.Embed(ctx, model, []string{text})
+	if err != nil {
+		return nil, fmt.Errorf("embed query failed: %w", err)
+	}
+	return vectors[0], nil
+}
+
+func (s *PGVectorRAG) GetModelList(ctx context.Context) ([]*domain.Model, error) {
+	var ragModels []pgvectorModel
+	if err := s.db.WithContext(ctx).Order("type").Find(&ragModels).Error; err != nil {
+		return nil, err
+	}
+	models := make([]*domain.Model, len(ragModels))
+	for i := range ragModels {
+		models[i] = ragModels[i].toDomain()
+	}
+	return models, nil
+}
+
+// AddModel keeps a single model per type, the same as the ct provider's
+// default model
+func (s *PGVectorRAG) AddModel(ctx context.Context, model *domain.Model) (string, error) {
+	return s.saveModel(ctx, model)
+}
+
+func (s *PGVectorRAG) UpsertModel(ctx context.Context, model *domain.Model) error {
+	_, err := s.saveModel(ctx, model)
+	return err
+}
+
+func (s *PGVectorRAG) UpdateModel(ctx context.Context, model *domain.Model) error {
+	return s.db.WithContext(ctx).
+		Model(&pgvectorModel{}).
+		Where("id = ?", model.ID).
+		Updates(map[string]any{
+			"provider":    model.Provider,
+			"model":       model.Model,
+			"base_url":    model.BaseURL,
+			"api_key":     model.APIKey,
+			"api_header":  model.APIHeader,
+			"api_version": model.APIVersion,
+			"is_active":   model.IsActive,
+			"updated_at":  time.Now(),
+		}).Error
+}
+
+func (s *PGVectorRAG) DeleteModel(ctx context.Context, model *domain.Model) error {
+	return s.db.WithContext(ctx).Where("id = ?", model.ID).Delete(&pgvectorModel{}).Error
+}
+
+func (s *PGVectorRAG) saveModel(ctx context.Context, model *domain.Model) (string, error) {
+	ragModel := &pgvectorModel{
+		ID:         uuid.New().String(),
+		Type:       model.Type,
+		Provider:   model.Provider,
+		Model:      model.Model,
+		BaseURL:    model.BaseURL,
+		APIKey:     model.APIKey,
+		APIHeader:  model.APIHeader,
+		APIVersion: model.APIVersion,
+		IsActive:   model.IsActive,
+		UpdatedAt:  time.Now(),
+	}
+	if err := s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "type"}},
+			DoUpdates: clause.AssignmentColumns([]string{"provider", "model", "base_url", "api_key", "api_header", "api_version", "is_active", "updated_at"}),
+		}, clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Create(ragModel).Error; err != nil {
+		return "", err
+	}
+	return ragModel.ID, nil
+}
+
+// formatVector formats an embedding as a pgvector literal
+func formatVector(vector []float32) string {
+	var builder strings.Builder
+	builder.WriteByte('[')
+	for i, v := range vector {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	}
+	builder.WriteByte(']')
+	return builder.String()
+}
+
+func toInt64s(ids []int) []int64 {
+	result := make([]int64, len(ids))
+	for i, id := range ids {
+		result[i] = int64(id)
+	}
+	return result
+}
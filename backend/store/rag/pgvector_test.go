@@ -0,0 +1,144 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/store/pg"
+)
+
+// emptyConn is a database connection answering every statement with no rows
+type emptyConn struct{}
+
+func (emptyConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (emptyConn) Close() error                              { return nil }
+func (emptyConn) Begin() (driver.Tx, error)                 { return emptyTx{}, nil }
+
+func (emptyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return emptyRows{}, nil
+}
+
+func (emptyConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+type emptyTx struct{}
+
+func (emptyTx) Commit() error   { return nil }
+func (emptyTx) Rollback() error { return nil }
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string              { return nil }
+func (emptyRows) Close() error                   { return nil }
+func (emptyRows) Next(dest []driver.Value) error { return io.EOF }
+
+type emptyConnector struct{}
+
+func (emptyConnector) Connect(context.Context) (driver.Conn, error) { return emptyConn{}, nil }
+func (emptyConnector) Driver() driver.Driver                        { return nil }
+
+// sqlRecorder records the statements sent to the db
+type sqlRecorder struct {
+	logger.Interface
+	sqls []string
+}
+
+func (r *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	r.sqls = append(r.sqls, sql)
+}
+
+func newTestPGVectorRAG(t *testing.T) (*PGVectorRAG, *sqlRecorder) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(emptyConnector{})}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               recorder,
+	})
+	require.NoError(t, err)
+	return &PGVectorRAG{
+		db:     &pg.DB{DB: db},
+		logger: &log.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))},
+	}, recorder
+}
+
+func TestPGVectorQueryChunks(t *testing.T) {
+	s, recorder := newTestPGVectorRAG(t)
+	var chunks []pgvectorChunk
+	require.NoError(t, s.queryChunks(t.Context(), &QueryRecordsRequest{
+		DatasetID:           "ds",
+		GroupIDs:            []int{1, 2},
+		Tags:                []string{"faq"},
+		SimilarityThreshold: 0.2,
+	}, []float32{0.5, 1}).Limit(5).Scan(&chunks).Error)
+
+	require.Len(t, recorder.sqls, 1)
+	sql := recorder.sqls[0]
+	assert.Contains(t, sql, `(c.embedding::vector(2)) <=> '[0.5,1]'::vector AS distance`)
+	assert.Contains(t, sql, `c.dataset_id = 'ds'`)
+	assert.Contains(t, sql, `vector_dims(c.embedding) = 2`)
+	assert.Contains(t, sql, `d.group_ids IS NULL OR d.group_ids && '{1,2}'::integer[]`)
+	assert.Contains(t, sql, `d.tags && '{"faq"}'::text[]`)
+	assert.Contains(t, sql, `(c.embedding::vector(2)) <=> '[0.5,1]'::vector <= 0.8`)
+	assert.Contains(t, sql, `ORDER BY distance ASC LIMIT 5`)
+}
+
+func TestPGVectorQueryChunksWithoutGroups(t *testing.T) {
+	s, recorder := newTestPGVectorRAG(t)
+	var chunks []pgvectorChunk
+	require.NoError(t, s.queryChunks(t.Context(), &QueryRecordsRequest{DatasetID: "ds"}, []float32{1}).Scan(&chunks).Error)
+
+	require.Len(t, recorder.sqls, 1)
+	// documents limited to groups are hidden from users without groups
+	assert.Contains(t, recorder.sqls[0], `d.group_ids IS NULL OR d.group_ids && '{}'::integer[]`)
+	assert.NotContains(t, recorder.sqls[0], "d.tags")
+}
+
+func TestPGVectorChunkSQL(t *testing.T) {
+	s, recorder := newTestPGVectorRAG(t)
+
+	_, err := s.ListChunks(t.Context(), "ds", "doc")
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteChunks(t.Context(), "ds", "doc", []string{"c1", "c2"}))
+	require.NoError(t, s.DeleteChunks(t.Context(), "ds", "doc", nil))
+	require.NoError(t, s.UpdateDocumentGroupIDs(t.Context(), "ds", "doc", nil))
+
+	require.Len(t, recorder.sqls, 3)
+	assert.Equal(t, `SELECT id, document_id, seq, content FROM "rag_chunks" WHERE dataset_id = 'ds' AND document_id = 'doc' ORDER BY seq ASC`, recorder.sqls[0])
+	assert.Equal(t, `DELETE FROM rag_chunks WHERE dataset_id = 'ds' AND document_id = 'doc' AND id IN ('c1','c2')`, recorder.sqls[1])
+	assert.Contains(t, recorder.sqls[2], `"group_ids"=NULL`)
+}
+
+func TestMigratePGVectorChunks(t *testing.T) {
+	s, recorder := newTestPGVectorRAG(t)
+	require.NoError(t, migratePGVectorChunks(s.db))
+
+	require.Len(t, recorder.sqls, len(pgvectorChunksSchema)+len(pgvectorIndexDims))
+	assert.Equal(t, "CREATE EXTENSION IF NOT EXISTS vector", recorder.sqls[0])
+	assert.Contains(t, recorder.sqls[1], "CREATE TABLE IF NOT EXISTS rag_chunks")
+	assert.Equal(t, "CREATE INDEX IF NOT EXISTS idx_rag_chunks_embedding_1536 ON rag_chunks USING hnsw ((embedding::vector(1536)) vector_cosine_ops) WHERE vector_dims(embedding) = 1536", recorder.sqls[len(recorder.sqls)-1])
+}
+
+func TestPGVectorQueryText(t *testing.T) {
+	req := &QueryRecordsRequest{Query: "how much is it"}
+	assert.Equal(t, "how much is it", pgvectorQueryText(req))
+
+	req.HistoryMsgs = []*schema.Message{
+		schema.UserMessage("tell me about the pro plan"),
+		schema.AssistantMessage("the pro plan has sso", nil),
+	}
+	assert.Equal(t, "tell me about the pro plan\nhow much is it", pgvectorQueryText(req))
+}
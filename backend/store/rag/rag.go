@@ -2,6 +2,7 @@ package rag
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/cloudwego/eino/schema"
@@ -10,13 +11,18 @@ import (
 	"github.com/chaitin/panda-wiki/config"
 	"github.com/chaitin/panda-wiki/domain"
 	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/store/pg"
 )
 
+// ErrChunkUnsupported is returned by providers that do not expose the chunks of a document
+var ErrChunkUnsupported = errors.New("chunk operations are not supported by the rag provider")
+
 type QueryRecordsRequest struct {
 	DatasetID           string
 	Query               string
 	GroupIDs            []int
 	Tags                []string
+	TopK                int // 0 means the provider default
 	SimilarityThreshold float64
 	HistoryMsgs         []*schema.Message
 	MaxChunksPerDoc     int
@@ -53,8 +59,17 @@ type RAGService interface {
 	DeleteRecords(ctx context.Context, datasetID string, docIDs []string) error
 	DeleteKnowledgeBase(ctx context.Context, datasetID string) error
 	UpdateDocumentGroupIDs(ctx context.Context, datasetID string, docID string, groupIds []int) error
+	UpdateDocumentTags(ctx context.Context, datasetID string, docID string, tags []string) error
 	ListDocuments(ctx context.Context, datasetID string, documentIDs []string) ([]Document, error)
 
+	ListChunks(ctx context.Context, datasetID, docID string) ([]*domain.NodeContentChunk, error)
+	AddChunk(ctx context.Context, datasetID, docID, content string) (*domain.NodeContentChunk, error)
+	UpdateChunk(ctx context.Context, datasetID, docID, chunkID, content string) error
+	DeleteChunks(ctx context.Context, datasetID, docID string, chunkIDs []string) error
+
+	// EmbedQuery embeds the text with the embedding model of the datasets
+	EmbedQuery(ctx context.Context, text string) ([]float32, error)
+
 	GetModelList(ctx context.Context) ([]*domain.Model, error)
 	AddModel(ctx context.Context, model *domain.Model) (string, error)
 	UpdateModel(ctx context.Context, model *domain.Model) error
@@ -62,10 +77,12 @@ type RAGService interface {
 	DeleteModel(ctx context.Context, model *domain.Model) error
 }
 
-func NewRAGService(config *config.Config, logger *log.Logger) (RAGService, error) {
+func NewRAGService(config *config.Config, db *pg.DB, logger *log.Logger) (RAGService, error) {
 	switch config.RAG.Provider {
 	case "ct":
 		return NewCTRAG(config, logger)
+	case "pgvector":
+		return NewPGVectorRAG(config, db, logger)
 	default:
 		return nil, fmt.Errorf("unsupported vector provider: %s", config.RAG.Provider)
 	}
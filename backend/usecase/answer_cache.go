@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/repo/cache"
+	"github.com/chaitin/panda-wiki/repo/pg"
+	"github.com/chaitin/panda-wiki/store/rag"
+)
+
+// AnswerCacheUsecase serves answers of questions asked before from redis, so
+// the same question does not go through retrieval and the llm again
+type AnswerCacheUsecase struct {
+	rag              rag.RAGService
+	kbRepo           *pg.KnowledgeBaseRepository
+	conversationRepo *pg.ConversationRepository
+	answerCacheRepo  *cache.AnswerCacheRepo
+	logger           *log.Logger
+}
+
+func NewAnswerCacheUsecase(rag rag.RAGService, kbRepo *pg.KnowledgeBaseRepository, conversationRepo *pg.ConversationRepository, answerCacheRepo *cache.AnswerCacheRepo, logger *log.Logger) *AnswerCacheUsecase {
+	return &AnswerCacheUsecase{
+		rag:              rag,
+		kbRepo:           kbRepo,
+		conversationRepo: conversationRepo,
+		answerCacheRepo:  answerCacheRepo,
+		logger:           logger.WithModule("usecase.answer_cache"),
+	}
+}
+
+// AnswerCacheLookup is the result of a cache lookup, Hit is nil on a miss.
+// A miss keeps what is needed to store the answer generated instead.
+type AnswerCacheLookup struct {
+	Hit       *domain.CachedAnswer
+	kbID      string
+	scope     string
+	question  string
+	embedding []float32
+	ttl       time.Duration
+}
+
+// Lookup finds a cached answer for the question of a chat request. Only the
+// first question of a conversation without images is cached, as the answer
+// of a follow up depends on the history. It returns nil when the question is
+// not cacheable or the cache fails, the chat then goes on as usual.
+func (u *AnswerCacheUsecase) Lookup(ctx context.Context, req *domain.ChatRequest, settings domain.AppSettings, groupIDs []int) *AnswerCacheLookup {
+	if !settings.AnswerCacheSettings.Enabled || len(req.ImagePaths) > 0 || strings.TrimSpace(req.Message) == "" {
+		return nil
+	}
+	// answers depending on what the client sent besides the question
+	if len(req.History) > 0 || req.SystemPrompt != "" || len(req.Tools) > 0 || len(req.ToolMessages) > 0 {
+		return nil
+	}
+	msgs, err := u.conversationRepo.GetConversationMessagesByID(ctx, req.ConversationID)
+	if err != nil {
+		u.logger.Warn("get conversation messages failed", log.Error(err))
+		return nil
+	}
+	// the question itself is already saved
+	if len(msgs) > 1 {
+		return nil
+	}
+	scope, err := u.scope(ctx, req, settings.RetrievalSettings, groupIDs)
+	if err != nil {
+		u.logger.Warn("get answer cache scope failed", log.Error(err))
+		return nil
+	}
+	embedding, err := u.rag.EmbedQuery(ctx, req.Message)
+	if err != nil {
+		u.logger.Warn("embed question failed", log.Error(err))
+		return nil
+	}
+	lookup := &AnswerCacheLookup{
+		kbID:      req.KBID,
+		scope:     scope,
+		question:  req.Message,
+		embedding: embedding,
+		ttl:       settings.AnswerCacheSettings.GetTTL(),
+	}
+	answers, err := u.answerCacheRepo.GetAnswers(ctx, req.KBID, scope)
+	if err != nil {
+		u.logger.Warn("get cached answers failed", log.Error(err))
+		return lookup
+	}
+	lookup.Hit = domain.FindCachedAnswer(answers, embedding, settings.AnswerCacheSettings.GetSimilarityThreshold())
+	return lookup
+}
+
+// Store caches the answer generated after a miss
+func (u *AnswerCacheUsecase) Store(ctx context.Context, lookup *AnswerCacheLookup, answer string, nodes []*domain.RankedNodeChunks) {
+	if lookup == nil || lookup.Hit != nil || strings.TrimSpace(answer) == "" {
+		return
+	}
+	if err := u.answerCacheRepo.AddAnswer(ctx, lookup.kbID, lookup.scope, &domain.CachedAnswer{
+		Question:  lookup.question,
+		Embedding: lookup.embedding,
+		Answer:    answer,
+		Nodes:     nodes,
+		CreatedAt: time.Now(),
+	}, lookup.ttl); err != nil {
+		u.logger.Warn("cache answer failed", log.String("kb_id", lookup.kbID), log.Error(err))
+	}
+}
+
+// DeleteKBAnswers drops the cached answers of a kb when new content is released
+func (u *AnswerCacheUsecase) DeleteKBAnswers(ctx context.Context, kbID string) error {
+	return u.answerCacheRepo.DeleteKBAnswers(ctx, kbID)
+}
+
+// scope identifies what an answer depends on besides the question: the
+// released content of the kbs searched, what the user may read and the prompt
+func (u *AnswerCacheUsecase) scope(ctx context.Context, req *domain.ChatRequest, retrieval domain.RetrievalSettings, groupIDs []int) (string, error) {
+	var b strings.Builder
+	kbIDs := []string{req.KBID}
+	for _, federated := range retrieval.FederatedKBs {
+		kbIDs = append(kbIDs, federated.KBID)
+	}
+	for _, kbID := range kbIDs {
+		releaseID, err := u.latestReleaseID(ctx, kbID)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "kb:%s:%s\n", kbID, releaseID)
+	}
+	sortedGroupIDs := slices.Clone(groupIDs)
+	slices.Sort(sortedGroupIDs)
+	fmt.Fprintf(&b, "groups:%v\n", sortedGroupIDs)
+	// federated kbs are searched with the groups of the user in each kb
+	if len(retrieval.FederatedKBs) > 0 {
+		fmt.Fprintf(&b, "user:%d\n", req.Info.UserInfo.AuthUserID)
+	}
+	sortedTags := slices.Clone(req.Tags)
+	slices.Sort(sortedTags)
+	fmt.Fprintf(&b, "tags:%s\n", strings.Join(sortedTags, ","))
+	fmt.Fprintf(&b, "prompt:%s\n", req.Prompt)
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (u *AnswerCacheUsecase) latestReleaseID(ctx context.Context, kbID string) (string, error) {
+	release, err := u.kbRepo.GetLatestRelease(ctx, kbID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return release.ID, nil
+}
@@ -31,6 +31,7 @@ type AppUsecase struct {
 	kbRepo        *pg.KnowledgeBaseRepository
 	nodeUsecase   *NodeUsecase
 	chatUsecase   *ChatUsecase
+	modelUsecase  *ModelUsecase
 	logger        *log.Logger
 	config        *config.Config
 	cache         *cache.Cache
@@ -54,12 +55,14 @@ func NewAppUsecase(
 	logger *log.Logger,
 	config *config.Config,
 	chatUsecase *ChatUsecase,
+	modelUsecase *ModelUsecase,
 	cache *cache.Cache,
 ) *AppUsecase {
 	u := &AppUsecase{
 		repo:         repo,
 		nodeUsecase:  nodeUsecase,
 		chatUsecase:  chatUsecase,
+		modelUsecase: modelUsecase,
 		authRepo:     authRepo,
 		navRepo:      navRepo,
 		nodeRepo:     nodeRepo,
@@ -161,10 +164,38 @@ func (u *AppUsecase) ValidateUpdateApp(ctx context.Context, id string, req *doma
 		}
 	}
 
+	// only kbs the user can access may be added as federated kbs
+	for _, federated := range req.Settings.RetrievalSettings.FederatedKBs {
+		if slices.ContainsFunc(app.Settings.RetrievalSettings.FederatedKBs, func(old domain.FederatedKB) bool {
+			return old.KBID == federated.KBID
+		}) {
+			continue
+		}
+		perm, err := u.kbRepo.GetKBPermByUserId(ctx, federated.KBID)
+		if err != nil || perm == consts.UserKBPermissionNull {
+			return domain.ErrPermissionDenied
+		}
+	}
+
 	return nil
 }
 
 func (u *AppUsecase) UpdateApp(ctx context.Context, id string, appRequest *domain.UpdateAppReq) error {
+	if appRequest.Settings != nil {
+		if err := u.modelUsecase.ValidateChatModelSetting(ctx, appRequest.Settings.ChatModelSetting); err != nil {
+			return err
+		}
+		// links to a federated kb are built from its base url
+		for _, federated := range appRequest.Settings.RetrievalSettings.FederatedKBs {
+			kb, err := u.kbRepo.GetKnowledgeBaseByID(ctx, federated.KBID)
+			if err != nil {
+				return err
+			}
+			if kb.AccessSettings.BaseURL == "" {
+				return fmt.Errorf("%w: %s", domain.ErrFederatedKBNoBaseURL, kb.Name)
+			}
+		}
+	}
 	if err := u.handleBotAuths(ctx, id, appRequest.Settings); err != nil {
 		return err
 	}
@@ -222,6 +253,7 @@ func (u *AppUsecase) getQAFunc(kbID string, appType domain.AppType) bot.GetQAFun
 		var likeUrl = "%s/feedback?score=1&message_id=%s"
 		var dislikeUrl = "%s/feedback?score=-1&message_id=%s"
 		var messageId string
+		var suggestions []string
 		var kb *domain.KnowledgeBase
 
 		if appinfo.Settings.AIFeedbackSettings.AIFeedbackIsEnabled == nil || *appinfo.Settings.AIFeedbackSettings.AIFeedbackIsEnabled { // open
@@ -236,7 +268,8 @@ func (u *AppUsecase) getQAFunc(kbID string, appType domain.AppType) bot.GetQAFun
 		go func() {
 			defer close(contentCh)
 			for event := range eventCh {
-				if event.Type == "done" || event.Type == "error" {
+				// suggestions follow the done event
+				if event.Type == "error" {
 					break
 				}
 				if event.Type == "data" {
@@ -245,6 +278,12 @@ func (u *AppUsecase) getQAFunc(kbID string, appType domain.AppType) bot.GetQAFun
 				if event.Type == "message_id" {
 					messageId = event.Content
 				}
+				if event.Type == "suggestions" {
+					suggestions = event.Suggestions
+				}
+			}
+			if len(suggestions) > 0 {
+				contentCh <- u.chatUsecase.FormatBotSuggestions(ctx, kbID, suggestions)
 			}
 			// check again
 			// contact --> send
@@ -439,6 +478,15 @@ func (u *AppUsecase) GetLarkBotClient(appID string) (*lark.LarkClient, bool) {
 	return client, ok
 }
 
+// GetChatQuotaUsage returns the chat consumption of the app of the type
+func (u *AppUsecase) GetChatQuotaUsage(ctx context.Context, kbID string, appType domain.AppType) (*domain.ChatQuotaUsageResp, error) {
+	app, err := u.repo.GetOrCreateAppByKBIDAndType(ctx, kbID, appType)
+	if err != nil {
+		return nil, err
+	}
+	return u.chatUsecase.GetChatQuotaUsage(ctx, app)
+}
+
 func (u *AppUsecase) GetAppDetailByKBIDAndAppType(ctx context.Context, kbID string, appType domain.AppType) (*domain.AppDetailResp, error) {
 	app, err := u.repo.GetOrCreateAppByKBIDAndType(ctx, kbID, appType)
 	if err != nil {
@@ -558,8 +606,16 @@ func (u *AppUsecase) GetAppDetailByKBIDAndAppType(ctx context.Context, kbID stri
 
 		WecomAIBotSettings: app.Settings.WecomAIBotSettings,
 
-		MCPServerSettings: app.Settings.MCPServerSettings,
-		StatsSetting:      app.Settings.StatsSetting,
+		MCPServerSettings:    app.Settings.MCPServerSettings,
+		StatsSetting:         app.Settings.StatsSetting,
+		RetrievalSettings:    app.Settings.RetrievalSettings,
+		AnswerCacheSettings:  app.Settings.AnswerCacheSettings,
+		SuggestionSettings:   app.Settings.SuggestionSettings,
+		AgentSettings:        app.Settings.AgentSettings,
+		ChatModelSetting:     app.Settings.ChatModelSetting,
+		ChatQuotaSettings:    app.Settings.ChatQuotaSettings,
+		GroundednessSettings: app.Settings.GroundednessSettings,
+		HandoffSettings:      app.Settings.HandoffSettings,
 	}
 
 	if !domain.GetBaseEditionLimitation(ctx).AllowCustomCopyright {
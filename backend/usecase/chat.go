@@ -16,6 +16,7 @@ import (
 	"github.com/chaitin/panda-wiki/consts"
 	"github.com/chaitin/panda-wiki/domain"
 	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/repo/cache"
 	"github.com/chaitin/panda-wiki/repo/pg"
 	"github.com/chaitin/panda-wiki/utils"
 )
@@ -24,27 +25,37 @@ type ChatUsecase struct {
 	llmUsecase          *LLMUsecase
 	conversationUsecase *ConversationUsecase
 	modelUsecase        *ModelUsecase
+	answerCacheUsecase  *AnswerCacheUsecase
+	qaPairUsecase       *QAPairUsecase
+	handoffUsecase      *HandoffUsecase
 	appRepo             *pg.AppRepository
 	blockWordRepo       *pg.BlockWordRepo
 	kbRepo              *pg.KnowledgeBaseRepository
 	nodeRepo            *pg.NodeRepository
+	tagRepo             *pg.TagRepository
 	AuthRepo            *pg.AuthRepo
+	chatQuotaRepo       *cache.ChatQuotaRepo
 	logger              *log.Logger
 	modelkit            *modelkit.ModelKit
 }
 
-func NewChatUsecase(llmUsecase *LLMUsecase, kbRepo *pg.KnowledgeBaseRepository, conversationUsecase *ConversationUsecase, modelUsecase *ModelUsecase, appRepo *pg.AppRepository,
-	blockWordRepo *pg.BlockWordRepo, nodeRepo *pg.NodeRepository, authRepo *pg.AuthRepo, logger *log.Logger) (*ChatUsecase, error) {
+func NewChatUsecase(llmUsecase *LLMUsecase, kbRepo *pg.KnowledgeBaseRepository, conversationUsecase *ConversationUsecase, modelUsecase *ModelUsecase, answerCacheUsecase *AnswerCacheUsecase, qaPairUsecase *QAPairUsecase, handoffUsecase *HandoffUsecase, appRepo *pg.AppRepository,
+	blockWordRepo *pg.BlockWordRepo, nodeRepo *pg.NodeRepository, tagRepo *pg.TagRepository, authRepo *pg.AuthRepo, chatQuotaRepo *cache.ChatQuotaRepo, logger *log.Logger) (*ChatUsecase, error) {
 	modelkit := modelkit.NewModelKit(logger.Logger)
 	u := &ChatUsecase{
 		llmUsecase:          llmUsecase,
 		conversationUsecase: conversationUsecase,
 		modelUsecase:        modelUsecase,
+		answerCacheUsecase:  answerCacheUsecase,
+		qaPairUsecase:       qaPairUsecase,
+		handoffUsecase:      handoffUsecase,
 		appRepo:             appRepo,
 		blockWordRepo:       blockWordRepo,
 		kbRepo:              kbRepo,
 		nodeRepo:            nodeRepo,
+		tagRepo:             tagRepo,
 		AuthRepo:            authRepo,
+		chatQuotaRepo:       chatQuotaRepo,
 		logger:              logger.WithModule("usecase.chat"),
 		modelkit:            modelkit,
 	}
@@ -89,8 +100,14 @@ func (u *ChatUsecase) Chat(ctx context.Context, req *domain.ChatRequest) (<-chan
 		req.KBID = app.KBID
 		req.AppID = app.ID
 		req.AppType = app.Type
+		// counted before anonymous users get the auth of their app type
+		quotaUser := domain.ChatQuotaUser(req.Info.UserInfo, req.RemoteIP)
+		if reason := u.checkChatQuota(ctx, req.AppID, app.Settings.ChatQuotaSettings, quotaUser); reason != "" {
+			eventCh <- domain.SSEEvent{Type: "error", Content: reason, Error: domain.SSEErrorChatQuotaExceeded}
+			return
+		}
 		// 2. get model and validate model
-		model, err := u.modelUsecase.GetChatModel(ctx)
+		models, err := u.modelUsecase.GetChatModels(ctx, req.KBID, app.Settings.ChatModelSetting)
 		if err != nil {
 			if err == gorm.ErrRecordNotFound {
 				eventCh <- domain.SSEEvent{Type: "error", Content: "请前往管理后台，点击右上角的“系统设置”配置推理大模型。"}
@@ -99,7 +116,7 @@ func (u *ChatUsecase) Chat(ctx context.Context, req *domain.ChatRequest) (<-chan
 			}
 			return
 		}
-		req.ModelInfo = model
+		req.ModelInfo = models[0]
 		// 3. conversation management
 		if req.AppType == domain.AppTypeWechatServiceBot || req.AppType == domain.AppTypeWechatBot || req.AppType == domain.AppTypeWecomAIBot { // wechat service has its own id
 			nonce := uuid.New().String()
@@ -120,6 +137,23 @@ func (u *ChatUsecase) Chat(ctx context.Context, req *domain.ChatRequest) (<-chan
 				eventCh <- domain.SSEEvent{Type: "error", Content: "failed to create chat conversation"}
 				return
 			}
+		} else if req.AppType == domain.AppTypeOpenAIAPI && req.ConversationID != "" { // stable conversation of the api user, no nonce
+			eventCh <- domain.SSEEvent{Type: "conversation_id", Content: req.ConversationID}
+			err = u.conversationUsecase.CreateConversationIfNotExists(ctx, &domain.Conversation{
+				ID:        req.ConversationID,
+				Nonce:     uuid.New().String(),
+				AppID:     req.AppID,
+				KBID:      req.KBID,
+				Subject:   req.Message,
+				RemoteIP:  req.RemoteIP,
+				Info:      req.Info,
+				CreatedAt: time.Now(),
+			})
+			if err != nil {
+				u.logger.Error("failed to create chat conversation", log.Error(err))
+				eventCh <- domain.SSEEvent{Type: "error", Content: "failed to create chat conversation"}
+				return
+			}
 		} else if req.ConversationID == "" {
 			id, err := uuid.NewV7()
 			if err != nil {
@@ -172,7 +206,7 @@ func (u *ChatUsecase) Chat(ctx context.Context, req *domain.ChatRequest) (<-chan
 			Content:        req.Message,
 			ImagePaths:     req.ImagePaths,
 			RemoteIP:       req.RemoteIP,
-		}); err != nil {
+		}, nil); err != nil {
 			u.logger.Error("failed to save user question to conversation message", log.Error(err))
 			eventCh <- domain.SSEEvent{Type: "error", Content: "failed to save user question to conversation message"}
 			return
@@ -201,7 +235,7 @@ func (u *ChatUsecase) Chat(ctx context.Context, req *domain.ChatRequest) (<-chan
 					Model:          string(req.ModelInfo.Model),
 					RemoteIP:       req.RemoteIP,
 					ParentID:       userMessageId,
-				}); err != nil {
+				}, nil); err != nil {
 					u.logger.Error("failed to save assistant answer to conversation message", log.Error(err))
 					eventCh <- domain.SSEEvent{Type: "error", Content: "failed to save assistant answer to conversation message"}
 					return
@@ -210,6 +244,19 @@ func (u *ChatUsecase) Chat(ctx context.Context, req *domain.ChatRequest) (<-chan
 			}
 		}
 
+		// curated answers take precedence over generated ones
+		qaPair, err := u.qaPairUsecase.Match(ctx, req.KBID, req.Message)
+		if err != nil {
+			u.logger.Error("failed to match qa pair", log.Error(err))
+		}
+		if qaPair != nil && qaPair.AnswerMode == domain.QAPairAnswerVerbatim {
+			if u.sendStoredAnswer(ctx, req, qaPair.Answer, nil, messageId, userMessageId, eventCh) {
+				eventCh <- domain.SSEEvent{Type: "done"}
+				u.sendSuggestions(ctx, app.Settings.SuggestionSettings, req, quotaUser, qaPair.Answer, nil, eventCh)
+			}
+			return
+		}
+
 		if req.Info.UserInfo.AuthUserID == 0 {
 			auth, _ := u.AuthRepo.GetAuthBySourceType(ctx, req.AppType.ToSourceType())
 			if auth != nil {
@@ -224,35 +271,72 @@ func (u *ChatUsecase) Chat(ctx context.Context, req *domain.ChatRequest) (<-chan
 			return
 		}
 
-		messages, rankedNodes, err := u.llmUsecase.BuildConversationMessageWithRAG(ctx, req.ConversationID, req.KBID, groupIds, req.Prompt)
+		var cacheLookup *AnswerCacheLookup
+		if qaPair == nil {
+			cacheLookup = u.answerCacheUsecase.Lookup(ctx, req, app.Settings, groupIds)
+		}
+		if cacheLookup != nil && cacheLookup.Hit != nil {
+			if u.sendStoredAnswer(ctx, req, cacheLookup.Hit.Answer, cacheLookup.Hit.Nodes, messageId, userMessageId, eventCh) {
+				eventCh <- domain.SSEEvent{Type: "done"}
+				u.sendSuggestions(ctx, app.Settings.SuggestionSettings, req, quotaUser, cacheLookup.Hit.Answer, cacheLookup.Hit.Nodes, eventCh)
+			}
+			return
+		}
+
+		// tools of the client are answered by the client, not by the agent
+		agentMode := app.Settings.AgentSettings.Enabled && len(req.Tools) == 0 && len(req.ToolMessages) == 0
+		var messages []*schema.Message
+		var rankedNodes []*domain.RankedNodeChunks
+		if agentMode {
+			messages, err = u.llmUsecase.BuildAgentMessages(ctx, req.ConversationID, req.KBID, req.History, req.Message, req.Prompt, models)
+		} else if len(req.History) > 0 {
+			// the history supplied by the client is used for query rewrite and as chat history
+			historyMessages := append(slices.Clone(req.History), schema.UserMessage(req.Message))
+			messages, rankedNodes, err = u.llmUsecase.buildRAGMessages(ctx, ragMessagesRequest{
+				KBID:            req.KBID,
+				HistoryMessages: historyMessages,
+				GroupIDs:        groupIds,
+				Tags:            req.Tags,
+				Retrieval:       app.Settings.RetrievalSettings,
+				SystemPrompt:    req.Prompt,
+				AuthUserID:      req.Info.UserInfo.AuthUserID,
+				ContextLimit:    domain.ChatContextLimit(models),
+			})
+		} else {
+			messages, rankedNodes, err = u.llmUsecase.BuildConversationMessageWithRAG(ctx, req.ConversationID, req.KBID, groupIds, req.Tags, app.Settings.RetrievalSettings, req.Prompt, req.Info.UserInfo.AuthUserID, models)
+		}
 		if err != nil {
 			u.logger.Error("build messages failed", log.Error(err))
 			eventCh <- domain.SSEEvent{Type: "error", Content: err.Error()}
 			return
 		}
 
-		u.logger.Debug("message:", log.Any("schema", messages))
-		for _, node := range rankedNodes {
-			chunkResult := domain.NodeContentChunkSSE{
-				NodeID:        node.NodeID,
-				Name:          node.NodeName,
-				Summary:       node.NodeSummary,
-				NodePathNames: node.NodePathNames,
+		messages = append(messages, req.ToolMessages...)
+		if req.SystemPrompt != "" && len(messages) > 0 {
+			messages[0].Content += "\n\n" + req.SystemPrompt
+		}
+		if qaPair != nil && len(messages) > 0 {
+			messages[0].Content += "\n\n" + qaPair.ContextPrompt()
+		}
+		// a question the documents cannot answer goes to the staff instead of the llm
+		handoffSettings := app.Settings.HandoffSettings
+		handoffable := handoffSettings.Enabled && qaPair == nil && len(req.Tools) == 0 && len(req.ToolMessages) == 0
+		if handoffable && !agentMode {
+			if reason := domain.RetrievalUnanswerable(rankedNodes, handoffSettings.MinRerankScore); reason != "" {
+				if u.sendStoredAnswer(ctx, req, handoffSettings.GetFallbackMessage(), nil, messageId, userMessageId, eventCh) {
+					u.openHandoff(ctx, req, handoffSettings, reason, messageId, eventCh)
+					eventCh <- domain.SSEEvent{Type: "done"}
+				}
+				return
 			}
-			eventCh <- domain.SSEEvent{Type: "chunk_result", ChunkResult: &chunkResult}
 		}
+		u.logger.Debug("message:", log.Any("schema", messages))
+		sendChunkResults(rankedNodes, eventCh)
 		// 5. LLM inference (streaming callback), message storage, token statistics
 		answer := ""
 		usage := schema.TokenUsage{}
 
-		modelkitModel, err := req.ModelInfo.ToModelkitModel()
-		if err != nil {
-			u.logger.Error("failed to convert model to modelkit model", log.Error(err))
-			eventCh <- domain.SSEEvent{Type: "error", Content: "failed to convert model to modelkit model"}
-			return
-		}
-		chatModel, err := u.modelkit.GetChatModel(ctx, modelkitModel)
-
+		chatModel, err := u.newFallbackChatModel(ctx, models)
 		if err != nil {
 			u.logger.Error("failed to get chat model", log.Error(err))
 			eventCh <- domain.SSEEvent{Type: "error", Content: "failed to get chat model"}
@@ -261,13 +345,40 @@ func (u *ChatUsecase) Chat(ctx context.Context, req *domain.ChatRequest) (<-chan
 		// get words
 		onChunkAC, flushBuffer := u.CreateAcOnChunk(ctx, req.KBID, &answer, eventCh, blockWords)
 
-		chatErr := u.llmUsecase.ChatWithAgent(ctx, chatModel, messages, &usage, onChunkAC)
+		var toolCalls []schema.ToolCall
+		var agentSteps domain.AgentSteps
+		var chatErr error
+		if agentMode {
+			agent, err := u.newKBAgent(ctx, req, app.Settings.RetrievalSettings, groupIds, eventCh)
+			if err != nil {
+				u.logger.Error("failed to create kb agent", log.Error(err))
+				eventCh <- domain.SSEEvent{Type: "error", Content: "failed to create kb agent"}
+				return
+			}
+			agentSteps, chatErr = agent.Run(ctx, chatModel, messages, app.Settings.AgentSettings.GetMaxSteps(), &usage, onChunkAC)
+			rankedNodes = agent.rankedNodes
+		} else {
+			toolCalls, chatErr = u.llmUsecase.StreamChat(ctx, chatModel, messages, &usage, onChunkAC, chatModelOptions(req)...)
+		}
 
 		// 处理缓冲区中剩余的内容
 		if flushBuffer != nil {
 			flushBuffer(ctx, "data")
 		}
 
+		// the model declaring it has no answer is handed off as well
+		handoffReason := domain.HandoffReason("")
+		if handoffable && chatErr == nil && len(toolCalls) == 0 && domain.IsNoAnswer(u.llmUsecase.trimThinking(answer)) {
+			handoffReason = domain.HandoffReasonModelDeclined
+			fallback := "\n\n" + handoffSettings.GetFallbackMessage()
+			answer += fallback
+			eventCh <- domain.SSEEvent{Type: "data", Content: fallback}
+		}
+
+		// usage goes to the model that answered
+		req.ModelInfo = chatModel.Model()
+		u.addChatQuotaTokens(ctx, req.AppID, quotaUser, &usage)
+
 		// save assistant answer to conversation message
 
 		if err := u.conversationUsecase.CreateChatConversationMessage(ctx, req.KBID, &domain.ConversationMessage{
@@ -284,7 +395,8 @@ func (u *ChatUsecase) Chat(ctx context.Context, req *domain.ChatRequest) (<-chan
 			TotalTokens:      usage.TotalTokens,
 			RemoteIP:         req.RemoteIP,
 			ParentID:         userMessageId,
-		}); err != nil {
+			AgentSteps:       agentSteps,
+		}, rankedNodes); err != nil {
 			u.logger.Error("failed to save assistant answer to conversation message", log.Error(err))
 			eventCh <- domain.SSEEvent{Type: "error", Content: "failed to save assistant answer to conversation message"}
 			return
@@ -301,11 +413,93 @@ func (u *ChatUsecase) Chat(ctx context.Context, req *domain.ChatRequest) (<-chan
 			eventCh <- domain.SSEEvent{Type: "error", Content: "对话失败，请稍后再试"}
 			return
 		}
+		if len(toolCalls) > 0 {
+			eventCh <- domain.SSEEvent{Type: "tool_calls", ToolCalls: toolCalls}
+		}
+		if handoffReason != "" {
+			u.openHandoff(ctx, req, handoffSettings, handoffReason, messageId, eventCh)
+			eventCh <- domain.SSEEvent{Type: "done"}
+			return
+		}
+		u.answerCacheUsecase.Store(ctx, cacheLookup, answer, rankedNodes)
 		eventCh <- domain.SSEEvent{Type: "done"}
+		if len(toolCalls) == 0 {
+			u.checkGroundedness(ctx, app.Settings.GroundednessSettings, chatModel, req, quotaUser, messageId, answer, rankedNodes, eventCh)
+		}
+		u.sendSuggestions(ctx, app.Settings.SuggestionSettings, req, quotaUser, answer, rankedNodes, eventCh)
 	}()
 	return eventCh, nil
 }
 
+// openHandoff records the question for the staff and sends the handoff
+// event with its id, before the done event as clients may stop reading there
+func (u *ChatUsecase) openHandoff(ctx context.Context, req *domain.ChatRequest, settings domain.HandoffSettings, reason domain.HandoffReason, messageId string, eventCh chan<- domain.SSEEvent) {
+	handoff := &domain.Handoff{
+		KBID:           req.KBID,
+		AppID:          req.AppID,
+		AppType:        req.AppType,
+		ConversationID: req.ConversationID,
+		MessageID:      messageId,
+		Question:       req.Message,
+		Reason:         reason,
+		RemoteIP:       req.RemoteIP,
+		Info:           req.Info,
+	}
+	if err := u.handoffUsecase.Open(ctx, settings, handoff); err != nil {
+		u.logger.Error("failed to open handoff", log.Error(err))
+		return
+	}
+	eventCh <- domain.SSEEvent{Type: "handoff", Content: handoff.ID}
+}
+
+func sendChunkResults(rankedNodes []*domain.RankedNodeChunks, eventCh chan<- domain.SSEEvent) {
+	for _, node := range rankedNodes {
+		chunkResult := domain.NodeContentChunkSSE{
+			NodeID:        node.NodeID,
+			Name:          node.SourceName(),
+			Summary:       node.NodeSummary,
+			NodePathNames: node.NodePathNames,
+			RerankScore:   node.RerankScore,
+			Chunks:        node.Citations(),
+			KBID:          node.KBID,
+			URL:           node.ExternalURL(),
+		}
+		eventCh <- domain.SSEEvent{Type: "chunk_result", ChunkResult: &chunkResult}
+	}
+}
+
+// storedAnswerChunkRunes is the size of the data events a stored answer is
+// streamed in, so clients render it the same as a generated one
+const storedAnswerChunkRunes = 16
+
+// sendStoredAnswer answers with a curated or cached answer instead of the
+// llm, the message is saved with the documents of the answer and without
+// token usage. It reports whether the answer was saved, the caller sends the
+// done event.
+func (u *ChatUsecase) sendStoredAnswer(ctx context.Context, req *domain.ChatRequest, answer string, nodes []*domain.RankedNodeChunks, messageId, userMessageId string, eventCh chan<- domain.SSEEvent) bool {
+	sendChunkResults(nodes, eventCh)
+	for chunk := range slices.Chunk([]rune(answer), storedAnswerChunkRunes) {
+		eventCh <- domain.SSEEvent{Type: "data", Content: string(chunk)}
+	}
+	if err := u.conversationUsecase.CreateChatConversationMessage(ctx, req.KBID, &domain.ConversationMessage{
+		ID:             messageId,
+		ConversationID: req.ConversationID,
+		KBID:           req.KBID,
+		AppID:          req.AppID,
+		Role:           schema.Assistant,
+		Content:        answer,
+		Provider:       req.ModelInfo.Provider,
+		Model:          string(req.ModelInfo.Model),
+		RemoteIP:       req.RemoteIP,
+		ParentID:       userMessageId,
+	}, nodes); err != nil {
+		u.logger.Error("failed to save assistant answer to conversation message", log.Error(err))
+		eventCh <- domain.SSEEvent{Type: "error", Content: "failed to save assistant answer to conversation message"}
+		return false
+	}
+	return true
+}
+
 func (u *ChatUsecase) ChatRagOnly(ctx context.Context, req *domain.ChatRagOnlyRequest) (<-chan domain.SSEEvent, error) {
 	eventCh := make(chan domain.SSEEvent, 100)
 	go func() {
@@ -348,14 +542,34 @@ func (u *ChatUsecase) ChatRagOnly(ctx context.Context, req *domain.ChatRagOnlyRe
 			eventCh <- domain.SSEEvent{Type: "error", Content: "failed to get kb"}
 			return
 		}
-		_, rankedNodes, err := u.llmUsecase.GetRankNodes(ctx, GetRankNodesRequest{
+		app, err := u.appRepo.GetOrCreateAppByKBIDAndType(ctx, req.KBID, req.AppType)
+		if err != nil {
+			u.logger.Error("failed to get app", log.Error(err))
+			eventCh <- domain.SSEEvent{Type: "error", Content: "app not found"}
+			return
+		}
+		retrieval := app.Settings.RetrievalSettings
+		// rag only returns a list of documents, one chunk per doc unless configured
+		maxChunksPerDoc := retrieval.MaxChunksPerDoc
+		if maxChunksPerDoc == 0 {
+			maxChunksPerDoc = 1
+		}
+		similarityThreshold := 0.0
+		if retrieval.SimilarityThreshold != nil {
+			similarityThreshold = *retrieval.SimilarityThreshold
+		}
+		_, rankedNodes, err := u.llmUsecase.GetFederatedRankNodes(ctx, GetRankNodesRequest{
+			KBID:                req.KBID,
 			DatasetID:           kb.DatasetID,
 			Question:            req.Message,
 			GroupIDs:            groupIds,
+			Tags:                req.Tags,
+			TopK:                retrieval.GetTopK(),
 			HistoryMessages:     nil,
-			SimilarityThreshold: 0,
-			MaxChunksPerDoc:     1,
-		})
+			SimilarityThreshold: similarityThreshold,
+			MaxChunksPerDoc:     maxChunksPerDoc,
+			MaxContextTokens:    retrieval.MaxContextTokens,
+		}, retrieval.FederatedKBs, req.UserInfo.AuthUserID)
 		if err != nil {
 			u.logger.Error("failed to get rank nodes", log.Error(err))
 			eventCh <- domain.SSEEvent{Type: "error", Content: "failed to get rank nodes"}
@@ -442,13 +656,23 @@ func (u *ChatUsecase) Search(ctx context.Context, req *domain.ChatSearchReq) (*d
 	if err != nil {
 		return nil, err
 	}
-	_, rankedNodes, err := u.llmUsecase.GetRankNodes(ctx, GetRankNodesRequest{
+	app, err := u.appRepo.GetOrCreateAppByKBIDAndType(ctx, req.KBID, req.AppType)
+	if err != nil {
+		return nil, err
+	}
+	retrieval := app.Settings.RetrievalSettings
+	_, rankedNodes, err := u.llmUsecase.GetFederatedRankNodes(ctx, GetRankNodesRequest{
+		KBID:                req.KBID,
 		DatasetID:           kb.DatasetID,
 		Question:            req.Message,
 		GroupIDs:            groupIds,
-		SimilarityThreshold: 0.2,
+		Tags:                req.Tags,
+		TopK:                retrieval.GetTopK(),
+		SimilarityThreshold: retrieval.GetSimilarityThreshold(),
 		HistoryMessages:     nil,
-	})
+		MaxChunksPerDoc:     retrieval.MaxChunksPerDoc,
+		MaxContextTokens:    retrieval.MaxContextTokens,
+	}, retrieval.FederatedKBs, req.AuthUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -464,17 +688,36 @@ func (u *ChatUsecase) Search(ctx context.Context, req *domain.ChatSearchReq) (*d
 		return nil, err
 	}
 
-	// Get user's visitable node IDs (for partial permission check)
-	userGroupIds := lo.Map(groupIds, func(id int, _ int) uint {
-		return uint(id)
-	})
-	visitableNodeGroups, err := u.nodeRepo.GetNodeGroupsByGroupIdsPerm(ctx, userGroupIds, consts.NodePermNameVisitable)
-	if err != nil {
-		return nil, err
+	// Get user's visitable node IDs (for partial permission check), federated
+	// nodes are checked against the groups the user has in their own kb
+	visitableNodeIds := make(map[string][]string)
+	getVisitableNodeIds := func(kbID string) ([]string, error) {
+		if ids, ok := visitableNodeIds[kbID]; ok {
+			return ids, nil
+		}
+		kbGroupIds := groupIds
+		if kbID != req.KBID {
+			kbGroupIds = []int{}
+			if req.AuthUserID != 0 {
+				var err error
+				if kbGroupIds, err = u.AuthRepo.GetAuthGroupIdsInKB(ctx, req.AuthUserID, kbID); err != nil {
+					return nil, err
+				}
+			}
+		}
+		userGroupIds := lo.Map(kbGroupIds, func(id int, _ int) uint {
+			return uint(id)
+		})
+		visitableNodeGroups, err := u.nodeRepo.GetNodeGroupsByGroupIdsPerm(ctx, userGroupIds, consts.NodePermNameVisitable)
+		if err != nil {
+			return nil, err
+		}
+		ids := lo.Map(visitableNodeGroups, func(v domain.NodeAuthGroup, _ int) string {
+			return v.NodeID
+		})
+		visitableNodeIds[kbID] = ids
+		return ids, nil
 	}
-	visitableNodeIds := lo.Map(visitableNodeGroups, func(v domain.NodeAuthGroup, _ int) string {
-		return v.NodeID
-	})
 
 	resp := domain.ChatSearchResp{}
 	for _, node := range rankedNodes {
@@ -485,8 +728,16 @@ func (u *ChatUsecase) Search(ctx context.Context, req *domain.ChatSearchReq) (*d
 				// Skip nodes with closed visitable permission
 				continue
 			case consts.NodeAccessPermPartial:
+				kbID := node.KBID
+				if kbID == "" {
+					kbID = req.KBID
+				}
+				ids, err := getVisitableNodeIds(kbID)
+				if err != nil {
+					return nil, err
+				}
 				// Skip if user doesn't have visitable permission for this node
-				if !slices.Contains(visitableNodeIds, node.NodeID) {
+				if !slices.Contains(ids, node.NodeID) {
 					continue
 				}
 			}
@@ -494,10 +745,13 @@ func (u *ChatUsecase) Search(ctx context.Context, req *domain.ChatSearchReq) (*d
 
 		chunkResult := domain.NodeContentChunkSSE{
 			NodeID:        node.NodeID,
-			Name:          node.NodeName,
+			Name:          node.SourceName(),
 			Summary:       node.NodeSummary,
 			Emoji:         node.NodeEmoji,
 			NodePathNames: node.NodePathNames,
+			RerankScore:   node.RerankScore,
+			KBID:          node.KBID,
+			URL:           node.ExternalURL(),
 		}
 		resp.NodeResult = append(resp.NodeResult, chunkResult)
 	}
@@ -0,0 +1,405 @@
+package usecase
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	toolutils "github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+	"github.com/samber/lo"
+
+	"github.com/chaitin/panda-wiki/consts"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+)
+
+// maxAgentNodeContentRunes bounds the content of a node returned by get_node
+const maxAgentNodeContentRunes = 8000
+
+const agentNodeNotFound = "文档不存在或无权访问"
+
+// kbAgent answers a question with the model calling the kb tools. Tools only
+// return nodes the user may read: search_kb and get_node the nodes open to
+// answers, list_children and get_node_by_path the nodes visible in the
+// navigation. With tags in the request, documents without one of them are
+// hidden and so are folders leading to none of the rest.
+type kbAgent struct {
+	u         *ChatUsecase
+	req       *domain.ChatRequest
+	kb        *domain.KnowledgeBase
+	retrieval domain.RetrievalSettings
+	groupIDs  []int
+	eventCh   chan<- domain.SSEEvent
+
+	// documents found by the tools, numbered for citation across calls
+	rankedNodes []*domain.RankedNodeChunks
+	citationID  int
+
+	nodes         []*domain.ShareNodeListItemResp
+	groupNodeIDs  map[consts.NodePermName]map[string]struct{}
+	taggedNodeIDs map[string]struct{}
+}
+
+type searchKBInput struct {
+	Query string `json:"query" jsonschema:"required,description=the question or keywords to search for"`
+}
+
+type nodeIDInput struct {
+	NodeID string `json:"node_id" jsonschema:"required,description=the id of the node"`
+}
+
+type listChildrenInput struct {
+	NodeID string `json:"node_id,omitempty" jsonschema:"description=the id of the folder, empty for the root of the knowledge base"`
+}
+
+type nodePathInput struct {
+	Path string `json:"path" jsonschema:"required,description=the names of the folders and the document separated by /"`
+}
+
+func (u *ChatUsecase) newKBAgent(ctx context.Context, req *domain.ChatRequest, retrieval domain.RetrievalSettings, groupIDs []int, eventCh chan<- domain.SSEEvent) (*kbAgent, error) {
+	kb, err := u.kbRepo.GetKnowledgeBaseByID(ctx, req.KBID)
+	if err != nil {
+		return nil, fmt.Errorf("get kb failed: %w", err)
+	}
+	return &kbAgent{
+		u:            u,
+		req:          req,
+		kb:           kb,
+		retrieval:    retrieval,
+		groupIDs:     groupIDs,
+		eventCh:      eventCh,
+		rankedNodes:  make([]*domain.RankedNodeChunks, 0),
+		groupNodeIDs: make(map[consts.NodePermName]map[string]struct{}),
+	}, nil
+}
+
+func (a *kbAgent) tools() ([]tool.InvokableTool, error) {
+	searchKB, err := toolutils.InferTool("search_kb", "Search the knowledge base, returns the most relevant document chunks.", a.searchKB)
+	if err != nil {
+		return nil, err
+	}
+	getNode, err := toolutils.InferTool("get_node", "Read the full content of a document by its node id.", a.getNode)
+	if err != nil {
+		return nil, err
+	}
+	listChildren, err := toolutils.InferTool("list_children", "List the documents and folders in a folder of the knowledge base.", a.listChildren)
+	if err != nil {
+		return nil, err
+	}
+	getNodeByPath, err := toolutils.InferTool("get_node_by_path", "Read a document by its path, e.g. \"Manual/Install/Linux\". A folder lists its children.", a.getNodeByPath)
+	if err != nil {
+		return nil, err
+	}
+	return []tool.InvokableTool{searchKB, getNode, listChildren, getNodeByPath}, nil
+}
+
+// Run streams the answer to onChunk, calling the tools the model asks for
+// until it answers or maxSteps rounds of tool calls are done, then it has to
+// answer. The usage of all rounds is added up.
+func (a *kbAgent) Run(
+	ctx context.Context,
+	chatModel model.BaseChatModel,
+	messages []*schema.Message,
+	maxSteps int,
+	usage *schema.TokenUsage,
+	onChunk func(ctx context.Context, dataType, chunk string) error,
+) (domain.AgentSteps, error) {
+	tools, err := a.tools()
+	if err != nil {
+		return nil, fmt.Errorf("create agent tools failed: %w", err)
+	}
+	toolInfos := make([]*schema.ToolInfo, 0, len(tools))
+	toolMap := make(map[string]tool.InvokableTool, len(tools))
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get agent tool info failed: %w", err)
+		}
+		toolInfos = append(toolInfos, info)
+		toolMap[info.Name] = t
+	}
+
+	steps := make(domain.AgentSteps, 0)
+	for round := 0; ; round++ {
+		opts := []model.Option{model.WithTools(toolInfos)}
+		if round >= maxSteps {
+			opts = append(opts, model.WithToolChoice(schema.ToolChoiceForbidden))
+		}
+		roundUsage := schema.TokenUsage{}
+		toolCalls, err := a.u.llmUsecase.StreamChat(ctx, chatModel, messages, &roundUsage, onChunk, opts...)
+		usage.PromptTokens += roundUsage.PromptTokens
+		usage.CompletionTokens += roundUsage.CompletionTokens
+		usage.TotalTokens += roundUsage.TotalTokens
+		if err != nil {
+			return steps, err
+		}
+		if len(toolCalls) == 0 || round >= maxSteps {
+			return steps, nil
+		}
+
+		messages = append(messages, schema.AssistantMessage("", toolCalls))
+		for _, call := range toolCalls {
+			step := &domain.AgentStep{
+				ID:        call.ID,
+				Tool:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			}
+			a.eventCh <- domain.SSEEvent{Type: "agent_tool_call", AgentStep: &domain.AgentStep{ID: step.ID, Tool: step.Tool, Arguments: step.Arguments}}
+			result, err := a.runTool(ctx, toolMap, call)
+			if err != nil {
+				a.u.logger.Warn("agent tool failed", log.String("tool", call.Function.Name), log.Error(err))
+				step.Error = err.Error()
+				result = "工具调用失败：" + err.Error()
+			} else {
+				step.Result = domain.TruncateAgentStepResult(result)
+			}
+			a.eventCh <- domain.SSEEvent{Type: "agent_tool_result", AgentStep: step}
+			steps = append(steps, step)
+			messages = append(messages, schema.ToolMessage(result, call.ID, schema.WithToolName(call.Function.Name)))
+		}
+	}
+}
+
+func (a *kbAgent) runTool(ctx context.Context, toolMap map[string]tool.InvokableTool, call schema.ToolCall) (string, error) {
+	t, ok := toolMap[call.Function.Name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %s", call.Function.Name)
+	}
+	return t.InvokableRun(ctx, call.Function.Arguments)
+}
+
+func (a *kbAgent) searchKB(ctx context.Context, input *searchKBInput) (string, error) {
+	query := strings.TrimSpace(input.Query)
+	if query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+	_, rankedNodes, err := a.u.llmUsecase.GetFederatedRankNodes(ctx, GetRankNodesRequest{
+		KBID:                a.kb.ID,
+		DatasetID:           a.kb.DatasetID,
+		Question:            query,
+		GroupIDs:            a.groupIDs,
+		Tags:                a.req.Tags,
+		TopK:                a.retrieval.GetTopK(),
+		SimilarityThreshold: a.retrieval.GetSimilarityThreshold(),
+		MaxChunksPerDoc:     a.retrieval.MaxChunksPerDoc,
+		MaxContextTokens:    a.retrieval.MaxContextTokens,
+	}, a.retrieval.FederatedKBs, a.req.Info.UserInfo.AuthUserID)
+	if err != nil {
+		return "", fmt.Errorf("get rank nodes failed: %w", err)
+	}
+	if len(rankedNodes) == 0 {
+		return "未找到相关文档", nil
+	}
+	a.addDocuments(rankedNodes)
+	return domain.FormatNodeChunks(rankedNodes, a.kb.AccessSettings.BaseURL), nil
+}
+
+func (a *kbAgent) getNode(ctx context.Context, input *nodeIDInput) (string, error) {
+	node, err := a.u.nodeRepo.GetNodeReleaseDetailByKBIDAndID(ctx, a.kb.ID, input.NodeID)
+	if err != nil {
+		return agentNodeNotFound, nil
+	}
+	if node.Type == domain.NodeTypeFolder {
+		return a.listChildren(ctx, &listChildrenInput{NodeID: node.ID})
+	}
+	answerable, err := a.nodeAccessible(ctx, consts.NodePermNameAnswerable, node.ID, node.Permissions.Answerable)
+	if err != nil {
+		return "", err
+	}
+	if !answerable {
+		return agentNodeNotFound, nil
+	}
+	tagged, err := a.nodeTagged(ctx, node.ID)
+	if err != nil {
+		return "", err
+	}
+	if !tagged {
+		return agentNodeNotFound, nil
+	}
+	content := []rune(node.Content)
+	if len(content) > maxAgentNodeContentRunes {
+		content = append(content[:maxAgentNodeContentRunes], []rune("\n...")...)
+	}
+	rankedNode := &domain.RankedNodeChunks{
+		KBID:        a.kb.ID,
+		NodeID:      node.ID,
+		NodeName:    node.Name,
+		NodeSummary: node.Meta.Summary,
+		NodeEmoji:   node.Meta.Emoji,
+		Chunks:      []*domain.NodeContentChunk{{KBID: a.kb.ID, Name: node.Name, Content: string(content)}},
+	}
+	a.addDocuments([]*domain.RankedNodeChunks{rankedNode})
+	return domain.FormatNodeChunks([]*domain.RankedNodeChunks{rankedNode}, a.kb.AccessSettings.BaseURL), nil
+}
+
+func (a *kbAgent) listChildren(ctx context.Context, input *listChildrenInput) (string, error) {
+	nodes, err := a.visibleNodes(ctx)
+	if err != nil {
+		return "", err
+	}
+	if input.NodeID != "" && !lo.ContainsBy(nodes, func(node *domain.ShareNodeListItemResp) bool { return node.ID == input.NodeID }) {
+		return agentNodeNotFound, nil
+	}
+	children := lo.Filter(nodes, func(node *domain.ShareNodeListItemResp, _ int) bool { return node.ParentID == input.NodeID })
+	if len(children) == 0 {
+		return "目录为空", nil
+	}
+	slices.SortStableFunc(children, func(x, y *domain.ShareNodeListItemResp) int {
+		return cmp.Compare(x.Position, y.Position)
+	})
+	var b strings.Builder
+	for _, child := range children {
+		kind := lo.Ternary(child.Type == domain.NodeTypeFolder, "目录", "文档")
+		fmt.Fprintf(&b, "- [%s] %s (node_id: %s)\n", kind, child.Name, child.ID)
+	}
+	return b.String(), nil
+}
+
+func (a *kbAgent) getNodeByPath(ctx context.Context, input *nodePathInput) (string, error) {
+	nodes, err := a.visibleNodes(ctx)
+	if err != nil {
+		return "", err
+	}
+	var current *domain.ShareNodeListItemResp
+	for _, name := range strings.Split(input.Path, "/") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		parentID := ""
+		if current != nil {
+			parentID = current.ID
+		}
+		child, ok := lo.Find(nodes, func(node *domain.ShareNodeListItemResp) bool {
+			return node.ParentID == parentID && strings.TrimSpace(node.Name) == name
+		})
+		if !ok {
+			return agentNodeNotFound, nil
+		}
+		current = child
+	}
+	if current == nil {
+		return a.listChildren(ctx, &listChildrenInput{})
+	}
+	if current.Type == domain.NodeTypeFolder {
+		return a.listChildren(ctx, &listChildrenInput{NodeID: current.ID})
+	}
+	return a.getNode(ctx, &nodeIDInput{NodeID: current.ID})
+}
+
+// addDocuments numbers the chunks found after the ones found before, sends
+// them to the client and keeps them for the citations of the answer
+func (a *kbAgent) addDocuments(rankedNodes []*domain.RankedNodeChunks) {
+	for _, node := range rankedNodes {
+		for _, chunk := range node.Chunks {
+			a.citationID++
+			chunk.CitationID = a.citationID
+		}
+	}
+	sendChunkResults(rankedNodes, a.eventCh)
+	a.rankedNodes = append(a.rankedNodes, rankedNodes...)
+}
+
+// visibleNodes returns the released nodes of the kb visible to the user
+func (a *kbAgent) visibleNodes(ctx context.Context) ([]*domain.ShareNodeListItemResp, error) {
+	if a.nodes != nil {
+		return a.nodes, nil
+	}
+	nodes, err := a.u.nodeRepo.GetNodeReleaseListByKBID(ctx, a.kb.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get released nodes failed: %w", err)
+	}
+	a.nodes = make([]*domain.ShareNodeListItemResp, 0, len(nodes))
+	for _, node := range nodes {
+		visible, err := a.nodeAccessible(ctx, consts.NodePermNameVisible, node.ID, node.Permissions.Visible)
+		if err != nil {
+			return nil, err
+		}
+		if visible {
+			a.nodes = append(a.nodes, node)
+		}
+	}
+	if len(a.req.Tags) > 0 {
+		taggedNodeIDs, err := a.getTaggedNodeIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		a.nodes = filterTaggedNodes(a.nodes, taggedNodeIDs)
+	}
+	return a.nodes, nil
+}
+
+// filterTaggedNodes keeps the tagged documents and the folders leading to them
+func filterTaggedNodes(nodes []*domain.ShareNodeListItemResp, taggedNodeIDs map[string]struct{}) []*domain.ShareNodeListItemResp {
+	parentIDs := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		parentIDs[node.ID] = node.ParentID
+	}
+	kept := make(map[string]struct{})
+	for _, node := range nodes {
+		if _, ok := taggedNodeIDs[node.ID]; !ok || node.Type == domain.NodeTypeFolder {
+			continue
+		}
+		for id := node.ID; id != ""; id = parentIDs[id] {
+			if _, ok := kept[id]; ok {
+				break
+			}
+			kept[id] = struct{}{}
+		}
+	}
+	return lo.Filter(nodes, func(node *domain.ShareNodeListItemResp, _ int) bool {
+		_, ok := kept[node.ID]
+		return ok
+	})
+}
+
+// nodeTagged reports whether the node has one of the tags of the request,
+// every node does without tags
+func (a *kbAgent) nodeTagged(ctx context.Context, nodeID string) (bool, error) {
+	if len(a.req.Tags) == 0 {
+		return true, nil
+	}
+	taggedNodeIDs, err := a.getTaggedNodeIDs(ctx)
+	if err != nil {
+		return false, err
+	}
+	_, ok := taggedNodeIDs[nodeID]
+	return ok, nil
+}
+
+func (a *kbAgent) getTaggedNodeIDs(ctx context.Context) (map[string]struct{}, error) {
+	if a.taggedNodeIDs == nil {
+		nodeIDs, err := a.u.tagRepo.GetNodeIDsByTagNames(ctx, a.kb.ID, a.req.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("get tagged nodes failed: %w", err)
+		}
+		a.taggedNodeIDs = lo.SliceToMap(nodeIDs, func(id string) (string, struct{}) { return id, struct{}{} })
+	}
+	return a.taggedNodeIDs, nil
+}
+
+func (a *kbAgent) nodeAccessible(ctx context.Context, permName consts.NodePermName, nodeID string, perm consts.NodeAccessPerm) (bool, error) {
+	if perm != consts.NodeAccessPermPartial {
+		return domain.NodeAccessible(perm, nodeID, nil), nil
+	}
+	groupNodeIDs, ok := a.groupNodeIDs[permName]
+	if !ok {
+		groupNodeIDs = make(map[string]struct{})
+		if len(a.groupIDs) > 0 {
+			nodeGroups, err := a.u.nodeRepo.GetNodeGroupsByGroupIdsPerm(ctx, lo.Map(a.groupIDs, func(id int, _ int) uint { return uint(id) }), permName)
+			if err != nil {
+				return false, fmt.Errorf("get node groups failed: %w", err)
+			}
+			for _, nodeGroup := range nodeGroups {
+				groupNodeIDs[nodeGroup.NodeID] = struct{}{}
+			}
+		}
+		a.groupNodeIDs[permName] = groupNodeIDs
+	}
+	return domain.NodeAccessible(perm, nodeID, groupNodeIDs), nil
+}
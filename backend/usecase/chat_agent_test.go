@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/chaitin/panda-wiki/domain"
+)
+
+func TestFilterTaggedNodes(t *testing.T) {
+	nodes := []*domain.ShareNodeListItemResp{
+		{ID: "guides", Type: domain.NodeTypeFolder},
+		{ID: "setup", ParentID: "guides", Type: domain.NodeTypeDocument},
+		{ID: "faq", ParentID: "guides", Type: domain.NodeTypeDocument},
+		{ID: "internal", Type: domain.NodeTypeFolder},
+		{ID: "oncall", ParentID: "internal", Type: domain.NodeTypeDocument},
+		{ID: "empty", Type: domain.NodeTypeFolder},
+		{ID: "pricing", Type: domain.NodeTypeDocument},
+	}
+	// a tagged folder is only kept for the documents below it
+	tagged := map[string]struct{}{"setup": {}, "pricing": {}, "empty": {}}
+
+	kept := filterTaggedNodes(nodes, tagged)
+	assert.Equal(t, []string{"guides", "setup", "pricing"}, lo.Map(kept, func(node *domain.ShareNodeListItemResp, _ int) string { return node.ID }))
+}
@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+)
+
+// checkGroundedness judges the answer against the documents it was given and
+// sends the verdict as a groundedness event after the done event, if the
+// client is still reading. A verdict scoring below the threshold is stored
+// with the message for review. The tokens of the judge count toward the quota
+// and the usage of the model. The check is best effort, a failure only skips
+// the event.
+func (u *ChatUsecase) checkGroundedness(ctx context.Context, settings domain.GroundednessSettings, chatModel model.BaseChatModel, req *domain.ChatRequest, quotaUser, messageId, answer string, nodes []*domain.RankedNodeChunks, eventCh chan<- domain.SSEEvent) {
+	answer = strings.TrimSpace(u.llmUsecase.trimThinking(answer))
+	// without documents there is nothing to hold the answer to
+	if !settings.Enabled || answer == "" || len(nodes) == 0 {
+		return
+	}
+	// clients may stop reading at the done event and cancel ctx, the verdict
+	// is still judged and stored
+	judgeCtx := context.WithoutCancel(ctx)
+	usage := schema.TokenUsage{}
+	groundedness, err := u.JudgeGroundedness(judgeCtx, chatModel, answer, nodes, &usage)
+	u.addChatQuotaTokens(judgeCtx, req.AppID, quotaUser, &usage)
+	if usage.TotalTokens > 0 {
+		if err := u.modelUsecase.UpdateUsage(judgeCtx, req.ModelInfo.ID, &usage); err != nil {
+			u.logger.Warn("update model usage of groundedness judge failed", log.Error(err))
+		}
+	}
+	if err != nil {
+		u.logger.Warn("judge groundedness failed", log.Error(err))
+		return
+	}
+	if ctx.Err() == nil {
+		eventCh <- domain.SSEEvent{Type: "groundedness", Groundedness: groundedness}
+	}
+	if groundedness.Score >= settings.GetThreshold() {
+		return
+	}
+	if err := u.conversationUsecase.UpdateMessageGroundedness(judgeCtx, messageId, groundedness); err != nil {
+		u.logger.Warn("save groundedness failed", log.String("message_id", messageId), log.Error(err))
+	}
+}
+
+// JudgeGroundedness asks the chat model which claims of the answer are not
+// supported by the documents, the tokens of the judge are added to usage
+func (u *ChatUsecase) JudgeGroundedness(ctx context.Context, chatModel model.BaseChatModel, answer string, nodes []*domain.RankedNodeChunks, usage *schema.TokenUsage) (*domain.Groundedness, error) {
+	template := prompt.FromMessages(schema.GoTemplate,
+		schema.SystemMessage(domain.GroundednessJudgePrompt),
+		schema.UserMessage(domain.GroundednessJudgeUserFormatter),
+	)
+	messages, err := template.Format(ctx, map[string]any{
+		"Documents": domain.FormatNodeChunks(nodes, ""),
+		"Answer":    answer,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := chatModel.Generate(ctx, messages)
+	if err != nil {
+		return nil, fmt.Errorf("generate failed: %w", err)
+	}
+	addResponseUsage(usage, resp)
+	return domain.ParseGroundedness(u.llmUsecase.trimThinking(resp.Content))
+}
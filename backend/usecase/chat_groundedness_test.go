@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/repo/cache"
+	"github.com/chaitin/panda-wiki/repo/pg"
+	storecache "github.com/chaitin/panda-wiki/store/cache"
+	storepg "github.com/chaitin/panda-wiki/store/pg"
+)
+
+// emptyConn is a database connection answering every statement with no rows
+type emptyConn struct{}
+
+func (emptyConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (emptyConn) Close() error                              { return nil }
+func (emptyConn) Begin() (driver.Tx, error)                 { return emptyTx{}, nil }
+
+func (emptyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return emptyRows{}, nil
+}
+
+func (emptyConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+type emptyTx struct{}
+
+func (emptyTx) Commit() error   { return nil }
+func (emptyTx) Rollback() error { return nil }
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string              { return nil }
+func (emptyRows) Close() error                   { return nil }
+func (emptyRows) Next(dest []driver.Value) error { return io.EOF }
+
+type emptyConnector struct{}
+
+func (emptyConnector) Connect(context.Context) (driver.Conn, error) { return emptyConn{}, nil }
+func (emptyConnector) Driver() driver.Driver                        { return nil }
+
+// sqlRecorder records the statements the db ran without error
+type sqlRecorder struct {
+	logger.Interface
+	sqls []string
+}
+
+func (r *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if sql, _ := fc(); err == nil {
+		r.sqls = append(r.sqls, sql)
+	}
+}
+
+func newTestDB(t *testing.T) (*storepg.DB, *sqlRecorder) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(emptyConnector{})}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               recorder,
+	})
+	require.NoError(t, err)
+	return &storepg.DB{DB: db}, recorder
+}
+
+// fakeChatModel answers every prompt with output, failing once ctx is canceled
+type fakeChatModel struct {
+	output string
+}
+
+func (m *fakeChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &schema.Message{
+		Role:         schema.Assistant,
+		Content:      m.output,
+		ResponseMeta: &schema.ResponseMeta{Usage: &schema.TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}},
+	}, nil
+}
+
+func (m *fakeChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, errors.New("not implemented")
+}
+
+// newTestChatUsecase records the statements of the repos, redis is
+// unreachable as the quota is best effort
+func newTestChatUsecase(t *testing.T) (*ChatUsecase, *sqlRecorder) {
+	db, recorder := newTestDB(t)
+	logger := newTestLLMUsecase().logger
+	rdb := redis.NewClient(&redis.Options{
+		MaxRetries: -1,
+		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return nil, errors.New("no redis")
+		},
+	})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return &ChatUsecase{
+		llmUsecase:          newTestLLMUsecase(),
+		conversationUsecase: &ConversationUsecase{repo: pg.NewConversationRepository(db, logger)},
+		modelUsecase:        &ModelUsecase{modelRepo: pg.NewModelRepository(db, logger)},
+		chatQuotaRepo:       cache.NewChatQuotaRepo(&storecache.Cache{Client: rdb}),
+		logger:              logger,
+	}, recorder
+}
+
+func TestCheckGroundednessAfterClientLeft(t *testing.T) {
+	u, recorder := newTestChatUsecase(t)
+	nodes := []*domain.RankedNodeChunks{{
+		KBID:     "kb",
+		NodeID:   "node",
+		NodeName: "Pricing",
+		Chunks:   []*domain.NodeContentChunk{{ID: "c1", Content: "the pro plan costs 10 dollars"}},
+	}}
+
+	// the consumer stopped reading at the done event and canceled the request
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	eventCh := make(chan domain.SSEEvent, 1)
+	u.checkGroundedness(ctx, domain.GroundednessSettings{Enabled: true}, &fakeChatModel{
+		output: `{"score": 0.2, "unsupported_sentences": ["it is free"]}`,
+	}, &domain.ChatRequest{AppID: "app", ModelInfo: &domain.Model{ID: "model"}}, "ip:", "msg", "it is free", nodes, eventCh)
+
+	assert.Empty(t, eventCh)
+	var saved, counted bool
+	for _, sql := range recorder.sqls {
+		if strings.HasPrefix(sql, `UPDATE "conversation_messages" SET "groundedness"=`) && strings.Contains(sql, `id = 'msg'`) {
+			saved = true
+		}
+		if strings.HasPrefix(sql, `UPDATE "models"`) && strings.Contains(sql, "total_tokens + 15") {
+			counted = true
+		}
+	}
+	assert.True(t, saved, "low score not saved: %v", recorder.sqls)
+	assert.True(t, counted, "judge tokens not counted: %v", recorder.sqls)
+}
@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+)
+
+// chatModelFirstChunkTimeout is how long a model of the fallback chain may
+// take to start answering before the next one is tried
+const chatModelFirstChunkTimeout = 60 * time.Second
+
+// fallbackChatModel moves through a chain of chat models until one answers.
+// A stream falls back only before its first chunk, as the chunks after it are
+// already sent to the client. Once a model answered it is tried first by the
+// later calls of the chat, e.g. the rounds of the agent mode.
+type fallbackChatModel struct {
+	models     []*domain.Model
+	chatModels []model.BaseChatModel
+	current    int
+	logger     *log.Logger
+}
+
+func (u *ChatUsecase) newFallbackChatModel(ctx context.Context, models []*domain.Model) (*fallbackChatModel, error) {
+	m := &fallbackChatModel{logger: u.logger}
+	for _, domainModel := range models {
+		modelkitModel, err := domainModel.ToModelkitModel()
+		if err != nil {
+			u.logger.Warn("failed to convert model to modelkit model", log.String("model", domainModel.Model), log.Error(err))
+			continue
+		}
+		chatModel, err := u.modelkit.GetChatModel(ctx, modelkitModel)
+		if err != nil {
+			u.logger.Warn("failed to get chat model", log.String("model", domainModel.Model), log.Error(err))
+			continue
+		}
+		m.models = append(m.models, domainModel)
+		m.chatModels = append(m.chatModels, chatModel)
+	}
+	if len(m.chatModels) == 0 {
+		return nil, fmt.Errorf("no chat model available")
+	}
+	return m, nil
+}
+
+// Model returns the model that answered last, or the first of the chain
+func (m *fallbackChatModel) Model() *domain.Model {
+	return m.models[m.current]
+}
+
+func (m *fallbackChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	var errs []error
+	for i := m.current; i < len(m.chatModels); i++ {
+		msg, err := m.chatModels[i].Generate(ctx, input, opts...)
+		if err == nil {
+			m.current = i
+			return msg, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		m.logger.Warn("chat model failed, try the next one", log.String("model", m.models[i].Model), log.Error(err))
+		errs = append(errs, fmt.Errorf("%s: %w", m.models[i].Model, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (m *fallbackChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	var errs []error
+	for i := m.current; i < len(m.chatModels); i++ {
+		reader, err := m.stream(ctx, m.chatModels[i], input, opts...)
+		if err == nil {
+			m.current = i
+			return reader, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		m.logger.Warn("chat model failed, try the next one", log.String("model", m.models[i].Model), log.Error(err))
+		errs = append(errs, fmt.Errorf("%s: %w", m.models[i].Model, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// stream starts the stream of a model and waits for its first chunk, so a
+// model failing or timing out before it answers can be replaced
+func (m *fallbackChatModel) stream(ctx context.Context, chatModel model.BaseChatModel, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	timer := time.AfterFunc(chatModelFirstChunkTimeout, cancel)
+	reader, err := chatModel.Stream(streamCtx, input, opts...)
+	if err != nil {
+		timer.Stop()
+		cancel()
+		return nil, err
+	}
+	first, err := reader.Recv()
+	if !timer.Stop() {
+		reader.Close()
+		cancel()
+		return nil, fmt.Errorf("no response in %s", chatModelFirstChunkTimeout)
+	}
+	if errors.Is(err, io.EOF) {
+		reader.Close()
+		cancel()
+		return schema.StreamReaderFromArray([]*schema.Message{}), nil
+	}
+	if err != nil {
+		reader.Close()
+		cancel()
+		return nil, err
+	}
+
+	out, writer := schema.Pipe[*schema.Message](1)
+	go func() {
+		defer cancel()
+		defer reader.Close()
+		defer writer.Close()
+		if closed := writer.Send(first, nil); closed {
+			return
+		}
+		for {
+			msg, err := reader.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if closed := writer.Send(msg, err); closed || err != nil {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
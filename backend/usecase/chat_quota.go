@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+)
+
+// checkChatQuota counts the chat against the quota of the app and returns
+// why it is rejected, empty when it may go on. The chat goes on when redis
+// fails, as the quota should not take the chat down with it.
+func (u *ChatUsecase) checkChatQuota(ctx context.Context, appID string, settings domain.ChatQuotaSettings, user string) string {
+	now := time.Now()
+	if settings.AppTokensPerDay > 0 || settings.UserTokensPerDay > 0 {
+		appTokens, userTokens, err := u.chatQuotaRepo.GetTokens(ctx, appID, user, now)
+		if err != nil {
+			u.logger.Warn("get chat quota tokens failed", log.String("app_id", appID), log.Error(err))
+		} else {
+			if settings.AppTokensPerDay > 0 && appTokens >= settings.AppTokensPerDay {
+				return "今日对话额度已用完，请明天再试"
+			}
+			if settings.UserTokensPerDay > 0 && userTokens >= settings.UserTokensPerDay {
+				return "您今日的对话额度已用完，请明天再试"
+			}
+		}
+	}
+	// requests are counted without a limit too, for the consumption view
+	appRequests, userRequests, err := u.chatQuotaRepo.IncrRequests(ctx, appID, user, now)
+	if err != nil {
+		u.logger.Warn("count chat quota requests failed", log.String("app_id", appID), log.Error(err))
+		return ""
+	}
+	if settings.AppRequestsPerMinute > 0 && appRequests > settings.AppRequestsPerMinute {
+		return "当前提问人数过多，请稍后再试"
+	}
+	if settings.UserRequestsPerMinute > 0 && userRequests > settings.UserRequestsPerMinute {
+		return "提问过于频繁，请稍后再试"
+	}
+	return ""
+}
+
+// addChatQuotaTokens counts the tokens of a chat for the app and the user
+func (u *ChatUsecase) addChatQuotaTokens(ctx context.Context, appID, user string, usage *schema.TokenUsage) {
+	if usage.TotalTokens <= 0 {
+		return
+	}
+	if err := u.chatQuotaRepo.AddTokens(ctx, appID, user, int64(usage.TotalTokens), time.Now()); err != nil {
+		u.logger.Warn("add chat quota tokens failed", log.String("app_id", appID), log.Error(err))
+	}
+}
+
+// addResponseUsage adds the tokens of a generated response to usage
+func addResponseUsage(usage *schema.TokenUsage, resp *schema.Message) {
+	if resp.ResponseMeta != nil && resp.ResponseMeta.Usage != nil {
+		usage.PromptTokens += resp.ResponseMeta.Usage.PromptTokens
+		usage.CompletionTokens += resp.ResponseMeta.Usage.CompletionTokens
+		usage.TotalTokens += resp.ResponseMeta.Usage.TotalTokens
+	}
+}
+
+// GetChatQuotaUsage returns the current consumption of the app against its
+// quota
+func (u *ChatUsecase) GetChatQuotaUsage(ctx context.Context, app *domain.App) (*domain.ChatQuotaUsageResp, error) {
+	now := time.Now()
+	requests, err := u.chatQuotaRepo.GetAppRequests(ctx, app.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	tokens, _, err := u.chatQuotaRepo.GetTokens(ctx, app.ID, "", now)
+	if err != nil {
+		return nil, err
+	}
+	topUsers, err := u.chatQuotaRepo.GetTopUsers(ctx, app.ID, now, domain.MaxChatQuotaTopUsers)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ChatQuotaUsageResp{
+		AppID:              app.ID,
+		Settings:           app.Settings.ChatQuotaSettings,
+		RequestsThisMinute: requests,
+		TokensToday:        tokens,
+		TopUsers:           topUsers,
+	}, nil
+}
@@ -0,0 +1,19 @@
+package usecase
+
+import (
+	"github.com/cloudwego/eino/components/model"
+
+	"github.com/chaitin/panda-wiki/domain"
+)
+
+// chatModelOptions passes the tools of the client to the chat model
+func chatModelOptions(req *domain.ChatRequest) []model.Option {
+	opts := make([]model.Option, 0, 2)
+	if len(req.Tools) > 0 {
+		opts = append(opts, model.WithTools(req.Tools))
+		if req.ToolChoice != nil {
+			opts = append(opts, model.WithToolChoice(*req.ToolChoice))
+		}
+	}
+	return opts
+}
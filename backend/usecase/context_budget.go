@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"github.com/cloudwego/eino/schema"
+	"github.com/pkoukk/tiktoken-go"
+
+	"github.com/chaitin/panda-wiki/domain"
+)
+
+// limitContextTokens keeps the chunks of the ranked nodes in rank order until
+// the token budget is spent, nodes left without chunks are dropped. The first
+// chunk is always kept so a small budget still yields an answer. The chunks
+// left out are returned with copies of their nodes.
+func limitContextTokens(rankedNodes []*domain.RankedNodeChunks, maxTokens int) ([]*domain.RankedNodeChunks, []*domain.RankedNodeChunks, error) {
+	if maxTokens <= 0 || len(rankedNodes) == 0 {
+		return rankedNodes, nil, nil
+	}
+	encoding, err := tiktoken.GetEncoding("cl100k_base")
+	if err != nil {
+		return rankedNodes, nil, err
+	}
+	used := 0
+	result := make([]*domain.RankedNodeChunks, 0, len(rankedNodes))
+	var dropped []*domain.RankedNodeChunks
+	for _, node := range rankedNodes {
+		chunks := make([]*domain.NodeContentChunk, 0, len(node.Chunks))
+		var droppedChunks []*domain.NodeContentChunk
+		for _, chunk := range node.Chunks {
+			tokens := len(encoding.Encode(chunk.Content, nil, nil))
+			if used+tokens > maxTokens && used > 0 {
+				droppedChunks = append(droppedChunks, chunk)
+				continue
+			}
+			used += tokens
+			chunks = append(chunks, chunk)
+		}
+		if len(droppedChunks) > 0 {
+			droppedNode := *node
+			droppedNode.Chunks = droppedChunks
+			dropped = append(dropped, &droppedNode)
+		}
+		if len(chunks) == 0 {
+			continue
+		}
+		node.Chunks = chunks
+		result = append(result, node)
+	}
+	return result, dropped, nil
+}
+
+func countTokens(encoding *tiktoken.Tiktoken, text string) int {
+	return len(encoding.Encode(text, nil, nil)) + domain.MessageOverheadTokens
+}
+
+// messageTokens returns the tokens each message takes in the prompt
+func messageTokens(encoding *tiktoken.Tiktoken, messages []*schema.Message) []int {
+	tokens := make([]int, len(messages))
+	for i, msg := range messages {
+		tokens[i] = countTokens(encoding, msg.Content)
+	}
+	return tokens
+}
+
+// fitContextBudget drops the oldest history messages beyond the history share
+// of the context limit and returns the tokens left for the documents. A limit
+// of 0 keeps the whole history and leaves the documents unlimited.
+func fitContextBudget(systemPrompt, question string, history []*schema.Message, contextLimit int) ([]*schema.Message, int, error) {
+	if contextLimit <= 0 {
+		return history, 0, nil
+	}
+	encoding, err := tiktoken.GetEncoding("cl100k_base")
+	if err != nil {
+		return history, 0, err
+	}
+	tokens := messageTokens(encoding, history)
+	start := domain.KeepRecentHistory(history, tokens, domain.HistoryContextLimit(contextLimit))
+	used := countTokens(encoding, systemPrompt) + countTokens(encoding, question)
+	for _, t := range tokens[start:] {
+		used += t
+	}
+	// keep a margin for the titles and urls the documents are formatted with
+	return history[start:], max((contextLimit-used)*9/10, 1), nil
+}
@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkoukk/tiktoken-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/utils"
+)
+
+func TestLimitContextTokensReturnsDropped(t *testing.T) {
+	tiktoken.SetBpeLoader(&utils.Localloader{})
+	long := strings.Repeat("word ", 100)
+	nodes := []*domain.RankedNodeChunks{
+		{NodeID: "a", Chunks: []*domain.NodeContentChunk{{ID: "a1", Content: long}, {ID: "a2", Content: long}}},
+		{NodeID: "b", Chunks: []*domain.NodeContentChunk{{ID: "b1", Content: long}}},
+	}
+
+	kept, dropped, err := limitContextTokens(nodes, 150)
+	require.NoError(t, err)
+
+	require.Len(t, kept, 1)
+	assert.Equal(t, "a", kept[0].NodeID)
+	assert.Equal(t, "a1", kept[0].Chunks[0].ID)
+	require.Len(t, kept[0].Chunks, 1)
+	require.Len(t, dropped, 2)
+	assert.Equal(t, "a", dropped[0].NodeID)
+	assert.Equal(t, "a2", dropped[0].Chunks[0].ID)
+	assert.Equal(t, "b", dropped[1].NodeID)
+	assert.Equal(t, "b1", dropped[1].Chunks[0].ID)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/samber/lo"
 
@@ -41,11 +42,38 @@ func NewConversationUsecase(
 	}
 }
 
-func (u *ConversationUsecase) CreateChatConversationMessage(ctx context.Context, kbID string, conversation *domain.ConversationMessage) error {
+// CreateChatConversationMessage saves the message with the references and
+// chunks cited in it, rankedNodes are the retrieved nodes the answer was
+// generated from
+func (u *ConversationUsecase) CreateChatConversationMessage(ctx context.Context, kbID string, conversation *domain.ConversationMessage, rankedNodes []*domain.RankedNodeChunks) error {
 	references := extractReferencesBlock(conversation.ID, conversation.AppID, conversation.Content)
+	annotateReferences(references, rankedNodes)
+	if len(rankedNodes) > 0 {
+		conversation.Citations = domain.CitedChunks(conversation.Content, rankedNodes)
+	}
 	return u.repo.CreateConversationMessage(ctx, conversation, references)
 }
 
+// annotateReferences fills the node id and rerank score of the references by
+// matching their node url against the retrieved nodes
+func annotateReferences(references []*domain.ConversationReference, rankedNodes []*domain.RankedNodeChunks) {
+	for _, ref := range references {
+		for _, node := range rankedNodes {
+			if strings.HasSuffix(strings.TrimSuffix(ref.URL, "/"), "/node/"+node.NodeID) {
+				ref.NodeID = node.NodeID
+				ref.RerankScore = node.RerankScore
+				break
+			}
+		}
+	}
+}
+
+// UpdateMessageGroundedness stores the groundedness verdict judged after the
+// message was saved
+func (u *ConversationUsecase) UpdateMessageGroundedness(ctx context.Context, messageID string, groundedness *domain.Groundedness) error {
+	return u.repo.UpdateMessageGroundedness(ctx, messageID, groundedness)
+}
+
 func (u *ConversationUsecase) GetConversationList(ctx context.Context, request *domain.ConversationListReq) (*domain.PaginatedResult[[]*domain.ConversationListItem], error) {
 	conversations, total, err := u.repo.GetConversationList(ctx, request)
 	if err != nil {
@@ -173,6 +201,24 @@ func (u *ConversationUsecase) CreateConversation(ctx context.Context, conversati
 	if err := u.repo.CreateConversation(ctx, conversation); err != nil {
 		return err
 	}
+	u.recordConversationGeo(ctx, conversation)
+	return nil
+}
+
+// CreateConversationIfNotExists keeps using the conversation with the id of
+// the given one, creating it on the first message
+func (u *ConversationUsecase) CreateConversationIfNotExists(ctx context.Context, conversation *domain.Conversation) error {
+	created, err := u.repo.CreateConversationIfNotExists(ctx, conversation)
+	if err != nil {
+		return err
+	}
+	if created {
+		u.recordConversationGeo(ctx, conversation)
+	}
+	return nil
+}
+
+func (u *ConversationUsecase) recordConversationGeo(ctx context.Context, conversation *domain.Conversation) {
 	remoteIP := conversation.RemoteIP
 	ipAddress, err := u.ipRepo.GetIPAddress(ctx, remoteIP)
 	if err != nil {
@@ -183,7 +229,6 @@ func (u *ConversationUsecase) CreateConversation(ctx context.Context, conversati
 			u.logger.Warn("set geo cache failed", log.Error(err), log.String("conversation_id", conversation.ID), log.String("ip", remoteIP))
 		}
 	}
-	return nil
 }
 
 func (u *ConversationUsecase) FeedBack(ctx context.Context, feedback *domain.FeedbackRequest) error {
@@ -275,6 +320,8 @@ func (u *ConversationUsecase) GetShareConversationDetail(ctx context.Context, kb
 			Role:       message.Role,
 			Content:    message.Content,
 			ImagePaths: message.ImagePaths,
+			Citations:  message.Citations,
+			AgentSteps: message.AgentSteps,
 			CreatedAt:  message.CreatedAt,
 		})
 	}
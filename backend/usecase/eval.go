@@ -0,0 +1,276 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+
+	v1 "github.com/chaitin/panda-wiki/api/eval/v1"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/repo/pg"
+)
+
+const defaultEvalK = 10
+
+type EvalUsecase struct {
+	evalRepo     *pg.EvalRepository
+	appRepo      *pg.AppRepository
+	kbRepo       *pg.KnowledgeBaseRepository
+	nodeRepo     *pg.NodeRepository
+	settingRepo  *pg.SettingRepo
+	llmUsecase   *LLMUsecase
+	modelUsecase *ModelUsecase
+	logger       *log.Logger
+}
+
+func NewEvalUsecase(
+	evalRepo *pg.EvalRepository,
+	appRepo *pg.AppRepository,
+	kbRepo *pg.KnowledgeBaseRepository,
+	nodeRepo *pg.NodeRepository,
+	settingRepo *pg.SettingRepo,
+	llmUsecase *LLMUsecase,
+	modelUsecase *ModelUsecase,
+	logger *log.Logger,
+) *EvalUsecase {
+	return &EvalUsecase{
+		evalRepo:     evalRepo,
+		appRepo:      appRepo,
+		kbRepo:       kbRepo,
+		nodeRepo:     nodeRepo,
+		settingRepo:  settingRepo,
+		llmUsecase:   llmUsecase,
+		modelUsecase: modelUsecase,
+		logger:       logger.WithModule("usecase.eval"),
+	}
+}
+
+func (u *EvalUsecase) GetSetList(ctx context.Context, kbID string) ([]v1.EvalSetListItemResp, error) {
+	return u.evalRepo.GetSetList(ctx, kbID)
+}
+
+func (u *EvalUsecase) GetSetDetail(ctx context.Context, kbID, id string) (*v1.EvalSetDetailResp, error) {
+	set, err := u.getSet(ctx, kbID, id)
+	if err != nil {
+		return nil, err
+	}
+	cases, err := u.evalRepo.GetCasesBySetID(ctx, set.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.EvalSetDetailResp{EvalSet: *set, Cases: cases}, nil
+}
+
+func (u *EvalUsecase) CreateSet(ctx context.Context, req *v1.CreateEvalSetReq) (string, error) {
+	now := time.Now()
+	set := &domain.EvalSet{
+		ID:          uuid.New().String(),
+		KBID:        req.KbId,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	cases, err := u.buildCases(ctx, set, req.Cases)
+	if err != nil {
+		return "", err
+	}
+	if err := u.evalRepo.CreateSet(ctx, set, cases); err != nil {
+		return "", err
+	}
+	return set.ID, nil
+}
+
+func (u *EvalUsecase) UpdateSet(ctx context.Context, req *v1.UpdateEvalSetReq) error {
+	set, err := u.getSet(ctx, req.KbId, req.ID)
+	if err != nil {
+		return err
+	}
+	set.Name = strings.TrimSpace(req.Name)
+	set.Description = req.Description
+	cases, err := u.buildCases(ctx, set, req.Cases)
+	if err != nil {
+		return err
+	}
+	return u.evalRepo.UpdateSet(ctx, set, cases)
+}
+
+func (u *EvalUsecase) DeleteSet(ctx context.Context, kbID, id string) error {
+	return u.evalRepo.DeleteSet(ctx, kbID, id)
+}
+
+func (u *EvalUsecase) GetRunList(ctx context.Context, kbID, setID string) ([]v1.EvalRunListItemResp, error) {
+	return u.evalRepo.GetRunList(ctx, kbID, setID)
+}
+
+func (u *EvalUsecase) GetRunDetail(ctx context.Context, kbID, id string) (*domain.EvalRun, error) {
+	run, err := u.evalRepo.GetRunByID(ctx, kbID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("eval run %s not found", id)
+		}
+		return nil, err
+	}
+	return run, nil
+}
+
+// Run replays every question of the set through the retrieval pipeline and
+// stores the scored run
+func (u *EvalUsecase) Run(ctx context.Context, req *v1.CreateEvalRunReq) (*domain.EvalRun, error) {
+	set, err := u.getSet(ctx, req.KbId, req.SetID)
+	if err != nil {
+		return nil, err
+	}
+	cases, err := u.evalRepo.GetCasesBySetID(ctx, set.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, errors.New("eval set has no cases")
+	}
+	kb, err := u.kbRepo.GetKnowledgeBaseByID(ctx, req.KbId)
+	if err != nil {
+		return nil, fmt.Errorf("get kb failed: %w", err)
+	}
+	// replay with the retrieval settings of the web app
+	app, err := u.appRepo.GetOrCreateAppByKBIDAndType(ctx, req.KbId, domain.AppTypeWeb)
+	if err != nil {
+		return nil, fmt.Errorf("get web app failed: %w", err)
+	}
+	retrieval := app.Settings.RetrievalSettings
+	k := req.K
+	if k <= 0 {
+		k = defaultEvalK
+	}
+
+	run := &domain.EvalRun{
+		ID:        uuid.New().String(),
+		SetID:     set.ID,
+		KBID:      set.KBID,
+		K:         k,
+		CaseCount: len(cases),
+		Note:      req.Note,
+		Settings:  u.snapshotSettings(ctx, set.KBID, retrieval),
+		Results:   make(domain.EvalCaseResults, 0, len(cases)),
+		CreatedAt: time.Now(),
+	}
+	for _, evalCase := range cases {
+		result := domain.EvalCaseResult{
+			CaseID:           evalCase.ID,
+			Question:         evalCase.Question,
+			ExpectedNodeIDs:  evalCase.ExpectedNodeIDs,
+			RetrievedNodeIDs: []string{},
+		}
+		_, rankedNodes, err := u.llmUsecase.GetRankNodes(ctx, GetRankNodesRequest{
+			KBID:                kb.ID,
+			DatasetID:           kb.DatasetID,
+			Question:            evalCase.Question,
+			TopK:                retrieval.GetTopK(),
+			SimilarityThreshold: retrieval.GetSimilarityThreshold(),
+			MaxChunksPerDoc:     retrieval.MaxChunksPerDoc,
+			MaxContextTokens:    retrieval.MaxContextTokens,
+		})
+		if err != nil {
+			// a failed question scores zero, the rest of the set still runs
+			u.logger.Warn("eval get rank nodes failed", log.String("case_id", evalCase.ID), log.Error(err))
+			result.Error = err.Error()
+		} else {
+			result.RetrievedNodeIDs = lo.Map(rankedNodes, func(node *domain.RankedNodeChunks, _ int) string {
+				return node.NodeID
+			})
+		}
+		result.Recall, result.ReciprocalRank, result.MissedNodeIDs = domain.ScoreRetrieval(result.ExpectedNodeIDs, result.RetrievedNodeIDs, k)
+		run.Recall += result.Recall
+		run.MRR += result.ReciprocalRank
+		run.Results = append(run.Results, result)
+	}
+	run.Recall /= float64(len(cases))
+	run.MRR /= float64(len(cases))
+
+	if err := u.evalRepo.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (u *EvalUsecase) getSet(ctx context.Context, kbID, id string) (*domain.EvalSet, error) {
+	set, err := u.evalRepo.GetSetByID(ctx, kbID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("eval set %s not found", id)
+		}
+		return nil, err
+	}
+	return set, nil
+}
+
+// buildCases validates that the expected nodes are documents of the kb
+func (u *EvalUsecase) buildCases(ctx context.Context, set *domain.EvalSet, reqs []v1.EvalCaseReq) ([]*domain.EvalCase, error) {
+	nodeIDs := lo.Uniq(lo.FlatMap(reqs, func(req v1.EvalCaseReq, _ int) []string {
+		return req.ExpectedNodeIDs
+	}))
+	nodes, err := u.nodeRepo.GetNodesByIDs(ctx, nodeIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range nodeIDs {
+		node, ok := nodes[id]
+		if !ok || node.KBID != set.KBID {
+			return nil, fmt.Errorf("node %s not found", id)
+		}
+		if node.Type != domain.NodeTypeDocument {
+			return nil, fmt.Errorf("node %s is not a document", id)
+		}
+	}
+	now := time.Now()
+	cases := make([]*domain.EvalCase, 0, len(reqs))
+	for i, req := range reqs {
+		question := strings.TrimSpace(req.Question)
+		if question == "" {
+			return nil, fmt.Errorf("question of case %d is empty", i+1)
+		}
+		cases = append(cases, &domain.EvalCase{
+			ID:              uuid.New().String(),
+			SetID:           set.ID,
+			KBID:            set.KBID,
+			Question:        question,
+			ExpectedNodeIDs: lo.Uniq(req.ExpectedNodeIDs),
+			// keep the order of the request
+			CreatedAt: now.Add(time.Duration(i) * time.Microsecond),
+			UpdatedAt: now,
+		})
+	}
+	return cases, nil
+}
+
+// snapshotSettings records the retrieval settings of the run, lookups are
+// best effort
+func (u *EvalUsecase) snapshotSettings(ctx context.Context, kbID string, retrieval domain.RetrievalSettings) domain.EvalRunSettings {
+	settings := domain.EvalRunSettings{
+		RankFusion: domain.DefaultRankFusionSetting,
+		Rerank:     domain.DefaultRerankSetting,
+		Retrieval:  retrieval,
+	}
+	if fusion, err := u.settingRepo.GetRankFusionSetting(ctx, kbID); err == nil {
+		settings.RankFusion = *fusion
+	}
+	if rerank, err := u.settingRepo.GetRerankSetting(ctx, kbID); err == nil {
+		settings.Rerank = *rerank
+	}
+	if model, err := u.modelUsecase.GetModelByType(ctx, domain.ModelTypeEmbedding); err == nil {
+		settings.EmbeddingModel = model.Model
+	}
+	if settings.Rerank.Enabled {
+		if model, err := u.modelUsecase.GetRerankModel(ctx); err == nil {
+			settings.RerankModel = model.Model
+		}
+	}
+	return settings
+}
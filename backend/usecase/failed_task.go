@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	v1 "github.com/chaitin/panda-wiki/api/kb/v1"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/repo/mq"
+	"github.com/chaitin/panda-wiki/repo/pg"
+)
+
+type FailedTaskUsecase struct {
+	failedTaskRepo *pg.FailedTaskRepository
+	ragRepo        *mq.RAGRepository
+	reindexUsecase *ReindexUsecase
+	logger         *log.Logger
+}
+
+func NewFailedTaskUsecase(failedTaskRepo *pg.FailedTaskRepository, ragRepo *mq.RAGRepository, reindexUsecase *ReindexUsecase, logger *log.Logger) *FailedTaskUsecase {
+	return &FailedTaskUsecase{
+		failedTaskRepo: failedTaskRepo,
+		ragRepo:        ragRepo,
+		reindexUsecase: reindexUsecase,
+		logger:         logger.WithModule("usecase.failed_task"),
+	}
+}
+
+// RecordDeadLetter persists a message of the dead letter topic
+func (u *FailedTaskUsecase) RecordDeadLetter(ctx context.Context, deadLetter *domain.DeadLetterMessage) error {
+	task := &domain.FailedTask{
+		ID:        uuid.New().String(),
+		Topic:     deadLetter.Topic,
+		Payload:   deadLetter.Data,
+		Error:     deadLetter.Error,
+		Attempts:  deadLetter.Attempts,
+		Status:    domain.FailedTaskStatusFailed,
+		FailedAt:  deadLetter.FailedAt,
+		CreatedAt: time.Now(),
+	}
+	var request domain.NodeReleaseVectorRequest
+	if deadLetter.Topic == domain.VectorTaskTopic && json.Unmarshal(deadLetter.Data, &request) == nil {
+		task.KBID = request.KBID
+		task.Action = request.Action
+	}
+	if err := u.failedTaskRepo.Create(ctx, task); err != nil {
+		return fmt.Errorf("create failed task failed: %w", err)
+	}
+	// the node of a re-index job is done for good
+	if request.Action == "upsert" {
+		u.reindexUsecase.RecordProgress(ctx, &request, false)
+	}
+	return nil
+}
+
+func (u *FailedTaskUsecase) GetList(ctx context.Context, req *v1.FailedTaskListReq) (*v1.FailedTaskListResp, error) {
+	total, tasks, err := u.failedTaskRepo.GetList(ctx, req.KBId, req.Status, req.Offset(), req.Limit())
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewPaginatedResult(tasks, uint64(total)), nil
+}
+
+func (u *FailedTaskUsecase) GetDetail(ctx context.Context, kbID, id string) (*v1.FailedTaskDetailResp, error) {
+	task, err := u.failedTaskRepo.GetByID(ctx, kbID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.FailedTaskDetailResp{
+		FailedTask: task,
+		Payload:    string(task.Payload),
+	}, nil
+}
+
+// Replay publishes the tasks to their topics again, a replay failing again
+// creates a new failed task
+func (u *FailedTaskUsecase) Replay(ctx context.Context, kbID string, ids []string) error {
+	tasks, err := u.failedTaskRepo.GetByIDs(ctx, kbID, ids)
+	if err != nil {
+		return err
+	}
+	if len(tasks) != len(ids) {
+		return fmt.Errorf("failed task not found")
+	}
+	for _, task := range tasks {
+		if err := u.ragRepo.Republish(ctx, task.Topic, task.Payload); err != nil {
+			return fmt.Errorf("replay task %s failed: %w", task.ID, err)
+		}
+		if err := u.failedTaskRepo.MarkReplayed(ctx, task.ID); err != nil {
+			return err
+		}
+		u.logger.Info("failed task replayed", log.String("task_id", task.ID), log.String("topic", task.Topic))
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/chaitin/panda-wiki/consts"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+)
+
+// GetFederatedRankNodes retrieves from the kb of the request and the
+// federated kbs of the app in parallel, then merges the results by weighted
+// reciprocal rank. Each federated kb is searched with the groups the user has
+// in that kb, a failing federated kb or one the user can't access is skipped.
+func (u *LLMUsecase) GetFederatedRankNodes(ctx context.Context, req GetRankNodesRequest, federatedKBs []domain.FederatedKB, authUserID uint) (string, []*domain.RankedNodeChunks, error) {
+	federatedKBs = filterFederatedKBs(req.KBID, federatedKBs)
+	if len(federatedKBs) == 0 {
+		return u.GetRankNodes(ctx, req)
+	}
+
+	// the context budget applies to the merged result
+	maxContextTokens := req.MaxContextTokens
+	req.MaxContextTokens = 0
+
+	var rewrittenQuery string
+	lists := make([][]*domain.RankedNodeChunks, len(federatedKBs)+1)
+	weights := make([]float64, len(federatedKBs)+1)
+	weights[0] = 1
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		query, nodes, err := u.GetRankNodes(gctx, req)
+		if err != nil {
+			return err
+		}
+		rewrittenQuery, lists[0] = query, nodes
+		return nil
+	})
+	for i, federated := range federatedKBs {
+		weights[i+1] = federated.GetWeight()
+		g.Go(func() error {
+			nodes, err := u.getFederatedKBRankNodes(gctx, req, federated.KBID, authUserID)
+			if err != nil {
+				u.logger.Warn("retrieve from federated kb failed", log.String("kb_id", federated.KBID), log.Error(err))
+				return nil
+			}
+			lists[i+1] = nodes
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return "", nil, err
+	}
+
+	rankedNodes := mergeFederatedRankNodes(lists, weights, max(req.TopK, rankFusionMaxDocs))
+	if maxContextTokens > 0 {
+		limited, dropped, err := limitContextTokens(rankedNodes, maxContextTokens)
+		if err != nil {
+			u.logger.Warn("limit context tokens failed", log.String("kb_id", req.KBID), log.Error(err))
+		} else {
+			rankedNodes = limited
+			if req.Trace != nil {
+				req.Trace.BudgetDropped = dropped
+			}
+		}
+	}
+	return rewrittenQuery, rankedNodes, nil
+}
+
+func (u *LLMUsecase) getFederatedKBRankNodes(ctx context.Context, req GetRankNodesRequest, kbID string, authUserID uint) ([]*domain.RankedNodeChunks, error) {
+	kb, err := u.kbRepo.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return nil, fmt.Errorf("get kb failed: %w", err)
+	}
+	// links to the nodes would point to the asked kb
+	if kb.AccessSettings.BaseURL == "" {
+		return nil, domain.ErrFederatedKBNoBaseURL
+	}
+	var kbAuth *domain.Auth
+	if authUserID != 0 {
+		if kbAuth, err = u.authRepo.GetAuthInKB(ctx, authUserID, kbID); err != nil {
+			return nil, fmt.Errorf("get auth in kb failed: %w", err)
+		}
+	}
+	if !federatedKBAccessible(kb.AccessSettings, kbAuth != nil) {
+		u.logger.Debug("skip federated kb without access", log.String("kb_id", kbID), log.Any("auth_user_id", authUserID))
+		return nil, nil
+	}
+	groupIDs := []int{}
+	if kbAuth != nil {
+		if groupIDs, err = u.authRepo.GetAuthGroupIdsWithParentsByAuthId(ctx, kbAuth.ID); err != nil {
+			return nil, fmt.Errorf("get auth groups failed: %w", err)
+		}
+	}
+	// tags are names of the asked kb and mean nothing here
+	_, nodes, err := u.GetRankNodes(ctx, GetRankNodesRequest{
+		KBID:                kbID,
+		DatasetID:           kb.DatasetID,
+		Question:            req.Question,
+		GroupIDs:            groupIDs,
+		TopK:                req.TopK,
+		SimilarityThreshold: req.SimilarityThreshold,
+		HistoryMessages:     req.HistoryMessages,
+		MaxChunksPerDoc:     req.MaxChunksPerDoc,
+	})
+	if err != nil {
+		return nil, err
+	}
+	baseURL := kb.AccessSettings.BaseURL
+	for _, node := range nodes {
+		node.BaseURL = baseURL
+	}
+	return nodes, nil
+}
+
+// federatedKBAccessible reports whether a user may read a federated kb. A
+// password session of the kb can't be proven from the asked kb, so kbs behind
+// simple auth are never searched, kbs behind enterprise auth only for users
+// known to them.
+func federatedKBAccessible(settings domain.AccessSettings, hasAuth bool) bool {
+	if settings.IsForbidden {
+		return false
+	}
+	switch settings.GetAuthType() {
+	case consts.AuthTypeSimple:
+		return false
+	case consts.AuthTypeEnterprise:
+		return hasAuth
+	}
+	return true
+}
+
+// filterFederatedKBs drops the asked kb and duplicates
+func filterFederatedKBs(kbID string, federatedKBs []domain.FederatedKB) []domain.FederatedKB {
+	seen := map[string]struct{}{kbID: {}}
+	result := make([]domain.FederatedKB, 0, len(federatedKBs))
+	for _, federated := range federatedKBs {
+		if _, ok := seen[federated.KBID]; ok {
+			continue
+		}
+		seen[federated.KBID] = struct{}{}
+		result = append(result, federated)
+	}
+	return result
+}
+
+// mergeFederatedRankNodes merges the ranked lists of several kbs by weighted
+// reciprocal rank, weights[i] applies to lists[i]. Ties keep the list order.
+func mergeFederatedRankNodes(lists [][]*domain.RankedNodeChunks, weights []float64, maxDocs int) []*domain.RankedNodeChunks {
+	type scoredNode struct {
+		node  *domain.RankedNodeChunks
+		score float64
+	}
+	scored := make([]scoredNode, 0)
+	for i, nodes := range lists {
+		for rank, node := range nodes {
+			scored = append(scored, scoredNode{node: node, score: weights[i] / float64(rrfK+rank+1)})
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+	merged := make([]*domain.RankedNodeChunks, 0, min(len(scored), maxDocs))
+	for _, s := range scored[:min(len(scored), maxDocs)] {
+		merged = append(merged, s.node)
+	}
+	return merged
+}
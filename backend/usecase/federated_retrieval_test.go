@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chaitin/panda-wiki/domain"
+)
+
+func TestFederatedRerankKeepsKB(t *testing.T) {
+	asked := &domain.RankedNodeChunks{
+		KBID:     "kb",
+		NodeID:   "node",
+		NodeName: "Asked",
+		Chunks:   []*domain.NodeContentChunk{{ID: "a1", Content: "asked chunk"}},
+	}
+	federated := &domain.RankedNodeChunks{
+		KBID:     "other",
+		BaseURL:  "https://other.example.com",
+		NodeID:   "node",
+		NodeName: "Federated",
+		Chunks:   []*domain.NodeContentChunk{{ID: "f1", Content: "federated chunk"}},
+	}
+	u := newTestLLMUsecase()
+
+	askedNodes, err := u.rerankNodes(t.Context(), newTestRerankModel(t, []float64{0.5}), "question", []*domain.RankedNodeChunks{asked}, 10)
+	require.NoError(t, err)
+	federatedNodes, err := u.rerankNodes(t.Context(), newTestRerankModel(t, []float64{0.9}), "question", []*domain.RankedNodeChunks{federated}, 10)
+	require.NoError(t, err)
+
+	merged := mergeFederatedRankNodes([][]*domain.RankedNodeChunks{askedNodes, federatedNodes}, []float64{1, 2}, 10)
+	require.Len(t, merged, 2)
+
+	// nodes of both kbs share the node id but stay apart
+	assert.Equal(t, "other", merged[0].KBID)
+	assert.Equal(t, "https://other.example.com/node/node", merged[0].ExternalURL())
+	assert.Equal(t, "f1", merged[0].Chunks[0].ID)
+	assert.Equal(t, "kb", merged[1].KBID)
+	assert.Empty(t, merged[1].ExternalURL())
+	assert.Equal(t, "a1", merged[1].Chunks[0].ID)
+}
+
+func TestFederatedKBAccessible(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings domain.AccessSettings
+		hasAuth  bool
+		want     bool
+	}{
+		{name: "open", want: true},
+		{name: "forbidden", settings: domain.AccessSettings{IsForbidden: true}, hasAuth: true, want: false},
+		{name: "simple auth", settings: domain.AccessSettings{SimpleAuth: domain.SimpleAuth{Enabled: true, Password: "secret"}}, hasAuth: true, want: false},
+		{name: "enterprise auth anonymous", settings: domain.AccessSettings{EnterpriseAuth: domain.EnterpriseAuth{Enabled: true}}, want: false},
+		{name: "enterprise auth known user", settings: domain.AccessSettings{EnterpriseAuth: domain.EnterpriseAuth{Enabled: true}}, hasAuth: true, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, federatedKBAccessible(tt.settings, tt.hasAuth))
+		})
+	}
+}
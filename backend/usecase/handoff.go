@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	v1 "github.com/chaitin/panda-wiki/api/kb/v1"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/repo/pg"
+)
+
+const handoffNotifyTimeout = 10 * time.Second
+
+// HandoffUsecase keeps the questions the kb could not answer for the staff,
+// notifying them through the connector of the app
+type HandoffUsecase struct {
+	handoffRepo *pg.HandoffRepository
+	httpClient  *http.Client
+	logger      *log.Logger
+}
+
+func NewHandoffUsecase(handoffRepo *pg.HandoffRepository, logger *log.Logger) *HandoffUsecase {
+	return &HandoffUsecase{
+		handoffRepo: handoffRepo,
+		httpClient:  &http.Client{Timeout: handoffNotifyTimeout},
+		logger:      logger.WithModule("usecase.handoff"),
+	}
+}
+
+// Open saves a pending handoff and notifies the connector in the background
+func (u *HandoffUsecase) Open(ctx context.Context, settings domain.HandoffSettings, handoff *domain.Handoff) error {
+	handoff.ID = uuid.New().String()
+	handoff.Status = domain.HandoffStatusPending
+	handoff.CreatedAt = time.Now()
+	handoff.UpdatedAt = handoff.CreatedAt
+	if err := u.handoffRepo.Create(ctx, handoff); err != nil {
+		return fmt.Errorf("create handoff failed: %w", err)
+	}
+	if settings.Connector != "" && settings.WebhookURL != "" {
+		go u.notify(settings, handoff)
+	}
+	return nil
+}
+
+func (u *HandoffUsecase) notify(settings domain.HandoffSettings, handoff *domain.Handoff) {
+	ctx, cancel := context.WithTimeout(context.Background(), handoffNotifyTimeout)
+	defer cancel()
+	body, err := json.Marshal(domain.HandoffWebhookPayload(settings.Connector, handoff))
+	if err != nil {
+		u.logger.Error("marshal handoff notice failed", log.Error(err))
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, settings.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		u.logger.Error("create handoff notice request failed", log.Error(err))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		u.logger.Warn("send handoff notice failed", log.String("handoff_id", handoff.ID), log.String("connector", string(settings.Connector)), log.Error(err))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		u.logger.Warn("handoff notice rejected", log.String("handoff_id", handoff.ID), log.String("connector", string(settings.Connector)), log.Int("status", resp.StatusCode))
+	}
+}
+
+func (u *HandoffUsecase) GetList(ctx context.Context, req *v1.HandoffListReq) (*v1.HandoffListResp, error) {
+	total, handoffs, err := u.handoffRepo.GetList(ctx, req.KBId, req.Status, req.Offset(), req.Limit())
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewPaginatedResult(handoffs, uint64(total)), nil
+}
+
+func (u *HandoffUsecase) Update(ctx context.Context, req *v1.HandoffUpdateReq, userID string) error {
+	handoff, err := u.handoffRepo.GetByID(ctx, req.KBId, req.ID)
+	if err != nil {
+		return fmt.Errorf("get handoff failed: %w", err)
+	}
+	handoff.Status = req.Status
+	handoff.Note = req.Note
+	handoff.UpdatedAt = time.Now()
+	// back in the queue it is up for anyone to pick up
+	if req.Status == domain.HandoffStatusPending {
+		handoff.Assignee = ""
+	} else {
+		handoff.Assignee = userID
+	}
+	if err := u.handoffRepo.Update(ctx, handoff); err != nil {
+		return fmt.Errorf("update handoff failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/minio/minio-go/v7"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/repo/pg"
+	"github.com/chaitin/panda-wiki/store/s3"
+)
+
+// ImageCaptionUsecase turns the images of a document into text with the
+// analysis-vl model, so retrieval can find what screenshots and diagrams show
+type ImageCaptionUsecase struct {
+	llmUsecase   *LLMUsecase
+	modelUsecase *ModelUsecase
+	captionRepo  *pg.ImageCaptionRepository
+	s3Client     *s3.MinioClient
+	logger       *log.Logger
+}
+
+func NewImageCaptionUsecase(llmUsecase *LLMUsecase, modelUsecase *ModelUsecase, captionRepo *pg.ImageCaptionRepository, s3Client *s3.MinioClient, logger *log.Logger) *ImageCaptionUsecase {
+	return &ImageCaptionUsecase{
+		llmUsecase:   llmUsecase,
+		modelUsecase: modelUsecase,
+		captionRepo:  captionRepo,
+		s3Client:     s3Client,
+		logger:       logger.WithModule("usecase.image_caption"),
+	}
+}
+
+// AddImageCaptions returns the content with the caption of each embedded
+// image inserted after it. It is best effort: without an active vision model
+// or when an image fails the content is indexed without those captions.
+func (u *ImageCaptionUsecase) AddImageCaptions(ctx context.Context, content string) string {
+	imageURLs := domain.ExtractImageURLs(content)
+	if len(imageURLs) == 0 {
+		return content
+	}
+	if len(imageURLs) > domain.MaxCaptionImagesPerNode {
+		imageURLs = imageURLs[:domain.MaxCaptionImagesPerNode]
+	}
+
+	vlModel := &captionModel{}
+	captions := make(map[string]string, len(imageURLs))
+	for _, imageURL := range imageURLs {
+		caption, err := u.captionImage(ctx, imageURL, vlModel)
+		if err != nil {
+			u.logger.Warn("caption image failed", log.String("image", imageURL), log.Error(err))
+			continue
+		}
+		if caption != "" {
+			captions[imageURL] = caption
+		}
+	}
+	return domain.InsertImageCaptions(content, captions)
+}
+
+func (u *ImageCaptionUsecase) captionImage(ctx context.Context, imageURL string, vlModel *captionModel) (string, error) {
+	data, err := u.readImage(ctx, imageURL)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	cached, err := u.captionRepo.GetByHash(ctx, hash)
+	if err != nil {
+		return "", fmt.Errorf("get cached caption failed: %w", err)
+	}
+	if cached != nil {
+		return cached.Caption, nil
+	}
+
+	chatModel, err := u.loadCaptionModel(ctx, vlModel)
+	if err != nil {
+		return "", fmt.Errorf("get analysis-vl model failed: %w", err)
+	}
+	dataURL := fmt.Sprintf("data:%s;base64,%s", http.DetectContentType(data), base64.StdEncoding.EncodeToString(data))
+	answer, err := u.llmUsecase.Generate(ctx, chatModel, []*schema.Message{
+		{
+			Role: schema.User,
+			MultiContent: []schema.ChatMessagePart{
+				{Type: schema.ChatMessagePartTypeText, Text: domain.ImageCaptionPrompt},
+				{Type: schema.ChatMessagePartTypeImageURL, ImageURL: &schema.ChatMessageImageURL{URL: dataURL}},
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("generate caption failed: %w", err)
+	}
+	caption := strings.TrimSpace(u.llmUsecase.trimThinking(answer))
+	if err := u.captionRepo.Create(ctx, &domain.ImageCaption{
+		Hash:    hash,
+		Caption: caption,
+		Model:   vlModel.name,
+	}); err != nil {
+		u.logger.Warn("cache image caption failed", log.String("hash", hash), log.Error(err))
+	}
+	return caption, nil
+}
+
+// captionModel is the vision model of one pass, loaded on the first image
+// that is not cached yet
+type captionModel struct {
+	chatModel model.BaseChatModel
+	name      string
+	err       error
+}
+
+func (u *ImageCaptionUsecase) loadCaptionModel(ctx context.Context, m *captionModel) (model.BaseChatModel, error) {
+	if m.chatModel != nil || m.err != nil {
+		return m.chatModel, m.err
+	}
+	vlModel, err := u.modelUsecase.GetAnalysisVLModel(ctx)
+	if err != nil {
+		m.err = err
+		return nil, err
+	}
+	// the vision model is called as a chat model
+	chatModelInfo := *vlModel
+	chatModelInfo.Type = domain.ModelTypeChat
+	modelkitModel, err := chatModelInfo.ToModelkitModel()
+	if err != nil {
+		m.err = err
+		return nil, err
+	}
+	m.chatModel, m.err = u.llmUsecase.modelkit.GetChatModel(ctx, modelkitModel)
+	m.name = vlModel.Model
+	return m.chatModel, m.err
+}
+
+// readImage reads the image from the static-file bucket
+func (u *ImageCaptionUsecase) readImage(ctx context.Context, imageURL string) ([]byte, error) {
+	parsed, err := url.Parse(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	_, key, ok := strings.Cut(parsed.Path, "/"+domain.Bucket+"/")
+	if !ok || key == "" {
+		return nil, fmt.Errorf("not a static file: %s", imageURL)
+	}
+	object, err := u.s3Client.GetObject(ctx, domain.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+	data, err := io.ReadAll(io.LimitReader(object, domain.MaxCaptionImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > domain.MaxCaptionImageSize {
+		return nil, fmt.Errorf("image is larger than %d bytes", domain.MaxCaptionImageSize)
+	}
+	if !strings.HasPrefix(http.DetectContentType(data), "image/") {
+		return nil, fmt.Errorf("not an image: %s", imageURL)
+	}
+	return data, nil
+}
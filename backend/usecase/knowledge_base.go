@@ -19,28 +19,34 @@ import (
 )
 
 type KnowledgeBaseUsecase struct {
-	repo     *pg.KnowledgeBaseRepository
-	nodeRepo *pg.NodeRepository
-	navRepo  *pg.NavRepository
-	ragRepo  *mq.RAGRepository
-	userRepo *pg.UserRepository
-	rag      rag.RAGService
-	kbCache  *cache.KBRepo
-	logger   *log.Logger
-	config   *config.Config
+	repo         *pg.KnowledgeBaseRepository
+	nodeRepo     *pg.NodeRepository
+	navRepo      *pg.NavRepository
+	ragRepo      *mq.RAGRepository
+	userRepo     *pg.UserRepository
+	settingRepo  *pg.SettingRepo
+	modelUsecase *ModelUsecase
+	rag          rag.RAGService
+	kbCache      *cache.KBRepo
+	answerCache  *cache.AnswerCacheRepo
+	logger       *log.Logger
+	config       *config.Config
 }
 
-func NewKnowledgeBaseUsecase(repo *pg.KnowledgeBaseRepository, nodeRepo *pg.NodeRepository, navRepo *pg.NavRepository, ragRepo *mq.RAGRepository, userRepo *pg.UserRepository, rag rag.RAGService, kbCache *cache.KBRepo, logger *log.Logger, config *config.Config) (*KnowledgeBaseUsecase, error) {
+func NewKnowledgeBaseUsecase(repo *pg.KnowledgeBaseRepository, nodeRepo *pg.NodeRepository, navRepo *pg.NavRepository, ragRepo *mq.RAGRepository, userRepo *pg.UserRepository, settingRepo *pg.SettingRepo, modelUsecase *ModelUsecase, rag rag.RAGService, kbCache *cache.KBRepo, answerCache *cache.AnswerCacheRepo, logger *log.Logger, config *config.Config) (*KnowledgeBaseUsecase, error) {
 	u := &KnowledgeBaseUsecase{
-		repo:     repo,
-		nodeRepo: nodeRepo,
-		navRepo:  navRepo,
-		ragRepo:  ragRepo,
-		userRepo: userRepo,
-		rag:      rag,
-		logger:   logger.WithModule("usecase.knowledge_base"),
-		config:   config,
-		kbCache:  kbCache,
+		repo:         repo,
+		nodeRepo:     nodeRepo,
+		navRepo:      navRepo,
+		ragRepo:      ragRepo,
+		userRepo:     userRepo,
+		settingRepo:  settingRepo,
+		modelUsecase: modelUsecase,
+		rag:          rag,
+		logger:       logger.WithModule("usecase.knowledge_base"),
+		config:       config,
+		kbCache:      kbCache,
+		answerCache:  answerCache,
 	}
 	return u, nil
 }
@@ -192,6 +198,10 @@ func (u *KnowledgeBaseUsecase) CreateKBRelease(ctx context.Context, req *domain.
 	if err := u.repo.CreateKBRelease(ctx, release); err != nil {
 		return "", fmt.Errorf("failed to create kb release: %w", err)
 	}
+	// answers cached before the release may quote outdated content
+	if err := u.answerCache.DeleteKBAnswers(ctx, req.KBID); err != nil {
+		u.logger.Warn("delete cached answers failed", log.String("kb_id", req.KBID), log.Error(err))
+	}
 
 	return release.ID, nil
 }
@@ -296,3 +306,64 @@ func (u *KnowledgeBaseUsecase) KBUserDelete(ctx context.Context, req v1.KBUserDe
 
 	return nil
 }
+
+func (u *KnowledgeBaseUsecase) GetRankFusionSetting(ctx context.Context, kbID string) (*v1.RankFusionSettingResp, error) {
+	setting, err := u.settingRepo.GetRankFusionSetting(ctx, kbID)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.RankFusionSettingResp{
+		VectorWeight:  setting.VectorWeight,
+		KeywordWeight: setting.KeywordWeight,
+	}, nil
+}
+
+func (u *KnowledgeBaseUsecase) UpdateRankFusionSetting(ctx context.Context, req v1.UpdateRankFusionSettingReq) error {
+	if req.VectorWeight == 0 && req.KeywordWeight == 0 {
+		return fmt.Errorf("at least one retrieval weight must be positive")
+	}
+	return u.settingRepo.UpdateRankFusionSetting(ctx, req.KBId, &domain.RankFusionSetting{
+		VectorWeight:  req.VectorWeight,
+		KeywordWeight: req.KeywordWeight,
+	})
+}
+
+func (u *KnowledgeBaseUsecase) GetRerankSetting(ctx context.Context, kbID string) (*v1.RerankSettingResp, error) {
+	setting, err := u.settingRepo.GetRerankSetting(ctx, kbID)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.RerankSettingResp{
+		Enabled: setting.Enabled,
+		TopN:    setting.TopN,
+	}, nil
+}
+
+func (u *KnowledgeBaseUsecase) UpdateRerankSetting(ctx context.Context, req v1.UpdateRerankSettingReq) error {
+	return u.settingRepo.UpdateRerankSetting(ctx, req.KBId, &domain.RerankSetting{
+		Enabled: req.Enabled,
+		TopN:    req.TopN,
+	})
+}
+
+func (u *KnowledgeBaseUsecase) GetChatModelSetting(ctx context.Context, kbID string) (*v1.ChatModelSettingResp, error) {
+	setting, err := u.settingRepo.GetChatModelSetting(ctx, kbID)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ChatModelSettingResp{
+		ModelID:          setting.ModelID,
+		FallbackModelIDs: setting.FallbackModelIDs,
+	}, nil
+}
+
+func (u *KnowledgeBaseUsecase) UpdateChatModelSetting(ctx context.Context, req v1.UpdateChatModelSettingReq) error {
+	setting := &domain.ChatModelSetting{
+		ModelID:          req.ModelID,
+		FallbackModelIDs: req.FallbackModelIDs,
+	}
+	if err := u.modelUsecase.ValidateChatModelSetting(ctx, *setting); err != nil {
+		return err
+	}
+	return u.settingRepo.UpdateChatModelSetting(ctx, req.KBId, setting)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/chaitin/panda-wiki/config"
 	"github.com/chaitin/panda-wiki/domain"
 	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/pkg/rerank"
 	"github.com/chaitin/panda-wiki/repo/pg"
 	"github.com/chaitin/panda-wiki/store/rag"
 	"github.com/chaitin/panda-wiki/utils"
@@ -32,6 +33,12 @@ type LLMUsecase struct {
 	nodeRepo         *pg.NodeRepository
 	modelRepo        *pg.ModelRepository
 	promptRepo       *pg.PromptRepo
+	settingRepo      *pg.SettingRepo
+	appRepo          *pg.AppRepository
+	authRepo         *pg.AuthRepo
+	attachmentRepo   *pg.NodeAttachmentRepository
+	modelUsecase     *ModelUsecase
+	reranker         *rerank.Client
 	config           *config.Config
 	logger           *log.Logger
 	modelkit         *modelkit.ModelKit
@@ -42,7 +49,7 @@ const (
 	summaryMaxChunks       = 4     // max chunks to process for summary
 )
 
-func NewLLMUsecase(config *config.Config, rag rag.RAGService, conversationRepo *pg.ConversationRepository, kbRepo *pg.KnowledgeBaseRepository, nodeRepo *pg.NodeRepository, modelRepo *pg.ModelRepository, promptRepo *pg.PromptRepo, logger *log.Logger) *LLMUsecase {
+func NewLLMUsecase(config *config.Config, rag rag.RAGService, conversationRepo *pg.ConversationRepository, kbRepo *pg.KnowledgeBaseRepository, nodeRepo *pg.NodeRepository, modelRepo *pg.ModelRepository, promptRepo *pg.PromptRepo, settingRepo *pg.SettingRepo, appRepo *pg.AppRepository, authRepo *pg.AuthRepo, attachmentRepo *pg.NodeAttachmentRepository, modelUsecase *ModelUsecase, logger *log.Logger) *LLMUsecase {
 	tiktoken.SetBpeLoader(&utils.Localloader{})
 	modelkit := modelkit.NewModelKit(logger.Logger)
 	return &LLMUsecase{
@@ -53,6 +60,12 @@ func NewLLMUsecase(config *config.Config, rag rag.RAGService, conversationRepo *
 		nodeRepo:         nodeRepo,
 		modelRepo:        modelRepo,
 		promptRepo:       promptRepo,
+		settingRepo:      settingRepo,
+		appRepo:          appRepo,
+		authRepo:         authRepo,
+		attachmentRepo:   attachmentRepo,
+		modelUsecase:     modelUsecase,
+		reranker:         rerank.NewClient(),
 		logger:           logger.WithModule("usecase.llm"),
 		modelkit:         modelkit,
 	}
@@ -63,79 +76,291 @@ func (u *LLMUsecase) BuildConversationMessageWithRAG(
 	conversationID string,
 	kbID string,
 	groupIDs []int,
+	tags []string,
+	retrieval domain.RetrievalSettings,
 	systemPrompt string,
+	authUserID uint,
+	models []*domain.Model,
 ) ([]*schema.Message, []*domain.RankedNodeChunks, error) {
 	messages := make([]*schema.Message, 0)
 	rankedNodes := make([]*domain.RankedNodeChunks, 0)
 
+	historyMessages, summary, err := u.getConversationContext(ctx, conversationID, models)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(historyMessages) > 0 {
+		return u.buildRAGMessages(ctx, ragMessagesRequest{
+			KBID:            kbID,
+			HistoryMessages: historyMessages,
+			Summary:         summary,
+			GroupIDs:        groupIDs,
+			Tags:            tags,
+			Retrieval:       retrieval,
+			SystemPrompt:    systemPrompt,
+			AuthUserID:      authUserID,
+			ContextLimit:    domain.ChatContextLimit(models),
+		})
+	}
+	return messages, rankedNodes, nil
+}
+
+// BuildAgentMessages formats the prompt of the agent mode, which comes
+// without documents as the model looks them up with the kb tools. The
+// history of the conversation is used unless the client supplied one.
+func (u *LLMUsecase) BuildAgentMessages(
+	ctx context.Context,
+	conversationID string,
+	kbID string,
+	history []*schema.Message,
+	question string,
+	systemPrompt string,
+	models []*domain.Model,
+) ([]*schema.Message, error) {
+	historyMessages := slices.Clone(history)
+	summary := ""
+	if len(historyMessages) == 0 {
+		msgs, conversationSummary, err := u.getConversationContext(ctx, conversationID, models)
+		if err != nil {
+			return nil, err
+		}
+		if len(msgs) > 0 {
+			// the question itself is already saved
+			question = msgs[len(msgs)-1].Content
+			historyMessages = msgs[:len(msgs)-1]
+		}
+		summary = conversationSummary
+	}
+	template := prompt.FromMessages(schema.GoTemplate,
+		schema.SystemMessage(u.getSystemPrompt(ctx, kbID, systemPrompt)+"\n\n"+domain.AgentSystemPrompt),
+		schema.UserMessage(domain.AgentQuestionFormatter),
+	)
+	formattedMessages, err := template.Format(ctx, map[string]any{
+		"CurrentDate": time.Now().Format("2006-01-02"),
+		"Question":    question,
+	})
+	if err != nil {
+		u.logger.Error("format messages failed", log.Error(err))
+		return nil, errors.New("format messages failed")
+	}
+	if summary != "" {
+		formattedMessages[0].Content += domain.ConversationSummaryHeader + summary
+	}
+	historyMessages, _, err = fitContextBudget(formattedMessages[0].Content, formattedMessages[1].Content, historyMessages, domain.ChatContextLimit(models))
+	if err != nil {
+		u.logger.Warn("fit context budget failed", log.Error(err))
+	}
+	return slices.Insert(formattedMessages, 1, historyMessages...), nil
+}
+
+// getConversationContext returns the history of the conversation, the last
+// message being the question asked, and the summary of the turns before it.
+// Once the history outgrows its share of the context limit the older turns
+// are rolled into the summary, which is stored on the conversation.
+func (u *LLMUsecase) getConversationContext(ctx context.Context, conversationID string, models []*domain.Model) ([]*schema.Message, string, error) {
 	msgs, err := u.conversationRepo.GetConversationMessagesByID(ctx, conversationID)
 	if err != nil {
 		u.logger.Error("get conversation messages failed", log.Error(err))
-		return nil, nil, errors.New("get conversation messages failed")
-	}
-	if len(msgs) > 0 {
-		historyMessages := make([]*schema.Message, 0)
-		for _, msg := range msgs {
-			switch msg.Role {
-			case schema.Assistant:
-				historyMessages = append(historyMessages, schema.AssistantMessage(msg.Content, nil))
-			case schema.User:
-				content := u.formatMessageWithImages(msg.Content, msg.ImagePaths)
-				historyMessages = append(historyMessages, schema.UserMessage(content))
-			default:
-				continue
-			}
+		return nil, "", errors.New("get conversation messages failed")
+	}
+	conversation, err := u.conversationRepo.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		u.logger.Warn("get conversation failed, the history is not summarized", log.Error(err))
+		conversation = &domain.Conversation{}
+	}
+	if conversation.SummaryMessageID != "" {
+		if i := slices.IndexFunc(msgs, func(msg *domain.ConversationMessage) bool {
+			return msg.ID == conversation.SummaryMessageID
+		}); i >= 0 {
+			msgs = msgs[i+1:]
 		}
-		if len(historyMessages) > 0 {
-			question := historyMessages[len(historyMessages)-1].Content
-			var rewrittenQuery string
-			if systemPrompt == "" {
-				if settingPrompt, err := u.promptRepo.GetPromptContent(ctx, kbID); err != nil {
-					u.logger.Error("get prompt from settings failed", log.Error(err))
-				} else {
-					if settingPrompt != "" {
-						systemPrompt = settingPrompt
-					} else {
-						systemPrompt = domain.SystemDefaultPrompt
-					}
-				}
-			}
+	}
+	historyMessages, messageIDs := u.toHistoryMessages(msgs)
+	summary := conversation.Summary
 
-			template := prompt.FromMessages(schema.GoTemplate,
-				schema.SystemMessage(systemPrompt),
-				schema.UserMessage(domain.UserQuestionFormatter),
-			)
-			kb, err := u.kbRepo.GetKnowledgeBaseByID(ctx, kbID)
-			if err != nil {
-				u.logger.Error("get kb failed", log.Error(err))
-				return nil, nil, errors.New("get kb failed")
-			}
-			rewrittenQuery, rankedNodes, err = u.GetRankNodes(ctx, GetRankNodesRequest{
-				DatasetID:           kb.DatasetID,
-				Question:            question,
-				GroupIDs:            groupIDs,
-				SimilarityThreshold: 0.2,
-				HistoryMessages:     historyMessages[:len(historyMessages)-1],
-			})
-			if err != nil {
-				u.logger.Error("get rank nodes failed", log.Error(err))
-				return nil, nil, errors.New("get rank nodes failed")
-			}
-			documents := domain.FormatNodeChunks(rankedNodes, kb.AccessSettings.BaseURL)
-			u.logger.Debug("documents", log.String("documents", documents))
-
-			formattedMessages, err := template.Format(ctx, map[string]any{
-				"CurrentDate": time.Now().Format("2006-01-02"),
-				"Question":    rewrittenQuery,
-				"Documents":   documents,
-			})
-			if err != nil {
-				u.logger.Error("format messages failed", log.Error(err))
-				return nil, nil, errors.New("format messages failed")
-			}
-			messages = slices.Insert(formattedMessages, 1, historyMessages[:len(historyMessages)-1]...)
+	contextLimit := domain.ChatContextLimit(models)
+	if len(historyMessages) < 2 || contextLimit <= 0 {
+		return historyMessages, summary, nil
+	}
+	encoding, err := tiktoken.GetEncoding("cl100k_base")
+	if err != nil {
+		u.logger.Warn("get encoding failed, the history is not summarized", log.Error(err))
+		return historyMessages, summary, nil
+	}
+	history := historyMessages[:len(historyMessages)-1]
+	tokens := messageTokens(encoding, history)
+	historyLimit := domain.HistoryContextLimit(contextLimit) - countTokens(encoding, summary)
+	if lo.Sum(tokens) <= historyLimit {
+		return historyMessages, summary, nil
+	}
+	// roll the older turns into the summary, leaving room for the next turns
+	start := domain.KeepRecentHistory(history, tokens, historyLimit/2)
+	if start == 0 {
+		return historyMessages, summary, nil
+	}
+	newSummary, err := u.summarizeConversation(ctx, models[0], summary, history[:start])
+	if err != nil {
+		// the older turns are dropped by the context budget instead
+		u.logger.Warn("summarize conversation failed", log.String("conversation_id", conversationID), log.Error(err))
+		return historyMessages, summary, nil
+	}
+	if err := u.conversationRepo.UpdateConversationSummary(ctx, conversationID, newSummary, messageIDs[start-1]); err != nil {
+		u.logger.Warn("update conversation summary failed", log.String("conversation_id", conversationID), log.Error(err))
+	}
+	return historyMessages[start:], newSummary, nil
+}
+
+// toHistoryMessages converts the user and assistant messages of a
+// conversation, returned along with their ids
+func (u *LLMUsecase) toHistoryMessages(msgs []*domain.ConversationMessage) ([]*schema.Message, []string) {
+	historyMessages := make([]*schema.Message, 0, len(msgs))
+	messageIDs := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		switch msg.Role {
+		case schema.Assistant:
+			historyMessages = append(historyMessages, schema.AssistantMessage(msg.Content, nil))
+		case schema.User:
+			content := u.formatMessageWithImages(msg.Content, msg.ImagePaths)
+			historyMessages = append(historyMessages, schema.UserMessage(content))
+		default:
+			continue
 		}
+		messageIDs = append(messageIDs, msg.ID)
+	}
+	return historyMessages, messageIDs
+}
+
+// summarizeConversation merges the messages into the running summary of the
+// conversation
+func (u *LLMUsecase) summarizeConversation(ctx context.Context, chatModel *domain.Model, summary string, messages []*schema.Message) (string, error) {
+	modelkitModel, err := chatModel.ToModelkitModel()
+	if err != nil {
+		return "", err
+	}
+	cm, err := u.modelkit.GetChatModel(ctx, modelkitModel)
+	if err != nil {
+		return "", err
+	}
+	var conversation strings.Builder
+	for _, msg := range messages {
+		fmt.Fprintf(&conversation, "%s: %s\n", msg.Role, msg.Content)
 	}
+	if summary == "" {
+		summary = "无"
+	}
+	template := prompt.FromMessages(schema.GoTemplate,
+		schema.SystemMessage(domain.ConversationSummaryPrompt),
+		schema.UserMessage(domain.ConversationSummaryUserFormatter),
+	)
+	input, err := template.Format(ctx, map[string]any{
+		"Summary":  summary,
+		"Messages": conversation.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	result, err := u.Generate(ctx, cm, input)
+	if err != nil {
+		return "", err
+	}
+	result = strings.TrimSpace(u.trimThinking(result))
+	if result == "" {
+		return "", errors.New("empty summary")
+	}
+	return result, nil
+}
+
+// getSystemPrompt returns the prompt given, or the prompt of the kb settings
+// falling back to the default one
+func (u *LLMUsecase) getSystemPrompt(ctx context.Context, kbID, systemPrompt string) string {
+	if systemPrompt != "" {
+		return systemPrompt
+	}
+	settingPrompt, err := u.promptRepo.GetPromptContent(ctx, kbID)
+	if err != nil {
+		u.logger.Error("get prompt from settings failed", log.Error(err))
+	}
+	if settingPrompt != "" {
+		return settingPrompt
+	}
+	return domain.SystemDefaultPrompt
+}
+
+type ragMessagesRequest struct {
+	KBID            string
+	HistoryMessages []*schema.Message // the question last
+	Summary         string            // summary of the turns before the history
+	GroupIDs        []int
+	Tags            []string
+	Retrieval       domain.RetrievalSettings
+	SystemPrompt    string
+	AuthUserID      uint
+	ContextLimit    int // tokens the prompt may take, 0 means unlimited
+	Trace           *RetrievalTrace
+}
+
+// buildRAGMessages retrieves documents for the last message of the history
+// and formats the prompt, the earlier messages are kept as chat history. The
+// history is trimmed to its share of the context limit and the documents to
+// what is left of it.
+func (u *LLMUsecase) buildRAGMessages(ctx context.Context, req ragMessagesRequest) ([]*schema.Message, []*domain.RankedNodeChunks, error) {
+	question := req.HistoryMessages[len(req.HistoryMessages)-1].Content
+	systemPrompt := u.getSystemPrompt(ctx, req.KBID, req.SystemPrompt)
+	template := prompt.FromMessages(schema.GoTemplate,
+		schema.SystemMessage(systemPrompt),
+		schema.UserMessage(domain.UserQuestionFormatter),
+	)
+	if req.Summary != "" {
+		systemPrompt += domain.ConversationSummaryHeader + req.Summary
+	}
+	historyMessages, documentTokens, err := fitContextBudget(systemPrompt, domain.UserQuestionFormatter+question, req.HistoryMessages[:len(req.HistoryMessages)-1], req.ContextLimit)
+	if err != nil {
+		u.logger.Warn("fit context budget failed", log.Error(err))
+	}
+	maxContextTokens := req.Retrieval.MaxContextTokens
+	if documentTokens > 0 && (maxContextTokens <= 0 || documentTokens < maxContextTokens) {
+		maxContextTokens = documentTokens
+	}
+	kb, err := u.kbRepo.GetKnowledgeBaseByID(ctx, req.KBID)
+	if err != nil {
+		u.logger.Error("get kb failed", log.Error(err))
+		return nil, nil, errors.New("get kb failed")
+	}
+	rewrittenQuery, rankedNodes, err := u.GetFederatedRankNodes(ctx, GetRankNodesRequest{
+		KBID:                req.KBID,
+		DatasetID:           kb.DatasetID,
+		Question:            question,
+		GroupIDs:            req.GroupIDs,
+		Tags:                req.Tags,
+		TopK:                req.Retrieval.GetTopK(),
+		SimilarityThreshold: req.Retrieval.GetSimilarityThreshold(),
+		HistoryMessages:     historyMessages,
+		MaxChunksPerDoc:     req.Retrieval.MaxChunksPerDoc,
+		MaxContextTokens:    maxContextTokens,
+		Trace:               req.Trace,
+	}, req.Retrieval.FederatedKBs, req.AuthUserID)
+	if err != nil {
+		u.logger.Error("get rank nodes failed", log.Error(err))
+		return nil, nil, errors.New("get rank nodes failed")
+	}
+	domain.AssignCitationIDs(rankedNodes)
+	documents := domain.FormatNodeChunks(rankedNodes, kb.AccessSettings.BaseURL)
+	u.logger.Debug("documents", log.String("documents", documents))
+
+	formattedMessages, err := template.Format(ctx, map[string]any{
+		"CurrentDate": time.Now().Format("2006-01-02"),
+		"Question":    rewrittenQuery,
+		"Documents":   documents,
+	})
+	if err != nil {
+		u.logger.Error("format messages failed", log.Error(err))
+		return nil, nil, errors.New("format messages failed")
+	}
+	// the summary is added after formatting as it may contain template syntax
+	if req.Summary != "" {
+		formattedMessages[0].Content += domain.ConversationSummaryHeader + req.Summary
+	}
+	messages := slices.Insert(formattedMessages, 1, historyMessages...)
 	return messages, rankedNodes, nil
 }
 
@@ -146,12 +371,27 @@ func (u *LLMUsecase) ChatWithAgent(
 	usage *schema.TokenUsage,
 	onChunk func(ctx context.Context, dataType, chunk string) error,
 ) error {
-	resp, err := chatModel.Stream(ctx, messages)
+	_, err := u.StreamChat(ctx, chatModel, messages, usage, onChunk)
+	return err
+}
+
+// StreamChat streams the answer to onChunk and returns the tool calls of the
+// model, which only happen when tools are passed in opts
+func (u *LLMUsecase) StreamChat(
+	ctx context.Context,
+	chatModel model.BaseChatModel,
+	messages []*schema.Message,
+	usage *schema.TokenUsage,
+	onChunk func(ctx context.Context, dataType, chunk string) error,
+	opts ...model.Option,
+) ([]schema.ToolCall, error) {
+	resp, err := chatModel.Stream(ctx, messages, opts...)
 	if err != nil {
-		return fmt.Errorf("stream failed: %w", err)
+		return nil, fmt.Errorf("stream failed: %w", err)
 	}
 	firstReasoning := false
 	firstData := false
+	toolCallChunks := make([]*schema.Message, 0)
 
 	for {
 		msg, err := resp.Recv()
@@ -159,7 +399,16 @@ func (u *LLMUsecase) ChatWithAgent(
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("recv failed: %w", err)
+			return nil, fmt.Errorf("recv failed: %w", err)
+		}
+		if len(msg.ToolCalls) > 0 {
+			toolCallChunks = append(toolCallChunks, &schema.Message{Role: schema.Assistant, ToolCalls: msg.ToolCalls})
+			if msg.ResponseMeta != nil && msg.ResponseMeta.Usage != nil {
+				*usage = *msg.ResponseMeta.Usage
+			}
+			if msg.Content == "" {
+				continue
+			}
 		}
 		reasoning, ok := deepseek.GetReasoningContent(msg)
 		if ok {
@@ -168,7 +417,7 @@ func (u *LLMUsecase) ChatWithAgent(
 				reasoning = "<think>" + reasoning
 			}
 			if err := onChunk(ctx, "data", reasoning); err != nil {
-				return fmt.Errorf("on chunk reasoning: %w", err)
+				return nil, fmt.Errorf("on chunk reasoning: %w", err)
 			}
 			continue
 		}
@@ -176,12 +425,12 @@ func (u *LLMUsecase) ChatWithAgent(
 			firstData = true
 			msg.Content = "</think>\n" + msg.Content
 			if err := onChunk(ctx, "data", msg.Content); err != nil {
-				return fmt.Errorf("on chunk data: %w", err)
+				return nil, fmt.Errorf("on chunk data: %w", err)
 			}
 			continue
 		}
 		if err := onChunk(ctx, "data", msg.Content); err != nil {
-			return fmt.Errorf("on chunk data: %w", err)
+			return nil, fmt.Errorf("on chunk data: %w", err)
 		}
 
 		// set to usage
@@ -190,7 +439,15 @@ func (u *LLMUsecase) ChatWithAgent(
 		}
 	}
 
-	return nil
+	if len(toolCallChunks) == 0 {
+		return nil, nil
+	}
+	// tool calls are streamed in pieces, joined by their index
+	toolCallMsg, err := schema.ConcatMessages(toolCallChunks)
+	if err != nil {
+		return nil, fmt.Errorf("concat tool calls failed: %w", err)
+	}
+	return toolCallMsg.ToolCalls, nil
 }
 
 func (u *LLMUsecase) Generate(
@@ -455,63 +712,185 @@ func (u *LLMUsecase) SplitByTokenLimit(text string, maxTokens int) ([]string, er
 }
 
 type GetRankNodesRequest struct {
+	KBID                string
 	DatasetID           string
 	Question            string
 	GroupIDs            []int
+	Tags                []string
+	TopK                int // 0 means the provider default
 	SimilarityThreshold float64
 	HistoryMessages     []*schema.Message
 	MaxChunksPerDoc     int
+	MaxContextTokens    int // 0 means unlimited
+
+	Trace *RetrievalTrace // collects intermediate results when set
 }
 
 func (u *LLMUsecase) GetRankNodes(ctx context.Context, req GetRankNodesRequest) (string, []*domain.RankedNodeChunks, error) {
-	var rankedNodes []*domain.RankedNodeChunks
-	// get related documents from raglite
-	rewrittenQuery, records, err := u.rag.QueryRecords(ctx, &rag.QueryRecordsRequest{
-		DatasetID:           req.DatasetID,
-		Query:               req.Question,
-		GroupIDs:            req.GroupIDs,
-		SimilarityThreshold: req.SimilarityThreshold,
-		HistoryMsgs:         req.HistoryMessages,
-		MaxChunksPerDoc:     req.MaxChunksPerDoc,
-	})
-	if err != nil {
-		return "", nil, fmt.Errorf("get records from raglite failed: %w", err)
+	fusion := &domain.DefaultRankFusionSetting
+	if req.KBID != "" {
+		setting, err := u.settingRepo.GetRankFusionSetting(ctx, req.KBID)
+		if err != nil {
+			u.logger.Warn("get rank fusion setting failed, use default", log.String("kb_id", req.KBID), log.Error(err))
+		} else {
+			fusion = setting
+		}
 	}
-	u.logger.Info("get related documents from raglite", log.Any("record_count", len(records)))
-	rankedNodesMap := make(map[string]*domain.RankedNodeChunks)
+
+	// over-fetch candidates when they are going to be reranked
+	rerankModel, rerankTopN := u.getRerankModel(ctx, req.KBID)
+	topK, maxDocs := req.TopK, rankFusionMaxDocs
+	if rerankModel != nil {
+		topK, maxDocs = max(req.TopK, rerankCandidateTopK), rerankCandidateTopK
+	}
+
+	rewrittenQuery := req.Question
+	var records []*domain.NodeContentChunk
+	if fusion.VectorWeight > 0 {
+		// get related documents from raglite
+		var err error
+		rewrittenQuery, records, err = u.rag.QueryRecords(ctx, &rag.QueryRecordsRequest{
+			DatasetID:           req.DatasetID,
+			Query:               req.Question,
+			GroupIDs:            req.GroupIDs,
+			Tags:                req.Tags,
+			TopK:                topK,
+			SimilarityThreshold: req.SimilarityThreshold,
+			HistoryMsgs:         req.HistoryMessages,
+			MaxChunksPerDoc:     req.MaxChunksPerDoc,
+		})
+		if err != nil {
+			return "", nil, fmt.Errorf("get records from raglite failed: %w", err)
+		}
+		u.logger.Info("get related documents from raglite", log.Any("record_count", len(records)))
+	}
+
+	var keywordHits []*pg.NodeReleaseKeywordHit
+	if fusion.KeywordWeight > 0 && req.KBID != "" {
+		hits, err := u.nodeRepo.SearchNodeReleasesByKeyword(ctx, req.KBID, rewrittenQuery, req.GroupIDs, req.Tags, keywordCandidateLimit)
+		if err != nil {
+			// keyword leg is best effort, keep the vector results
+			u.logger.Warn("keyword search failed", log.String("kb_id", req.KBID), log.Error(err))
+		} else {
+			keywordHits = hits
+			u.logger.Info("get related documents from keyword search", log.Any("hit_count", len(hits)))
+		}
+	}
+
+	docIDs, docChunks, fusionScores := fuseRankedChunks(req.KBID, records, keywordHits, rewrittenQuery, fusion, maxDocs)
+	if req.Trace != nil {
+		req.Trace.RewrittenQuery = rewrittenQuery
+		req.Trace.VectorChunks = records
+		req.Trace.KeywordHits = keywordHits
+		req.Trace.FusionScores = fusionScores
+	}
+	if len(docIDs) == 0 {
+		return rewrittenQuery, nil, nil
+	}
+	u.logger.Info("node chunk doc ids", log.Any("docIDs", docIDs))
 	// get raw node by doc_id
-	if len(records) > 0 {
-		docIDs := lo.Uniq(lo.Map(records, func(item *domain.NodeContentChunk, _ int) string {
-			return item.DocID
-		}))
-		u.logger.Info("node chunk doc ids", log.Any("docIDs", docIDs))
-		docIDNode, err := u.nodeRepo.GetNodeReleasesWithPathsByDocIDs(ctx, docIDs)
+	docIDNode, err := u.nodeRepo.GetNodeReleasesWithPathsByDocIDs(ctx, docIDs)
+	if err != nil {
+		return "", nil, fmt.Errorf("get nodes by ids failed: %w", err)
+	}
+	u.logger.Info("get node release by doc ids", log.Any("docIDNode", lo.Keys(docIDNode)))
+	attachments, attachmentNodes, err := u.getAttachmentNodes(ctx, lo.Filter(docIDs, func(docID string, _ int) bool {
+		_, ok := docIDNode[docID]
+		return !ok
+	}))
+	if err != nil {
+		return "", nil, err
+	}
+	var rankedNodes []*domain.RankedNodeChunks
+	for _, docID := range docIDs {
+		docNode, ok := docIDNode[docID]
+		var attachmentName string
+		if !ok {
+			// chunks of a file linked from the node
+			attachment, ok := attachments[docID]
+			if !ok {
+				continue
+			}
+			if docNode, ok = attachmentNodes[attachment.NodeID]; !ok {
+				continue
+			}
+			attachmentName = attachment.FileName
+		}
+		rankedNodes = append(rankedNodes, &domain.RankedNodeChunks{
+			KBID:          req.KBID,
+			NodeID:        docNode.NodeID,
+			NodeName:      docNode.Name,
+			NodeSummary:   docNode.Meta.Summary,
+			NodeEmoji:     docNode.Meta.Emoji,
+			NodePathNames: docNode.PathNames,
+			Attachment:    attachmentName,
+			Chunks:        docChunks[docID],
+		})
+	}
+	if rerankModel != nil {
+		reranked, err := u.rerankNodes(ctx, rerankModel, rewrittenQuery, rankedNodes, rerankTopN)
 		if err != nil {
-			return "", nil, fmt.Errorf("get nodes by ids failed: %w", err)
+			// fall back to the fused order
+			u.logger.Warn("rerank chunks failed", log.String("kb_id", req.KBID), log.Error(err))
+			rankedNodes = rankedNodes[:min(len(rankedNodes), rankFusionMaxDocs)]
+		} else {
+			rankedNodes = reranked
 		}
-		u.logger.Info("get node release by doc ids", log.Any("docIDNode", lo.Keys(docIDNode)))
-		for _, record := range records {
-			if nodeChunk, ok := rankedNodesMap[record.DocID]; !ok {
-				if docNode, ok := docIDNode[record.DocID]; ok {
-					rankNodeChunk := &domain.RankedNodeChunks{
-						NodeID:        docNode.NodeID,
-						NodeName:      docNode.Name,
-						NodeSummary:   docNode.Meta.Summary,
-						NodeEmoji:     docNode.Meta.Emoji,
-						NodePathNames: docNode.PathNames,
-						Chunks:        []*domain.NodeContentChunk{record},
-					}
-					rankedNodes = append(rankedNodes, rankNodeChunk)
-					rankedNodesMap[record.DocID] = rankNodeChunk
-				}
-			} else {
-				nodeChunk.Chunks = append(nodeChunk.Chunks, record)
+	}
+	if req.MaxContextTokens > 0 {
+		limited, dropped, err := limitContextTokens(rankedNodes, req.MaxContextTokens)
+		if err != nil {
+			u.logger.Warn("limit context tokens failed", log.String("kb_id", req.KBID), log.Error(err))
+		} else {
+			rankedNodes = limited
+			if req.Trace != nil {
+				req.Trace.BudgetDropped = dropped
 			}
 		}
 	}
 	return rewrittenQuery, rankedNodes, nil
 }
 
+// getAttachmentNodes returns the attachments indexed as the docs and the
+// latest releases of the nodes linking them, keyed by node id
+func (u *LLMUsecase) getAttachmentNodes(ctx context.Context, docIDs []string) (map[string]*domain.NodeAttachment, map[string]*pg.NodeReleaseWithPath, error) {
+	if len(docIDs) == 0 {
+		return nil, nil, nil
+	}
+	attachments, err := u.attachmentRepo.GetByDocIDs(ctx, docIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get attachments by doc ids failed: %w", err)
+	}
+	if len(attachments) == 0 {
+		return attachments, nil, nil
+	}
+	nodeDocIDs := make([]string, 0, len(attachments))
+	for kbID, kbAttachments := range lo.GroupBy(lo.Values(attachments), func(attachment *domain.NodeAttachment) string {
+		return attachment.KBID
+	}) {
+		releases, err := u.nodeRepo.GetLatestNodeReleaseByNodeIDs(ctx, kbID, lo.Uniq(lo.Map(kbAttachments, func(attachment *domain.NodeAttachment, _ int) string {
+			return attachment.NodeID
+		})))
+		if err != nil {
+			return nil, nil, fmt.Errorf("get latest node releases failed: %w", err)
+		}
+		for _, release := range releases {
+			if release.DocID != "" {
+				nodeDocIDs = append(nodeDocIDs, release.DocID)
+			}
+		}
+	}
+	docNodes, err := u.nodeRepo.GetNodeReleasesWithPathsByDocIDs(ctx, nodeDocIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get nodes by ids failed: %w", err)
+	}
+	nodes := make(map[string]*pg.NodeReleaseWithPath, len(docNodes))
+	for _, node := range docNodes {
+		nodes[node.NodeID] = node
+	}
+	return attachments, nodes, nil
+}
+
 // formatMessageWithImages converts image paths to markdown format and appends to message
 func (u *LLMUsecase) formatMessageWithImages(message string, imagePaths []string) string {
 	if len(imagePaths) == 0 {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/cloudwego/eino/schema"
+	"github.com/samber/lo"
 
 	modelkitDomain "github.com/chaitin/ModelKit/v2/domain"
 	modelkit "github.com/chaitin/ModelKit/v2/usecase"
@@ -28,10 +29,12 @@ type ModelUsecase struct {
 	ragStore          rag.RAGService
 	kbRepo            *pg.KnowledgeBaseRepository
 	systemSettingRepo *pg.SystemSettingRepo
+	kbSettingRepo     *pg.SettingRepo
+	reindexUsecase    *ReindexUsecase
 	modelkit          *modelkit.ModelKit
 }
 
-func NewModelUsecase(modelRepo *pg.ModelRepository, nodeRepo *pg.NodeRepository, ragRepo *mq.RAGRepository, ragStore rag.RAGService, logger *log.Logger, config *config.Config, kbRepo *pg.KnowledgeBaseRepository, settingRepo *pg.SystemSettingRepo) *ModelUsecase {
+func NewModelUsecase(modelRepo *pg.ModelRepository, nodeRepo *pg.NodeRepository, ragRepo *mq.RAGRepository, ragStore rag.RAGService, logger *log.Logger, config *config.Config, kbRepo *pg.KnowledgeBaseRepository, settingRepo *pg.SystemSettingRepo, kbSettingRepo *pg.SettingRepo, reindexUsecase *ReindexUsecase) *ModelUsecase {
 	modelkit := modelkit.NewModelKit(logger.Logger)
 	u := &ModelUsecase{
 		modelRepo:         modelRepo,
@@ -42,6 +45,8 @@ func NewModelUsecase(modelRepo *pg.ModelRepository, nodeRepo *pg.NodeRepository,
 		ragStore:          ragStore,
 		kbRepo:            kbRepo,
 		systemSettingRepo: settingRepo,
+		kbSettingRepo:     kbSettingRepo,
+		reindexUsecase:    reindexUsecase,
 		modelkit:          modelkit,
 	}
 	return u
@@ -87,27 +92,26 @@ func (u *ModelUsecase) TriggerUpsertRecords(ctx context.Context) error {
 			return fmt.Errorf("update knowledge base dataset id failed: %w", err)
 		}
 	}
-	// traverse all nodes
-	err = u.nodeRepo.TraverseNodesByCursor(ctx, func(nodeRelease *domain.NodeRelease) error {
-		// async upsert vector content via mq
-		nodeContentVectorRequests := []*domain.NodeReleaseVectorRequest{
-			{
-				KBID:          nodeRelease.KBID,
-				NodeReleaseID: nodeRelease.ID,
-				Action:        "upsert",
-			},
-		}
-		if err := u.ragRepo.AsyncUpdateNodeReleaseVector(ctx, nodeContentVectorRequests); err != nil {
-			return err
-		}
-		return nil
-	})
-	if err != nil {
+	// re-index all nodes by a resumable job
+	if _, err := u.reindexUsecase.CreateJob(ctx, u.embeddingModelName(ctx)); err != nil {
 		return err
 	}
 	return nil
 }
 
+// embeddingModelName returns the name of the embedding model of the current model mode
+func (u *ModelUsecase) embeddingModelName(ctx context.Context) string {
+	modelModeSetting, err := u.GetModelModeSetting(ctx)
+	if err == nil && modelModeSetting.Mode == consts.ModelSettingModeAuto {
+		return consts.GetAutoModeDefaultModel(string(domain.ModelTypeEmbedding))
+	}
+	model, err := u.modelRepo.GetModelByType(ctx, domain.ModelTypeEmbedding)
+	if err != nil || model == nil {
+		return ""
+	}
+	return model.Model
+}
+
 func (u *ModelUsecase) Update(ctx context.Context, req *domain.UpdateModelReq) error {
 	var updatedEmbeddingModel bool
 	if req.Type == domain.ModelTypeEmbedding {
@@ -120,8 +124,19 @@ func (u *ModelUsecase) Update(ctx context.Context, req *domain.UpdateModelReq) e
 	if err != nil {
 		return err
 	}
-	if err := u.ragStore.UpsertModel(ctx, model); err != nil {
-		return err
+	// the rag store keeps one model per type, only the default chat model is synced
+	syncRAGModel := true
+	if model.Type == domain.ModelTypeChat {
+		defaultModel, err := u.modelRepo.GetChatModel(ctx)
+		if err != nil {
+			return err
+		}
+		syncRAGModel = defaultModel.ID == model.ID
+	}
+	if syncRAGModel {
+		if err := u.ragStore.UpsertModel(ctx, model); err != nil {
+			return err
+		}
 	}
 	// 模型更新成功后，如果更新嵌入模型，则触发记录更新
 	if updatedEmbeddingModel {
@@ -163,7 +178,129 @@ func (u *ModelUsecase) GetChatModel(ctx context.Context) (*domain.Model, error)
 	return model, nil
 }
 
+// GetChatModels returns the chat models answering the questions of a kb in
+// the order to try them: the model of the app or else of the kb, then the
+// fallbacks. The default chat model answers when no model is routed to or the
+// routed one is gone. Auto mode has a single chat model.
+func (u *ModelUsecase) GetChatModels(ctx context.Context, kbID string, appSetting domain.ChatModelSetting) ([]*domain.Model, error) {
+	modelModeSetting, err := u.GetModelModeSetting(ctx)
+	if err == nil && modelModeSetting.Mode == consts.ModelSettingModeAuto && modelModeSetting.AutoModeAPIKey != "" {
+		model, err := u.GetChatModel(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []*domain.Model{model}, nil
+	}
+	setting, err := u.kbSettingRepo.GetChatModelSetting(ctx, kbID)
+	if err != nil {
+		u.logger.Warn("get chat model setting failed, use default chat model", log.String("kb_id", kbID), log.Error(err))
+		setting = &domain.ChatModelSetting{}
+	}
+	routing := setting.Override(appSetting)
+	modelIDs := routing.ModelIDs()
+	modelMap, err := u.modelRepo.GetModelsByIDs(ctx, modelIDs)
+	if err != nil {
+		return nil, err
+	}
+	models := make([]*domain.Model, 0, len(modelIDs)+1)
+	for _, id := range modelIDs {
+		model, ok := modelMap[id]
+		if !ok || model.Type != domain.ModelTypeChat {
+			u.logger.Warn("routed chat model not found", log.String("kb_id", kbID), log.String("model_id", id))
+			continue
+		}
+		models = append(models, model)
+	}
+	if len(models) > 0 && routing.ModelID != "" && models[0].ID == routing.ModelID {
+		return models, nil
+	}
+	defaultModel, err := u.modelRepo.GetChatModel(ctx)
+	if err != nil {
+		if len(models) > 0 {
+			return models, nil
+		}
+		return nil, err
+	}
+	models = lo.Filter(models, func(model *domain.Model, _ int) bool { return model.ID != defaultModel.ID })
+	return append([]*domain.Model{defaultModel}, models...), nil
+}
+
+// ValidateChatModelSetting checks the routed models are chat models
+func (u *ModelUsecase) ValidateChatModelSetting(ctx context.Context, setting domain.ChatModelSetting) error {
+	if len(setting.FallbackModelIDs) > domain.MaxChatModelFallbacks {
+		return fmt.Errorf("at most %d fallback models are allowed", domain.MaxChatModelFallbacks)
+	}
+	modelIDs := setting.ModelIDs()
+	modelMap, err := u.modelRepo.GetModelsByIDs(ctx, modelIDs)
+	if err != nil {
+		return err
+	}
+	for _, id := range modelIDs {
+		model, ok := modelMap[id]
+		if !ok {
+			return fmt.Errorf("model %s not found", id)
+		}
+		if model.Type != domain.ModelTypeChat {
+			return fmt.Errorf("model %s is not a chat model", model.Model)
+		}
+	}
+	return nil
+}
+
+// GetRerankModel returns the rerank model of the current model mode
+func (u *ModelUsecase) GetRerankModel(ctx context.Context) (*domain.Model, error) {
+	modelModeSetting, err := u.GetModelModeSetting(ctx)
+	if err == nil && modelModeSetting.Mode == consts.ModelSettingModeAuto && modelModeSetting.AutoModeAPIKey != "" {
+		provider, baseURL := autoModeProviderAndBaseURL(modelModeSetting.AutoModeProvider)
+		return &domain.Model{
+			Model:    consts.GetAutoModeDefaultModel(string(domain.ModelTypeRerank)),
+			Type:     domain.ModelTypeRerank,
+			IsActive: true,
+			BaseURL:  baseURL,
+			APIKey:   modelModeSetting.AutoModeAPIKey,
+			Provider: provider,
+		}, nil
+	}
+	model, err := u.modelRepo.GetModelByType(ctx, domain.ModelTypeRerank)
+	if err != nil {
+		return nil, err
+	}
+	if !model.IsActive {
+		return nil, fmt.Errorf("rerank model is not active")
+	}
+	return model, nil
+}
+
+// GetAnalysisVLModel returns the vision model used to read document images
+func (u *ModelUsecase) GetAnalysisVLModel(ctx context.Context) (*domain.Model, error) {
+	modelModeSetting, err := u.GetModelModeSetting(ctx)
+	if err == nil && modelModeSetting.Mode == consts.ModelSettingModeAuto && modelModeSetting.AutoModeAPIKey != "" {
+		provider, baseURL := autoModeProviderAndBaseURL(modelModeSetting.AutoModeProvider)
+		return &domain.Model{
+			Model:    consts.GetAutoModeDefaultModel(string(domain.ModelTypeAnalysisVL)),
+			Type:     domain.ModelTypeAnalysisVL,
+			IsActive: true,
+			BaseURL:  baseURL,
+			APIKey:   modelModeSetting.AutoModeAPIKey,
+			Provider: provider,
+		}, nil
+	}
+	model, err := u.modelRepo.GetModelByType(ctx, domain.ModelTypeAnalysisVL)
+	if err != nil {
+		return nil, err
+	}
+	if !model.IsActive {
+		return nil, fmt.Errorf("analysis-vl model is not active")
+	}
+	return model, nil
+}
+
+// GetModelByType returns the model of the type, the default one for chat
+// models as there may be several
 func (u *ModelUsecase) GetModelByType(ctx context.Context, modelType domain.ModelType) (*domain.Model, error) {
+	if modelType == domain.ModelTypeChat {
+		return u.modelRepo.GetChatModel(ctx)
+	}
 	return u.modelRepo.GetModelByType(ctx, modelType)
 }
 
@@ -204,7 +341,7 @@ func (u *ModelUsecase) SwitchMode(ctx context.Context, req *domain.SwitchModeReq
 			domain.ModelTypeAnalysis,
 		}
 		for _, modelType := range needModelTypes {
-			model, err := u.modelRepo.GetModelByType(ctx, modelType)
+			model, err := u.GetModelByType(ctx, modelType)
 			if err != nil {
 				return fmt.Errorf("需要配置 %s 模型", modelType)
 			}
@@ -322,7 +459,7 @@ func (u *ModelUsecase) updateRAGModelsByMode(ctx context.Context, mode, autoMode
 
 		if mode == string(consts.ModelSettingModeManual) {
 			// 获取该类型的活跃模型
-			m, err := u.modelRepo.GetModelByType(ctx, modelType)
+			m, err := u.GetModelByType(ctx, modelType)
 			if err != nil {
 				u.logger.Warn("failed to get model by type", log.String("type", string(modelType)), log.Any("error", err))
 				continue
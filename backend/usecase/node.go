@@ -29,6 +29,7 @@ import (
 type NodeUsecase struct {
 	nodeRepo     *pg.NodeRepository
 	navRepo      *pg.NavRepository
+	tagRepo      *pg.TagRepository
 	appRepo      *pg.AppRepository
 	ragRepo      *mq.RAGRepository
 	kbRepo       *pg.KnowledgeBaseRepository
@@ -45,6 +46,7 @@ type NodeUsecase struct {
 func NewNodeUsecase(
 	nodeRepo *pg.NodeRepository,
 	navRepo *pg.NavRepository,
+	tagRepo *pg.TagRepository,
 	appRepo *pg.AppRepository,
 	ragRepo *mq.RAGRepository,
 	userRepo *pg.UserRepository,
@@ -60,6 +62,7 @@ func NewNodeUsecase(
 	return &NodeUsecase{
 		nodeRepo:     nodeRepo,
 		navRepo:      navRepo,
+		tagRepo:      tagRepo,
 		rAGService:   ragService,
 		appRepo:      appRepo,
 		ragRepo:      ragRepo,
@@ -128,6 +131,15 @@ func (u *NodeUsecase) GetNodeByKBID(ctx context.Context, id, kbId, format string
 	}
 	node.PV = nodeStat.PV
 
+	nodeTags, err := u.tagRepo.GetTagNamesByNodeIDs(ctx, []string{node.ID})
+	if err != nil {
+		return nil, err
+	}
+	node.Tags = nodeTags[node.ID]
+	if node.Tags == nil {
+		node.Tags = []string{}
+	}
+
 	if node.Meta.ContentType == domain.ContentTypeMD {
 		return node, nil
 	}
@@ -408,13 +420,20 @@ func (u *NodeUsecase) convertMDToHTML(mdStr string) string {
 	return string(html)
 }
 
-func (u *NodeUsecase) GetShareNodeList(ctx context.Context, kbId string, authId uint) ([]*shareV1.NodeListGroupNavResp, error) {
+func (u *NodeUsecase) GetShareNodeList(ctx context.Context, kbId string, authId uint, tags []string) ([]*shareV1.NodeListGroupNavResp, error) {
 
 	nodes, err := u.nodeRepo.GetNodeReleaseListByKBID(ctx, kbId)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(tags) > 0 {
+		nodes, err = u.filterShareNodesByTags(ctx, kbId, nodes, tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	nodeGroupIds, err := u.GetNodeIdsByAuthId(ctx, authId, consts.NodePermNameVisible)
 	if err != nil {
 		return nil, err
@@ -461,6 +480,36 @@ func (u *NodeUsecase) GetShareNodeList(ctx context.Context, kbId string, authId
 	return result, nil
 }
 
+// filterShareNodesByTags keeps documents with any of the tags and the folders
+// on their paths
+func (u *NodeUsecase) filterShareNodesByTags(ctx context.Context, kbId string, nodes []*domain.ShareNodeListItemResp, tags []string) ([]*domain.ShareNodeListItemResp, error) {
+	taggedIDs, err := u.tagRepo.GetNodeIDsByTagNames(ctx, kbId, tags)
+	if err != nil {
+		return nil, err
+	}
+	nodeMap := lo.SliceToMap(nodes, func(node *domain.ShareNodeListItemResp) (string, *domain.ShareNodeListItemResp) {
+		return node.ID, node
+	})
+	keep := make(map[string]struct{}, len(taggedIDs))
+	for _, id := range taggedIDs {
+		node, ok := nodeMap[id]
+		if !ok || node.Type != domain.NodeTypeDocument {
+			continue
+		}
+		for node != nil {
+			if _, ok := keep[node.ID]; ok {
+				break
+			}
+			keep[node.ID] = struct{}{}
+			node = nodeMap[node.ParentID]
+		}
+	}
+	return lo.Filter(nodes, func(node *domain.ShareNodeListItemResp, _ int) bool {
+		_, ok := keep[node.ID]
+		return ok
+	}), nil
+}
+
 func (u *NodeUsecase) GetNodeReleaseListByParentID(ctx context.Context, kbID, parentID string, authId uint) ([]*domain.ShareNodeDetailItem, error) {
 	// 一次性查询所有节点
 	allNodes, err := u.nodeRepo.GetNodeReleaseListByKBID(ctx, kbID)
@@ -0,0 +1,323 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/samber/lo"
+
+	"github.com/chaitin/panda-wiki/consts"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	mqConsumer "github.com/chaitin/panda-wiki/mq"
+	"github.com/chaitin/panda-wiki/pkg/anydoc"
+	"github.com/chaitin/panda-wiki/repo/mq"
+	"github.com/chaitin/panda-wiki/repo/pg"
+	"github.com/chaitin/panda-wiki/store/rag"
+	"github.com/chaitin/panda-wiki/store/s3"
+)
+
+const (
+	attachmentExportPollInterval = 2 * time.Second
+	attachmentExportTimeout      = 5 * time.Minute
+)
+
+// NodeAttachmentUsecase indexes the text of files linked from node content as
+// rag documents of their own, tied to the node that links them
+type NodeAttachmentUsecase struct {
+	nodeRepo       *pg.NodeRepository
+	kbRepo         *pg.KnowledgeBaseRepository
+	tagRepo        *pg.TagRepository
+	attachmentRepo *pg.NodeAttachmentRepository
+	ragRepo        *mq.RAGRepository
+	rag            rag.RAGService
+	s3Client       *s3.MinioClient
+	anydocClient   *anydoc.Client
+	logger         *log.Logger
+}
+
+func NewNodeAttachmentUsecase(nodeRepo *pg.NodeRepository, kbRepo *pg.KnowledgeBaseRepository, tagRepo *pg.TagRepository, attachmentRepo *pg.NodeAttachmentRepository, ragRepo *mq.RAGRepository, rag rag.RAGService, s3Client *s3.MinioClient, consumer mqConsumer.MQConsumer, logger *log.Logger) (*NodeAttachmentUsecase, error) {
+	anydocClient, err := anydoc.NewClient(logger, consumer)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeAttachmentUsecase{
+		nodeRepo:       nodeRepo,
+		kbRepo:         kbRepo,
+		tagRepo:        tagRepo,
+		attachmentRepo: attachmentRepo,
+		ragRepo:        ragRepo,
+		rag:            rag,
+		s3Client:       s3Client,
+		anydocClient:   anydocClient,
+		logger:         logger.WithModule("usecase.node_attachment"),
+	}, nil
+}
+
+// AsyncSyncAttachments queues syncing the attachments of the node release,
+// extraction is slow so it runs apart from the node upsert
+func (u *NodeAttachmentUsecase) AsyncSyncAttachments(ctx context.Context, kbID, nodeID, nodeReleaseID string) error {
+	return u.ragRepo.AsyncUpdateNodeReleaseVector(ctx, []*domain.NodeReleaseVectorRequest{
+		{
+			KBID:          kbID,
+			NodeID:        nodeID,
+			NodeReleaseID: nodeReleaseID,
+			Action:        "sync_attachments",
+		},
+	})
+}
+
+// SyncAttachments makes the indexed attachments of the node match the links
+// of the release: removed links are deleted, new and changed files are
+// extracted and upserted, unchanged ones only get the node's groups and tags.
+func (u *NodeAttachmentUsecase) SyncAttachments(ctx context.Context, nodeReleaseID string) error {
+	nodeRelease, err := u.nodeRepo.GetNodeReleaseWithDirPathByID(ctx, nodeReleaseID)
+	if err != nil {
+		return fmt.Errorf("get node release failed: %w", err)
+	}
+	kb, err := u.kbRepo.GetKnowledgeBaseByID(ctx, nodeRelease.KBID)
+	if err != nil {
+		return fmt.Errorf("get kb failed: %w", err)
+	}
+	existing, err := u.attachmentRepo.GetByNodeID(ctx, nodeRelease.NodeID)
+	if err != nil {
+		return fmt.Errorf("get node attachments failed: %w", err)
+	}
+
+	links := domain.ExtractAttachmentLinks(nodeRelease.Content)
+	linked := lo.SliceToMap(links, func(link domain.AttachmentLink) (string, struct{}) {
+		return link.FileKey, struct{}{}
+	})
+	removed := lo.Filter(existing, func(attachment *domain.NodeAttachment, _ int) bool {
+		_, ok := linked[attachment.FileKey]
+		return !ok
+	})
+	if err := u.deleteAttachments(ctx, kb.DatasetID, removed); err != nil {
+		return err
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	groupIDs, err := u.nodeRepo.GetNodeAuthGroupIdsByNodeId(ctx, nodeRelease.NodeID, consts.NodePermNameAnswerable)
+	if err != nil {
+		return fmt.Errorf("get groupIds failed: %w", err)
+	}
+	nodeTags, err := u.tagRepo.GetTagNamesByNodeIDs(ctx, []string{nodeRelease.NodeID})
+	if err != nil {
+		return fmt.Errorf("get node tags failed: %w", err)
+	}
+	tags := nodeTags[nodeRelease.NodeID]
+
+	byKey := lo.SliceToMap(existing, func(attachment *domain.NodeAttachment) (string, *domain.NodeAttachment) {
+		return attachment.FileKey, attachment
+	})
+	var errs []error
+	for _, link := range links {
+		attachment, ok := byKey[link.FileKey]
+		if !ok {
+			attachment = &domain.NodeAttachment{
+				ID:        uuid.New().String(),
+				KBID:      nodeRelease.KBID,
+				NodeID:    nodeRelease.NodeID,
+				FileKey:   link.FileKey,
+				CreatedAt: time.Now(),
+			}
+		}
+		attachment.FileName = link.FileName
+		if err := u.syncAttachment(ctx, kb.DatasetID, nodeRelease.Name, attachment, groupIDs, tags); err != nil {
+			errs = append(errs, fmt.Errorf("sync attachment %s failed: %w", link.FileKey, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (u *NodeAttachmentUsecase) syncAttachment(ctx context.Context, datasetID, nodeName string, attachment *domain.NodeAttachment, groupIDs []int, tags []string) error {
+	info, err := u.s3Client.StatObject(ctx, domain.Bucket, attachment.FileKey, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			// the link points to nothing, retrying won't help
+			return u.saveFailed(ctx, attachment, "file not found")
+		}
+		return fmt.Errorf("stat file failed: %w", err)
+	}
+
+	if attachment.Status == domain.NodeAttachmentStatusIndexed && attachment.ETag == info.ETag && attachment.DocID != "" {
+		if err := u.rag.UpdateDocumentGroupIDs(ctx, datasetID, attachment.DocID, groupIDs); err != nil {
+			return fmt.Errorf("update group ids failed: %w", err)
+		}
+		if err := u.rag.UpdateDocumentTags(ctx, datasetID, attachment.DocID, tags); err != nil {
+			return fmt.Errorf("update tags failed: %w", err)
+		}
+		return nil
+	}
+
+	content, err := u.extractText(ctx, attachment)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskPermanent) {
+			return u.saveFailed(ctx, attachment, err.Error())
+		}
+		return err
+	}
+	docID, err := u.rag.UpsertRecords(ctx, &rag.UpsertRecordsRequest{
+		ID:        attachment.ID,
+		DatasetID: datasetID,
+		DocID:     attachment.DocID,
+		Title:     domain.AttachmentSourceName(attachment.FileName, nodeName),
+		Content:   content,
+		GroupIDs:  groupIDs,
+		Tags:      tags,
+	})
+	if err != nil {
+		return fmt.Errorf("upsert records failed: %w", err)
+	}
+	if attachment.DocID != "" && attachment.DocID != docID {
+		if err := u.rag.DeleteRecords(ctx, datasetID, []string{attachment.DocID}); err != nil {
+			u.logger.Warn("delete old attachment records failed", log.String("doc_id", attachment.DocID), log.Error(err))
+		}
+	}
+	attachment.DocID = docID
+	attachment.ETag = info.ETag
+	attachment.Status = domain.NodeAttachmentStatusIndexed
+	attachment.Error = ""
+	attachment.UpdatedAt = time.Now()
+	return u.attachmentRepo.Save(ctx, attachment)
+}
+
+func (u *NodeAttachmentUsecase) saveFailed(ctx context.Context, attachment *domain.NodeAttachment, reason string) error {
+	attachment.Status = domain.NodeAttachmentStatusFailed
+	attachment.Error = reason
+	attachment.UpdatedAt = time.Now()
+	return u.attachmentRepo.Save(ctx, attachment)
+}
+
+// extractText converts the file to markdown with anydoc
+func (u *NodeAttachmentUsecase) extractText(ctx context.Context, attachment *domain.NodeAttachment) (string, error) {
+	fileURL := fmt.Sprintf("http://panda-wiki-minio:9000/%s/%s", domain.Bucket, attachment.FileKey)
+	docs, err := u.anydocClient.GetUrlList(ctx, fileURL, attachment.ID)
+	if err != nil {
+		return "", fmt.Errorf("parse file failed: %w", err)
+	}
+	docID, ok := firstAnydocFile(docs.Data.Docs)
+	if !ok {
+		return "", domain.PermanentTaskError(errors.New("no document found in file"))
+	}
+	exportRes, err := u.anydocClient.UrlExport(ctx, attachment.ID, docID, attachment.KBID)
+	if err != nil {
+		return "", fmt.Errorf("export file failed: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, attachmentExportTimeout)
+	defer cancel()
+	ticker := time.NewTicker(attachmentExportPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("wait for export task %s failed: %w", exportRes.Data, ctx.Err())
+		case <-ticker.C:
+		}
+		taskRes, err := u.anydocClient.TaskList(ctx, []string{exportRes.Data})
+		if err != nil {
+			return "", fmt.Errorf("get export task failed: %w", err)
+		}
+		task := taskRes.Data[0]
+		switch task.Status {
+		case anydoc.StatusPending, anydoc.StatusInProgress:
+			continue
+		case anydoc.StatusFailed:
+			return "", domain.PermanentTaskError(fmt.Errorf("export file failed: %s", task.Err))
+		case anydoc.StatusCompleted:
+			content, err := u.anydocClient.DownloadDoc(ctx, task.Markdown)
+			if err != nil {
+				return "", fmt.Errorf("download exported file failed: %w", err)
+			}
+			return string(content), nil
+		default:
+			return "", fmt.Errorf("unsupported task status: %s", task.Status)
+		}
+	}
+}
+
+func firstAnydocFile(doc anydoc.Child) (string, bool) {
+	if doc.Value.File && doc.Value.ID != "" {
+		return doc.Value.ID, true
+	}
+	for _, child := range doc.Children {
+		if id, ok := firstAnydocFile(child); ok {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+func (u *NodeAttachmentUsecase) deleteAttachments(ctx context.Context, datasetID string, attachments []*domain.NodeAttachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+	docIDs := lo.FilterMap(attachments, func(attachment *domain.NodeAttachment, _ int) (string, bool) {
+		return attachment.DocID, attachment.DocID != ""
+	})
+	if len(docIDs) > 0 {
+		if err := u.rag.DeleteRecords(ctx, datasetID, docIDs); err != nil {
+			return fmt.Errorf("delete attachment records failed: %w", err)
+		}
+	}
+	return u.attachmentRepo.Delete(ctx, lo.Map(attachments, func(attachment *domain.NodeAttachment, _ int) string {
+		return attachment.ID
+	}))
+}
+
+// DeleteOrphanAttachments removes the attachments of deleted nodes
+func (u *NodeAttachmentUsecase) DeleteOrphanAttachments(ctx context.Context, kbID string) error {
+	orphans, err := u.attachmentRepo.GetOrphans(ctx, kbID)
+	if err != nil {
+		return fmt.Errorf("get orphan attachments failed: %w", err)
+	}
+	if len(orphans) == 0 {
+		return nil
+	}
+	kb, err := u.kbRepo.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return fmt.Errorf("get kb failed: %w", err)
+	}
+	return u.deleteAttachments(ctx, kb.DatasetID, orphans)
+}
+
+// CheckChangedAttachments queues a sync for nodes whose indexed attachment
+// files were replaced since they were indexed
+func (u *NodeAttachmentUsecase) CheckChangedAttachments(ctx context.Context) error {
+	attachments, err := u.attachmentRepo.GetByStatus(ctx, domain.NodeAttachmentStatusIndexed)
+	if err != nil {
+		return fmt.Errorf("get indexed attachments failed: %w", err)
+	}
+	changedNodes := make(map[string]struct{})
+	for _, attachment := range attachments {
+		if _, ok := changedNodes[attachment.NodeID]; ok {
+			continue
+		}
+		info, err := u.s3Client.StatObject(ctx, domain.Bucket, attachment.FileKey, minio.StatObjectOptions{})
+		if err != nil && minio.ToErrorResponse(err).Code != "NoSuchKey" {
+			u.logger.Warn("stat attachment failed", log.String("file_key", attachment.FileKey), log.Error(err))
+			continue
+		}
+		if err == nil && info.ETag == attachment.ETag {
+			continue
+		}
+		changedNodes[attachment.NodeID] = struct{}{}
+		release, err := u.nodeRepo.GetLatestNodeReleaseByNodeID(ctx, attachment.NodeID)
+		if err != nil {
+			u.logger.Warn("get latest node release failed", log.String("node_id", attachment.NodeID), log.Error(err))
+			continue
+		}
+		if err := u.AsyncSyncAttachments(ctx, release.KBID, release.NodeID, release.ID); err != nil {
+			return fmt.Errorf("queue attachment sync failed: %w", err)
+		}
+		u.logger.Info("attachment changed, re-index", log.String("node_id", attachment.NodeID), log.String("file_key", attachment.FileKey))
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+
+	v1 "github.com/chaitin/panda-wiki/api/node/v1"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/repo/mq"
+	"github.com/chaitin/panda-wiki/repo/pg"
+	"github.com/chaitin/panda-wiki/store/rag"
+)
+
+// NodeChunkUsecase lets editors change how a node is chunked. Every change is
+// applied to the rag provider at once and stored as an override, which is
+// applied again after the node is re-chunked on publish.
+type NodeChunkUsecase struct {
+	nodeRepo      *pg.NodeRepository
+	kbRepo        *pg.KnowledgeBaseRepository
+	nodeChunkRepo *pg.NodeChunkRepository
+	ragRepo       *mq.RAGRepository
+	rag           rag.RAGService
+	logger        *log.Logger
+}
+
+func NewNodeChunkUsecase(nodeRepo *pg.NodeRepository, kbRepo *pg.KnowledgeBaseRepository, nodeChunkRepo *pg.NodeChunkRepository, ragRepo *mq.RAGRepository, rag rag.RAGService, logger *log.Logger) *NodeChunkUsecase {
+	return &NodeChunkUsecase{
+		nodeRepo:      nodeRepo,
+		kbRepo:        kbRepo,
+		nodeChunkRepo: nodeChunkRepo,
+		ragRepo:       ragRepo,
+		rag:           rag,
+		logger:        logger.WithModule("usecase.node_chunk"),
+	}
+}
+
+type nodeDocument struct {
+	release   *domain.NodeRelease
+	datasetID string
+}
+
+// getNodeDocument returns the rag document of the latest release of the node
+func (u *NodeChunkUsecase) getNodeDocument(ctx context.Context, kbID, nodeID string) (*nodeDocument, error) {
+	release, err := u.nodeRepo.GetLatestNodeReleaseByNodeID(ctx, nodeID)
+	if err != nil || release.KBID != kbID {
+		return nil, fmt.Errorf("文档未发布，无法查看分段")
+	}
+	if release.DocID == "" {
+		return nil, fmt.Errorf("文档尚未学习完成，无法查看分段")
+	}
+	kb, err := u.kbRepo.GetKnowledgeBaseByID(ctx, kbID)
+	if err != nil {
+		return nil, fmt.Errorf("get kb failed: %w", err)
+	}
+	return &nodeDocument{release: release, datasetID: kb.DatasetID}, nil
+}
+
+func (u *NodeChunkUsecase) listChunks(ctx context.Context, doc *nodeDocument) ([]*domain.NodeContentChunk, error) {
+	chunks, err := u.rag.ListChunks(ctx, doc.datasetID, doc.release.DocID)
+	if errors.Is(err, rag.ErrChunkUnsupported) {
+		return nil, fmt.Errorf("当前向量库不支持查看和编辑分段")
+	}
+	return chunks, err
+}
+
+func (u *NodeChunkUsecase) getChunk(ctx context.Context, doc *nodeDocument, chunkID string) (*domain.NodeContentChunk, error) {
+	chunks, err := u.listChunks(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	chunk, ok := lo.Find(chunks, func(chunk *domain.NodeContentChunk) bool {
+		return chunk.ID == chunkID
+	})
+	if !ok {
+		return nil, fmt.Errorf("chunk %s not found", chunkID)
+	}
+	return chunk, nil
+}
+
+func (u *NodeChunkUsecase) GetChunkList(ctx context.Context, req *v1.NodeChunkListReq) (*v1.NodeChunkListResp, error) {
+	doc, err := u.getNodeDocument(ctx, req.KbId, req.NodeId)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := u.listChunks(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := u.nodeChunkRepo.GetOverridesByNodeID(ctx, req.NodeId)
+	if err != nil {
+		return nil, err
+	}
+	resp := &v1.NodeChunkListResp{
+		Chunks:    make([]*domain.ChunkListItemResp, 0, len(chunks)),
+		Overrides: overrides,
+	}
+	for _, chunk := range chunks {
+		item := &domain.ChunkListItemResp{
+			ID:      chunk.ID,
+			Seq:     chunk.Seq,
+			Name:    doc.release.Name,
+			Content: chunk.Content,
+		}
+		if override := domain.MatchChunkOverride(chunk.Content, overrides); override != nil {
+			item.Override = override.Action
+			item.OverrideID = override.ID
+		}
+		resp.Chunks = append(resp.Chunks, item)
+	}
+	return resp, nil
+}
+
+func (u *NodeChunkUsecase) AddChunk(ctx context.Context, req *v1.NodeChunkAddReq) (*domain.ChunkListItemResp, error) {
+	doc, err := u.getNodeDocument(ctx, req.KbId, req.NodeId)
+	if err != nil {
+		return nil, err
+	}
+	chunk, err := u.rag.AddChunk(ctx, doc.datasetID, doc.release.DocID, req.Content)
+	if err != nil {
+		if errors.Is(err, rag.ErrChunkUnsupported) {
+			return nil, fmt.Errorf("当前向量库不支持查看和编辑分段")
+		}
+		return nil, err
+	}
+	override := u.newOverride(req.KbId, req.NodeId, domain.ChunkOverrideAdd, "", req.Content)
+	if err := u.nodeChunkRepo.CreateOverride(ctx, override); err != nil {
+		return nil, err
+	}
+	return &domain.ChunkListItemResp{
+		ID:         chunk.ID,
+		Seq:        chunk.Seq,
+		Name:       doc.release.Name,
+		Content:    chunk.Content,
+		Override:   override.Action,
+		OverrideID: override.ID,
+	}, nil
+}
+
+func (u *NodeChunkUsecase) UpdateChunk(ctx context.Context, req *v1.NodeChunkUpdateReq) error {
+	doc, err := u.getNodeDocument(ctx, req.KbId, req.NodeId)
+	if err != nil {
+		return err
+	}
+	chunk, err := u.getChunk(ctx, doc, req.ChunkId)
+	if err != nil {
+		return err
+	}
+	overrides, err := u.nodeChunkRepo.GetOverridesByNodeID(ctx, req.NodeId)
+	if err != nil {
+		return err
+	}
+	if err := u.rag.UpdateChunk(ctx, doc.datasetID, doc.release.DocID, chunk.ID, req.Content); err != nil {
+		return err
+	}
+	switch override := domain.MatchChunkOverride(chunk.Content, overrides); {
+	case override == nil:
+		return u.nodeChunkRepo.CreateOverride(ctx, u.newOverride(req.KbId, req.NodeId, domain.ChunkOverrideEdit, domain.ChunkContentHash(chunk.Content), req.Content))
+	case override.Action == domain.ChunkOverrideAdd:
+		return u.nodeChunkRepo.UpdateOverride(ctx, override.ID, domain.ChunkOverrideAdd, req.Content)
+	default:
+		// a pinned chunk becomes an edited one, the source stays the same
+		return u.nodeChunkRepo.UpdateOverride(ctx, override.ID, domain.ChunkOverrideEdit, req.Content)
+	}
+}
+
+func (u *NodeChunkUsecase) PinChunk(ctx context.Context, req *v1.NodeChunkActionReq) error {
+	doc, err := u.getNodeDocument(ctx, req.KbId, req.NodeId)
+	if err != nil {
+		return err
+	}
+	chunk, err := u.getChunk(ctx, doc, req.ChunkId)
+	if err != nil {
+		return err
+	}
+	overrides, err := u.nodeChunkRepo.GetOverridesByNodeID(ctx, req.NodeId)
+	if err != nil {
+		return err
+	}
+	if override := domain.MatchChunkOverride(chunk.Content, overrides); override != nil {
+		if override.Action == domain.ChunkOverridePin {
+			return nil
+		}
+		return fmt.Errorf("分段已被手动修改，无需固定")
+	}
+	return u.nodeChunkRepo.CreateOverride(ctx, u.newOverride(req.KbId, req.NodeId, domain.ChunkOverridePin, domain.ChunkContentHash(chunk.Content), chunk.Content))
+}
+
+func (u *NodeChunkUsecase) ExcludeChunk(ctx context.Context, req *v1.NodeChunkActionReq) error {
+	doc, err := u.getNodeDocument(ctx, req.KbId, req.NodeId)
+	if err != nil {
+		return err
+	}
+	chunk, err := u.getChunk(ctx, doc, req.ChunkId)
+	if err != nil {
+		return err
+	}
+	overrides, err := u.nodeChunkRepo.GetOverridesByNodeID(ctx, req.NodeId)
+	if err != nil {
+		return err
+	}
+	if err := u.rag.DeleteChunks(ctx, doc.datasetID, doc.release.DocID, []string{chunk.ID}); err != nil {
+		return err
+	}
+	switch override := domain.MatchChunkOverride(chunk.Content, overrides); {
+	case override == nil:
+		return u.nodeChunkRepo.CreateOverride(ctx, u.newOverride(req.KbId, req.NodeId, domain.ChunkOverrideExclude, domain.ChunkContentHash(chunk.Content), ""))
+	case override.Action == domain.ChunkOverrideAdd:
+		_, err := u.nodeChunkRepo.DeleteOverride(ctx, req.KbId, req.NodeId, override.ID)
+		return err
+	default:
+		return u.nodeChunkRepo.UpdateOverride(ctx, override.ID, domain.ChunkOverrideExclude, "")
+	}
+}
+
+// DeleteOverride reverts an override by re-chunking the node with the remaining ones
+func (u *NodeChunkUsecase) DeleteOverride(ctx context.Context, req *v1.NodeChunkOverrideDeleteReq) error {
+	doc, err := u.getNodeDocument(ctx, req.KbId, req.NodeId)
+	if err != nil {
+		return err
+	}
+	deleted, err := u.nodeChunkRepo.DeleteOverride(ctx, req.KbId, req.NodeId, req.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("override %s not found", req.ID)
+	}
+	return u.ragRepo.AsyncUpdateNodeReleaseVector(ctx, []*domain.NodeReleaseVectorRequest{
+		{
+			KBID:          req.KbId,
+			NodeReleaseID: doc.release.ID,
+			Action:        "upsert",
+		},
+	})
+}
+
+// ApplyOverrides applies the overrides of the node to the freshly chunked document
+func (u *NodeChunkUsecase) ApplyOverrides(ctx context.Context, datasetID, docID, nodeID string) error {
+	overrides, err := u.nodeChunkRepo.GetOverridesByNodeID(ctx, nodeID)
+	if err != nil {
+		return fmt.Errorf("get chunk overrides failed: %w", err)
+	}
+	if len(overrides) == 0 {
+		return nil
+	}
+	chunks, err := u.rag.ListChunks(ctx, datasetID, docID)
+	if err != nil {
+		if errors.Is(err, rag.ErrChunkUnsupported) {
+			// the overrides can't be applied, show them as stale to the editors
+			u.logger.Warn("rag provider does not support chunks, overrides not applied", log.String("node_id", nodeID), log.Int("overrides", len(overrides)))
+			return u.nodeChunkRepo.UpdateStaleOverrides(ctx, nodeID, lo.Map(overrides, func(override *domain.NodeChunkOverride, _ int) string {
+				return override.ID
+			}))
+		}
+		return fmt.Errorf("list chunks failed: %w", err)
+	}
+	plan := domain.PlanChunkOverrides(chunks, overrides)
+	if err := u.rag.DeleteChunks(ctx, datasetID, docID, plan.Delete); err != nil {
+		return err
+	}
+	for chunkID, content := range plan.Update {
+		if err := u.rag.UpdateChunk(ctx, datasetID, docID, chunkID, content); err != nil {
+			return err
+		}
+	}
+	for _, content := range plan.Add {
+		if _, err := u.rag.AddChunk(ctx, datasetID, docID, content); err != nil {
+			return err
+		}
+	}
+	if err := u.nodeChunkRepo.UpdateStaleOverrides(ctx, nodeID, lo.Keys(plan.Stale)); err != nil {
+		return err
+	}
+	u.logger.Info("chunk overrides applied", log.String("node_id", nodeID), log.Int("overrides", len(overrides)), log.Int("stale", len(plan.Stale)))
+	return nil
+}
+
+func (u *NodeChunkUsecase) newOverride(kbID, nodeID string, action domain.ChunkOverrideAction, sourceHash, content string) *domain.NodeChunkOverride {
+	return &domain.NodeChunkOverride{
+		ID:         uuid.New().String(),
+		KBID:       kbID,
+		NodeID:     nodeID,
+		Action:     action,
+		SourceHash: sourceHash,
+		Content:    content,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+}
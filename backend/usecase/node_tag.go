@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+
+	v1 "github.com/chaitin/panda-wiki/api/node/v1"
+	"github.com/chaitin/panda-wiki/domain"
+)
+
+func (u *NodeUsecase) GetTagList(ctx context.Context, kbID string) ([]v1.TagListItemResp, error) {
+	return u.tagRepo.GetList(ctx, kbID)
+}
+
+func (u *NodeUsecase) CreateTag(ctx context.Context, req *v1.CreateTagReq) (string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return "", fmt.Errorf("tag name is required")
+	}
+	now := time.Now()
+	tag := &domain.Tag{
+		ID:        uuid.New().String(),
+		KBID:      req.KbId,
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := u.tagRepo.Create(ctx, tag); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return "", fmt.Errorf("tag %s already exists", name)
+		}
+		return "", err
+	}
+	return tag.ID, nil
+}
+
+func (u *NodeUsecase) UpdateTag(ctx context.Context, req *v1.UpdateTagReq) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("tag name is required")
+	}
+	if _, err := u.tagRepo.GetByID(ctx, req.KbId, req.ID); err != nil {
+		return err
+	}
+	if err := u.tagRepo.Update(ctx, req.KbId, req.ID, name); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return fmt.Errorf("tag %s already exists", name)
+		}
+		return err
+	}
+	nodeIDs, err := u.tagRepo.GetNodeIDsByTagID(ctx, req.KbId, req.ID)
+	if err != nil {
+		return err
+	}
+	return u.syncNodeTagsToRAG(ctx, req.KbId, nodeIDs)
+}
+
+func (u *NodeUsecase) DeleteTag(ctx context.Context, req *v1.DeleteTagReq) error {
+	nodeIDs, err := u.tagRepo.Delete(ctx, req.KbId, req.ID)
+	if err != nil {
+		return err
+	}
+	return u.syncNodeTagsToRAG(ctx, req.KbId, nodeIDs)
+}
+
+// UpdateNodeTags replaces the tags of the nodes, unknown tag names are created
+func (u *NodeUsecase) UpdateNodeTags(ctx context.Context, req *v1.NodeTagsUpdateReq) error {
+	nodes, err := u.nodeRepo.GetNodesByIDs(ctx, req.IDs)
+	if err != nil {
+		return err
+	}
+	for _, id := range req.IDs {
+		if node, ok := nodes[id]; !ok || node.KBID != req.KbId {
+			return fmt.Errorf("node %s not found", id)
+		}
+	}
+	names := lo.Uniq(lo.FilterMap(req.Tags, func(name string, _ int) (string, bool) {
+		name = strings.TrimSpace(name)
+		return name, name != ""
+	}))
+	if err := u.tagRepo.SetNodeTags(ctx, req.KbId, req.IDs, names); err != nil {
+		return err
+	}
+	return u.syncNodeTagsToRAG(ctx, req.KbId, req.IDs)
+}
+
+// syncNodeTagsToRAG pushes the current tags of the nodes to their indexed
+// documents, nodes never released are picked up on release
+func (u *NodeUsecase) syncNodeTagsToRAG(ctx context.Context, kbID string, nodeIDs []string) error {
+	if len(nodeIDs) == 0 {
+		return nil
+	}
+	nodeReleases, err := u.nodeRepo.GetLatestNodeReleaseByNodeIDs(ctx, kbID, nodeIDs)
+	if err != nil {
+		return fmt.Errorf("get latest node release failed: %w", err)
+	}
+	nodeTags, err := u.tagRepo.GetTagNamesByNodeIDs(ctx, nodeIDs)
+	if err != nil {
+		return fmt.Errorf("get node tags failed: %w", err)
+	}
+	requests := make([]*domain.NodeReleaseVectorRequest, 0)
+	for _, nodeRelease := range nodeReleases {
+		if nodeRelease.DocID == "" {
+			continue
+		}
+		tags := nodeTags[nodeRelease.NodeID]
+		if tags == nil {
+			tags = []string{}
+		}
+		requests = append(requests, &domain.NodeReleaseVectorRequest{
+			KBID:   kbID,
+			NodeID: nodeRelease.NodeID,
+			DocID:  nodeRelease.DocID,
+			Action: "update_tags",
+			Tags:   tags,
+		})
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+	return u.ragRepo.AsyncUpdateNodeReleaseVector(ctx, requests)
+}
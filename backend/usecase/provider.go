@@ -36,4 +36,13 @@ var ProviderSet = wire.NewSet(
 	NewWechatAppUsecase,
 	NewAuthUsecase,
 	NewNavUsecase,
+	NewEvalUsecase,
+	NewReindexUsecase,
+	NewFailedTaskUsecase,
+	NewNodeChunkUsecase,
+	NewNodeAttachmentUsecase,
+	NewImageCaptionUsecase,
+	NewAnswerCacheUsecase,
+	NewQAPairUsecase,
+	NewHandoffUsecase,
 )
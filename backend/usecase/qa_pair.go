@@ -0,0 +1,226 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+
+	v1 "github.com/chaitin/panda-wiki/api/kb/v1"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/repo/cache"
+	"github.com/chaitin/panda-wiki/repo/pg"
+	"github.com/chaitin/panda-wiki/store/rag"
+)
+
+// QAPairUsecase manages the curated question and answer pairs of a kb, which
+// take precedence over generated answers
+type QAPairUsecase struct {
+	qaPairRepo       *pg.QAPairRepository
+	conversationRepo *pg.ConversationRepository
+	answerCache      *cache.AnswerCacheRepo
+	rag              rag.RAGService
+	logger           *log.Logger
+}
+
+func NewQAPairUsecase(qaPairRepo *pg.QAPairRepository, conversationRepo *pg.ConversationRepository, answerCache *cache.AnswerCacheRepo, rag rag.RAGService, logger *log.Logger) *QAPairUsecase {
+	return &QAPairUsecase{
+		qaPairRepo:       qaPairRepo,
+		conversationRepo: conversationRepo,
+		answerCache:      answerCache,
+		rag:              rag,
+		logger:           logger.WithModule("usecase.qa_pair"),
+	}
+}
+
+func (u *QAPairUsecase) GetList(ctx context.Context, req *v1.QAPairListReq) (*v1.QAPairListResp, error) {
+	total, pairs, err := u.qaPairRepo.GetList(ctx, req.KBId, strings.TrimSpace(req.Search), req.Offset(), req.Limit())
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewPaginatedResult(pairs, uint64(total)), nil
+}
+
+func (u *QAPairUsecase) Create(ctx context.Context, req *v1.QAPairCreateReq) (string, error) {
+	pair := newQAPair(req)
+	pair.ID = uuid.New().String()
+	pair.CreatedAt = time.Now()
+	pair.UpdatedAt = pair.CreatedAt
+	if err := u.create(ctx, pair); err != nil {
+		return "", err
+	}
+	return pair.ID, nil
+}
+
+func (u *QAPairUsecase) Update(ctx context.Context, req *v1.QAPairUpdateReq) error {
+	if _, err := u.qaPairRepo.GetByID(ctx, req.KBId, req.ID); err != nil {
+		return fmt.Errorf("get qa pair failed: %w", err)
+	}
+	pair := newQAPair(&req.QAPairCreateReq)
+	pair.ID = req.ID
+	if err := u.setEmbedding(ctx, pair); err != nil {
+		return err
+	}
+	if err := u.qaPairRepo.Update(ctx, pair); err != nil {
+		return fmt.Errorf("update qa pair failed: %w", err)
+	}
+	u.dropCachedAnswers(ctx, req.KBId)
+	return nil
+}
+
+func (u *QAPairUsecase) Delete(ctx context.Context, kbID, id string) error {
+	if err := u.qaPairRepo.Delete(ctx, kbID, id); err != nil {
+		return fmt.Errorf("delete qa pair failed: %w", err)
+	}
+	u.dropCachedAnswers(ctx, kbID)
+	return nil
+}
+
+// Promote creates a pair from an assistant message of a conversation, with
+// the question and answer edited by the admin when given
+func (u *QAPairUsecase) Promote(ctx context.Context, req *v1.QAPairPromoteReq) (string, error) {
+	message, err := u.conversationRepo.GetConversationMessagesDetailByKbID(ctx, req.KBId, req.MessageID)
+	if err != nil {
+		return "", fmt.Errorf("get conversation message failed: %w", err)
+	}
+	if message.Role != schema.Assistant {
+		return "", fmt.Errorf("only assistant messages can be promoted")
+	}
+	question := strings.TrimSpace(req.Question)
+	if question == "" && message.ParentID != "" {
+		parent, err := u.conversationRepo.GetConversationMessagesDetailByID(ctx, message.ParentID)
+		if err != nil {
+			return "", fmt.Errorf("get question message failed: %w", err)
+		}
+		question = parent.Content
+	}
+	if question == "" {
+		return "", fmt.Errorf("question is required")
+	}
+	answer := lo.Ternary(strings.TrimSpace(req.Answer) != "", req.Answer, message.Content)
+
+	pair := newQAPair(&v1.QAPairCreateReq{
+		KBId:       req.KBId,
+		Question:   question,
+		Answer:     answer,
+		Keywords:   req.Keywords,
+		MatchType:  req.MatchType,
+		AnswerMode: req.AnswerMode,
+	})
+	pair.ID = uuid.New().String()
+	pair.SourceMessageID = message.ID
+	pair.CreatedAt = time.Now()
+	pair.UpdatedAt = pair.CreatedAt
+	if err := u.create(ctx, pair); err != nil {
+		return "", err
+	}
+	return pair.ID, nil
+}
+
+// Match returns the pair answering the question and records the hit, nil
+// when no pair matches
+func (u *QAPairUsecase) Match(ctx context.Context, kbID, question string) (*domain.QAPair, error) {
+	pairs, err := u.qaPairRepo.GetByKBID(ctx, kbID)
+	if err != nil {
+		return nil, fmt.Errorf("get qa pairs failed: %w", err)
+	}
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	var embedding []float32
+	if lo.ContainsBy(pairs, func(pair *domain.QAPair) bool { return pair.MatchType == domain.QAPairMatchSemantic }) {
+		// exact and keyword pairs still match without the embedding
+		if embedding, err = u.rag.EmbedQuery(ctx, question); err != nil {
+			u.logger.Warn("embed question failed", log.Error(err))
+		}
+	}
+	pair := domain.MatchQAPair(pairs, question, embedding, domain.DefaultQAPairSimilarityThreshold)
+	if pair == nil {
+		return nil, nil
+	}
+	if err := u.qaPairRepo.RecordHit(ctx, pair.ID); err != nil {
+		u.logger.Warn("record qa pair hit failed", log.String("id", pair.ID), log.Error(err))
+	}
+	return pair, nil
+}
+
+func (u *QAPairUsecase) create(ctx context.Context, pair *domain.QAPair) error {
+	if err := u.setEmbedding(ctx, pair); err != nil {
+		return err
+	}
+	if err := u.qaPairRepo.Create(ctx, pair); err != nil {
+		return fmt.Errorf("create qa pair failed: %w", err)
+	}
+	u.dropCachedAnswers(ctx, pair.KBID)
+	return nil
+}
+
+func (u *QAPairUsecase) setEmbedding(ctx context.Context, pair *domain.QAPair) error {
+	pair.Embedding = []float32{}
+	if pair.MatchType != domain.QAPairMatchSemantic {
+		return nil
+	}
+	embedding, err := u.rag.EmbedQuery(ctx, pair.Question)
+	if err != nil {
+		return fmt.Errorf("embed question failed: %w", err)
+	}
+	pair.Embedding = embedding
+	return nil
+}
+
+// ReembedKB embeds the semantic pairs of the kb again with the current
+// embedding model, the pairs failing to embed keep their embedding
+func (u *QAPairUsecase) ReembedKB(ctx context.Context, kbID string) error {
+	pairs, err := u.qaPairRepo.GetByKBID(ctx, kbID)
+	if err != nil {
+		return fmt.Errorf("get qa pairs failed: %w", err)
+	}
+	var failed int
+	for _, pair := range pairs {
+		if pair.MatchType != domain.QAPairMatchSemantic {
+			continue
+		}
+		if err := u.setEmbedding(ctx, pair); err != nil {
+			u.logger.Warn("re-embed qa pair failed", log.String("id", pair.ID), log.Error(err))
+			failed++
+			continue
+		}
+		if err := u.qaPairRepo.UpdateEmbedding(ctx, pair.ID, pair.Embedding); err != nil {
+			return fmt.Errorf("update qa pair embedding failed: %w", err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d qa pairs failed to embed", failed)
+	}
+	return nil
+}
+
+// dropCachedAnswers keeps the answer cache from serving answers the pairs
+// would now override
+func (u *QAPairUsecase) dropCachedAnswers(ctx context.Context, kbID string) {
+	if err := u.answerCache.DeleteKBAnswers(ctx, kbID); err != nil {
+		u.logger.Warn("delete cached answers failed", log.String("kb_id", kbID), log.Error(err))
+	}
+}
+
+func newQAPair(req *v1.QAPairCreateReq) *domain.QAPair {
+	keywords := make([]string, 0, len(req.Keywords))
+	for _, keyword := range req.Keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return &domain.QAPair{
+		KBID:       req.KBId,
+		Question:   strings.TrimSpace(req.Question),
+		Answer:     req.Answer,
+		Keywords:   keywords,
+		MatchType:  req.MatchType,
+		AnswerMode: req.AnswerMode,
+	}
+}
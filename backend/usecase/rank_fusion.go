@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/repo/pg"
+	"github.com/chaitin/panda-wiki/utils"
+)
+
+const (
+	rrfK                  = 60 // reciprocal rank fusion constant
+	keywordCandidateLimit = 20
+	rankFusionMaxDocs     = 10
+	keywordSnippetRunes   = 400
+	maxSnippetQueryTokens = 64
+)
+
+// fuseRankedChunks merges the vector records and keyword hits by weighted
+// reciprocal rank fusion on doc id, returning doc ids in fused order, the
+// chunks and the fused score of each doc. Docs only found by keyword get a
+// snippet chunk around the first matched term. At most max(maxDocs, vector
+// docs) docs are kept.
+func fuseRankedChunks(kbID string, records []*domain.NodeContentChunk, hits []*pg.NodeReleaseKeywordHit, query string, fusion *domain.RankFusionSetting, maxDocs int) ([]string, map[string][]*domain.NodeContentChunk, map[string]float64) {
+	scores := make(map[string]float64)
+	docChunks := make(map[string][]*domain.NodeContentChunk)
+	docIDs := make([]string, 0)
+
+	// records are ordered by similarity, a doc ranks by its best chunk
+	vectorDocs := 0
+	for _, record := range records {
+		if _, ok := docChunks[record.DocID]; !ok {
+			docIDs = append(docIDs, record.DocID)
+			scores[record.DocID] = fusion.VectorWeight / float64(rrfK+vectorDocs+1)
+			vectorDocs++
+		}
+		docChunks[record.DocID] = append(docChunks[record.DocID], record)
+	}
+	for rank, hit := range hits {
+		if _, ok := docChunks[hit.DocID]; !ok {
+			docIDs = append(docIDs, hit.DocID)
+			docChunks[hit.DocID] = []*domain.NodeContentChunk{{
+				KBID:    kbID,
+				DocID:   hit.DocID,
+				Name:    hit.Name,
+				Content: keywordSnippet(hit.Content, query),
+			}}
+		}
+		scores[hit.DocID] += fusion.KeywordWeight / float64(rrfK+rank+1)
+	}
+
+	// stable sort keeps vector order on ties
+	slices.SortStableFunc(docIDs, func(a, b string) int {
+		switch {
+		case scores[a] > scores[b]:
+			return -1
+		case scores[a] < scores[b]:
+			return 1
+		}
+		return 0
+	})
+	if limit := max(vectorDocs, maxDocs); len(docIDs) > limit {
+		for _, docID := range docIDs[limit:] {
+			delete(docChunks, docID)
+			delete(scores, docID)
+		}
+		docIDs = docIDs[:limit]
+	}
+	return docIDs, docChunks, scores
+}
+
+// keywordSnippet cuts a window of the document text around the first query
+// term found in it
+func keywordSnippet(content, query string) string {
+	text := []rune(strings.Join(strings.Fields(utils.HTMLToText(content)), " "))
+	if len(text) <= keywordSnippetRunes {
+		return string(text)
+	}
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+	pos := -1
+	for _, token := range utils.SearchTokens(query, maxSnippetQueryTokens) {
+		if idx := indexRunes(lower, []rune(token)); idx >= 0 && (pos < 0 || idx < pos) {
+			pos = idx
+		}
+	}
+	start := 0
+	if pos > 0 {
+		start = max(0, pos-keywordSnippetRunes/4)
+	}
+	end := min(len(text), start+keywordSnippetRunes)
+	return string(text[start:end])
+}
+
+func indexRunes(s, sub []rune) int {
+	if len(sub) == 0 {
+		return -1
+	}
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if slices.Equal(s[i:i+len(sub)], sub) {
+			return i
+		}
+	}
+	return -1
+}
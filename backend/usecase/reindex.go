@@ -0,0 +1,249 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+
+	v1 "github.com/chaitin/panda-wiki/api/model/v1"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/repo/mq"
+	"github.com/chaitin/panda-wiki/repo/pg"
+)
+
+const (
+	reindexTickInterval = time.Second
+	// a fully dispatched job without any progress for this long is closed,
+	// the remaining messages are considered lost
+	reindexStallTimeout = 10 * time.Minute
+)
+
+type ReindexUsecase struct {
+	reindexRepo *pg.ReindexRepository
+	nodeRepo    *pg.NodeRepository
+	kbRepo      *pg.KnowledgeBaseRepository
+	ragRepo     *mq.RAGRepository
+	qaPair      *QAPairUsecase
+	logger      *log.Logger
+}
+
+func NewReindexUsecase(reindexRepo *pg.ReindexRepository, nodeRepo *pg.NodeRepository, kbRepo *pg.KnowledgeBaseRepository, ragRepo *mq.RAGRepository, qaPair *QAPairUsecase, logger *log.Logger) *ReindexUsecase {
+	return &ReindexUsecase{
+		reindexRepo: reindexRepo,
+		nodeRepo:    nodeRepo,
+		kbRepo:      kbRepo,
+		ragRepo:     ragRepo,
+		qaPair:      qaPair,
+		logger:      logger.WithModule("usecase.reindex"),
+	}
+}
+
+// CreateJob creates a re-index job of all knowledge bases, unfinished jobs are cancelled.
+// The nodes are dispatched by Run in the consumer.
+func (u *ReindexUsecase) CreateJob(ctx context.Context, embeddingModel string) (*domain.ReindexJob, error) {
+	kbList, err := u.kbRepo.GetKnowledgeBaseList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get knowledge base list failed: %w", err)
+	}
+	job := &domain.ReindexJob{
+		ID:             uuid.New().String(),
+		Status:         domain.ReindexJobStatusRunning,
+		EmbeddingModel: embeddingModel,
+		RateLimit:      domain.DefaultReindexRateLimit,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	kbs := make([]*domain.ReindexJobKB, 0, len(kbList))
+	for _, kb := range kbList {
+		total, err := u.nodeRepo.CountReleasedNodes(ctx, kb.ID)
+		if err != nil {
+			return nil, fmt.Errorf("count released nodes failed: %w", err)
+		}
+		kbs = append(kbs, &domain.ReindexJobKB{
+			JobID:     job.ID,
+			KBID:      kb.ID,
+			Total:     int(total),
+			UpdatedAt: time.Now(),
+		})
+	}
+	if err := u.reindexRepo.CreateJob(ctx, job, kbs); err != nil {
+		return nil, fmt.Errorf("create reindex job failed: %w", err)
+	}
+	u.logger.Info("reindex job created", log.String("job_id", job.ID), log.Int("kb_count", len(kbs)))
+	return job, nil
+}
+
+func (u *ReindexUsecase) GetJob(ctx context.Context, id string) (*v1.ReindexJobResp, error) {
+	var job *domain.ReindexJob
+	var err error
+	if id == "" {
+		job, err = u.reindexRepo.GetLatestJob(ctx)
+	} else {
+		job, err = u.reindexRepo.GetJobByID(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	kbs, err := u.reindexRepo.GetJobKBs(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+	kbList, err := u.kbRepo.GetKnowledgeBaseList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	kbNames := lo.SliceToMap(kbList, func(kb *domain.KnowledgeBaseListItem) (string, string) {
+		return kb.ID, kb.Name
+	})
+
+	resp := &v1.ReindexJobResp{
+		ReindexJob: job,
+		KBs:        make([]*v1.ReindexJobKBResp, 0, len(kbs)),
+	}
+	for _, kb := range kbs {
+		resp.Total += kb.Total
+		resp.Dispatched += kb.Dispatched
+		resp.Succeeded += kb.Succeeded
+		resp.Failed += kb.Failed
+		resp.KBs = append(resp.KBs, &v1.ReindexJobKBResp{
+			ReindexJobKB: kb,
+			KBName:       kbNames[kb.KBID],
+			Finished:     kb.Finished(),
+		})
+	}
+	return resp, nil
+}
+
+func (u *ReindexUsecase) PauseJob(ctx context.Context, id string) error {
+	return u.updateJobStatus(ctx, id, []domain.ReindexJobStatus{domain.ReindexJobStatusRunning}, domain.ReindexJobStatusPaused)
+}
+
+func (u *ReindexUsecase) ResumeJob(ctx context.Context, id string) error {
+	return u.updateJobStatus(ctx, id, []domain.ReindexJobStatus{domain.ReindexJobStatusPaused}, domain.ReindexJobStatusRunning)
+}
+
+func (u *ReindexUsecase) CancelJob(ctx context.Context, id string) error {
+	return u.updateJobStatus(ctx, id, []domain.ReindexJobStatus{domain.ReindexJobStatusRunning, domain.ReindexJobStatusPaused}, domain.ReindexJobStatusCancelled)
+}
+
+func (u *ReindexUsecase) updateJobStatus(ctx context.Context, id string, from []domain.ReindexJobStatus, status domain.ReindexJobStatus) error {
+	ok, err := u.reindexRepo.UpdateJobStatus(ctx, id, from, status)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("reindex job %s can not be %s", id, status)
+	}
+	return nil
+}
+
+func (u *ReindexUsecase) UpdateRateLimit(ctx context.Context, id string, rateLimit int) error {
+	return u.reindexRepo.UpdateJobRateLimit(ctx, id, rateLimit)
+}
+
+// RecordProgress records the result of a node dispatched by a re-index job
+func (u *ReindexUsecase) RecordProgress(ctx context.Context, req *domain.NodeReleaseVectorRequest, success bool) {
+	if req.ReindexJobID == "" {
+		return
+	}
+	if err := u.reindexRepo.IncrJobKBProgress(ctx, req.ReindexJobID, req.KBID, success); err != nil {
+		u.logger.Error("record reindex progress failed", log.String("job_id", req.ReindexJobID), log.Error(err))
+	}
+}
+
+// Run dispatches the nodes of running jobs until ctx is done. Progress is
+// checkpointed per kb, so a restarted consumer picks up where it stopped.
+func (u *ReindexUsecase) Run(ctx context.Context) {
+	ticker := time.NewTicker(reindexTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			jobs, err := u.reindexRepo.GetJobsByStatus(ctx, domain.ReindexJobStatusRunning)
+			if err != nil {
+				u.logger.Error("get running reindex jobs failed", log.Error(err))
+				continue
+			}
+			for _, job := range jobs {
+				if err := u.dispatch(ctx, job); err != nil {
+					u.logger.Error("dispatch reindex job failed", log.String("job_id", job.ID), log.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// dispatch sends at most one tick worth of nodes of the job
+func (u *ReindexUsecase) dispatch(ctx context.Context, job *domain.ReindexJob) error {
+	kbs, err := u.reindexRepo.GetJobKBs(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+	budget := int(float64(job.GetRateLimit()) * reindexTickInterval.Seconds())
+	for _, kb := range kbs {
+		if kb.Exhausted || budget <= 0 {
+			continue
+		}
+		// qa pairs are matched by embedding as well, they are re-embedded
+		// before the first nodes of the kb are dispatched
+		if kb.Cursor == "" && kb.Dispatched == 0 {
+			if err := u.qaPair.ReembedKB(ctx, kb.KBID); err != nil {
+				u.logger.Error("re-embed qa pairs failed", log.String("job_id", job.ID), log.String("kb_id", kb.KBID), log.Error(err))
+			}
+		}
+		releases, err := u.nodeRepo.GetLatestNodeReleasesAfter(ctx, kb.KBID, kb.Cursor, budget)
+		if err != nil {
+			return err
+		}
+		for _, release := range releases {
+			if err := u.ragRepo.AsyncUpdateNodeReleaseVector(ctx, []*domain.NodeReleaseVectorRequest{
+				{
+					KBID:          release.KBID,
+					NodeReleaseID: release.ID,
+					Action:        "upsert",
+					ReindexJobID:  job.ID,
+				},
+			}); err != nil {
+				return err
+			}
+		}
+		cursor := kb.Cursor
+		if len(releases) > 0 {
+			cursor = releases[len(releases)-1].NodeID
+		}
+		exhausted := len(releases) < budget
+		if err := u.reindexRepo.AdvanceJobKB(ctx, job.ID, kb.KBID, cursor, len(releases), exhausted); err != nil {
+			return err
+		}
+		kb.Cursor = cursor
+		kb.Dispatched += len(releases)
+		kb.Exhausted = exhausted
+		budget -= len(releases)
+	}
+
+	// complete the job once every kb is done, or nothing moved for too long
+	finished, lastUpdate := true, job.UpdatedAt
+	for _, kb := range kbs {
+		if !kb.Exhausted {
+			return nil
+		}
+		finished = finished && kb.Finished()
+		if kb.UpdatedAt.After(lastUpdate) {
+			lastUpdate = kb.UpdatedAt
+		}
+	}
+	if !finished && time.Since(lastUpdate) < reindexStallTimeout {
+		return nil
+	}
+	if _, err := u.reindexRepo.UpdateJobStatus(ctx, job.ID, []domain.ReindexJobStatus{domain.ReindexJobStatusRunning}, domain.ReindexJobStatusCompleted); err != nil {
+		return err
+	}
+	u.logger.Info("reindex job completed", log.String("job_id", job.ID), log.Any("stalled", !finished))
+	return nil
+}
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+)
+
+// rerankCandidateTopK is the number of candidates fetched for reranking
+const rerankCandidateTopK = 30
+
+// getRerankModel returns the rerank model and top n when rerank is enabled
+// for the kb, nil otherwise
+func (u *LLMUsecase) getRerankModel(ctx context.Context, kbID string) (*domain.Model, int) {
+	if kbID == "" {
+		return nil, 0
+	}
+	setting, err := u.settingRepo.GetRerankSetting(ctx, kbID)
+	if err != nil {
+		u.logger.Warn("get rerank setting failed, skip rerank", log.String("kb_id", kbID), log.Error(err))
+		return nil, 0
+	}
+	if !setting.Enabled {
+		return nil, 0
+	}
+	model, err := u.modelUsecase.GetRerankModel(ctx)
+	if err != nil {
+		u.logger.Warn("get rerank model failed, skip rerank", log.String("kb_id", kbID), log.Error(err))
+		return nil, 0
+	}
+	return model, setting.TopN
+}
+
+// rerankNodes rescores all chunks of the ranked nodes with the rerank model
+// and keeps the topN chunks, nodes are reordered by their best chunk
+func (u *LLMUsecase) rerankNodes(ctx context.Context, model *domain.Model, query string, rankedNodes []*domain.RankedNodeChunks, topN int) ([]*domain.RankedNodeChunks, error) {
+	type chunkRef struct {
+		node  *domain.RankedNodeChunks
+		chunk *domain.NodeContentChunk
+	}
+	refs := make([]chunkRef, 0)
+	documents := make([]string, 0)
+	for _, node := range rankedNodes {
+		for _, chunk := range node.Chunks {
+			refs = append(refs, chunkRef{node: node, chunk: chunk})
+			documents = append(documents, fmt.Sprintf("%s\n%s", node.SourceName(), chunk.Content))
+		}
+	}
+	if len(documents) == 0 {
+		return rankedNodes, nil
+	}
+	results, err := u.reranker.Rerank(ctx, model, query, documents, topN)
+	if err != nil {
+		return nil, err
+	}
+	u.logger.Info("rerank chunks", log.Int("candidate_count", len(documents)), log.Int("result_count", len(results)))
+
+	// the chunks of a node and of the files linked from it are separate
+	// sources, as are nodes of different kbs
+	type nodeKey struct {
+		kbID       string
+		nodeID     string
+		attachment string
+	}
+	reranked := make([]*domain.RankedNodeChunks, 0)
+	nodeMap := make(map[nodeKey]*domain.RankedNodeChunks)
+	for _, result := range results[:min(len(results), topN)] {
+		ref := refs[result.Index]
+		score := result.Score
+		ref.chunk.RerankScore = &score
+		key := nodeKey{kbID: ref.node.KBID, nodeID: ref.node.NodeID, attachment: ref.node.Attachment}
+		node, ok := nodeMap[key]
+		if !ok {
+			n := *ref.node
+			n.Chunks = nil
+			n.RerankScore = &score // results are sorted, the first chunk is the best
+			node = &n
+			nodeMap[key] = node
+			reranked = append(reranked, node)
+		}
+		node.Chunks = append(node.Chunks, ref.chunk)
+	}
+	return reranked, nil
+}
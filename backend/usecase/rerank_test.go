@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+	"github.com/chaitin/panda-wiki/pkg/rerank"
+)
+
+// newTestRerankModel serves a rerank endpoint scoring the documents with
+// scores, in the order they are sent
+func newTestRerankModel(t *testing.T, scores []float64) *domain.Model {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Documents []string `json:"documents"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Len(t, req.Documents, len(scores))
+		results := make([]rerank.Result, 0, len(scores))
+		for i, score := range scores {
+			results = append(results, rerank.Result{Index: i, Score: score})
+		}
+		sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]any{"results": results}))
+	}))
+	t.Cleanup(server.Close)
+	return &domain.Model{Model: "rerank", BaseURL: server.URL}
+}
+
+func newTestLLMUsecase() *LLMUsecase {
+	return &LLMUsecase{
+		reranker: rerank.NewClient(),
+		logger:   &log.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))},
+	}
+}
+
+func TestRerankNodesKeepsAttachments(t *testing.T) {
+	node := &domain.RankedNodeChunks{
+		KBID:          "kb",
+		NodeID:        "node",
+		NodeName:      "Node X",
+		NodePathNames: []string{"Docs"},
+		Chunks:        []*domain.NodeContentChunk{{ID: "n1", Content: "node chunk"}},
+	}
+	attachment := &domain.RankedNodeChunks{
+		KBID:       "kb",
+		NodeID:     "node",
+		NodeName:   "Node X",
+		Attachment: "Manual.pdf",
+		Chunks:     []*domain.NodeContentChunk{{ID: "a1", Content: "manual chunk"}},
+	}
+	u := newTestLLMUsecase()
+	model := newTestRerankModel(t, []float64{0.2, 0.9})
+
+	reranked, err := u.rerankNodes(t.Context(), model, "question", []*domain.RankedNodeChunks{node, attachment}, 10)
+	require.NoError(t, err)
+	require.Len(t, reranked, 2)
+
+	// the attachment scored best and stays its own source
+	assert.Equal(t, "Manual.pdf", reranked[0].Attachment)
+	assert.Equal(t, domain.AttachmentSourceName("Manual.pdf", "Node X"), reranked[0].SourceName())
+	assert.Equal(t, "a1", reranked[0].Chunks[0].ID)
+	assert.InDelta(t, 0.9, *reranked[0].RerankScore, 1e-9)
+
+	assert.Empty(t, reranked[1].Attachment)
+	assert.Equal(t, []string{"Docs"}, reranked[1].NodePathNames)
+	assert.Equal(t, "n1", reranked[1].Chunks[0].ID)
+	assert.InDelta(t, 0.2, *reranked[1].RerankScore, 1e-9)
+}
+
+func TestRerankNodesTopN(t *testing.T) {
+	node := &domain.RankedNodeChunks{
+		NodeID: "node",
+		Chunks: []*domain.NodeContentChunk{{ID: "1"}, {ID: "2"}, {ID: "3"}},
+	}
+	u := newTestLLMUsecase()
+	model := newTestRerankModel(t, []float64{0.1, 0.8, 0.5})
+
+	reranked, err := u.rerankNodes(t.Context(), model, "question", []*domain.RankedNodeChunks{node}, 2)
+	require.NoError(t, err)
+	require.Len(t, reranked, 1)
+	assert.Equal(t, []string{"2", "3"}, []string{reranked[0].Chunks[0].ID, reranked[0].Chunks[1].ID})
+}
@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/samber/lo"
+
+	v1 "github.com/chaitin/panda-wiki/api/kb/v1"
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/repo/pg"
+)
+
+// RetrievalTrace collects the intermediate results of GetRankNodes
+type RetrievalTrace struct {
+	RewrittenQuery string
+	VectorChunks   []*domain.NodeContentChunk
+	KeywordHits    []*pg.NodeReleaseKeywordHit
+	FusionScores   map[string]float64         // by doc id
+	BudgetDropped  []*domain.RankedNodeChunks // chunks left out by the context budget
+}
+
+// DebugRetrieval runs retrieval and prompt building like a chat would,
+// without calling the model
+func (u *LLMUsecase) DebugRetrieval(ctx context.Context, req *v1.RetrievalDebugReq) (*v1.RetrievalDebugResp, error) {
+	appType := req.AppType
+	if appType == 0 {
+		appType = domain.AppTypeWeb
+	}
+	app, err := u.appRepo.GetOrCreateAppByKBIDAndType(ctx, req.KBId, appType)
+	if err != nil {
+		return nil, fmt.Errorf("get app failed: %w", err)
+	}
+	retrieval := app.Settings.RetrievalSettings
+	// the prompt is trimmed to the context of the models the chat would use
+	models, err := u.modelUsecase.GetChatModels(ctx, req.KBId, app.Settings.ChatModelSetting)
+	if err != nil {
+		return nil, fmt.Errorf("get chat models failed: %w", err)
+	}
+
+	// all groups of the kb stand for a user who may read everything
+	allGroupIDs, err := u.authRepo.GetAuthGroupIdsByKBID(ctx, req.KBId)
+	if err != nil {
+		return nil, fmt.Errorf("get auth groups failed: %w", err)
+	}
+	groupIDs := req.AuthGroupIDs
+	if groupIDs == nil {
+		groupIDs = allGroupIDs
+	}
+
+	historyMessages := make([]*schema.Message, 0, len(req.History)+1)
+	for _, msg := range req.History {
+		historyMessages = append(historyMessages, &schema.Message{
+			Role:    schema.RoleType(msg.Role),
+			Content: msg.Content,
+		})
+	}
+	historyMessages = append(historyMessages, schema.UserMessage(req.Question))
+
+	trace := &RetrievalTrace{}
+	messages, rankedNodes, err := u.buildRAGMessages(ctx, ragMessagesRequest{
+		KBID:            req.KBId,
+		HistoryMessages: historyMessages,
+		GroupIDs:        groupIDs,
+		Tags:            req.Tags,
+		Retrieval:       retrieval,
+		AuthUserID:      req.AuthUserID,
+		ContextLimit:    domain.ChatContextLimit(models),
+		Trace:           trace,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &v1.RetrievalDebugResp{
+		RewrittenQuery: trace.RewrittenQuery,
+		Settings:       retrieval,
+		Candidates:     u.traceChunks(trace),
+		Dropped:        []*v1.RetrievalDebugChunk{},
+		BudgetDropped:  make([]*v1.RetrievalDebugChunk, 0),
+		Documents:      make([]*v1.RetrievalDebugDoc, 0, len(rankedNodes)),
+		Prompt: lo.Map(messages, func(msg *schema.Message, _ int) *v1.RetrievalDebugMessage {
+			return &v1.RetrievalDebugMessage{Role: string(msg.Role), Content: msg.Content}
+		}),
+		Citations: make([]domain.ChunkCitation, 0),
+	}
+
+	if req.AuthGroupIDs != nil {
+		// rerun without the permission of the groups, chunks only found
+		// there were dropped by permission filtering
+		baseline := &RetrievalTrace{}
+		kb, err := u.kbRepo.GetKnowledgeBaseByID(ctx, req.KBId)
+		if err != nil {
+			return nil, fmt.Errorf("get kb failed: %w", err)
+		}
+		if _, _, err := u.GetRankNodes(ctx, GetRankNodesRequest{
+			KBID:                req.KBId,
+			DatasetID:           kb.DatasetID,
+			Question:            req.Question,
+			GroupIDs:            allGroupIDs,
+			Tags:                req.Tags,
+			TopK:                retrieval.GetTopK(),
+			SimilarityThreshold: retrieval.GetSimilarityThreshold(),
+			HistoryMessages:     historyMessages[:len(historyMessages)-1],
+			MaxChunksPerDoc:     retrieval.MaxChunksPerDoc,
+			Trace:               baseline,
+		}); err != nil {
+			return nil, fmt.Errorf("get baseline rank nodes failed: %w", err)
+		}
+		kept := lo.SliceToMap(resp.Candidates, func(chunk *v1.RetrievalDebugChunk) (string, struct{}) {
+			return chunk.Source + ":" + chunk.ChunkID + ":" + chunk.DocID, struct{}{}
+		})
+		for _, chunk := range u.traceChunks(baseline) {
+			if _, ok := kept[chunk.Source+":"+chunk.ChunkID+":"+chunk.DocID]; !ok {
+				resp.Dropped = append(resp.Dropped, chunk)
+			}
+		}
+	}
+
+	// mark the chunks given to the model and fill node names
+	selected := make(map[string]*domain.NodeContentChunk)
+	docNodeIDs := make(map[string]string)
+	for _, node := range rankedNodes {
+		for _, chunk := range node.Chunks {
+			selected[chunk.ID] = chunk
+			docNodeIDs[chunk.DocID] = node.NodeID
+		}
+		resp.Citations = append(resp.Citations, node.Citations()...)
+		var fusionScore float64
+		if len(node.Chunks) > 0 {
+			fusionScore = trace.FusionScores[node.Chunks[0].DocID]
+		}
+		resp.Documents = append(resp.Documents, &v1.RetrievalDebugDoc{
+			NodeID:      node.NodeID,
+			NodeName:    node.NodeName,
+			FusionScore: fusionScore,
+			RerankScore: node.RerankScore,
+			ChunkCount:  len(node.Chunks),
+		})
+	}
+	for _, node := range trace.BudgetDropped {
+		for _, chunk := range node.Chunks {
+			resp.BudgetDropped = append(resp.BudgetDropped, &v1.RetrievalDebugChunk{
+				Source:   "vector",
+				ChunkID:  chunk.ID,
+				DocID:    chunk.DocID,
+				NodeID:   node.NodeID,
+				NodeName: node.NodeName,
+				Seq:      chunk.Seq,
+				Content:  chunk.Content,
+				Score:    chunk.Score,
+				Rerank:   chunk.RerankScore,
+			})
+		}
+	}
+	for _, chunk := range resp.Candidates {
+		if picked, ok := selected[chunk.ChunkID]; ok && chunk.ChunkID != "" {
+			chunk.Selected = true
+			chunk.Rerank = picked.RerankScore
+		} else if chunk.Source == "keyword" {
+			_, chunk.Selected = docNodeIDs[chunk.DocID]
+		}
+	}
+	if err := u.fillDebugChunkNodes(ctx, append(resp.Candidates, resp.Dropped...)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (u *LLMUsecase) traceChunks(trace *RetrievalTrace) []*v1.RetrievalDebugChunk {
+	chunks := make([]*v1.RetrievalDebugChunk, 0, len(trace.VectorChunks)+len(trace.KeywordHits))
+	for _, chunk := range trace.VectorChunks {
+		chunks = append(chunks, &v1.RetrievalDebugChunk{
+			Source:  "vector",
+			ChunkID: chunk.ID,
+			DocID:   chunk.DocID,
+			Seq:     chunk.Seq,
+			Content: chunk.Content,
+			Score:   chunk.Score,
+		})
+	}
+	for _, hit := range trace.KeywordHits {
+		chunks = append(chunks, &v1.RetrievalDebugChunk{
+			Source:   "keyword",
+			DocID:    hit.DocID,
+			NodeID:   hit.NodeID,
+			NodeName: hit.Name,
+			Content:  keywordSnippet(hit.Content, trace.RewrittenQuery),
+			Score:    hit.Rank,
+		})
+	}
+	return chunks
+}
+
+func (u *LLMUsecase) fillDebugChunkNodes(ctx context.Context, chunks []*v1.RetrievalDebugChunk) error {
+	docIDs := lo.Uniq(lo.FilterMap(chunks, func(chunk *v1.RetrievalDebugChunk, _ int) (string, bool) {
+		return chunk.DocID, chunk.NodeID == ""
+	}))
+	if len(docIDs) == 0 {
+		return nil
+	}
+	docNodes, err := u.nodeRepo.GetNodeReleasesWithPathsByDocIDs(ctx, docIDs)
+	if err != nil {
+		return fmt.Errorf("get nodes by doc ids failed: %w", err)
+	}
+	for _, chunk := range chunks {
+		if node, ok := docNodes[chunk.DocID]; ok && chunk.NodeID == "" {
+			chunk.NodeID = node.NodeID
+			chunk.NodeName = node.Name
+		}
+	}
+	return nil
+}
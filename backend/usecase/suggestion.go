@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/chaitin/panda-wiki/domain"
+	"github.com/chaitin/panda-wiki/log"
+)
+
+// sendSuggestions sends the follow up questions of an answer after the done
+// event. Their tokens count toward the quota and the usage of the model.
+// Suggestions are best effort, a failure only skips the event.
+func (u *ChatUsecase) sendSuggestions(ctx context.Context, settings domain.SuggestionSettings, req *domain.ChatRequest, quotaUser, answer string, nodes []*domain.RankedNodeChunks, eventCh chan<- domain.SSEEvent) {
+	if !settings.Enabled || strings.TrimSpace(answer) == "" {
+		return
+	}
+	modelkitModel, err := req.ModelInfo.ToModelkitModel()
+	if err != nil {
+		u.logger.Warn("convert model for suggestions failed", log.Error(err))
+		return
+	}
+	chatModel, err := u.modelkit.GetChatModel(ctx, modelkitModel)
+	if err != nil {
+		u.logger.Warn("get chat model for suggestions failed", log.Error(err))
+		return
+	}
+	usage := schema.TokenUsage{}
+	suggestions, err := u.GenerateSuggestions(ctx, chatModel, req.Message, answer, nodes, settings.GetCount(), &usage)
+	// the tokens are spent even if the client left meanwhile
+	usageCtx := context.WithoutCancel(ctx)
+	u.addChatQuotaTokens(usageCtx, req.AppID, quotaUser, &usage)
+	if usage.TotalTokens > 0 {
+		if err := u.modelUsecase.UpdateUsage(usageCtx, req.ModelInfo.ID, &usage); err != nil {
+			u.logger.Warn("update model usage of suggestions failed", log.Error(err))
+		}
+	}
+	if err != nil {
+		u.logger.Warn("generate suggestions failed", log.Error(err))
+		return
+	}
+	if len(suggestions) > 0 {
+		eventCh <- domain.SSEEvent{Type: "suggestions", Suggestions: suggestions}
+	}
+}
+
+// GenerateSuggestions asks the chat model for questions the user may ask
+// next, based on the answer and the documents it was given. The tokens of the
+// model are added to usage.
+func (u *ChatUsecase) GenerateSuggestions(ctx context.Context, chatModel model.BaseChatModel, question, answer string, nodes []*domain.RankedNodeChunks, count int, usage *schema.TokenUsage) ([]string, error) {
+	var documents strings.Builder
+	for _, node := range nodes {
+		fmt.Fprintf(&documents, "- %s", node.SourceName())
+		if node.NodeSummary != "" {
+			fmt.Fprintf(&documents, "：%s", node.NodeSummary)
+		}
+		documents.WriteString("\n")
+	}
+	template := prompt.FromMessages(schema.GoTemplate,
+		schema.SystemMessage(domain.SuggestionPrompt),
+		schema.UserMessage(domain.SuggestionUserFormatter),
+	)
+	messages, err := template.Format(ctx, map[string]any{
+		"Count":     count,
+		"Question":  question,
+		"Answer":    u.llmUsecase.trimThinking(answer),
+		"Documents": documents.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := chatModel.Generate(ctx, messages)
+	if err != nil {
+		return nil, fmt.Errorf("generate failed: %w", err)
+	}
+	addResponseUsage(usage, resp)
+	return domain.ParseSuggestions(u.llmUsecase.trimThinking(resp.Content), question, count), nil
+}
+
+// FormatBotSuggestions renders the suggestions appended to bot replies,
+// linking each to the wiki of the kb
+func (u *ChatUsecase) FormatBotSuggestions(ctx context.Context, kbID string, suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	baseURL := ""
+	if kb, err := u.kbRepo.GetKnowledgeBaseByID(ctx, kbID); err != nil {
+		u.logger.Warn("get kb for suggestions failed", log.String("kb_id", kbID), log.Error(err))
+	} else {
+		baseURL = kb.AccessSettings.BaseURL
+	}
+	return domain.FormatSuggestions(suggestions, baseURL)
+}
@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSuggestionsCountsTokens(t *testing.T) {
+	u, _ := newTestChatUsecase(t)
+	usage := schema.TokenUsage{TotalTokens: 100}
+
+	suggestions, err := u.GenerateSuggestions(t.Context(), &fakeChatModel{
+		output: "1. How much is the pro plan?\n2. Does it have sso?",
+	}, "what plans are there", "there are free and pro plans", nil, 3, &usage)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"How much is the pro plan?", "Does it have sso?"}, suggestions)
+	assert.Equal(t, schema.TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 115}, usage)
+}
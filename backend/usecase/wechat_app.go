@@ -107,13 +107,21 @@ func (u *WechatAppUsecase) getQAFunc(kbID string, appType domain.AppType) bot.Ge
 		contentCh := make(chan string, 10)
 		go func() {
 			defer close(contentCh)
+			var suggestions []string
 			for event := range eventCh {
-				if event.Type == "done" || event.Type == "error" {
+				// suggestions follow the done event
+				if event.Type == "error" {
 					break
 				}
 				if event.Type == "data" {
 					contentCh <- event.Content
 				}
+				if event.Type == "suggestions" {
+					suggestions = event.Suggestions
+				}
+			}
+			if len(suggestions) > 0 {
+				contentCh <- u.chatUsecase.FormatBotSuggestions(ctx, kbID, suggestions)
 			}
 		}()
 		return contentCh, nil
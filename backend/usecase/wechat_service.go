@@ -39,7 +39,7 @@ func (u *WechatServiceUsecase) VerifyUrlWechatService(ctx context.Context, signa
 }
 
 func (u *WechatServiceUsecase) WechatService(ctx context.Context, msg *wechat_service.WeixinUserAskMsg, kbID string, WechatServiceConfig *wechat_service.WechatServiceConfig) error {
-	getQA := u.getQAFunc(kbID, domain.AppTypeWechatServiceBot)
+	getQA := u.getQAFunc(kbID, domain.AppTypeWechatServiceBot, WechatServiceConfig, msg.OpenKfId)
 	WechatServiceConfig.WeRepo = u.weRepo
 
 	err := WechatServiceConfig.Wechat(msg, getQA, u.AppUsecase.GetWebAppCopyright(ctx, kbID))
@@ -65,7 +65,9 @@ func (u *WechatServiceUsecase) NewWechatServiceConfig(ctx context.Context, kbID
 	)
 }
 
-func (u *WechatServiceUsecase) getQAFunc(kbID string, appType domain.AppType) bot.GetQAFun {
+// getQAFunc answers with the kb, questions handed off are transferred to a
+// human servicer of the wechat customer service
+func (u *WechatServiceUsecase) getQAFunc(kbID string, appType domain.AppType, cfg *wechat_service.WechatServiceConfig, openKfID string) bot.GetQAFun {
 	return func(ctx context.Context, msg string, info domain.ConversationInfo, ConversationID string) (chan string, error) {
 		auth, err := u.authRepo.GetAuthBySourceType(ctx, domain.AppTypeWechatServiceBot.ToSourceType())
 		if err != nil {
@@ -88,15 +90,42 @@ func (u *WechatServiceUsecase) getQAFunc(kbID string, appType domain.AppType) bo
 		contentCh := make(chan string, 10)
 		go func() {
 			defer close(contentCh)
+			var suggestions []string
 			for event := range eventCh {
-				if event.Type == "done" || event.Type == "error" {
+				// suggestions follow the done event
+				if event.Type == "error" {
 					break
 				}
 				if event.Type == "data" {
 					contentCh <- event.Content
 				}
+				if event.Type == "suggestions" {
+					suggestions = event.Suggestions
+				}
+				if event.Type == "handoff" {
+					u.transferToHuman(cfg, info.UserInfo.UserID, openKfID)
+				}
+			}
+			if len(suggestions) > 0 {
+				contentCh <- u.chatUsecase.FormatBotSuggestions(ctx, kbID, suggestions)
 			}
 		}()
 		return contentCh, nil
 	}
 }
+
+func (u *WechatServiceUsecase) transferToHuman(cfg *wechat_service.WechatServiceConfig, userID, openKfID string) {
+	token, err := cfg.GetAccessToken()
+	if err != nil {
+		u.logger.Error("get access token failed", log.Error(err))
+		return
+	}
+	transferred, err := cfg.TransferToHuman(token, userID, openKfID)
+	if err != nil {
+		u.logger.Error("transfer handoff to human failed", log.Error(err))
+		return
+	}
+	if !transferred {
+		u.logger.Info("no human available for handoff", log.String("open_kfid", openKfID))
+	}
+}
@@ -146,7 +146,7 @@ func (u *WecomUsecase) HandleMsg(ctx context.Context, kbID, signature, timestamp
 						}
 						return
 					}
-					u.SendQuestionToAI(conversationID, eventCh)
+					u.SendQuestionToAI(kbID, conversationID, eventCh)
 				}()
 			}
 		}
@@ -210,7 +210,7 @@ func (u *WecomUsecase) HandleMsg(ctx context.Context, kbID, signature, timestamp
 }
 
 // SendQuestionToAI processes AI response events and stores them in conversation state buffer
-func (u *WecomUsecase) SendQuestionToAI(conversationID string, eventCh <-chan domain.SSEEvent) {
+func (u *WecomUsecase) SendQuestionToAI(kbID, conversationID string, eventCh <-chan domain.SSEEvent) {
 	val, ok := domain.ConversationManager.Load(conversationID)
 	if !ok {
 		u.logger.Error("conversation not found in manager", log.String("conversation_id", conversationID))
@@ -227,21 +227,25 @@ func (u *WecomUsecase) SendQuestionToAI(conversationID string, eventCh <-chan do
 		u.logger.Info("AI response completed", log.String("conversation_id", conversationID))
 	}()
 
-	// Process AI response events
+	// Process AI response events, suggestions follow the done event
 	for event := range eventCh {
-		if event.Type == "done" || event.Type == "error" {
-			if event.Type == "error" {
-				u.logger.Error("AI response error", log.String("conversation_id", conversationID), log.String("error", event.Content))
-			}
+		if event.Type == "error" {
+			u.logger.Error("AI response error", log.String("conversation_id", conversationID), log.String("error", event.Content))
 			break
 		}
-		if event.Type == "data" {
-			state.Mutex.Lock()
-			if state.IsVisited {
-				state.NotificationChan <- event.Content // notify has new data
-			}
-			state.Buffer.WriteString(event.Content)
-			state.Mutex.Unlock()
+		content := event.Content
+		switch event.Type {
+		case "data":
+		case "suggestions":
+			content = u.chatUsecase.FormatBotSuggestions(context.Background(), kbID, event.Suggestions)
+		default:
+			continue
 		}
+		state.Mutex.Lock()
+		if state.IsVisited {
+			state.NotificationChan <- content // notify has new data
+		}
+		state.Buffer.WriteString(content)
+		state.Mutex.Unlock()
 	}
 }
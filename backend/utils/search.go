@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// MaxSearchTokens bounds the tokens indexed per document, postgres keeps
+// tsvector positions below 16384 anyway
+const MaxSearchTokens = 16000
+
+// HTMLToText extracts the visible text of html content, markdown and plain
+// text pass through unchanged
+func HTMLToText(content string) string {
+	var sb strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	skip := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return sb.String()
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			if tag := string(name); tag == "script" || tag == "style" {
+				skip++
+			}
+			sb.WriteByte(' ')
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if tag := string(name); (tag == "script" || tag == "style") && skip > 0 {
+				skip--
+			}
+			sb.WriteByte(' ')
+		case html.TextToken:
+			if skip == 0 {
+				sb.Write(tokenizer.Text())
+			}
+		}
+	}
+}
+
+// SearchTokens splits text into full-text search terms. Latin words and
+// numbers are kept whole and lowercased, CJK runs are split into overlapping
+// bigrams since they have no word boundaries (a single character run is kept
+// as is). The same function must be used for documents and queries.
+func SearchTokens(text string, limit int) []string {
+	tokens := make([]string, 0)
+	var (
+		word []rune
+		cjk  []rune
+	)
+	full := func() bool {
+		return limit > 0 && len(tokens) >= limit
+	}
+	flushWord := func() {
+		if len(word) > 0 && !full() {
+			tokens = append(tokens, strings.ToLower(string(word)))
+		}
+		word = word[:0]
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 && !full() {
+			tokens = append(tokens, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk) && !full(); i++ {
+			tokens = append(tokens, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range text {
+		if full() {
+			break
+		}
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		limit    int
+		expected []string
+	}{
+		{
+			name:     "latin words and codes",
+			text:     "Error ERR_1042: call GetUserInfo()",
+			expected: []string{"error", "err", "1042", "call", "getuserinfo"},
+		},
+		{
+			name:     "cjk bigrams",
+			text:     "知识库问答",
+			expected: []string{"知识", "识库", "库问", "问答"},
+		},
+		{
+			name:     "mixed",
+			text:     "安装 PandaWiki 的步骤",
+			expected: []string{"安装", "pandawiki", "的步", "步骤"},
+		},
+		{
+			name:     "single cjk character",
+			text:     "用 v2",
+			expected: []string{"用", "v2"},
+		},
+		{
+			name:     "limit",
+			text:     "a b c d",
+			limit:    2,
+			expected: []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SearchTokens(tt.text, tt.limit))
+		})
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	text := HTMLToText(`<h1>Title</h1><p>hello <b>world</b></p><script>var a = 1</script>`)
+	assert.Equal(t, []string{"title", "hello", "world"}, SearchTokens(text, 0))
+}
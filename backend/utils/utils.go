@@ -124,9 +124,11 @@ func decode(data []byte, encoding string) (string, error) {
 func GetHeaderMap(header string) map[string]string {
 	headerMap := make(map[string]string)
 	for _, h := range strings.Split(header, "\n") {
-		if key, value, ok := strings.Cut(h, "="); ok {
-			headerMap[key] = value
+		key, value, ok := strings.Cut(strings.TrimSpace(h), "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
 		}
+		headerMap[strings.TrimSpace(key)] = strings.TrimSpace(value)
 	}
 	return headerMap
 }